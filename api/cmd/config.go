@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v3"
+)
+
+func NewConfigCmd() cli.Command {
+	return cli.Command{
+		Name:  "config",
+		Usage: "config related actions",
+		Subcommands: []cli.Command{
+			NewPrintCmd(),
+		},
+	}
+}
+
+func NewPrintCmd() cli.Command {
+	return cli.Command{
+		Name:  "print",
+		Usage: "print the config the server would run with, file and environment merged, secrets redacted",
+		Action: func(c *cli.Context) error {
+			provider, err := config.DefaultFileProvider()
+			if err != nil {
+				return err
+			}
+			cfg, err := config.NewConfig(provider)
+			if err != nil {
+				return err
+			}
+
+			enc := yaml.NewEncoder(os.Stdout)
+			enc.SetIndent(2)
+			if err := enc.Encode(cfg.Redacted()); err != nil {
+				return errors.Wrap(err, "print config")
+			}
+			return enc.Close()
+		},
+	}
+}
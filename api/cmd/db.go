@@ -7,19 +7,19 @@ import (
 	"github.com/urfave/cli"
 )
 
-func NewDbCmd(app *hexFwk.App) cli.Command {
+func NewDbCmd(initApp func() *hexFwk.App) cli.Command {
 	return cli.Command{
 		Name:    "database",
 		Aliases: []string{"db"},
 		Usage:   "database related actions",
 		Subcommands: []cli.Command{
-			NewMigrateCmd(app),
-			NewResetCmd(app),
+			NewMigrateCmd(initApp),
+			NewResetCmd(initApp),
 		},
 	}
 }
 
-func NewMigrateCmd(app *hexFwk.App) cli.Command {
+func NewMigrateCmd(initApp func() *hexFwk.App) cli.Command {
 	return cli.Command{
 		Name:  "migrate",
 		Usage: "execute migrations located in the ./migrations folder",
@@ -35,6 +35,7 @@ func NewMigrateCmd(app *hexFwk.App) cli.Command {
 			},
 		},
 		Action: func(c *cli.Context) error {
+			app := initApp()
 			migration := database.NewMigrationProcess(app.DB, app.Logger)
 
 			reset := c.Bool("reset")
@@ -56,11 +57,12 @@ func NewMigrateCmd(app *hexFwk.App) cli.Command {
 	}
 }
 
-func NewResetCmd(app *hexFwk.App) cli.Command {
+func NewResetCmd(initApp func() *hexFwk.App) cli.Command {
 	return cli.Command{
 		Name:  "reset",
 		Usage: "truncate connected database",
 		Action: func(c *cli.Context) error {
+			app := initApp()
 			migration := database.NewMigrationProcess(app.DB, app.Logger)
 			return performReset(migration, app)
 		},
@@ -3,27 +3,40 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/api/cmd"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/app"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
 	"github.com/urfave/cli"
 )
 
 func RunCLIApplication() {
 
-	app := app.MustInitializeApp()
-
 	cliApp := cli.NewApp()
 	cliApp.Name = "exchange-gateway"
 	cliApp.Description = "Command line utility for egw development"
+	cliApp.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "config",
+			Usage: "path of the config file, secrets/config.yaml by default",
+		},
+	}
+	// the config file has to be known before the app is initialized by the commands
+	cliApp.Before = func(c *cli.Context) error {
+		config.SetFile(c.GlobalString("config"))
+		return nil
+	}
 	cliApp.Commands = []cli.Command{
-		cmd.NewDbCmd(app),
+		// the app is only initialized by the commands needing it, so the config can be printed without a database
+		cmd.NewDbCmd(app.MustInitializeApp),
+		cmd.NewConfigCmd(),
 	}
 
 	err := cliApp.Run(os.Args)
 	if err != nil {
-		app.Logger.Error("failed running command", "err", err)
+		fmt.Fprintf(os.Stderr, "failed running command: %s\n", err)
 		os.Exit(1)
 	}
 
@@ -19,6 +19,7 @@ import (
 func envConfigProvider(cfg config.Config) config.Environment   { return cfg.Env }
 func httpConfigProvider(cfg config.Config) config.ServerConfig { return cfg.Http }
 func dbConfigProvider(cfg config.Config) config.DatabaseConfig { return cfg.Database }
+func logConfigProvider(cfg config.Config) config.LogConfig     { return cfg.Log }
 
 var ConfigSet = wire.NewSet(
 	config.FileProviderSet,
@@ -32,6 +33,7 @@ var TestConfigSet = wire.NewSet(
 
 var LoggerSet = wire.NewSet(
 	envConfigProvider,
+	logConfigProvider,
 	initializeLogger,
 )
 
@@ -92,8 +94,15 @@ func MustInitializeTestApp() *App {
 	return app
 }
 
-func initializeLogger(env config.Environment) log.Logger {
-	logger, err := log.NewLogger(env == config.EnvLocal)
+func initializeLogger(env config.Environment, cfg config.LogConfig) log.Logger {
+	format := cfg.Format
+	if format == "" {
+		format = log.FormatJSON
+		if env == config.EnvLocal {
+			format = log.FormatConsole
+		}
+	}
+	logger, err := log.NewLogger(cfg.Level, format)
 	if err != nil {
 		fmt.Println(errors.Wrap(err, "config logger"))
 		os.Exit(1)
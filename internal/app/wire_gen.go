@@ -21,13 +21,17 @@ import (
 
 // Injectors
 func InitializeApp() (*App, error) {
-	fileProvider := config.DefaultFileProvider()
+	fileProvider, err := config.DefaultFileProvider()
+	if err != nil {
+		return nil, err
+	}
 	configConfig, err := config.NewConfig(fileProvider)
 	if err != nil {
 		return nil, err
 	}
 	environment := envConfigProvider(configConfig)
-	logger := initializeLogger(environment)
+	logConfig := logConfigProvider(configConfig)
+	logger := initializeLogger(environment, logConfig)
 	db, err := initializeDatabase(configConfig)
 	if err != nil {
 		return nil, err
@@ -41,13 +45,17 @@ func InitializeApp() (*App, error) {
 }
 
 func InitializeTestApp() (*App, error) {
-	fileProvider := config.TestFileProvider()
+	fileProvider, err := config.TestFileProvider()
+	if err != nil {
+		return nil, err
+	}
 	configConfig, err := config.NewConfig(fileProvider)
 	if err != nil {
 		return nil, err
 	}
 	environment := envConfigProvider(configConfig)
-	logger := initializeLogger(environment)
+	logConfig := logConfigProvider(configConfig)
+	logger := initializeLogger(environment, logConfig)
 	db, err := initializeDatabase(configConfig)
 	if err != nil {
 		return nil, err
@@ -69,12 +77,15 @@ func httpConfigProvider(cfg config.Config) config.ServerConfig { return cfg.Http
 
 func dbConfigProvider(cfg config.Config) config.DatabaseConfig { return cfg.Database }
 
+func logConfigProvider(cfg config.Config) config.LogConfig { return cfg.Log }
+
 var ConfigSet = wire.NewSet(config.FileProviderSet, config.NewConfig)
 
 var TestConfigSet = wire.NewSet(config.TestFileProviderSet, config.NewConfig)
 
 var LoggerSet = wire.NewSet(
 	envConfigProvider,
+	logConfigProvider,
 	initializeLogger,
 )
 
@@ -112,8 +123,15 @@ func MustInitializeTestApp() *App {
 	return app
 }
 
-func initializeLogger(env config.Environment) log.Logger {
-	logger, err := log.NewLogger(env == config.EnvLocal)
+func initializeLogger(env config.Environment, cfg config.LogConfig) log.Logger {
+	format := cfg.Format
+	if format == "" {
+		format = log.FormatJSON
+		if env == config.EnvLocal {
+			format = log.FormatConsole
+		}
+	}
+	logger, err := log.NewLogger(cfg.Level, format)
 	if err != nil {
 		fmt.Println(errors.Wrap(err, "config logger"))
 		os.Exit(1)
@@ -0,0 +1,148 @@
+// Package cache keeps the catalog reads, the products and categories, so they don't hit the database every time
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/metrics"
+)
+
+const (
+	DriverMemory = "memory"
+	DriverNone   = "none"
+)
+
+const (
+	// How long the reads are kept, when not configured
+	DefaultTTL = time.Minute
+	// How many entries the memory cache keeps, when not configured
+	DefaultSize = 10000
+)
+
+// Creates the cache chosen by the config, nil when the reads aren't cached
+func NewCache(cfg config.CacheConfig) (ports.Cache, error) {
+	switch cfg.Driver {
+	case DriverMemory:
+		size := cfg.Size
+		if size == 0 {
+			size = DefaultSize
+		}
+		return NewMemoryCache(size), nil
+	case DriverNone, "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown cache driver %q", cfg.Driver)
+}
+
+// Whether the reads made with the context skip the cache: the ones in a transaction, which may see its
+// uncommitted writes, and the ones which must see the latest writes
+func bypass(ctx context.Context) bool {
+	_, inTx := database.FromContext(ctx)
+	return inTx || database.IsPrimary(ctx)
+}
+
+// Reads through a cache, the values encoded as JSON
+// The failures of the cache are logged, the reads then go to the database
+type readThrough struct {
+	cache ports.Cache
+	ttl   time.Duration
+	// name of the cache in the metrics
+	name  string
+	loads group
+	// generation is bumped by every invalidation, a load only keeps its value when none happened since it started
+	mu         sync.Mutex
+	generation uint64
+}
+
+func newReadThrough(cache ports.Cache, ttl time.Duration, name string) *readThrough {
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	return &readThrough{cache: cache, ttl: ttl, name: name}
+}
+
+// Decodes the value of the key into dest, loading and keeping it when it isn't there
+// The loads of a key made at the same time share a single call to load, made on the primary so
+// a replica lagging behind isn't kept for the whole ttl
+func (r *readThrough) get(ctx context.Context, key string, dest interface{}, load func(ctx context.Context) (interface{}, error)) error {
+	if r.lookup(ctx, key, dest) {
+		return nil
+	}
+	value, err := r.loads.do(key, func() ([]byte, error) {
+		generation := r.current()
+		loaded, err := load(database.WithPrimary(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return r.set(ctx, key, loaded, generation)
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(value, dest)
+}
+
+// Decodes the value of the key into dest, returns whether it was there
+func (r *readThrough) lookup(ctx context.Context, key string, dest interface{}) bool {
+	value, ok, err := r.cache.Get(ctx, key)
+	if err != nil {
+		log.FromContext(ctx).Warn("cache read failed", "key", key, "error", err)
+	}
+	if ok {
+		ok = json.Unmarshal(value, dest) == nil
+	}
+	if ok {
+		metrics.CacheRequests.WithLabelValues(r.name, metrics.CacheHit).Inc()
+	} else {
+		metrics.CacheRequests.WithLabelValues(r.name, metrics.CacheMiss).Inc()
+	}
+	return ok
+}
+
+// Generation of the cache, to be given to set with the values loaded from now on
+func (r *readThrough) current() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generation
+}
+
+// Keeps the value of the key, loaded at the generation, returns it encoded
+// The value isn't kept when the cache was invalidated in the meantime, as it may be the previous one
+func (r *readThrough) set(ctx context.Context, key string, value interface{}, generation uint64) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation != generation {
+		return encoded, nil
+	}
+	if err := r.cache.Set(ctx, key, encoded, r.ttl); err != nil {
+		log.FromContext(ctx).Warn("cache write failed", "key", key, "error", err)
+	}
+	return encoded, nil
+}
+
+// Removes the keys once the transaction of the context is committed, so the reads made in the meantime
+// by others, which still see the previous values, don't keep them again
+// The loads started before the commit don't keep their values either, which may be the previous ones
+func (r *readThrough) invalidate(ctx context.Context, keys ...string) {
+	database.AfterCommit(ctx, func() {
+		// bumped before the delete: the values kept until then are deleted, the loads ending later see the new generation
+		r.mu.Lock()
+		r.generation++
+		r.mu.Unlock()
+		if err := r.cache.Delete(ctx, keys...); err != nil {
+			log.FromContext(ctx).Warn("cache invalidation failed", "keys", keys, "error", err)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+)
+
+var _ ports.CategoryRepo = (*CategoryRepo)(nil)
+
+const categoriesKey = "catalog:categories"
+
+func categoryKey(id int64) string {
+	return "catalog:category:" + strconv.FormatInt(id, 10)
+}
+
+// CategoryRepo caches the category reads of the repository it wraps, and drops them when they're written
+type CategoryRepo struct {
+	next  ports.CategoryRepo
+	cache *readThrough
+}
+
+func NewCategoryRepo(next ports.CategoryRepo, cache ports.Cache, ttl time.Duration) *CategoryRepo {
+	return &CategoryRepo{
+		next:  next,
+		cache: newReadThrough(cache, ttl, "categories"),
+	}
+}
+
+func (r *CategoryRepo) GetAllCategories(ctx context.Context) (*[]domain.Category, error) {
+	if bypass(ctx) {
+		return r.next.GetAllCategories(ctx)
+	}
+	var categories []domain.Category
+	err := r.cache.get(ctx, categoriesKey, &categories, func(ctx context.Context) (interface{}, error) {
+		return r.next.GetAllCategories(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &categories, nil
+}
+
+func (r *CategoryRepo) FindCategoryById(ctx context.Context, id int64) (*domain.Category, error) {
+	if bypass(ctx) {
+		return r.next.FindCategoryById(ctx, id)
+	}
+	var category domain.Category
+	err := r.cache.get(ctx, categoryKey(id), &category, func(ctx context.Context) (interface{}, error) {
+		return r.next.FindCategoryById(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *CategoryRepo) InsertCategory(ctx context.Context, category *domain.Category) (int64, error) {
+	id, err := r.next.InsertCategory(ctx, category)
+	if err == nil {
+		r.cache.invalidate(ctx, categoriesKey)
+	}
+	return id, err
+}
+
+func (r *CategoryRepo) DeleteCategory(ctx context.Context, id int64, version int) (int64, error) {
+	rows, err := r.next.DeleteCategory(ctx, id, version)
+	if err == nil {
+		r.cache.invalidate(ctx, categoryKey(id), categoriesKey)
+	}
+	return rows, err
+}
+
+func (r *CategoryRepo) UpdateCategory(ctx context.Context, category *domain.Category, id int64) (int64, error) {
+	rows, err := r.next.UpdateCategory(ctx, category, id)
+	if err == nil {
+		r.cache.invalidate(ctx, categoryKey(id), categoriesKey)
+	}
+	return rows, err
+}
+
+func (r *CategoryRepo) PatchCategory(ctx context.Context, id int64, patch *domain.CategoryPatch) (int64, error) {
+	rows, err := r.next.PatchCategory(ctx, id, patch)
+	if err == nil {
+		r.cache.invalidate(ctx, categoryKey(id), categoriesKey)
+	}
+	return rows, err
+}
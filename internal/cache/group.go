@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"sync"
+)
+
+// Makes the loads of a key made at the same time share the first one, so when a value expires the
+// requests waiting for it don't all go to the database
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done  sync.WaitGroup
+	value []byte
+	err   error
+}
+
+// Calls fn, unless it's already being called for the key, in which case its result is waited for
+func (g *group) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call{}
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.done.Wait()
+		return c.value, c.err
+	}
+	c := &call{}
+	c.done.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.done.Done()
+	}()
+	c.value, c.err = fn()
+	return c.value, c.err
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+)
+
+var _ ports.Cache = (*MemoryCache)(nil)
+
+// MemoryCache keeps the values in process, dropping the least recently used when full
+// Each instance of the server has its own, the changes made by the others are seen once the values expire
+type MemoryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// most recently used first
+	recent *list.List
+	now    func() time.Time
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// Creates a cache keeping up to size values
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		recent:  list.New(),
+		now:     time.Now,
+	}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+	c.recent.MoveToFront(el)
+	return e.value, true, nil
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry)
+		e.value, e.expires = value, expires
+		c.recent.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.recent.PushFront(&entry{key: key, value: value, expires: expires})
+	for c.recent.Len() > c.size {
+		c.remove(c.recent.Back())
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := c.entries[key]; ok {
+			c.remove(el)
+		}
+	}
+	return nil
+}
+
+// Number of values kept, expired ones included until they're looked up or pushed out
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recent.Len()
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	c.recent.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryCacheSuite struct {
+	suite.Suite
+	cache *MemoryCache
+	now   time.Time
+}
+
+func (suite *MemoryCacheSuite) SetupTest() {
+	suite.now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.cache = NewMemoryCache(2)
+	suite.cache.now = func() time.Time { return suite.now }
+}
+
+func TestMemoryCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryCacheSuite))
+}
+
+func (suite *MemoryCacheSuite) get(key string) string {
+	value, ok, err := suite.cache.Get(context.Background(), key)
+	assert.Nil(suite.T(), err)
+	if !ok {
+		return "<missing>"
+	}
+	return string(value)
+}
+
+func (suite *MemoryCacheSuite) TestLeastRecentlyUsedAreDropped() {
+	ctx := context.Background()
+	suite.cache.Set(ctx, "a", []byte("1"), time.Minute)
+	suite.cache.Set(ctx, "b", []byte("2"), time.Minute)
+	// a is now used more recently than b
+	suite.get("a")
+	suite.cache.Set(ctx, "c", []byte("3"), time.Minute)
+
+	assert.Equal(suite.T(), "1", suite.get("a"))
+	assert.Equal(suite.T(), "<missing>", suite.get("b"))
+	assert.Equal(suite.T(), "3", suite.get("c"))
+	assert.Equal(suite.T(), 2, suite.cache.Len())
+}
+
+func (suite *MemoryCacheSuite) TestValuesExpire() {
+	ctx := context.Background()
+	suite.cache.Set(ctx, "a", []byte("1"), time.Minute)
+	suite.cache.Set(ctx, "b", []byte("2"), 2*time.Minute)
+
+	suite.now = suite.now.Add(time.Minute)
+	assert.Equal(suite.T(), "<missing>", suite.get("a"))
+	assert.Equal(suite.T(), "2", suite.get("b"))
+	assert.Equal(suite.T(), 1, suite.cache.Len())
+
+	// setting again renews the value and its expiry
+	suite.cache.Set(ctx, "b", []byte("3"), time.Minute)
+	suite.now = suite.now.Add(59 * time.Second)
+	assert.Equal(suite.T(), "3", suite.get("b"))
+}
+
+func (suite *MemoryCacheSuite) TestDelete() {
+	ctx := context.Background()
+	suite.cache.Set(ctx, "a", []byte("1"), time.Minute)
+	suite.cache.Set(ctx, "b", []byte("2"), time.Minute)
+
+	assert.Nil(suite.T(), suite.cache.Delete(ctx, "a", "missing"))
+	assert.Equal(suite.T(), "<missing>", suite.get("a"))
+	assert.Equal(suite.T(), "2", suite.get("b"))
+}
@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+)
+
+var _ ports.ProductRepo = (*ProductRepo)(nil)
+
+const productsKey = "catalog:products"
+
+func productKey(id int64) string {
+	return "catalog:product:" + strconv.FormatInt(id, 10)
+}
+
+// ProductRepo caches the product reads of the repository it wraps, and drops them when they're written
+// The products are kept without their category, which is read from the categories, so a category
+// change doesn't have to drop every product in it
+type ProductRepo struct {
+	next       ports.ProductRepo
+	categories ports.CategoryRepo
+	cache      *readThrough
+}
+
+// The categories are best cached as well, with a CategoryRepo
+func NewProductRepo(next ports.ProductRepo, categories ports.CategoryRepo, cache ports.Cache, ttl time.Duration) *ProductRepo {
+	return &ProductRepo{
+		next:       next,
+		categories: categories,
+		cache:      newReadThrough(cache, ttl, "products"),
+	}
+}
+
+func (r *ProductRepo) GetAllProducts(ctx context.Context) (*[]domain.Product, error) {
+	if bypass(ctx) {
+		return r.next.GetAllProducts(ctx)
+	}
+	var products []domain.Product
+	err := r.cache.get(ctx, productsKey, &products, func(ctx context.Context) (interface{}, error) {
+		products, err := r.next.GetAllProducts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range *products {
+			withoutCategory(&(*products)[i])
+		}
+		return products, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]*domain.Product, len(products))
+	for i := range products {
+		refs[i] = &products[i]
+	}
+	if err := r.withCategories(ctx, refs); err != nil {
+		return nil, err
+	}
+	return &products, nil
+}
+
+func (r *ProductRepo) FindProductById(ctx context.Context, id int64) (*domain.Product, error) {
+	if bypass(ctx) {
+		return r.next.FindProductById(ctx, id)
+	}
+	var product domain.Product
+	err := r.cache.get(ctx, productKey(id), &product, func(ctx context.Context) (interface{}, error) {
+		product, err := r.next.FindProductById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		withoutCategory(product)
+		return product, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.withCategories(ctx, []*domain.Product{&product}); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// The products which aren't cached are read together, and kept one by one
+func (r *ProductRepo) FindProductsByIds(ctx context.Context, ids []int64) (map[int64]*domain.Product, error) {
+	if bypass(ctx) {
+		return r.next.FindProductsByIds(ctx, ids)
+	}
+	found := map[int64]*domain.Product{}
+	var missing []int64
+	for _, id := range ids {
+		var product domain.Product
+		if r.cache.lookup(ctx, productKey(id), &product) {
+			found[id] = &product
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		generation := r.cache.current()
+		loaded, err := r.next.FindProductsByIds(database.WithPrimary(ctx), missing)
+		if err != nil {
+			return nil, err
+		}
+		for id, product := range loaded {
+			withoutCategory(product)
+			if _, err := r.cache.set(ctx, productKey(id), product, generation); err != nil {
+				return nil, err
+			}
+			found[id] = product
+		}
+	}
+	refs := make([]*domain.Product, 0, len(found))
+	for _, product := range found {
+		refs = append(refs, product)
+	}
+	if err := r.withCategories(ctx, refs); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func (r *ProductRepo) InsertProduct(ctx context.Context, product *domain.Product) (int64, error) {
+	id, err := r.next.InsertProduct(ctx, product)
+	if err == nil {
+		r.cache.invalidate(ctx, productsKey)
+	}
+	return id, err
+}
+
+func (r *ProductRepo) DeleteProduct(ctx context.Context, id int64, version int) (int64, error) {
+	rows, err := r.next.DeleteProduct(ctx, id, version)
+	if err == nil {
+		r.cache.invalidate(ctx, productKey(id), productsKey)
+	}
+	return rows, err
+}
+
+func (r *ProductRepo) UpdateProduct(ctx context.Context, product *domain.Product, id int64) (int64, error) {
+	rows, err := r.next.UpdateProduct(ctx, product, id)
+	if err == nil {
+		r.cache.invalidate(ctx, productKey(id), productsKey)
+	}
+	return rows, err
+}
+
+func (r *ProductRepo) PatchProduct(ctx context.Context, id int64, patch *domain.ProductPatch) (int64, error) {
+	rows, err := r.next.PatchProduct(ctx, id, patch)
+	if err == nil {
+		r.cache.invalidate(ctx, productKey(id), productsKey)
+	}
+	return rows, err
+}
+
+func (r *ProductRepo) TakeFromStock(ctx context.Context, id int64, quantity int) (*domain.Product, error) {
+	product, err := r.next.TakeFromStock(ctx, id, quantity)
+	if err == nil {
+		r.cache.invalidate(ctx, productKey(id), productsKey)
+	}
+	return product, err
+}
+
+// Keeps only the id of the category of the product
+func withoutCategory(product *domain.Product) {
+	if product.Category != nil {
+		product.Category = &domain.Category{Id: product.Category.Id}
+	}
+}
+
+// Sets the categories of the products, from their id
+// A single product reads its own category, more read all of them at once
+func (r *ProductRepo) withCategories(ctx context.Context, products []*domain.Product) error {
+	var all map[int]domain.Category
+	if len(products) > 1 {
+		categories, err := r.categories.GetAllCategories(ctx)
+		if err != nil {
+			return err
+		}
+		all = map[int]domain.Category{}
+		for _, category := range *categories {
+			all[category.Id] = category
+		}
+	}
+	for _, product := range products {
+		if product.Category == nil {
+			continue
+		}
+		category, ok := all[product.Category.Id]
+		if !ok {
+			// created after the categories were cached
+			found, err := r.categories.FindCategoryById(ctx, int64(product.Category.Id))
+			if err != nil {
+				return err
+			}
+			category = *found
+		}
+		product.Category = &category
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// Catalog in memory, counting the reads which reach it
+type fakeCatalog struct {
+	mu         sync.Mutex
+	categories map[int64]domain.Category
+	products   map[int64]domain.Product
+	reads      int
+	// holds the reads until closed, when set
+	release chan struct{}
+}
+
+func (c *fakeCatalog) read() {
+	if c.release != nil {
+		<-c.release
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reads++
+}
+
+func (c *fakeCatalog) readCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reads
+}
+
+type fakeCategoryRepo struct{ *fakeCatalog }
+
+func (r fakeCategoryRepo) GetAllCategories(ctx context.Context) (*[]domain.Category, error) {
+	r.read()
+	var categories []domain.Category
+	for _, category := range r.categories {
+		categories = append(categories, category)
+	}
+	return &categories, nil
+}
+
+func (r fakeCategoryRepo) FindCategoryById(ctx context.Context, id int64) (*domain.Category, error) {
+	r.read()
+	category, ok := r.categories[id]
+	if !ok {
+		return nil, domain.NewNotFoundError("category")
+	}
+	return &category, nil
+}
+
+func (r fakeCategoryRepo) InsertCategory(ctx context.Context, category *domain.Category) (int64, error) {
+	category.Id = len(r.categories) + 1
+	r.categories[int64(category.Id)] = *category
+	return int64(category.Id), nil
+}
+
+func (r fakeCategoryRepo) DeleteCategory(ctx context.Context, id int64, version int) (int64, error) {
+	delete(r.categories, id)
+	return 1, nil
+}
+
+func (r fakeCategoryRepo) UpdateCategory(ctx context.Context, category *domain.Category, id int64) (int64, error) {
+	category.Id = int(id)
+	r.categories[id] = *category
+	return 1, nil
+}
+
+func (r fakeCategoryRepo) PatchCategory(ctx context.Context, id int64, patch *domain.CategoryPatch) (int64, error) {
+	category := r.categories[id]
+	category.Name = *patch.Name
+	r.categories[id] = category
+	return 1, nil
+}
+
+type fakeProductRepo struct{ *fakeCatalog }
+
+// Returns the product with its category, as the database repository does
+func (r fakeProductRepo) product(id int64) *domain.Product {
+	product := r.products[id]
+	category := r.categories[int64(product.Category.Id)]
+	product.Category = &category
+	return &product
+}
+
+func (r fakeProductRepo) GetAllProducts(ctx context.Context) (*[]domain.Product, error) {
+	r.read()
+	var products []domain.Product
+	for id := range r.products {
+		products = append(products, *r.product(id))
+	}
+	return &products, nil
+}
+
+func (r fakeProductRepo) FindProductById(ctx context.Context, id int64) (*domain.Product, error) {
+	r.read()
+	if _, ok := r.products[id]; !ok {
+		return nil, domain.NewNotFoundError("product")
+	}
+	return r.product(id), nil
+}
+
+func (r fakeProductRepo) FindProductsByIds(ctx context.Context, ids []int64) (map[int64]*domain.Product, error) {
+	r.read()
+	found := map[int64]*domain.Product{}
+	for _, id := range ids {
+		if _, ok := r.products[id]; ok {
+			found[id] = r.product(id)
+		}
+	}
+	return found, nil
+}
+
+func (r fakeProductRepo) InsertProduct(ctx context.Context, product *domain.Product) (int64, error) {
+	product.ProductId = len(r.products) + 1
+	r.products[int64(product.ProductId)] = *product
+	return int64(product.ProductId), nil
+}
+
+func (r fakeProductRepo) DeleteProduct(ctx context.Context, id int64, version int) (int64, error) {
+	delete(r.products, id)
+	return 1, nil
+}
+
+func (r fakeProductRepo) UpdateProduct(ctx context.Context, product *domain.Product, id int64) (int64, error) {
+	product.ProductId = int(id)
+	r.products[id] = *product
+	return 1, nil
+}
+
+func (r fakeProductRepo) PatchProduct(ctx context.Context, id int64, patch *domain.ProductPatch) (int64, error) {
+	product := r.products[id]
+	product.Quantity = *patch.Quantity
+	r.products[id] = product
+	return 1, nil
+}
+
+func (r fakeProductRepo) TakeFromStock(ctx context.Context, id int64, quantity int) (*domain.Product, error) {
+	product := r.products[id]
+	product.Quantity -= quantity
+	r.products[id] = product
+	return r.product(id), nil
+}
+
+type CatalogCacheSuite struct {
+	suite.Suite
+	catalog    *fakeCatalog
+	categories *CategoryRepo
+	products   *ProductRepo
+}
+
+func (suite *CatalogCacheSuite) SetupTest() {
+	suite.catalog = &fakeCatalog{
+		categories: map[int64]domain.Category{1: {Id: 1, Name: "books"}, 2: {Id: 2, Name: "games"}},
+		products: map[int64]domain.Product{
+			1: {ProductId: 1, Name: "novel", Price: 10, Quantity: 5, Category: &domain.Category{Id: 1}},
+			2: {ProductId: 2, Name: "chess", Price: 30, Quantity: 2, Category: &domain.Category{Id: 2}},
+		},
+	}
+	cache := NewMemoryCache(100)
+	suite.categories = NewCategoryRepo(fakeCategoryRepo{suite.catalog}, cache, time.Minute)
+	suite.products = NewProductRepo(fakeProductRepo{suite.catalog}, suite.categories, cache, time.Minute)
+}
+
+func TestCatalogCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(CatalogCacheSuite))
+}
+
+func (suite *CatalogCacheSuite) TestReadsAreCached() {
+	ctx := context.Background()
+	hits := testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("products", metrics.CacheHit))
+
+	for i := 0; i < 3; i++ {
+		product, err := suite.products.FindProductById(ctx, 1)
+		if err != nil {
+			suite.T().Fatal(err)
+		}
+		assert.Equal(suite.T(), "novel", product.Name)
+		assert.Equal(suite.T(), "books", product.Category.Name)
+	}
+	// the product and its category
+	assert.Equal(suite.T(), 2, suite.catalog.readCount())
+	assert.Equal(suite.T(), hits+2, testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("products", metrics.CacheHit)))
+
+	for i := 0; i < 3; i++ {
+		products, err := suite.products.GetAllProducts(ctx)
+		if err != nil {
+			suite.T().Fatal(err)
+		}
+		assert.Len(suite.T(), *products, 2)
+	}
+	// the products and all the categories
+	assert.Equal(suite.T(), 4, suite.catalog.readCount())
+
+	found, err := suite.products.FindProductsByIds(ctx, []int64{1, 2, 3})
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	assert.Len(suite.T(), found, 2)
+	assert.Equal(suite.T(), "games", found[2].Category.Name)
+	// only the product which wasn't cached on its own
+	assert.Equal(suite.T(), 5, suite.catalog.readCount())
+}
+
+func (suite *CatalogCacheSuite) TestCachedValuesAreCopies() {
+	ctx := context.Background()
+	product, _ := suite.products.FindProductById(ctx, 1)
+	product.Quantity = 0
+	product.Category.Name = "changed"
+
+	product, _ = suite.products.FindProductById(ctx, 1)
+	assert.Equal(suite.T(), 5, product.Quantity)
+	assert.Equal(suite.T(), "books", product.Category.Name)
+}
+
+func (suite *CatalogCacheSuite) TestWritesInvalidate() {
+	ctx := context.Background()
+	suite.products.GetAllProducts(ctx)
+	suite.products.FindProductById(ctx, 1)
+
+	quantity := 4
+	suite.products.PatchProduct(ctx, 1, &domain.ProductPatch{Quantity: &quantity})
+	product, _ := suite.products.FindProductById(ctx, 1)
+	assert.Equal(suite.T(), 4, product.Quantity)
+
+	// the products are kept without their category, so renaming it shows right away
+	name := "novels"
+	suite.categories.PatchCategory(ctx, 1, &domain.CategoryPatch{Name: &name})
+	product, _ = suite.products.FindProductById(ctx, 1)
+	assert.Equal(suite.T(), "novels", product.Category.Name)
+
+	suite.products.InsertProduct(ctx, &domain.Product{Name: "puzzle", Category: &domain.Category{Id: 2}})
+	products, _ := suite.products.GetAllProducts(ctx)
+	assert.Len(suite.T(), *products, 3)
+}
+
+func (suite *CatalogCacheSuite) TestReadsWhichMustBeFreshSkipTheCache() {
+	ctx := context.Background()
+	suite.products.FindProductById(ctx, 1)
+	reads := suite.catalog.readCount()
+
+	suite.products.FindProductById(database.WithPrimary(ctx), 1)
+	suite.categories.GetAllCategories(database.WithPrimary(ctx))
+	assert.Equal(suite.T(), reads+2, suite.catalog.readCount())
+}
+
+func (suite *CatalogCacheSuite) TestConcurrentMissesMakeOneRead() {
+	suite.catalog.release = make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			categories, err := suite.categories.GetAllCategories(context.Background())
+			assert.Nil(suite.T(), err)
+			assert.Len(suite.T(), *categories, 2)
+		}()
+	}
+	// let the goroutines reach the read
+	time.Sleep(50 * time.Millisecond)
+	close(suite.catalog.release)
+	wg.Wait()
+
+	assert.Equal(suite.T(), 1, suite.catalog.readCount())
+}
+
+func (suite *CatalogCacheSuite) TestNotFoundIsntCached() {
+	ctx := context.Background()
+	_, err := suite.products.FindProductById(ctx, 3)
+	var notFound *domain.NotFoundError
+	assert.ErrorAs(suite.T(), err, &notFound)
+
+	suite.products.InsertProduct(ctx, &domain.Product{Name: "puzzle", Category: &domain.Category{Id: 2}})
+	product, err := suite.products.FindProductById(ctx, 3)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "puzzle", product.Name)
+}
+
+func (suite *CatalogCacheSuite) TestLoadsStartedBeforeAnInvalidationAreNotKept() {
+	ctx := context.Background()
+	cache := newReadThrough(NewMemoryCache(10), time.Minute, "test")
+	var value string
+	err := cache.get(ctx, "key", &value, func(ctx context.Context) (interface{}, error) {
+		// the value is changed by another request while it's loaded
+		cache.invalidate(ctx, "key")
+		return "previous", nil
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "previous", value)
+
+	err = cache.get(ctx, "key", &value, func(ctx context.Context) (interface{}, error) {
+		return "current", nil
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "current", value)
+	assert.True(suite.T(), cache.lookup(ctx, "key", &value))
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
-	"fmt"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/google/wire"
 	"github.com/pkg/errors"
@@ -20,6 +22,17 @@ var TestFileProviderSet = wire.NewSet(
 	wire.Bind(new(Provider), new(*FileProvider)),
 )
 
+// Prefix of the environment variables overriding the config file, e.g. HEXFWK_DB_HOST for db.host
+const EnvPrefix = "HEXFWK"
+
+// File given with --config, loaded instead of the one found in secrets/
+var configFile string
+
+// Sets the file the default provider loads, instead of looking for config.yaml in secrets/
+func SetFile(path string) {
+	configFile = path
+}
+
 type Provider interface {
 	Load(cfg interface{}) (string, error)
 }
@@ -29,19 +42,22 @@ type FileProvider struct {
 	v viper.Viper
 }
 
-func DefaultFileProvider() *FileProvider {
+func DefaultFileProvider() (*FileProvider, error) {
+	if configFile != "" {
+		return NewFileProviderAt(configFile)
+	}
 	return NewFileProvider("config")
 }
 
-func TestFileProvider() *FileProvider {
+func TestFileProvider() (*FileProvider, error) {
 	return NewFileProvider("config_test")
 }
 
-// Creates a new file provider which reads vars from the given config file
-func NewFileProvider(configFile string) *FileProvider {
-	v := viper.New()
-	v.SetConfigName(configFile)
-	v.SetConfigType("yaml")
+// Creates a new file provider which reads vars from the given config file, looked up in secrets/
+// The file is optional, e.g. in containers the whole config can come from the environment
+func NewFileProvider(configName string) (*FileProvider, error) {
+	v := newViper()
+	v.SetConfigName(configName)
 	// when running from project root
 	v.AddConfigPath("secrets/")
 	// when running from subdirs (e.g. tests)
@@ -50,21 +66,90 @@ func NewFileProvider(configFile string) *FileProvider {
 
 	err := v.ReadInConfig()
 	if err != nil {
-		panic(fmt.Errorf("error reading viper config %w", err))
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, errors.Wrap(err, "read config")
+		}
 	}
 
-	fp := &FileProvider{
-		*v,
+	return &FileProvider{*v}, nil
+}
+
+// Creates a new file provider which reads vars from the file at the given path, which must exist
+func NewFileProviderAt(path string) (*FileProvider, error) {
+	v := newViper()
+	v.SetConfigFile(path)
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, errors.Wrapf(err, "read config %s", path)
 	}
 
-	return fp
+	return &FileProvider{*v}, nil
 }
 
-// Loads the config file into the provided interface
+func newViper() *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	return v
+}
+
+// Loads the config file into the provided interface, the environment variables taking precedence
 func (fp FileProvider) Load(cfg interface{}) (string, error) {
+	// viper only looks up the variables of the keys it knows, which are missing when they aren't in the file
+	bindEnvs(&fp.v, reflect.TypeOf(cfg), "")
+
 	err := fp.v.Unmarshal(cfg)
 	if err != nil {
 		return "", errors.Wrap(err, "error unmarshalling config")
 	}
 	return fp.v.ConfigFileUsed(), nil
 }
+
+// Binds every key of the struct, named as in the mapstructure tags, to its HEXFWK_ variable
+// The lists, like the rate limit groups, can only be set in the file
+func bindEnvs(v *viper.Viper, t reflect.Type, prefix string) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, squash := mapstructureName(field)
+		if name == "-" {
+			continue
+		}
+		key := prefix + name
+		switch {
+		case field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}):
+			if squash {
+				bindEnvs(v, field.Type, prefix)
+			} else {
+				bindEnvs(v, field.Type, key+".")
+			}
+		case field.Type.Kind() == reflect.Interface, field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			continue
+		default:
+			v.BindEnv(key)
+		}
+	}
+}
+
+// The key of the field, as mapstructure decodes it: the tag name, or the field name
+func mapstructureName(field reflect.StructField) (name string, squash bool) {
+	tag := field.Tag.Get("mapstructure")
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "squash" {
+			squash = true
+		}
+	}
+	if parts[0] != "" {
+		return parts[0], squash
+	}
+	return strings.ToLower(field.Name), squash
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"reflect"
+)
+
+// Value the secrets are replaced with by Redacted
+const redacted = "[REDACTED]"
+
+// Returns a copy of the config with the fields tagged `secret:"true"` replaced, so it can be printed or logged
+// The tag can be set on strings, and lists and maps of strings
+func (c Config) Redacted() Config {
+	redactValue(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redactValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if t.Field(i).Tag.Get("secret") == "true" {
+				redactSecret(field)
+				continue
+			}
+			redactValue(field)
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		// the copy of the config shares the elements with the original
+		elems := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(elems, v)
+		for i := 0; i < elems.Len(); i++ {
+			redactValue(elems.Index(i))
+		}
+		v.Set(elems)
+	}
+}
+
+// Replaces the secret, or each of the secrets of a list, unless empty
+func redactSecret(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() != "" {
+			v.SetString(redacted)
+		}
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		secrets := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(secrets, v)
+		for i := 0; i < secrets.Len(); i++ {
+			redactSecret(secrets.Index(i))
+		}
+		v.Set(secrets)
+	case reflect.Map:
+		if v.IsNil() || v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		// the keys, e.g. the names of the headers, are kept
+		secrets := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			secret := reflect.New(v.Type().Elem()).Elem()
+			secret.Set(iter.Value())
+			redactSecret(secret)
+			secrets.SetMapIndex(iter.Key(), secret)
+		}
+		v.Set(secrets)
+	}
+}
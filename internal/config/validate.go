@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+)
+
+// ValidationError lists every problem of the config, so they can all be fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Checks the required settings are there and the others within their range
+// The keys are reported as in the file, e.g. db.host, which is HEXFWK_DB_HOST in the environment
+func (c Config) Validate() error {
+	v := &validator{}
+
+	v.oneOf("env", string(c.Env), string(EnvLocal), string(EnvProd))
+	v.oneOf("log.level", c.Log.Level, "", "debug", "info", "warn", "error")
+	v.oneOf("log.format", c.Log.Format, "", log.FormatJSON, log.FormatConsole)
+	if c.SentryDSN != "" {
+		u, err := url.Parse(c.SentryDSN)
+		v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.User != nil, "sentry_dsn", "must be a DSN like https://<key>@<host>/<project>")
+	}
+
+	v.required("db.name", c.Database.Name)
+	v.required("db.host", c.Database.Host)
+	v.between("db.port", c.Database.Port, 1, 65535)
+	v.required("db.user", c.Database.User)
+	v.required("db.schema", c.Database.Schema)
+	v.oneOf("db.ssl_mode", c.Database.SSLMode, "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	v.notNegative("db.statement_timeout", c.Database.StatementTimeout)
+	v.check(c.Database.MaxOpenConns >= 0, "db.max_open_conns", "must not be negative")
+	v.check(c.Database.MaxIdleConns >= 0, "db.max_idle_conns", "must not be negative")
+	if c.Database.MaxOpenConns > 0 {
+		v.check(c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "db.max_idle_conns", "must not be over db.max_open_conns")
+	}
+	v.notNegative("db.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	v.notNegative("db.conn_max_idle_time", c.Database.ConnMaxIdleTime)
+	for i, replica := range c.Database.Replicas {
+		v.required(fmt.Sprintf("db.replicas[%d]", i), replica)
+	}
+
+	c.Http.validate(v, c.SentryDSN)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (c ServerConfig) validate(v *validator, sentryDSN string) {
+	v.between("http.port", c.Port, 1, 65535)
+	v.check(len(c.JWTSigningKey) >= 32, "http.jwt_signing_key", "is required, at least 32 characters")
+	v.notNegative("http.idempotency_ttl", c.IdempotencyTTL)
+	v.notNegative("http.shutdown_timeout", c.ShutdownTimeout)
+
+	v.check(c.Webhooks.MaxAttempts >= 0, "http.webhooks.max_attempts", "must not be negative")
+	v.notNegative("http.webhooks.backoff", c.Webhooks.Backoff)
+	v.notNegative("http.webhooks.max_backoff", c.Webhooks.MaxBackoff)
+	v.notNegative("http.webhooks.timeout", c.Webhooks.Timeout)
+
+	v.oneOf("http.mail.driver", c.Mail.Driver, "", "smtp", "file", "memory")
+	if c.Mail.Driver == "smtp" {
+		v.required("http.mail.host", c.Mail.Host)
+		v.between("http.mail.port", c.Mail.Port, 1, 65535)
+	}
+	v.check(c.Mail.QueueSize >= 0, "http.mail.queue_size", "must not be negative")
+
+	v.notNegative("http.login.window", c.Login.Window)
+	v.check(c.Login.MaxAccountFailures >= 0, "http.login.max_account_failures", "must not be negative")
+	v.check(c.Login.MaxIPFailures >= 0, "http.login.max_ip_failures", "must not be negative")
+	v.notNegative("http.login.lockout_duration", c.Login.LockoutDuration)
+	v.notNegative("http.login.delay", c.Login.Delay)
+	v.notNegative("http.login.max_delay", c.Login.MaxDelay)
+
+	c.RateLimit.Default.validate(v, "http.rate_limit.default")
+	for i, group := range c.RateLimit.Groups {
+		key := fmt.Sprintf("http.rate_limit.groups[%d]", i)
+		v.required(key+".prefix", group.Prefix)
+		group.RateLimit.validate(v, key)
+	}
+
+	v.notNegative("http.health.timeout", c.Health.Timeout)
+
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "http.tracing.sample_ratio", "must be between 0 and 1")
+	if c.Tracing.Exporter == "file" {
+		v.required("http.tracing.file", c.Tracing.File)
+	}
+
+	v.oneOf("http.error_reporting.driver", c.ErrorReporting.Driver, "", "sentry", "file", "memory", "none")
+	if c.ErrorReporting.Driver == "sentry" {
+		v.check(sentryDSN != "", "sentry_dsn", "is required by the sentry error reporting driver")
+	}
+	v.check(c.ErrorReporting.MaxBreadcrumbs >= 0, "http.error_reporting.max_breadcrumbs", "must not be negative")
+	v.check(c.ErrorReporting.QueueSize >= 0, "http.error_reporting.queue_size", "must not be negative")
+
+	v.oneOf("http.cache.driver", c.Cache.Driver, "", "memory", "none")
+	v.notNegative("http.cache.ttl", c.Cache.TTL)
+	v.check(c.Cache.Size >= 0, "http.cache.size", "must not be negative")
+
+	if c.ApiVersions.Sunset != "" {
+		_, err := time.Parse("2006-01-02", c.ApiVersions.Sunset)
+		v.check(err == nil, "http.api_versions.sunset", "must be a date like 2027-06-30")
+	}
+}
+
+func (r RateLimit) validate(v *validator, key string) {
+	v.check(r.Requests >= 0, key+".requests", "must not be negative")
+	v.check(r.Burst >= 0, key+".burst", "must not be negative")
+	if r.Requests > 0 {
+		v.check(r.Period > 0, key+".period", "is required when requests is set")
+	}
+}
+
+// Collects the problems of the config
+type validator struct {
+	problems []string
+}
+
+func (v *validator) check(ok bool, key string, problem string) {
+	if !ok {
+		v.problems = append(v.problems, key+": "+problem)
+	}
+}
+
+func (v *validator) required(key string, value string) {
+	v.check(value != "", key, "is required")
+}
+
+func (v *validator) between(key string, value int, min int, max int) {
+	v.check(value >= min && value <= max, key, fmt.Sprintf("must be between %d and %d, got %d", min, max, value))
+}
+
+func (v *validator) notNegative(key string, d time.Duration) {
+	v.check(d >= 0, key, "must not be negative")
+}
+
+func (v *validator) oneOf(key string, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	var named []string
+	for _, a := range allowed {
+		if a != "" {
+			named = append(named, a)
+		}
+	}
+	v.check(false, key, fmt.Sprintf("must be one of %s, got %q", strings.Join(named, ", "), value))
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Role of the requests authenticated with an API key instead of a user's JWT
+const RoleService = "SERVICE"
+
+// What an API key is allowed to do
+type ApiKeyScope string
+
+const (
+	ScopeReadCatalog ApiKeyScope = "catalog:read"
+	ScopeWriteStock  ApiKeyScope = "stock:write"
+	ScopeReadOrders  ApiKeyScope = "orders:read"
+)
+
+var ApiKeyScopes = []ApiKeyScope{ScopeReadCatalog, ScopeWriteStock, ScopeReadOrders}
+
+func (s ApiKeyScope) IsValid() bool {
+	for _, scope := range ApiKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ApiKey lets other services call the API without a user, it is created by an admin
+// Only the hash of the key is stored, the key itself is shown once when it's created
+type ApiKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Start of the key, for telling the keys apart
+	Prefix  string        `json:"prefix"`
+	KeyHash string        `json:"-"`
+	Scopes  []ApiKeyScope `json:"scopes"`
+	// Admin who created the key
+	CreatedBy string `json:"createdBy"`
+
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (e *ApiKey) IsUsable() bool {
+	return e.RevokedAt == nil && (e.ExpiresAt == nil || time.Now().Before(*e.ExpiresAt))
+}
+
+func (e *ApiKey) HasScope(scope ApiKeyScope) bool {
+	for _, s := range e.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *ApiKey) ToString() string {
+	return fmt.Sprintf("%s %s %s %v", e.ID, e.Name, e.Prefix, e.Scopes)
+}
@@ -10,8 +10,20 @@ type Category struct {
 	Name      string    `json:"categoryName"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int       `json:"version"`
 }
 
 func (e *Category) ToString() string {
 	return fmt.Sprintf("%d %s", e.Id, e.Name)
 }
+
+// CategoryPatch holds the category fields to change, fields left nil are not updated
+type CategoryPatch struct {
+	Name *string
+	// Version the caller expects the category to be at (0 for any), set to the new version once patched
+	Version int
+}
+
+func (e *CategoryPatch) IsEmpty() bool {
+	return e.Name == nil
+}
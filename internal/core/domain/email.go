@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Email is a rendered message, ready to be sent by a mailer
+type Email struct {
+	To          []string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+func (e *Email) ToString() string {
+	return fmt.Sprintf("%s %s (%d attachments)", strings.Join(e.To, ","), e.Subject, len(e.Attachments))
+}
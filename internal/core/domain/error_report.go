@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"time"
+)
+
+// Levels of the error reports
+const (
+	// An error answered with a 5xx
+	ErrorLevelError = "error"
+	// A panic
+	ErrorLevelFatal = "fatal"
+)
+
+// ErrorReport is a failure of the server worth investigating: a panic, or an error answered with a 5xx
+type ErrorReport struct {
+	ID    string
+	Time  time.Time
+	Level string
+	// Type of the error, e.g. *pq.Error, or "panic"
+	Type    string
+	Message string
+	// Most recent call first
+	Stack []StackFrame
+
+	Request   ErrorRequest
+	RequestID string
+	UserID    string
+
+	// Version of the server, and environment it runs in
+	Release     string
+	Environment string
+
+	// What happened during the request before the failure, oldest first
+	Breadcrumbs []Breadcrumb
+}
+
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// The request which failed, its sensitive headers already scrubbed
+type ErrorRequest struct {
+	Method  string
+	URL     string
+	Route   string
+	Headers map[string]string
+}
+
+type Breadcrumb struct {
+	Time     time.Time
+	Category string
+	Level    string
+	Message  string
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned when an entity was modified since the version the caller expected
+var ErrVersionConflict = errors.New("entity was modified by another request")
+
+// Returned for password reset and email verification tokens which don't exist, expired or were already used
+var ErrInvalidToken = errors.New("token is invalid or expired")
+
+// Returned when a user who didn't verify their email does something which requires it
+var ErrEmailNotVerified = errors.New("email is not verified")
+
+// Returned when the email and password don't match, whether or not the email belongs to a user
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// Returned when a two-factor authentication code or recovery code doesn't match
+var ErrInvalidTwoFactorCode = errors.New("invalid two-factor authentication code")
+
+// Returned when enrolling a user who already has two-factor authentication enabled
+var ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+
+// Returned when confirming or disabling two-factor authentication, for a user who didn't enroll
+var ErrTwoFactorNotEnrolled = errors.New("two-factor authentication is not enrolled")
+
+// Returned for API keys which don't exist, expired or were revoked
+var ErrInvalidApiKey = errors.New("API key is invalid or expired")
+
+// NotFoundError is returned when the entity looked up doesn't exist
+type NotFoundError struct {
+	// e.g. "product"
+	Entity string
+}
+
+func NewNotFoundError(entity string) *NotFoundError {
+	return &NotFoundError{Entity: entity}
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Entity + " not found"
+}
+
+// ConflictError is returned when a change clashes with the current state, e.g. a duplicate email
+type ConflictError struct {
+	Message string
+}
+
+func NewConflictError(msg string) *ConflictError {
+	return &ConflictError{Message: msg}
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// FieldError describes why the value of a single field was refused
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned for input which is refused, listing the fields at fault
+type ValidationError struct {
+	Message string
+	Fields  []FieldError
+}
+
+func NewValidationError(msg string, fields ...FieldError) *ValidationError {
+	return &ValidationError{Message: msg, Fields: fields}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// InsufficientStockError is returned when more items of a product are ordered than are in stock
+type InsufficientStockError struct {
+	ProductID int64
+	Requested int
+	Available int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("not enough items of product %d in stock: %d requested, %d available", e.ProductID, e.Requested, e.Available)
+}
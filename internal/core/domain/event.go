@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventOrderCreated       EventType = "order.created"
+	EventOrderStatusChanged EventType = "order.status_changed"
+	EventProductCreated     EventType = "product.created"
+	EventProductUpdated     EventType = "product.updated"
+	EventProductDeleted     EventType = "product.deleted"
+	EventProductStockLow    EventType = "product.stock_low"
+	EventUserRegistered     EventType = "user.registered"
+)
+
+// All the event types raised by the core
+var EventTypes = []EventType{
+	EventOrderCreated,
+	EventOrderStatusChanged,
+	EventProductCreated,
+	EventProductUpdated,
+	EventProductDeleted,
+	EventProductStockLow,
+	EventUserRegistered,
+}
+
+// Whether the core raises events of this type
+func (t EventType) IsValid() bool {
+	for _, eventType := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Products with less items in stock than this are considered low on stock
+const LowStockThreshold = 5
+
+// Event is something that happened in the core, which other systems may want to know about
+// Events are stored in the outbox together with the change that raised them, and published afterwards
+type Event struct {
+	ID          string          `json:"id"`
+	Type        EventType       `json:"type"`
+	AggregateID string          `json:"aggregateId"`
+	Payload     json.RawMessage `json:"payload"`
+	OccurredAt  time.Time       `json:"occurredAt"`
+	PublishedAt *time.Time      `json:"publishedAt,omitempty"`
+	Attempts    int             `json:"-"`
+	// names of the subscribers which handled the event already
+	DeliveredTo []string `json:"-"`
+}
+
+type OrderCreatedPayload struct {
+	OrderID      string           `json:"orderId"`
+	UserID       string           `json:"userId"`
+	Status       string           `json:"status"`
+	ProductItems []OrderedProduct `json:"productItems"`
+}
+
+type OrderStatusChangedPayload struct {
+	OrderID   string `json:"orderId"`
+	UserID    string `json:"userId"`
+	OldStatus string `json:"oldStatus"`
+	Status    string `json:"status"`
+}
+
+type ProductChangedPayload struct {
+	ProductID  int     `json:"productId"`
+	Name       string  `json:"name"`
+	Price      float32 `json:"price"`
+	Quantity   int     `json:"quantity"`
+	CategoryID int     `json:"categoryId"`
+	Version    int     `json:"version"`
+}
+
+type ProductDeletedPayload struct {
+	ProductID int `json:"productId"`
+}
+
+type ProductStockLowPayload struct {
+	ProductID int    `json:"productId"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	Threshold int    `json:"threshold"`
+}
+
+type UserRegisteredPayload struct {
+	UserID  string `json:"userId"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+}
+
+func NewEvent(eventType EventType, aggregateId string, payload interface{}) (*Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		Type:        eventType,
+		AggregateID: aggregateId,
+		Payload:     data,
+		OccurredAt:  time.Now(),
+	}, nil
+}
+
+func NewOrderCreatedEvent(order *Order) (*Event, error) {
+	payload := OrderCreatedPayload{OrderID: order.ID, Status: order.Status}
+	if order.User != nil {
+		payload.UserID = order.User.ID
+	}
+	if order.ProductItems != nil {
+		payload.ProductItems = *order.ProductItems
+	}
+	return NewEvent(EventOrderCreated, order.ID, payload)
+}
+
+func NewOrderStatusChangedEvent(order *Order, oldStatus string) (*Event, error) {
+	payload := OrderStatusChangedPayload{OrderID: order.ID, OldStatus: oldStatus, Status: order.Status}
+	if order.User != nil {
+		payload.UserID = order.User.ID
+	}
+	return NewEvent(EventOrderStatusChanged, order.ID, payload)
+}
+
+// Creates a product.created or product.updated event, carrying the current state of the product
+func NewProductChangedEvent(eventType EventType, product *Product) (*Event, error) {
+	payload := ProductChangedPayload{
+		ProductID: product.ProductId,
+		Name:      product.Name,
+		Price:     product.Price,
+		Quantity:  product.Quantity,
+		Version:   product.Version,
+	}
+	if product.Category != nil {
+		payload.CategoryID = product.Category.Id
+	}
+	return NewEvent(eventType, fmt.Sprint(product.ProductId), payload)
+}
+
+func NewProductDeletedEvent(id int64) (*Event, error) {
+	return NewEvent(EventProductDeleted, fmt.Sprint(id), ProductDeletedPayload{ProductID: int(id)})
+}
+
+func NewProductStockLowEvent(product *Product) (*Event, error) {
+	return NewEvent(EventProductStockLow, fmt.Sprint(product.ProductId), ProductStockLowPayload{
+		ProductID: product.ProductId,
+		Name:      product.Name,
+		Quantity:  product.Quantity,
+		Threshold: LowStockThreshold,
+	})
+}
+
+func NewUserRegisteredEvent(user *User) (*Event, error) {
+	return NewEvent(EventUserRegistered, user.ID, UserRegisteredPayload{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Name:    user.Name,
+		Surname: user.Surname,
+	})
+}
+
+// Decodes the event payload into v
+func (e *Event) DecodePayload(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+func (e *Event) ToString() string {
+	return fmt.Sprintf("%s %s %s %s", e.ID, e.Type, e.AggregateID, string(e.Payload))
+}
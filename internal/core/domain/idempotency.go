@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// IdempotencyKey is a stored result of a request sent with an Idempotency-Key header
+// A StatusCode of 0 means the original request is still being processed
+type IdempotencyKey struct {
+	Key          string
+	Scope        string
+	Fingerprint  string
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+func (e *IdempotencyKey) IsCompleted() bool {
+	return e.StatusCode != 0
+}
+
+func (e *IdempotencyKey) ToString() string {
+	return fmt.Sprintf("%s %s %s %d", e.Key, e.Scope, e.Fingerprint, e.StatusCode)
+}
@@ -0,0 +1,16 @@
+package domain
+
+// Locale used for users which didn't choose one
+const DefaultLocale = "en"
+
+// Locales the user facing content (e.g. emails) is available in
+var Locales = []string{"en", "sr"}
+
+func IsSupportedLocale(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Events recorded in the auth audit
+type AuthEventType string
+
+const (
+	AuthLoginSucceeded AuthEventType = "LOGIN_SUCCEEDED"
+	AuthLoginFailed    AuthEventType = "LOGIN_FAILED"
+	// the login was refused because of the earlier failures, without checking the password
+	AuthLoginBlocked    AuthEventType = "LOGIN_BLOCKED"
+	AuthAccountUnlocked AuthEventType = "ACCOUNT_UNLOCKED"
+	// the password was right, the login waits for the second factor
+	AuthPasswordVerified  AuthEventType = "PASSWORD_VERIFIED"
+	AuthRecoveryCodeUsed  AuthEventType = "RECOVERY_CODE_USED"
+	AuthTwoFactorEnabled  AuthEventType = "TWO_FACTOR_ENABLED"
+	AuthTwoFactorDisabled AuthEventType = "TWO_FACTOR_DISABLED"
+)
+
+type AuthEvent struct {
+	ID    string        `json:"id" db:"id"`
+	Type  AuthEventType `json:"type" db:"event"`
+	Email string        `json:"email" db:"email"`
+	// Set when the email belongs to a user
+	UserID *string `json:"user_id" db:"user_id"`
+	// Admin who acted on the account
+	ActorID *string `json:"actor_id" db:"actor_id"`
+	IP      string  `json:"ip" db:"ip"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Failed logins counted against an account or an IP
+type LoginFailures struct {
+	Count int
+	Last  time.Time
+}
+
+// LoginPolicy decides how long logins are refused after failed attempts
+// Every failure delays the next attempt twice as long as the one before, until the failures reach the
+// maximum, which locks logins out for LockoutDuration
+type LoginPolicy struct {
+	// Failures older than the window aren't counted
+	Window time.Duration
+
+	MaxAccountFailures int
+	MaxIPFailures      int
+	LockoutDuration    time.Duration
+
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// Returns how long logins are refused for, given the failures of the account and of the IP
+// Returns 0 when a login can be attempted now
+func (p LoginPolicy) RetryAfter(account LoginFailures, ip LoginFailures, now time.Time) time.Duration {
+	wait := p.wait(account, p.MaxAccountFailures, now)
+	if ipWait := p.wait(ip, p.MaxIPFailures, now); ipWait > wait {
+		wait = ipWait
+	}
+	return wait
+}
+
+func (p LoginPolicy) wait(failures LoginFailures, max int, now time.Time) time.Duration {
+	if failures.Count == 0 {
+		return 0
+	}
+	var until time.Time
+	if max > 0 && failures.Count >= max {
+		until = failures.Last.Add(p.LockoutDuration)
+	} else {
+		until = failures.Last.Add(p.delay(failures.Count))
+	}
+	if !until.After(now) {
+		return 0
+	}
+	return until.Sub(now)
+}
+
+func (p LoginPolicy) delay(failures int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Returned when logins are refused because of too many failed attempts
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return fmt.Sprintf("too many failed logins, retry in %s", e.RetryAfter.Round(time.Second))
+}
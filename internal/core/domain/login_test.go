@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testPolicy = LoginPolicy{
+	Window:             time.Hour,
+	MaxAccountFailures: 5,
+	MaxIPFailures:      20,
+	LockoutDuration:    15 * time.Minute,
+	Delay:              time.Second,
+	MaxDelay:           10 * time.Second,
+}
+
+func TestLoginDelayDoubles(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, time.Duration(0), testPolicy.RetryAfter(LoginFailures{}, LoginFailures{}, now))
+	assert.Equal(t, time.Second, testPolicy.RetryAfter(LoginFailures{Count: 1, Last: now}, LoginFailures{}, now))
+	assert.Equal(t, 4*time.Second, testPolicy.RetryAfter(LoginFailures{Count: 3, Last: now}, LoginFailures{}, now))
+	assert.Equal(t, 8*time.Second, testPolicy.RetryAfter(LoginFailures{Count: 4, Last: now}, LoginFailures{}, now))
+	assert.Equal(t, 10*time.Second, testPolicy.RetryAfter(LoginFailures{}, LoginFailures{Count: 10, Last: now}, now))
+	// the delay counts from the last failure
+	assert.Equal(t, time.Duration(0), testPolicy.RetryAfter(LoginFailures{Count: 3, Last: now.Add(-5 * time.Second)}, LoginFailures{}, now))
+}
+
+func TestLoginLockout(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, 15*time.Minute, testPolicy.RetryAfter(LoginFailures{Count: 5, Last: now}, LoginFailures{}, now))
+	assert.Equal(t, 5*time.Minute, testPolicy.RetryAfter(LoginFailures{Count: 5, Last: now.Add(-10 * time.Minute)}, LoginFailures{}, now))
+	// the IP is locked out too, for whichever account
+	assert.Equal(t, 15*time.Minute, testPolicy.RetryAfter(LoginFailures{Count: 1, Last: now}, LoginFailures{Count: 20, Last: now}, now))
+}
@@ -12,10 +12,13 @@ type Order struct {
 	CreatedAt    time.Time         `json:"createdAt"`
 	UpdatedAt    time.Time         `json:"updatedAt"`
 	User         *User             `json:"user"`
+	Version      int               `json:"version"`
 }
 type OrderedProduct struct {
 	ProductId int64 `json:"productId"`
 	Quantity  int   `json:"quantity"`
+	// Price of the product when it was ordered
+	UnitPrice float32 `json:"unitPrice"`
 }
 
 func (e *Order) ToString() string {
@@ -15,8 +15,31 @@ type Product struct {
 	UpdatedAt        time.Time `json:"updatedAt"`
 	Quantity         int       `json:"quantity"`
 	Category         *Category `json:"category"`
+	Version          int       `json:"version"`
 }
 
 func (e *Product) ToString() string {
 	return fmt.Sprintf("%d %s %s %s %f %d %v", e.ProductId, e.Name, e.ShortDescription, e.Description, e.Price, e.Quantity, e.Category)
 }
+
+// ProductPatch holds the product fields to change, fields left nil are not updated
+type ProductPatch struct {
+	Name             *string
+	ShortDescription *string
+	Description      *string
+	Price            *float32
+	Quantity         *int
+	CategoryId       *int
+	// Version the caller expects the product to be at (0 for any), set to the new version once patched
+	Version int
+}
+
+func (e *ProductPatch) IsEmpty() bool {
+	return e.Name == nil && e.ShortDescription == nil && e.Description == nil && e.Price == nil &&
+		e.Quantity == nil && e.CategoryId == nil
+}
+
+// Whether the product dropped below the low stock threshold, when the given quantity was taken out of stock
+func (e *Product) DroppedBelowLowStock(taken int) bool {
+	return e.Quantity+taken >= LowStockThreshold && e.Quantity < LowStockThreshold
+}
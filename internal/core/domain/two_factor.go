@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+)
+
+// How long the second step of the login can be completed in
+const LoginChallengeTTL = 5 * time.Minute
+
+// How many recovery codes are issued when two-factor authentication is enabled
+const RecoveryCodeCount = 10
+
+// UserTOTP is the user's authenticator app secret
+// It only protects the logins once confirmed with a first code, proving the app was set up
+type UserTOTP struct {
+	UserID      string     `db:"user_id"`
+	Secret      string     `db:"secret"`
+	ConfirmedAt *time.Time `db:"confirmed_at"`
+	// Time step of the last accepted code, codes can't be used twice
+	LastUsedStep int64     `db:"last_used_step"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+func (e *UserTOTP) IsConfirmed() bool {
+	return e.ConfirmedAt != nil
+}
+
+// TOTPEnrollment is what the user sets up their authenticator app with
+type TOTPEnrollment struct {
+	Secret string
+	// otpauth:// URI, usually shown as a QR code
+	URI string
+}
+
+// LoginResult is either the logged in user, or the challenge to complete the login with when
+// two-factor authentication is enabled
+type LoginResult struct {
+	User           *User
+	ChallengeToken string
+}
@@ -5,12 +5,24 @@ import (
 	"time"
 )
 
+// Roles of the users, admins can manage the other users' accounts
+const (
+	RoleUser  = "USER"
+	RoleAdmin = "ADMIN"
+)
+
 type User struct {
 	ID           string `json:"id" db:"id"`
 	Email        string `json:"email" db:"email"`
 	Name         string `json:"name" db:"first_name"`
 	Surname      string `json:"surname" db:"surname"`
 	PasswordHash string `json:"password_hash" db:"password_hash"`
+	Locale       string `json:"locale" db:"locale"`
+	Role         string `json:"role" db:"role"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	// JWTs issued for an older session version are revoked
+	SessionVersion int `json:"-" db:"session_version"`
 
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
@@ -24,6 +36,21 @@ func NewUser(id string, email string, name string, surname string) *User {
 	}
 }
 
+func (e *User) IsVerified() bool {
+	return e.EmailVerifiedAt != nil
+}
+
 func (e *User) ToString() string {
 	return fmt.Sprintf("#%s %s %s - %s", e.ID, e.Name, e.Surname, e.Email)
 }
+
+// UserPatch holds the user fields to change, fields left nil are not updated
+type UserPatch struct {
+	Name    *string
+	Surname *string
+	Locale  *string
+}
+
+func (e *UserPatch) IsEmpty() bool {
+	return e.Name == nil && e.Surname == nil && e.Locale == nil
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type TokenPurpose string
+
+const (
+	TokenPasswordReset     TokenPurpose = "PASSWORD_RESET"
+	TokenEmailVerification TokenPurpose = "EMAIL_VERIFICATION"
+	// issued after the password was checked, for the second step of the login
+	TokenLoginChallenge TokenPurpose = "LOGIN_CHALLENGE"
+)
+
+// How long the tokens can be used for
+const (
+	PasswordResetTokenTTL     = time.Hour
+	EmailVerificationTokenTTL = 48 * time.Hour
+)
+
+// UserToken is a single-use token given to the user, e.g. emailed to prove they own the email address
+// Only the hash of the token is stored, the token itself is only known to the user
+type UserToken struct {
+	ID        string
+	UserID    string
+	Purpose   TokenPurpose
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (e *UserToken) IsUsable() bool {
+	return e.UsedAt == nil && time.Now().Before(e.ExpiresAt)
+}
+
+func (e *UserToken) ToString() string {
+	return fmt.Sprintf("%s %s %s %v", e.ID, e.UserID, e.Purpose, e.ExpiresAt)
+}
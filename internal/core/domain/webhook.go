@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WebhookSubscription is a partner endpoint, which gets called back when events of the subscribed types happen
+type WebhookSubscription struct {
+	ID         string      `json:"id"`
+	UserID     string      `json:"userId"`
+	URL        string      `json:"url"`
+	EventTypes []EventType `json:"eventTypes"`
+	// Secret the deliveries are signed with
+	Secret    string    `json:"-"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Whether the subscription wants events of the given type
+func (e *WebhookSubscription) Accepts(eventType EventType) bool {
+	if !e.Active {
+		return false
+	}
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *WebhookSubscription) ToString() string {
+	return fmt.Sprintf("%s %s %s %v %t", e.ID, e.UserID, e.URL, e.EventTypes, e.Active)
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	// Waiting for the first attempt, or a retry
+	DeliveryPending WebhookDeliveryStatus = "PENDING"
+	// The receiver accepted the delivery
+	DeliverySucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	// All attempts failed, the delivery won't be retried anymore
+	DeliveryDeadLettered WebhookDeliveryStatus = "DEAD_LETTERED"
+)
+
+// WebhookDelivery is a single event sent to a single subscription, along with the outcome of its last attempt
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	SubscriptionID string                `json:"subscriptionId"`
+	EventID        string                `json:"eventId"`
+	EventType      EventType             `json:"eventType"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  time.Time             `json:"nextAttemptAt"`
+	LastStatusCode int                   `json:"lastStatusCode"`
+	LastError      string                `json:"lastError"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	DeliveredAt    *time.Time            `json:"deliveredAt"`
+}
+
+func (e *WebhookDelivery) ToString() string {
+	return fmt.Sprintf("%s %s %s %s %d", e.ID, e.SubscriptionID, e.EventType, e.Status, e.Attempts)
+}
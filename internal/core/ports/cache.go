@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// Keeps values for a while, so they aren't read from the database every time
+// The values are encoded, so they can as well be kept in process as in an external store
+type Cache interface {
+	// Returns the value of the key, and whether it was there and not expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Keeps the value for the given time
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Removes the keys, the ones which aren't there are ignored
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+)
+
+// ErrorReporter sends the failures of the server to where they are investigated, e.g. Sentry
+type ErrorReporter interface {
+	Report(ctx context.Context, report *domain.ErrorReport) error
+}
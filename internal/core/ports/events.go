@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	domain "github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+)
+
+// Delivers events from the outbox to the systems interested in them
+type EventPublisher interface {
+	Publish(ctx context.Context, event *domain.Event) error
+}
+
+// Checks the URLs the webhooks are delivered to, so they can't reach into the internal network
+type WebhookURLChecker interface {
+	CheckURL(ctx context.Context, rawURL string) error
+}
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+)
+
+type Mailer interface {
+	Send(ctx context.Context, email *domain.Email) error
+}
+
+// Emails the users the tokens proving they own their email address
+type UserNotifier interface {
+	SendPasswordReset(ctx context.Context, user *domain.User, token string) error
+	SendEmailVerification(ctx context.Context, user *domain.User, token string) error
+}
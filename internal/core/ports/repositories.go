@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	domain "github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 )
@@ -9,24 +10,66 @@ import (
 type UserRepo interface {
 	Insert(ctx context.Context, user *domain.User) error
 	Update(ctx context.Context, user *domain.User) error
+	Patch(ctx context.Context, id string, patch *domain.UserPatch) error
 	FindByID(ctx context.Context, id string) (*domain.User, error)
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+	MarkEmailVerified(ctx context.Context, id string) error
+	UpdatePassword(ctx context.Context, id string, passwordHash string) (int, error)
+}
+
+type UserTokenRepo interface {
+	InsertToken(ctx context.Context, token *domain.UserToken) error
+	FindTokenByHash(ctx context.Context, tokenHash string, purpose domain.TokenPurpose) (*domain.UserToken, error)
+	MarkTokenUsed(ctx context.Context, id string) (bool, error)
+	DeleteUserTokens(ctx context.Context, userId string, purpose domain.TokenPurpose) error
+}
+
+type AuthAuditRepo interface {
+	InsertAuthEvent(ctx context.Context, event *domain.AuthEvent) error
+	LockAccount(ctx context.Context, email string) error
+	CountAccountFailures(ctx context.Context, email string, since time.Time) (*domain.LoginFailures, error)
+	CountIPFailures(ctx context.Context, ip string, since time.Time) (*domain.LoginFailures, error)
+	FindAuthEventsByUser(ctx context.Context, userId string, limit int) (*[]domain.AuthEvent, error)
+}
+
+type TwoFactorRepo interface {
+	InsertTOTP(ctx context.Context, totp *domain.UserTOTP) error
+	FindTOTP(ctx context.Context, userId string) (*domain.UserTOTP, error)
+	ConfirmTOTP(ctx context.Context, userId string) error
+	UseTOTPStep(ctx context.Context, userId string, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userId string) error
+	ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userId string, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userId string) (int, error)
+}
+
+type ApiKeyRepo interface {
+	InsertApiKey(ctx context.Context, key *domain.ApiKey) error
+	FindApiKeyById(ctx context.Context, id string) (*domain.ApiKey, error)
+	FindApiKeyByHash(ctx context.Context, keyHash string) (*domain.ApiKey, error)
+	GetAllApiKeys(ctx context.Context) (*[]domain.ApiKey, error)
+	RevokeApiKey(ctx context.Context, id string) (bool, error)
+	TouchApiKey(ctx context.Context, id string, precision time.Duration) error
 }
 
 type ProductRepo interface {
 	GetAllProducts(ctx context.Context) (*[]domain.Product, error)
 	FindProductById(ctx context.Context, id int64) (*domain.Product, error)
+	FindProductsByIds(ctx context.Context, ids []int64) (map[int64]*domain.Product, error)
 	InsertProduct(ctx context.Context, product *domain.Product) (int64, error)
-	DeleteProduct(ctx context.Context, id int64) (int64, error)
+	DeleteProduct(ctx context.Context, id int64, version int) (int64, error)
 	UpdateProduct(ctx context.Context, product *domain.Product, id int64) (int64, error)
+	PatchProduct(ctx context.Context, id int64, patch *domain.ProductPatch) (int64, error)
+	TakeFromStock(ctx context.Context, id int64, quantity int) (*domain.Product, error)
 }
 
 type CategoryRepo interface {
 	GetAllCategories(ctx context.Context) (*[]domain.Category, error)
 	FindCategoryById(ctx context.Context, id int64) (*domain.Category, error)
 	InsertCategory(ctx context.Context, category *domain.Category) (int64, error)
-	DeleteCategory(ctx context.Context, id int64) (int64, error)
+	DeleteCategory(ctx context.Context, id int64, version int) (int64, error)
 	UpdateCategory(ctx context.Context, category *domain.Category, id int64) (int64, error)
+	PatchCategory(ctx context.Context, id int64, patch *domain.CategoryPatch) (int64, error)
 }
 
 type OrderRepo interface {
@@ -37,7 +80,42 @@ type OrderRepo interface {
 }
 
 type OrderProductRepo interface {
-	Add(ctx context.Context, orderId string, productId int64, quantity int) error
+	Add(ctx context.Context, orderId string, product domain.OrderedProduct) error
 	GetProducts(ctx context.Context, orderId string) (*[]domain.OrderedProduct, error)
 	Delete(ctx context.Context, orderId string, productId int64) error
 }
+
+type IdempotencyRepo interface {
+	FindIdempotencyKey(ctx context.Context, key string, scope string) (*domain.IdempotencyKey, error)
+	InsertIdempotencyKey(ctx context.Context, record *domain.IdempotencyKey) (bool, error)
+	UpdateIdempotencyKey(ctx context.Context, record *domain.IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, key string, scope string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+type OutboxRepo interface {
+	InsertEvent(ctx context.Context, event *domain.Event) error
+	FindDueEvents(ctx context.Context, limit int) (*[]domain.Event, error)
+	LockEvent(ctx context.Context, id string) (deliveredTo []string, locked bool, err error)
+	MarkEventDelivered(ctx context.Context, id string, subscriber string) error
+	MarkEventPublished(ctx context.Context, id string) error
+	MarkEventFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error
+	MarkEventDead(ctx context.Context, id string, reason string) error
+}
+
+type WebhookRepo interface {
+	InsertSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error
+	FindSubscriptionById(ctx context.Context, id string) (*domain.WebhookSubscription, error)
+	FindSubscriptionsByUser(ctx context.Context, userId string) (*[]domain.WebhookSubscription, error)
+	FindSubscriptionsForEvent(ctx context.Context, eventType domain.EventType, ownerRole string) (*[]domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) (int64, error)
+	InsertDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	ClaimDueDeliveries(ctx context.Context, limit int, leasedUntil time.Time) (*[]domain.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	FindDeliveries(ctx context.Context, subscriptionId string, status domain.WebhookDeliveryStatus, limit int) (*[]domain.WebhookDelivery, error)
+}
+
+// Runs fn in a transaction, repositories called with the ctx passed to fn take part in it
+type Transactor interface {
+	TxContext(ctx context.Context, fn func(ctx context.Context) error) error
+}
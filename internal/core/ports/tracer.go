@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"context"
+)
+
+// Tracer times the work of the core in spans, children of the span in ctx
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type Span interface {
+	// Ends the span, marking it failed when err isn't nil
+	End(err error)
+}
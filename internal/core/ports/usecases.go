@@ -11,22 +11,57 @@ type UserUsecase interface {
 	FindByID(ctx context.Context, id string) (*domain.User, error)
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
 	Update(ctx context.Context, user *domain.User) error
+	Patch(ctx context.Context, id string, patch *domain.UserPatch) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, passwordHash string) error
+	ChangePassword(ctx context.Context, id string, passwordHash string) (int, error)
+	RequestEmailVerification(ctx context.Context, id string) error
+	VerifyEmail(ctx context.Context, token string) error
+	IsSessionValid(ctx context.Context, id string, sessionVersion int) (bool, error)
+	CheckSession(ctx context.Context, id string, sessionVersion int) (string, bool, error)
+}
+
+type LoginUsecase interface {
+	Login(ctx context.Context, email string, password string, ip string) (*domain.LoginResult, error)
+	CompleteLogin(ctx context.Context, challengeToken string, code string, ip string) (*domain.User, error)
+	VerifyPassword(ctx context.Context, userId string, password string, ip string) error
+	Unlock(ctx context.Context, userId string, adminId string, ip string) error
+	FindAuthEvents(ctx context.Context, userId string, limit int) (*[]domain.AuthEvent, error)
+}
+
+type TwoFactorUsecase interface {
+	Enroll(ctx context.Context, userId string) (*domain.TOTPEnrollment, error)
+	Confirm(ctx context.Context, userId string, code string, ip string) ([]string, error)
+	Disable(ctx context.Context, userId string, code string, ip string) error
+	RegenerateRecoveryCodes(ctx context.Context, userId string, code string, ip string) ([]string, error)
+	IsEnabled(ctx context.Context, userId string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userId string) (int, error)
+}
+
+type ApiKeyUsecase interface {
+	CreateApiKey(ctx context.Context, key *domain.ApiKey) (string, error)
+	FindApiKeyById(ctx context.Context, id string) (*domain.ApiKey, error)
+	GetAllApiKeys(ctx context.Context) (*[]domain.ApiKey, error)
+	RevokeApiKey(ctx context.Context, id string) error
+	Authenticate(ctx context.Context, key string) (*domain.ApiKey, error)
 }
 
 type ProductUsecase interface {
 	GetAllProducts(ctx context.Context) (*[]domain.Product, error)
 	FindProductById(ctx context.Context, id int64) (*domain.Product, error)
 	CreateProduct(ctx context.Context, product *domain.Product) (int64, error)
-	DeleteProduct(ctx context.Context, id int64) (int64, error)
+	DeleteProduct(ctx context.Context, id int64, version int) (int64, error)
 	UpdateProduct(ctx context.Context, product *domain.Product, id int64) (int64, error)
+	PatchProduct(ctx context.Context, id int64, patch *domain.ProductPatch) (int64, error)
 }
 
 type CategoryUsecase interface {
 	GetAllCategories(ctx context.Context) (*[]domain.Category, error)
 	FindCategoryById(ctx context.Context, id int64) (*domain.Category, error)
 	CreateCategory(ctx context.Context, category *domain.Category) (int64, error)
-	DeleteCategory(ctx context.Context, id int64) (int64, error)
+	DeleteCategory(ctx context.Context, id int64, version int) (int64, error)
 	UpdateCategory(ctx context.Context, category *domain.Category, id int64) (int64, error)
+	PatchCategory(ctx context.Context, id int64, patch *domain.CategoryPatch) (int64, error)
 }
 
 type OrderUsecase interface {
@@ -35,4 +70,13 @@ type OrderUsecase interface {
 	UpdateOrderStatus(ctx context.Context, order *domain.Order) (*domain.Order, error)
 	DeleteOrder(ctx context.Context, order *domain.Order) error
 	GeneratePdf(ctx context.Context, order *domain.Order) error
+	InvoicePdf(ctx context.Context, order *domain.Order) ([]byte, error)
+}
+
+type WebhookUsecase interface {
+	CreateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error
+	FindSubscriptionById(ctx context.Context, id string) (*domain.WebhookSubscription, error)
+	FindSubscriptionsByUser(ctx context.Context, userId string) (*[]domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) (int64, error)
+	FindDeliveries(ctx context.Context, subscriptionId string, status domain.WebhookDeliveryStatus, limit int) (*[]domain.WebhookDelivery, error)
 }
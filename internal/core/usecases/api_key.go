@@ -0,0 +1,102 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
+	"github.com/pkg/errors"
+)
+
+var _ ports.ApiKeyUsecase = (*ApiKeyService)(nil)
+
+// Prefix of the generated keys, so leaked keys are easy to recognize
+const apiKeyPrefix = "hfk_"
+
+// How far behind the recorded last use of a key may be
+const apiKeyLastUsedPrecision = time.Minute
+
+type ApiKeyService struct {
+	apiKeyRepo *repo.ApiKeyRepository
+}
+
+func NewApiKeyService(apiKeyRepo *repo.ApiKeyRepository) *ApiKeyService {
+	return &ApiKeyService{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// Generates and stores the key, returns it since only its hash is stored
+func (s *ApiKeyService) CreateApiKey(ctx context.Context, key *domain.ApiKey) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "ApiKeyService.CreateApiKey")
+	defer func() { span.End(err) }()
+	token, _, err := newToken()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to generate API key")
+	}
+	rawKey := apiKeyPrefix + token
+	key.Prefix = rawKey[:len(apiKeyPrefix)+8]
+	key.KeyHash = hashToken(rawKey)
+	err = s.apiKeyRepo.InsertApiKey(ctx, key)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create API key")
+	}
+	return rawKey, nil
+}
+
+func (s *ApiKeyService) FindApiKeyById(ctx context.Context, id string) (_ *domain.ApiKey, err error) {
+	ctx, span := tracer.Start(ctx, "ApiKeyService.FindApiKeyById")
+	defer func() { span.End(err) }()
+	key, err := s.apiKeyRepo.FindApiKeyById(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve API key")
+	}
+	return key, nil
+}
+
+func (s *ApiKeyService) GetAllApiKeys(ctx context.Context) (_ *[]domain.ApiKey, err error) {
+	ctx, span := tracer.Start(ctx, "ApiKeyService.GetAllApiKeys")
+	defer func() { span.End(err) }()
+	keys, err := s.apiKeyRepo.GetAllApiKeys(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve API keys")
+	}
+	return keys, nil
+}
+
+func (s *ApiKeyService) RevokeApiKey(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "ApiKeyService.RevokeApiKey")
+	defer func() { span.End(err) }()
+	revoked, err := s.apiKeyRepo.RevokeApiKey(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to revoke API key")
+	}
+	if !revoked {
+		return repo.ErrApiKeyNotFound
+	}
+	return nil
+}
+
+// Returns the key the request was made with, recording its use
+// Returns domain.ErrInvalidApiKey for unknown, expired and revoked keys
+func (s *ApiKeyService) Authenticate(ctx context.Context, rawKey string) (_ *domain.ApiKey, err error) {
+	ctx, span := tracer.Start(ctx, "ApiKeyService.Authenticate")
+	defer func() { span.End(err) }()
+	key, err := s.apiKeyRepo.FindApiKeyByHash(ctx, hashToken(rawKey))
+	if err == repo.ErrApiKeyNotFound {
+		return nil, domain.ErrInvalidApiKey
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve API key")
+	}
+	if !key.IsUsable() {
+		return nil, domain.ErrInvalidApiKey
+	}
+	err = s.apiKeyRepo.TouchApiKey(ctx, key.ID, apiKeyLastUsedPrecision)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to record API key use")
+	}
+	return key, nil
+}
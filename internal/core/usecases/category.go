@@ -5,39 +5,44 @@ import (
 
 	domain "github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
-	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
 	"github.com/pkg/errors"
 )
 
 var _ ports.CategoryUsecase = (*CategoryService)(nil)
 
 type CategoryService struct {
-	categoryRepo *repo.CategoryRepository
+	categoryRepo ports.CategoryRepo
 }
 
-func NewCategoryService(categoryRepo *repo.CategoryRepository) *CategoryService {
+func NewCategoryService(categoryRepo ports.CategoryRepo) *CategoryService {
 	return &CategoryService{
 		categoryRepo: categoryRepo,
 	}
 }
 
-func (s *CategoryService) GetAllCategories(ctx context.Context) (*[]domain.Category, error) {
+func (s *CategoryService) GetAllCategories(ctx context.Context) (_ *[]domain.Category, err error) {
+	ctx, span := tracer.Start(ctx, "CategoryService.GetAllCategories")
+	defer func() { span.End(err) }()
 	categories, err := s.categoryRepo.GetAllCategories(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to retrieve categories")
 	}
 	return categories, nil
 }
-func (s *CategoryService) FindCategoryById(ctx context.Context, id int64) (*domain.Category, error) {
+func (s *CategoryService) FindCategoryById(ctx context.Context, id int64) (_ *domain.Category, err error) {
+	ctx, span := tracer.Start(ctx, "CategoryService.FindCategoryById")
+	defer func() { span.End(err) }()
 	category, err := s.categoryRepo.FindCategoryById(ctx, id)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to retrieve a category")
 	}
 	return category, nil
 }
-func (s *CategoryService) CreateCategory(ctx context.Context, category *domain.Category) (int64, error) {
+func (s *CategoryService) CreateCategory(ctx context.Context, category *domain.Category) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "CategoryService.CreateCategory")
+	defer func() { span.End(err) }()
 	if len(category.Name) < 1 {
-		return 0, errors.New("category doesn't have a name")
+		return 0, domain.NewValidationError("category doesn't have a name", domain.FieldError{Field: "name", Message: "is required"})
 	}
 	id, err := s.categoryRepo.InsertCategory(ctx, category)
 	if err != nil {
@@ -45,17 +50,42 @@ func (s *CategoryService) CreateCategory(ctx context.Context, category *domain.C
 	}
 	return id, nil
 }
-func (s *CategoryService) DeleteCategory(ctx context.Context, id int64) (int64, error) {
-	id, err := s.categoryRepo.DeleteCategory(ctx, id)
+func (s *CategoryService) DeleteCategory(ctx context.Context, id int64, version int) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "CategoryService.DeleteCategory")
+	defer func() { span.End(err) }()
+	rows, err := s.categoryRepo.DeleteCategory(ctx, id, version)
 	if err != nil {
 		return 0, errors.Wrap(err, "Failed to delete a category")
 	}
-	return id, nil
+	if rows == 0 {
+		return 0, domain.NewNotFoundError("category")
+	}
+	return rows, nil
 }
-func (s *CategoryService) UpdateCategory(ctx context.Context, category *domain.Category, id int64) (int64, error) {
-	id, err := s.categoryRepo.UpdateCategory(ctx, category, id)
+func (s *CategoryService) UpdateCategory(ctx context.Context, category *domain.Category, id int64) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "CategoryService.UpdateCategory")
+	defer func() { span.End(err) }()
+	rows, err := s.categoryRepo.UpdateCategory(ctx, category, id)
 	if err != nil {
 		return 0, errors.Wrap(err, "Failed to edit a category")
 	}
-	return id, nil
+	if rows == 0 {
+		return 0, domain.NewNotFoundError("category")
+	}
+	return rows, nil
+}
+func (s *CategoryService) PatchCategory(ctx context.Context, id int64, patch *domain.CategoryPatch) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "CategoryService.PatchCategory")
+	defer func() { span.End(err) }()
+	if patch.Name != nil && len(*patch.Name) < 1 {
+		return 0, domain.NewValidationError("category doesn't have a name", domain.FieldError{Field: "name", Message: "is required"})
+	}
+	rows, err := s.categoryRepo.PatchCategory(ctx, id, patch)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to patch a category")
+	}
+	if rows == 0 {
+		return 0, domain.NewNotFoundError("category")
+	}
+	return rows, nil
 }
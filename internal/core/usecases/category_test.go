@@ -54,7 +54,7 @@ func (suite *CategorySuite) TestGetCategories() {
 		suite.T().Fatal(err)
 	}
 	assert.NotNil(suite.T(), categories)
-	suite.categorySvc.DeleteCategory(context.TODO(), id)
+	suite.categorySvc.DeleteCategory(context.TODO(), id, 0)
 }
 func (suite *CategorySuite) TestGetCategory() {
 	testCategory := domain.Category{
@@ -69,7 +69,7 @@ func (suite *CategorySuite) TestGetCategory() {
 		suite.T().Fatal(err)
 	}
 	assert.Equal(suite.T(), testCategory.Name, category.Name)
-	suite.categorySvc.DeleteCategory(context.TODO(), id)
+	suite.categorySvc.DeleteCategory(context.TODO(), id, 0)
 }
 func (suite *CategorySuite) TestCreateCategory() {
 	testCategory := domain.Category{
@@ -81,7 +81,7 @@ func (suite *CategorySuite) TestCreateCategory() {
 	}
 	zeroId := int64(0)
 	assert.NotEqual(suite.T(), zeroId, id)
-	suite.categorySvc.DeleteCategory(context.TODO(), id)
+	suite.categorySvc.DeleteCategory(context.TODO(), id, 0)
 }
 func (suite *CategorySuite) TestUpdateCategory() {
 	testCategory := domain.Category{
@@ -100,7 +100,7 @@ func (suite *CategorySuite) TestUpdateCategory() {
 	}
 	zeroRows := int64(0)
 	assert.NotEqual(suite.T(), zeroRows, rows)
-	suite.categorySvc.DeleteCategory(context.TODO(), id)
+	suite.categorySvc.DeleteCategory(context.TODO(), id, 0)
 }
 func (suite *CategorySuite) TestDeleteCategory() {
 	testCategory := domain.Category{
@@ -110,7 +110,7 @@ func (suite *CategorySuite) TestDeleteCategory() {
 	if err != nil {
 		suite.T().Fatalf("error creating test category %v", err)
 	}
-	rows, err := suite.categorySvc.DeleteCategory(context.TODO(), id)
+	rows, err := suite.categorySvc.DeleteCategory(context.TODO(), id, 0)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
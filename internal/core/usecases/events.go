@@ -0,0 +1,20 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/pkg/errors"
+)
+
+// Stores the events in the outbox, as part of the transaction in ctx
+func recordEvents(ctx context.Context, outbox ports.OutboxRepo, events ...*domain.Event) error {
+	for _, event := range events {
+		err := outbox.InsertEvent(ctx, event)
+		if err != nil {
+			return errors.Wrap(err, "Failed to record "+string(event.Type)+" event")
+		}
+	}
+	return nil
+}
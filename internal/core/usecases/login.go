@@ -0,0 +1,338 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	domain "github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	ports "github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ ports.LoginUsecase = (*LoginService)(nil)
+
+// Policy values used for the ones left unset
+var defaultLoginPolicy = domain.LoginPolicy{
+	Window:             15 * time.Minute,
+	MaxAccountFailures: 5,
+	MaxIPFailures:      50,
+	LockoutDuration:    15 * time.Minute,
+	Delay:              time.Second,
+	MaxDelay:           30 * time.Second,
+}
+
+// LoginService checks the users' passwords, and second factors when enabled, refusing logins for a while
+// after repeated failures
+// Every attempt is recorded in the auth audit, which the failures are counted from
+type LoginService struct {
+	userRepo      *repo.UserRepository
+	auditRepo     *repo.AuthAuditRepository
+	tokenRepo     *repo.UserTokenRepository
+	twoFactorRepo *repo.TwoFactorRepository
+	tx            ports.Transactor
+	policy        domain.LoginPolicy
+
+	// compared against when the email doesn't belong to a user, so the response takes as long as for a wrong password
+	dummyHash []byte
+}
+
+func NewLoginService(userRepo *repo.UserRepository, auditRepo *repo.AuthAuditRepository, tokenRepo *repo.UserTokenRepository,
+	twoFactorRepo *repo.TwoFactorRepository, tx ports.Transactor, policy domain.LoginPolicy) *LoginService {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("not a password"), 10)
+	return &LoginService{
+		userRepo:      userRepo,
+		auditRepo:     auditRepo,
+		tokenRepo:     tokenRepo,
+		twoFactorRepo: twoFactorRepo,
+		tx:            tx,
+		policy:        withLoginPolicyDefaults(policy),
+		dummyHash:     dummyHash,
+	}
+}
+
+// Returns the policy with the values left unset taken from the default one
+func withLoginPolicyDefaults(policy domain.LoginPolicy) domain.LoginPolicy {
+	if policy.Window <= 0 {
+		policy.Window = defaultLoginPolicy.Window
+	}
+	if policy.MaxAccountFailures <= 0 {
+		policy.MaxAccountFailures = defaultLoginPolicy.MaxAccountFailures
+	}
+	if policy.MaxIPFailures <= 0 {
+		policy.MaxIPFailures = defaultLoginPolicy.MaxIPFailures
+	}
+	if policy.LockoutDuration <= 0 {
+		policy.LockoutDuration = defaultLoginPolicy.LockoutDuration
+	}
+	if policy.Delay <= 0 {
+		policy.Delay = defaultLoginPolicy.Delay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = defaultLoginPolicy.MaxDelay
+	}
+	return policy
+}
+
+// Logs in the user with the email and password
+// With two-factor authentication enabled, the result only carries the challenge token to complete the login with
+// Returns domain.ErrInvalidCredentials if they don't match, whether or not the email belongs to a user,
+// and a *domain.LoginBlockedError while logins to the account or from the IP are refused
+func (s *LoginService) Login(ctx context.Context, email string, password string, ip string) (_ *domain.LoginResult, err error) {
+	ctx, span := tracer.Start(ctx, "LoginService.Login")
+	defer func() { span.End(err) }()
+	var result *domain.LoginResult
+	err = lockedAttempt(ctx, s.tx, s.auditRepo, email, func(ctx context.Context) error {
+		var err error
+		result, err = s.login(ctx, email, password, ip)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *LoginService) login(ctx context.Context, email string, password string, ip string) (*domain.LoginResult, error) {
+	err := s.checkBlocked(ctx, email, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil && !errors.Is(err, repo.ErrUserNotFound) {
+		return nil, errors.Wrap(err, "Failed to retrieve user")
+	}
+	if user == nil {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		err = s.record(ctx, domain.AuthLoginFailed, email, nil, nil, ip)
+		if err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err != nil {
+		err = s.record(ctx, domain.AuthLoginFailed, email, &user.ID, nil, ip)
+		if err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	twoFactor, err := isTwoFactorEnabled(ctx, s.twoFactorRepo, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor {
+		// not a successful login yet, so the earlier failures still count
+		err = s.record(ctx, domain.AuthPasswordVerified, email, &user.ID, nil, ip)
+		if err != nil {
+			return nil, err
+		}
+		challengeToken, err := issueToken(ctx, s.tokenRepo, user.ID, domain.TokenLoginChallenge, domain.LoginChallengeTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.LoginResult{ChallengeToken: challengeToken}, nil
+	}
+
+	err = s.record(ctx, domain.AuthLoginSucceeded, email, &user.ID, nil, ip)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.LoginResult{User: user}, nil
+}
+
+// Completes the login with the challenge token, and a code from the authenticator app or a recovery code
+// A wrong code counts as a failed login, the challenge can be retried with another code until it expires
+// Returns domain.ErrInvalidToken for an unknown or expired challenge, and domain.ErrInvalidTwoFactorCode for a wrong code
+func (s *LoginService) CompleteLogin(ctx context.Context, challengeToken string, code string, ip string) (_ *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "LoginService.CompleteLogin")
+	defer func() { span.End(err) }()
+	challenge, err := findToken(ctx, s.tokenRepo, challengeToken, domain.TokenLoginChallenge)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve user")
+	}
+	err = lockedAttempt(ctx, s.tx, s.auditRepo, user.Email, func(ctx context.Context) error {
+		return s.completeLogin(ctx, user, challenge, code, ip)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *LoginService) completeLogin(ctx context.Context, user *domain.User, challenge *domain.UserToken, code string, ip string) error {
+	err := s.checkBlocked(ctx, user.Email, ip)
+	if err != nil {
+		return err
+	}
+	userTOTP, err := s.twoFactorRepo.FindTOTP(ctx, user.ID)
+	if err == repo.ErrTOTPNotFound || (err == nil && !userTOTP.IsConfirmed()) {
+		// two-factor authentication was disabled since, the password has to be checked again
+		return domain.ErrInvalidToken
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to retrieve TOTP")
+	}
+
+	recovery, err := verifySecondFactor(ctx, s.twoFactorRepo, userTOTP, code)
+	if err == domain.ErrInvalidTwoFactorCode {
+		recordErr := s.record(ctx, domain.AuthLoginFailed, user.Email, &user.ID, nil, ip)
+		if recordErr != nil {
+			return recordErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	err = markTokenUsed(ctx, s.tokenRepo, challenge)
+	if err != nil {
+		return err
+	}
+	if recovery {
+		err = s.record(ctx, domain.AuthRecoveryCodeUsed, user.Email, &user.ID, nil, ip)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.record(ctx, domain.AuthLoginSucceeded, user.Email, &user.ID, nil, ip)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Checks the password of the logged in user, e.g. before changing it, counting a wrong one as a failed login
+// Returns domain.ErrInvalidCredentials if it doesn't match, and a *domain.LoginBlockedError while logins to the account
+// or from the IP are refused
+func (s *LoginService) VerifyPassword(ctx context.Context, userId string, password string, ip string) (err error) {
+	ctx, span := tracer.Start(ctx, "LoginService.VerifyPassword")
+	defer func() { span.End(err) }()
+	user, err := s.userRepo.FindByID(ctx, userId)
+	if err != nil {
+		return errors.Wrap(err, "Failed to retrieve user")
+	}
+	return lockedAttempt(ctx, s.tx, s.auditRepo, user.Email, func(ctx context.Context) error {
+		err := s.checkBlocked(ctx, user.Email, ip)
+		if err != nil {
+			return err
+		}
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+		if err != nil {
+			err = s.record(ctx, domain.AuthLoginFailed, user.Email, &user.ID, nil, ip)
+			if err != nil {
+				return err
+			}
+			return domain.ErrInvalidCredentials
+		}
+		// not a login, so the earlier failures still count
+		return s.record(ctx, domain.AuthPasswordVerified, user.Email, &user.ID, nil, ip)
+	})
+}
+
+// Lets the user log in again right away, their earlier failures aren't counted anymore
+// Failures from the IPs aren't reset, those expire with the window
+func (s *LoginService) Unlock(ctx context.Context, userId string, adminId string, ip string) (err error) {
+	ctx, span := tracer.Start(ctx, "LoginService.Unlock")
+	defer func() { span.End(err) }()
+	user, err := s.userRepo.FindByID(ctx, userId)
+	if err != nil {
+		return errors.Wrap(err, "Failed to retrieve user")
+	}
+	return s.record(ctx, domain.AuthAccountUnlocked, user.Email, &user.ID, &adminId, ip)
+}
+
+func (s *LoginService) FindAuthEvents(ctx context.Context, userId string, limit int) (_ *[]domain.AuthEvent, err error) {
+	ctx, span := tracer.Start(ctx, "LoginService.FindAuthEvents")
+	defer func() { span.End(err) }()
+	events, err := s.auditRepo.FindAuthEventsByUser(ctx, userId, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve auth events")
+	}
+	return events, nil
+}
+
+// Returns a *domain.LoginBlockedError while logins to the account or from the IP are refused
+func (s *LoginService) checkBlocked(ctx context.Context, email string, ip string) error {
+	return checkLoginBlocked(ctx, s.auditRepo, s.policy, email, ip)
+}
+
+func (s *LoginService) record(ctx context.Context, eventType domain.AuthEventType, email string, userId *string, actorId *string, ip string) error {
+	return recordAuthEvent(ctx, s.auditRepo, eventType, email, userId, actorId, ip)
+}
+
+// Runs the attempt to authenticate as the account in a transaction holding the account's lock, so the concurrent
+// attempts are counted one after the other, and can't all be made before the first failures are recorded
+// The events recorded are kept when the attempt fails because of wrong credentials or a lockout
+func lockedAttempt(ctx context.Context, tx ports.Transactor, auditRepo *repo.AuthAuditRepository, email string,
+	attempt func(ctx context.Context) error) error {
+	var attemptErr error
+	err := tx.TxContext(ctx, func(ctx context.Context) error {
+		err := auditRepo.LockAccount(ctx, email)
+		if err != nil {
+			return errors.Wrap(err, "Failed to lock account")
+		}
+		attemptErr = attempt(ctx)
+		if isFailedAttempt(attemptErr) {
+			return nil
+		}
+		return attemptErr
+	})
+	if err != nil {
+		return err
+	}
+	return attemptErr
+}
+
+// Whether the error is one of a failed attempt to authenticate, rather than of the service
+func isFailedAttempt(err error) bool {
+	var blocked *domain.LoginBlockedError
+	return errors.Is(err, domain.ErrInvalidCredentials) || errors.Is(err, domain.ErrInvalidTwoFactorCode) ||
+		errors.As(err, &blocked)
+}
+
+// Returns a *domain.LoginBlockedError while the attempts to authenticate as the account or from the IP are refused,
+// recording the blocked attempt
+func checkLoginBlocked(ctx context.Context, auditRepo *repo.AuthAuditRepository, policy domain.LoginPolicy, email string, ip string) error {
+	now := time.Now()
+	accountFailures, err := auditRepo.CountAccountFailures(ctx, email, now.Add(-policy.Window))
+	if err != nil {
+		return errors.Wrap(err, "Failed to count account login failures")
+	}
+	ipFailures, err := auditRepo.CountIPFailures(ctx, ip, now.Add(-policy.Window))
+	if err != nil {
+		return errors.Wrap(err, "Failed to count IP login failures")
+	}
+	if wait := policy.RetryAfter(*accountFailures, *ipFailures, now); wait > 0 {
+		err = recordAuthEvent(ctx, auditRepo, domain.AuthLoginBlocked, email, nil, nil, ip)
+		if err != nil {
+			return err
+		}
+		return &domain.LoginBlockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func recordAuthEvent(ctx context.Context, auditRepo *repo.AuthAuditRepository, eventType domain.AuthEventType, email string,
+	userId *string, actorId *string, ip string) error {
+	err := auditRepo.InsertAuthEvent(ctx, &domain.AuthEvent{
+		Type:    eventType,
+		Email:   email,
+		UserID:  userId,
+		ActorID: actorId,
+		IP:      ip,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to record auth event")
+	}
+	return nil
+}
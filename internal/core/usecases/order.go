@@ -1,6 +1,7 @@
 package usecases
 
 import (
+	"bytes"
 	"context"
 	"strconv"
 
@@ -15,114 +16,232 @@ var _ ports.OrderUsecase = (*OrderService)(nil)
 
 type OrderService struct {
 	orderRepo   *repo.OrderRepository
-	productRepo *repo.ProductRepository
+	productRepo ports.ProductRepo
 	userRepo    *repo.UserRepository
+	outboxRepo  *repo.OutboxRepository
+	tx          ports.Transactor
 }
 
-func NewOrderService(orderRepo *repo.OrderRepository, productRepo *repo.ProductRepository, userRepo *repo.UserRepository) *OrderService {
+func NewOrderService(orderRepo *repo.OrderRepository, productRepo ports.ProductRepo, userRepo *repo.UserRepository,
+	outboxRepo *repo.OutboxRepository, tx ports.Transactor) *OrderService {
 	return &OrderService{
 		orderRepo:   orderRepo,
 		productRepo: productRepo,
 		userRepo:    userRepo,
+		outboxRepo:  outboxRepo,
+		tx:          tx,
 	}
 }
 
-func (s *OrderService) FindOrderById(ctx context.Context, id string) (*domain.Order, error) {
+func (s *OrderService) FindOrderById(ctx context.Context, id string) (_ *domain.Order, err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.FindOrderById")
+	defer func() { span.End(err) }()
 	order, err := s.orderRepo.FindOrderById(ctx, id)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to retrieve an product")
 	}
 	return order, nil
 }
-func (s *OrderService) CreateOrder(ctx context.Context, order *domain.Order) (*domain.Order, error) {
-	order.Status = "CREATED"
-	for _, item := range *order.ProductItems {
-		product, err := s.productRepo.FindProductById(ctx, item.ProductId)
+
+// Creates the order and takes the ordered items out of stock, in a single transaction
+// Only users who verified their email can order
+func (s *OrderService) CreateOrder(ctx context.Context, order *domain.Order) (_ *domain.Order, err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.CreateOrder")
+	defer func() { span.End(err) }()
+	if order.User == nil {
+		return nil, domain.NewValidationError("order has no user", domain.FieldError{Field: "user", Message: "is required"})
+	}
+	user, err := s.userRepo.FindByID(ctx, order.User.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve the user")
+	}
+	if !user.IsVerified() {
+		return nil, domain.ErrEmailNotVerified
+	}
+
+	var created *domain.Order
+	err = s.tx.TxContext(ctx, func(ctx context.Context) error {
+		order.Status = "CREATED"
+		events, err := s.takeFromStock(ctx, order)
 		if err != nil {
-			return nil, errors.New("product doesn't exist")
-		}
-		if item.Quantity <= 0 {
-			return nil, errors.New("invalid order")
+			return err
 		}
-		if product.Quantity < item.Quantity {
-			return nil, errors.New("not enough items")
+		created, err = s.orderRepo.CreateOrder(ctx, order)
+		if err != nil {
+			return errors.Wrap(err, "failed to create an order")
 		}
-		product.Quantity = product.Quantity - item.Quantity
-		_, err = s.productRepo.UpdateProduct(ctx, product, item.ProductId)
+		event, err := domain.NewOrderCreatedEvent(created)
 		if err != nil {
-			return nil, errors.New("error updating product quantity")
+			return errors.Wrap(err, "failed to create order created event")
 		}
-	}
-	created, err := s.orderRepo.CreateOrder(ctx, order)
+		return recordEvents(ctx, s.outboxRepo, append(events, event)...)
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create an order")
+		return nil, err
 	}
 	return created, nil
 }
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, order *domain.Order) (_ *domain.Order, err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.UpdateOrderStatus")
+	defer func() { span.End(err) }()
 	validStatus := order.Status == "" || order.Status == "CREATED" || order.Status == "PENDING" || order.Status == "COMPLETED" || order.Status == "CLOSED"
 	if !validStatus {
-		return nil, errors.New("invalid order status")
+		return nil, domain.NewValidationError("invalid order status", domain.FieldError{Field: "status", Message: "must be one of CREATED, PENDING, COMPLETED, CLOSED"})
 	}
-	for _, item := range *order.ProductItems {
-		product, err := s.productRepo.FindProductById(ctx, item.ProductId)
+	var updated *domain.Order
+	err = s.tx.TxContext(ctx, func(ctx context.Context) error {
+		current, err := s.orderRepo.FindOrderById(ctx, order.ID)
 		if err != nil {
-			return nil, errors.New("product doesn't exist")
+			return errors.Wrap(err, "failed to retrieve an order")
 		}
-		if item.Quantity <= 0 {
-			return nil, errors.New("invalid order")
-		}
-		if product.Quantity < item.Quantity {
-			return nil, errors.New("not enough items")
+		events, err := s.takeFromStock(ctx, order)
+		if err != nil {
+			return err
 		}
-		product.Quantity = product.Quantity - item.Quantity
-		_, err = s.productRepo.UpdateProduct(ctx, product, item.ProductId)
+		updated, err = s.orderRepo.UpdateOrderStatus(ctx, order)
 		if err != nil {
-			return nil, errors.New("error updating product quantity")
+			return errors.Wrap(err, "failed to update an order")
 		}
-	}
-	updated, err := s.orderRepo.UpdateOrderStatus(ctx, order)
+		if current.Status != updated.Status {
+			event, err := domain.NewOrderStatusChangedEvent(updated, current.Status)
+			if err != nil {
+				return errors.Wrap(err, "failed to create order status changed event")
+			}
+			events = append(events, event)
+		}
+		return recordEvents(ctx, s.outboxRepo, events...)
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to update an order")
+		return nil, err
 	}
 	return updated, nil
 }
 
-func (s *OrderService) DeleteOrder(ctx context.Context, order *domain.Order) error {
-	err := s.orderRepo.DeleteOrder(ctx, order)
+// Takes the ordered quantities out of stock, and sets the prices the products are ordered at on the items
+// Returns the stock low events, for products which dropped below the low stock threshold
+func (s *OrderService) takeFromStock(ctx context.Context, order *domain.Order) ([]*domain.Event, error) {
+	var events []*domain.Event
+	for i := range *order.ProductItems {
+		item := &(*order.ProductItems)[i]
+		if item.Quantity <= 0 {
+			return nil, domain.NewValidationError("invalid order", domain.FieldError{Field: "productItems.quantity", Message: "must be greater than 0"})
+		}
+		// decremented in place rather than written back at the version read, so concurrent orders don't conflict
+		product, err := s.productRepo.TakeFromStock(ctx, item.ProductId, item.Quantity)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to take an ordered product out of stock")
+		}
+		item.UnitPrice = product.Price
+		if product.DroppedBelowLowStock(item.Quantity) {
+			event, err := domain.NewProductStockLowEvent(product)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to create product stock low event")
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (s *OrderService) DeleteOrder(ctx context.Context, order *domain.Order) (err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.DeleteOrder")
+	defer func() { span.End(err) }()
+	// the lines are only deleted along with the order, not when its version changed in the meantime
+	err = s.tx.TxContext(ctx, func(ctx context.Context) error {
+		return s.orderRepo.DeleteOrder(ctx, order)
+	})
 	if err != nil {
 		return errors.Wrap(err, "failed to update an order")
 	}
 	return nil
 }
 
-func (s *OrderService) GeneratePdf(ctx context.Context, order *domain.Order) error {
-	pdf := s.newReport(ctx, order)
-	pdf = header(pdf, []string{"Product No.", "Name", "Quantity", "Unit Price", "Total Price"})
-
-	pdf = s.tableContent(ctx, pdf, order)
+func (s *OrderService) GeneratePdf(ctx context.Context, order *domain.Order) (err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.GeneratePdf")
+	defer func() { span.End(err) }()
+	// the order isn't stored, so it is priced at the current prices
+	err = s.priceItems(ctx, order)
+	if err != nil {
+		return err
+	}
+	pdf := s.invoice(ctx, order)
 	if pdf.Err() {
 		return pdf.Error()
 	}
-	err := pdf.OutputFileAndClose("pdf/order/order_[" + order.ID + "].pdf")
+	err = pdf.OutputFileAndClose("pdf/order/order_[" + order.ID + "].pdf")
 
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// Renders the order invoice, as attached to the order confirmation email
+func (s *OrderService) InvoicePdf(ctx context.Context, order *domain.Order) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.InvoicePdf")
+	defer func() { span.End(err) }()
+	pdf := s.invoice(ctx, order)
+	if pdf.Err() {
+		return nil, pdf.Error()
+	}
+	var buf bytes.Buffer
+	err = pdf.Output(&buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render the invoice")
+	}
+	return buf.Bytes(), nil
+}
+
+// Sets the current prices of the products on the items, the products which don't exist anymore are left at 0
+func (s *OrderService) priceItems(ctx context.Context, order *domain.Order) error {
+	ids := make([]int64, len(*order.ProductItems))
+	for i, item := range *order.ProductItems {
+		ids[i] = item.ProductId
+	}
+	products, err := s.productRepo.FindProductsByIds(ctx, ids)
+	if err != nil {
+		return errors.Wrap(err, "failed to retrieve the ordered products")
+	}
+	for i := range *order.ProductItems {
+		item := &(*order.ProductItems)[i]
+		if product, ok := products[item.ProductId]; ok {
+			item.UnitPrice = product.Price
+		}
+	}
+	return nil
+}
+
+func (s *OrderService) invoice(ctx context.Context, order *domain.Order) *gofpdf.Fpdf {
+	pdf := s.newReport(ctx, order)
+	pdf = header(pdf, []string{"Product No.", "Name", "Quantity", "Unit Price", "Total Price"})
+
+	return s.tableContent(ctx, pdf, order)
+}
 func (s *OrderService) tableContent(ctx context.Context, pdf *gofpdf.Fpdf, order *domain.Order) *gofpdf.Fpdf {
 	pdf.SetFont("Times", "", 16)
 	pdf.SetFillColor(255, 255, 255)
 
+	ids := make([]int64, len(*order.ProductItems))
 	for i, orderedProduct := range *order.ProductItems {
-		product, _ := s.productRepo.FindProductById(ctx, orderedProduct.ProductId)
+		ids[i] = orderedProduct.ProductId
+	}
+	products, err := s.productRepo.FindProductsByIds(ctx, ids)
+	if err != nil {
+		pdf.SetError(errors.Wrap(err, "failed to retrieve the ordered products"))
+		return pdf
+	}
+
+	for i, orderedProduct := range *order.ProductItems {
+		product, ok := products[orderedProduct.ProductId]
+		if !ok {
+			continue
+		}
 
 		pdf.CellFormat(40, 10, strconv.Itoa(i+1), "1", 0, "C", false, 0, "")
 		pdf.CellFormat(40, 10, product.Name, "1", 0, "C", false, 0, "")
 		pdf.CellFormat(40, 10, strconv.Itoa(orderedProduct.Quantity), "1", 0, "C", false, 0, "")
-		pdf.CellFormat(40, 10, strconv.FormatFloat(float64(product.Price), 'f', 2, 64), "1", 0, "C", false, 0, "")
-		pdf.CellFormat(40, 10, strconv.FormatFloat(float64(orderedProduct.Quantity)*float64(product.Price), 'f', 2, 64), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(40, 10, strconv.FormatFloat(float64(orderedProduct.UnitPrice), 'f', 2, 64), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(40, 10, strconv.FormatFloat(float64(orderedProduct.Quantity)*float64(orderedProduct.UnitPrice), 'f', 2, 64), "1", 0, "C", false, 0, "")
 		pdf.Ln(-1)
 	}
 
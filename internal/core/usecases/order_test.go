@@ -78,8 +78,8 @@ package usecases
 // 	assert.Equal(suite.T(), testOrder.ProductItems, order.ProductItems)
 // 	assert.Equal(suite.T(), "CREATED", order.Status)
 // 	suite.orderRep.DeleteOrder(context.TODO(), order)
-// 	suite.productRep.DeleteProduct(context.TODO(), pId)
-// 	suite.categoryRep.DeleteCategory(context.TODO(), cId)
+// 	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
+// 	suite.categoryRep.DeleteCategory(context.TODO(), cId, 0)
 // }
 
 // func (suite *OrderSuite) TestCreateOrder() {
@@ -113,8 +113,8 @@ package usecases
 // 	assert.Equal(suite.T(), testOrder.ProductItems, created.ProductItems)
 // 	assert.Equal(suite.T(), "CREATED", created.Status)
 // 	suite.orderRep.DeleteOrder(context.TODO(), created)
-// 	suite.productRep.DeleteProduct(context.TODO(), pId)
-// 	suite.categoryRep.DeleteCategory(context.TODO(), cId)
+// 	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
+// 	suite.categoryRep.DeleteCategory(context.TODO(), cId, 0)
 // }
 
 // func (suite *OrderSuite) TestUpdateOrderStatus() {
@@ -152,8 +152,8 @@ package usecases
 // 	}
 // 	assert.Equal(suite.T(), "PENDING", created.Status)
 // 	suite.orderRep.DeleteOrder(context.TODO(), created)
-// 	suite.productRep.DeleteProduct(context.TODO(), pId)
-// 	suite.categoryRep.DeleteCategory(context.TODO(), cId)
+// 	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
+// 	suite.categoryRep.DeleteCategory(context.TODO(), cId, 0)
 // }
 
 // func (suite *OrderSuite) TestDeleteOrder() {
@@ -188,8 +188,8 @@ package usecases
 // 	if err != nil {
 // 		suite.T().Fatal(err)
 // 	}
-// 	suite.productRep.DeleteProduct(context.TODO(), pId)
-// 	suite.categoryRep.DeleteCategory(context.TODO(), cId)
+// 	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
+// 	suite.categoryRep.DeleteCategory(context.TODO(), cId, 0)
 // }
 
 // func (suite *OrderSuite) TestCreateOrderWithInvalidProduct() {
@@ -226,8 +226,8 @@ package usecases
 // 	}
 // 	_, err = suite.orderSvc.CreateOrder(context.TODO(), &testOrder)
 // 	assert.NotNil(suite.T(), err)
-// 	suite.productRep.DeleteProduct(context.TODO(), pId)
-// 	suite.categoryRep.DeleteCategory(context.TODO(), cId)
+// 	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
+// 	suite.categoryRep.DeleteCategory(context.TODO(), cId, 0)
 // }
 // func (suite *OrderSuite) TestCreateOrderWithInvalidProductQuantity() {
 // 	testCategory := domain.Category{
@@ -255,8 +255,8 @@ package usecases
 // 	}
 // 	_, err = suite.orderSvc.CreateOrder(context.TODO(), &testOrder)
 // 	assert.NotNil(suite.T(), err)
-// 	suite.productRep.DeleteProduct(context.TODO(), pId)
-// 	suite.categoryRep.DeleteCategory(context.TODO(), cId)
+// 	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
+// 	suite.categoryRep.DeleteCategory(context.TODO(), cId, 0)
 // }
 // func (suite *OrderSuite) TestInvalidProductStatusUpdate() {
 // 	testCategory := domain.Category{
@@ -290,6 +290,6 @@ package usecases
 // 	_, err = suite.orderSvc.UpdateOrderStatus(context.TODO(), created)
 // 	assert.NotNil(suite.T(), err)
 // 	suite.orderRep.DeleteOrder(context.TODO(), created)
-// 	suite.productRep.DeleteProduct(context.TODO(), pId)
-// 	suite.categoryRep.DeleteCategory(context.TODO(), cId)
+// 	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
+// 	suite.categoryRep.DeleteCategory(context.TODO(), cId, 0)
 // }
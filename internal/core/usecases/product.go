@@ -12,47 +12,128 @@ import (
 var _ ports.ProductUsecase = (*ProductService)(nil)
 
 type ProductService struct {
-	productRepo *repo.ProductRepository
+	productRepo ports.ProductRepo
+	outboxRepo  *repo.OutboxRepository
+	tx          ports.Transactor
 }
 
-func NewProductService(productRepo *repo.ProductRepository) *ProductService {
+func NewProductService(productRepo ports.ProductRepo, outboxRepo *repo.OutboxRepository, tx ports.Transactor) *ProductService {
 	return &ProductService{
 		productRepo: productRepo,
+		outboxRepo:  outboxRepo,
+		tx:          tx,
 	}
 }
 
-func (s *ProductService) GetAllProducts(ctx context.Context) (*[]domain.Product, error) {
+func (s *ProductService) GetAllProducts(ctx context.Context) (_ *[]domain.Product, err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetAllProducts")
+	defer func() { span.End(err) }()
 	products, err := s.productRepo.GetAllProducts(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to retrieve products")
 	}
 	return products, nil
 }
-func (s *ProductService) FindProductById(ctx context.Context, id int64) (*domain.Product, error) {
+func (s *ProductService) FindProductById(ctx context.Context, id int64) (_ *domain.Product, err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.FindProductById")
+	defer func() { span.End(err) }()
 	product, err := s.productRepo.FindProductById(ctx, id)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to retrieve a product")
 	}
 	return product, nil
 }
-func (s *ProductService) CreateProduct(ctx context.Context, product *domain.Product) (int64, error) {
-	id, err := s.productRepo.InsertProduct(ctx, product)
+func (s *ProductService) CreateProduct(ctx context.Context, product *domain.Product) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.CreateProduct")
+	defer func() { span.End(err) }()
+	var id int64
+	err = s.tx.TxContext(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.productRepo.InsertProduct(ctx, product)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create a product")
+		}
+		return s.recordProductChanged(ctx, domain.EventProductCreated, id)
+	})
 	if err != nil {
-		return 0, errors.Wrap(err, "Failed to create a product")
+		return 0, err
 	}
 	return id, nil
 }
-func (s *ProductService) DeleteProduct(ctx context.Context, id int64) (int64, error) {
-	id, err := s.productRepo.DeleteProduct(ctx, id)
+func (s *ProductService) DeleteProduct(ctx context.Context, id int64, version int) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.DeleteProduct")
+	defer func() { span.End(err) }()
+	var rows int64
+	err = s.tx.TxContext(ctx, func(ctx context.Context) error {
+		var err error
+		rows, err = s.productRepo.DeleteProduct(ctx, id, version)
+		if err != nil {
+			return errors.Wrap(err, "Failed to delete a product")
+		}
+		if rows == 0 {
+			return domain.NewNotFoundError("product")
+		}
+		event, err := domain.NewProductDeletedEvent(id)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create product deleted event")
+		}
+		return recordEvents(ctx, s.outboxRepo, event)
+	})
 	if err != nil {
-		return 0, errors.Wrap(err, "Failed to delete a product")
+		return 0, err
 	}
-	return id, nil
+	return rows, nil
 }
-func (s *ProductService) UpdateProduct(ctx context.Context, product *domain.Product, id int64) (int64, error) {
-	id, err := s.productRepo.UpdateProduct(ctx, product, id)
+func (s *ProductService) UpdateProduct(ctx context.Context, product *domain.Product, id int64) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.UpdateProduct")
+	defer func() { span.End(err) }()
+	var rows int64
+	err = s.tx.TxContext(ctx, func(ctx context.Context) error {
+		var err error
+		rows, err = s.productRepo.UpdateProduct(ctx, product, id)
+		if err != nil {
+			return errors.Wrap(err, "Failed to edit a product")
+		}
+		if rows == 0 {
+			return domain.NewNotFoundError("product")
+		}
+		return s.recordProductChanged(ctx, domain.EventProductUpdated, id)
+	})
 	if err != nil {
-		return 0, errors.Wrap(err, "Failed to edit a product")
+		return 0, err
 	}
-	return id, nil
+	return rows, nil
+}
+func (s *ProductService) PatchProduct(ctx context.Context, id int64, patch *domain.ProductPatch) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.PatchProduct")
+	defer func() { span.End(err) }()
+	var rows int64
+	err = s.tx.TxContext(ctx, func(ctx context.Context) error {
+		var err error
+		rows, err = s.productRepo.PatchProduct(ctx, id, patch)
+		if err != nil {
+			return errors.Wrap(err, "Failed to patch a product")
+		}
+		if rows == 0 {
+			return domain.NewNotFoundError("product")
+		}
+		return s.recordProductChanged(ctx, domain.EventProductUpdated, id)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rows, nil
+}
+
+// Records an event with the state of the product, as written in the transaction in ctx
+func (s *ProductService) recordProductChanged(ctx context.Context, eventType domain.EventType, id int64) error {
+	product, err := s.productRepo.FindProductById(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to retrieve a product")
+	}
+	event, err := domain.NewProductChangedEvent(eventType, product)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create "+string(eventType)+" event")
+	}
+	return recordEvents(ctx, s.outboxRepo, event)
 }
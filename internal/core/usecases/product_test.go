@@ -2,9 +2,14 @@ package usecases
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/testutil"
 	"github.com/stretchr/testify/assert"
@@ -17,6 +22,9 @@ type ProductSuite struct {
 	productSvc  *ProductService
 	categoryRep *repo.CategoryRepository
 	categorySvc *CategoryService
+	orderRep    *repo.OrderRepository
+	orderSvc    *OrderService
+	userRep     *repo.UserRepository
 }
 
 func (suite *ProductSuite) SetupTest() {
@@ -30,9 +38,12 @@ func (suite *ProductSuite) TearDownTest() {
 func (suite *ProductSuite) SetupSuite() {
 	app := testutil.InitTestApp()
 	suite.productRep = repo.NewProductRepository(app.DB)
-	suite.productSvc = NewProductService(suite.productRep)
+	suite.productSvc = NewProductService(suite.productRep, repo.NewOutboxRepository(app.DB), app.DB)
 	suite.categoryRep = repo.NewCategoryRepository(app.DB)
 	suite.categorySvc = NewCategoryService(suite.categoryRep)
+	suite.orderRep = repo.NewOrderRepository(app.DB)
+	suite.userRep = repo.NewUserRepository(app.DB)
+	suite.orderSvc = NewOrderService(suite.orderRep, suite.productRep, suite.userRep, repo.NewOutboxRepository(app.DB), app.DB)
 }
 
 func TestProductTestSuite(t *testing.T) {
@@ -89,8 +100,8 @@ func (suite *ProductSuite) TestGetProducts() {
 		suite.T().Fatal(err)
 	}
 	assert.NotNil(suite.T(), products)
-	suite.categorySvc.DeleteCategory(context.TODO(), cId)
-	suite.productRep.DeleteProduct(context.TODO(), pId)
+	suite.categorySvc.DeleteCategory(context.TODO(), cId, 0)
+	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
 }
 func (suite *ProductSuite) TestGetProduct() {
 	testCategory := domain.Category{
@@ -123,8 +134,8 @@ func (suite *ProductSuite) TestGetProduct() {
 	assert.Equal(suite.T(), testProduct.Quantity, product.Quantity)
 	assert.Equal(suite.T(), testProduct.Category.Id, product.Category.Id)
 
-	suite.categorySvc.DeleteCategory(context.TODO(), cId)
-	suite.productRep.DeleteProduct(context.TODO(), pId)
+	suite.categorySvc.DeleteCategory(context.TODO(), cId, 0)
+	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
 }
 func (suite *ProductSuite) TestCreateProduct() {
 	testCategory := domain.Category{
@@ -148,8 +159,8 @@ func (suite *ProductSuite) TestCreateProduct() {
 	}
 	zeroId := int64(0)
 	assert.NotEqual(suite.T(), zeroId, pId)
-	suite.categorySvc.DeleteCategory(context.TODO(), cId)
-	suite.productRep.DeleteProduct(context.TODO(), pId)
+	suite.categorySvc.DeleteCategory(context.TODO(), cId, 0)
+	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
 }
 func (suite *ProductSuite) TestUpdateProduct() {
 	testCategory := domain.Category{
@@ -185,8 +196,8 @@ func (suite *ProductSuite) TestUpdateProduct() {
 	}
 	noRows := int64(0)
 	assert.NotEqual(suite.T(), noRows, rows)
-	suite.categorySvc.DeleteCategory(context.TODO(), cId)
-	suite.productRep.DeleteProduct(context.TODO(), pId)
+	suite.categorySvc.DeleteCategory(context.TODO(), cId, 0)
+	suite.productRep.DeleteProduct(context.TODO(), pId, 0)
 }
 func (suite *ProductSuite) TestDeleteProduct() {
 	testCategory := domain.Category{
@@ -208,11 +219,173 @@ func (suite *ProductSuite) TestDeleteProduct() {
 	if err != nil {
 		suite.T().Fatalf("Error creating test product: %s", err)
 	}
-	rows, err := suite.productSvc.DeleteProduct(context.TODO(), pId)
+	rows, err := suite.productSvc.DeleteProduct(context.TODO(), pId, 0)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
 	noRows := int64(0)
 	assert.NotEqual(suite.T(), noRows, rows)
-	suite.categorySvc.DeleteCategory(context.TODO(), cId)
+	suite.categorySvc.DeleteCategory(context.TODO(), cId, 0)
+}
+
+// Inserts the given number of products, in a new category, returning their ids
+func (suite *ProductSuite) insertProducts(n int) (int64, []int64) {
+	cId, err := suite.categoryRep.InsertCategory(context.TODO(), &domain.Category{Name: "test"})
+	if err != nil {
+		suite.T().Fatalf("Error creating test category: %s", err)
+	}
+	var ids []int64
+	for i := 0; i < n; i++ {
+		pId, err := suite.productRep.InsertProduct(context.TODO(), &domain.Product{
+			Name:     fmt.Sprintf("test %d", i),
+			Price:    100.0,
+			Quantity: 10,
+			Category: &domain.Category{Id: int(cId)},
+		})
+		if err != nil {
+			suite.T().Fatalf("Error creating test product: %s", err)
+		}
+		ids = append(ids, pId)
+	}
+	return cId, ids
+}
+
+// Inserts a user who verified their email, so they can order
+func (suite *ProductSuite) insertCustomer() *domain.User {
+	user := &domain.User{Email: fmt.Sprintf("customer-%d@provider.com", time.Now().UnixNano())}
+	if err := suite.userRep.Insert(context.TODO(), user); err != nil {
+		suite.T().Fatal(err)
+	}
+	if err := suite.userRep.MarkEmailVerified(context.TODO(), user.ID); err != nil {
+		suite.T().Fatal(err)
+	}
+	return user
+}
+
+func (suite *ProductSuite) deleteProducts(cId int64, ids []int64) {
+	for _, pId := range ids {
+		suite.productRep.DeleteProduct(context.TODO(), pId, 0)
+	}
+	suite.categoryRep.DeleteCategory(context.TODO(), cId, 0)
+}
+
+func (suite *ProductSuite) TestProductsAreReadWithTheirCategoryInOneQuery() {
+	cId, ids := suite.insertProducts(5)
+	defer suite.deleteProducts(cId, ids)
+
+	ctx, queries := database.WithQueryCounter(context.TODO())
+	products, err := suite.productSvc.GetAllProducts(ctx)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	assert.Equal(suite.T(), 1, queries.Count())
+	assert.GreaterOrEqual(suite.T(), len(*products), 5)
+	for _, product := range *products {
+		assert.NotEmpty(suite.T(), product.Category.Name)
+	}
+
+	ctx, queries = database.WithQueryCounter(context.TODO())
+	product, err := suite.productRep.FindProductById(ctx, ids[0])
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	assert.Equal(suite.T(), 1, queries.Count())
+	assert.Equal(suite.T(), int(cId), product.Category.Id)
+
+	ctx, queries = database.WithQueryCounter(context.TODO())
+	found, err := suite.productRep.FindProductsByIds(ctx, append(ids, 0))
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	assert.Equal(suite.T(), 1, queries.Count())
+	assert.Len(suite.T(), found, 5)
+	assert.Equal(suite.T(), "test", found[ids[4]].Category.Name)
+}
+
+func (suite *ProductSuite) TestOrderLinesAreReadInOneQuery() {
+	cId, ids := suite.insertProducts(5)
+	defer suite.deleteProducts(cId, ids)
+	user := &domain.User{Email: "order-lines@provider.com"}
+	if err := suite.userRep.Insert(context.TODO(), user); err != nil {
+		suite.T().Fatal(err)
+	}
+	var items []domain.OrderedProduct
+	for _, pId := range ids {
+		items = append(items, domain.OrderedProduct{ProductId: pId, Quantity: 1})
+	}
+	order, err := suite.orderRep.CreateOrder(context.TODO(), &domain.Order{User: user, ProductItems: &items})
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	defer suite.orderRep.DeleteOrder(context.TODO(), order)
+
+	ctx, queries := database.WithQueryCounter(context.TODO())
+	found, err := suite.orderRep.FindOrderById(ctx, order.ID)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	// the order, its lines and its user
+	assert.Equal(suite.T(), 3, queries.Count())
+	assert.Equal(suite.T(), items, *found.ProductItems)
+}
+
+func (suite *ProductSuite) TestOrdersKeepTheirPrices() {
+	cId, ids := suite.insertProducts(1)
+	defer suite.deleteProducts(cId, ids)
+	user := suite.insertCustomer()
+	items := []domain.OrderedProduct{{ProductId: ids[0], Quantity: 2}}
+	order, err := suite.orderSvc.CreateOrder(context.TODO(), &domain.Order{User: user, ProductItems: &items})
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	defer suite.orderRep.DeleteOrder(context.TODO(), order)
+
+	// the price changes after the order
+	product, _ := suite.productRep.FindProductById(context.TODO(), ids[0])
+	product.Price = 150.0
+	if _, err := suite.productRep.UpdateProduct(context.TODO(), product, ids[0]); err != nil {
+		suite.T().Fatal(err)
+	}
+
+	found, err := suite.orderRep.FindOrderById(context.TODO(), order.ID)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	assert.Equal(suite.T(), float32(100.0), (*found.ProductItems)[0].UnitPrice)
+}
+
+func (suite *ProductSuite) TestConcurrentOrdersTakeFromStock() {
+	cId, ids := suite.insertProducts(1)
+	defer suite.deleteProducts(cId, ids)
+	user := suite.insertCustomer()
+
+	// 10 in stock, 3 each: 3 orders go through, the others find too few items left
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			items := []domain.OrderedProduct{{ProductId: ids[0], Quantity: 3}}
+			order, err := suite.orderSvc.CreateOrder(context.TODO(), &domain.Order{User: user, ProductItems: &items})
+			if err == nil {
+				defer suite.orderRep.DeleteOrder(context.TODO(), order)
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		var insufficientStock *domain.InsufficientStockError
+		if err == nil {
+			created++
+		} else if !errors.As(err, &insufficientStock) {
+			suite.T().Fatal(err)
+		}
+	}
+	assert.Equal(suite.T(), 3, created)
+	product, _ := suite.productRep.FindProductById(database.WithPrimary(context.TODO()), ids[0])
+	assert.Equal(suite.T(), 1, product.Quantity)
 }
@@ -0,0 +1,86 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	domain "github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
+	"github.com/pkg/errors"
+)
+
+// Generates a random token, which is safe to put in a URL
+// Returns the token, and the hash to store instead of it
+func newToken() (string, string, error) {
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashToken(token), nil
+}
+
+// The tokens are random and long enough that a fast, unsalted hash is sufficient
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Stores a new token for the user, and returns it
+func issueToken(ctx context.Context, tokenRepo *repo.UserTokenRepository, userId string, purpose domain.TokenPurpose, ttl time.Duration) (string, error) {
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to generate token")
+	}
+	err = tokenRepo.InsertToken(ctx, &domain.UserToken{
+		UserID:    userId,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to store token")
+	}
+	return token, nil
+}
+
+// Returns the token, or domain.ErrInvalidToken unless it can be used
+func findToken(ctx context.Context, tokenRepo *repo.UserTokenRepository, token string, purpose domain.TokenPurpose) (*domain.UserToken, error) {
+	userToken, err := tokenRepo.FindTokenByHash(ctx, hashToken(token), purpose)
+	if err != nil {
+		return nil, err
+	}
+	if !userToken.IsUsable() {
+		return nil, domain.ErrInvalidToken
+	}
+	return userToken, nil
+}
+
+// Uses up the token, returns domain.ErrInvalidToken unless it can be used
+func useToken(ctx context.Context, tokenRepo *repo.UserTokenRepository, token string, purpose domain.TokenPurpose) (*domain.UserToken, error) {
+	userToken, err := findToken(ctx, tokenRepo, token, purpose)
+	if err != nil {
+		return nil, err
+	}
+	err = markTokenUsed(ctx, tokenRepo, userToken)
+	if err != nil {
+		return nil, err
+	}
+	return userToken, nil
+}
+
+func markTokenUsed(ctx context.Context, tokenRepo *repo.UserTokenRepository, userToken *domain.UserToken) error {
+	used, err := tokenRepo.MarkTokenUsed(ctx, userToken.ID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to use token")
+	}
+	if !used {
+		return domain.ErrInvalidToken
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+)
+
+// Tracer of the usecase methods, which does nothing until one is set
+var tracer ports.Tracer = noopTracer{}
+
+// Sets the tracer the usecase methods start their spans with, e.g. the OpenTelemetry one
+func SetTracer(t ports.Tracer) {
+	tracer = t
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string) (context.Context, ports.Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
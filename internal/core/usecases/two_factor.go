@@ -0,0 +1,300 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"time"
+
+	domain "github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	ports "github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/totp"
+	"github.com/pkg/errors"
+)
+
+var _ ports.TwoFactorUsecase = (*TwoFactorService)(nil)
+
+// Issuer shown in the authenticator apps when none is configured
+const DefaultTOTPIssuer = "HEXFWK"
+
+// TwoFactorService manages the users' authenticator apps and recovery codes
+// The second step of the login itself is done by the LoginService
+type TwoFactorService struct {
+	userRepo      *repo.UserRepository
+	twoFactorRepo *repo.TwoFactorRepository
+	auditRepo     *repo.AuthAuditRepository
+	tx            ports.Transactor
+	// the codes are throttled like the logins, a wrong one counting as a failed login
+	policy domain.LoginPolicy
+	issuer string
+}
+
+func NewTwoFactorService(userRepo *repo.UserRepository, twoFactorRepo *repo.TwoFactorRepository, auditRepo *repo.AuthAuditRepository,
+	tx ports.Transactor, policy domain.LoginPolicy, issuer string) *TwoFactorService {
+	if len(issuer) == 0 {
+		issuer = DefaultTOTPIssuer
+	}
+	return &TwoFactorService{
+		userRepo:      userRepo,
+		twoFactorRepo: twoFactorRepo,
+		auditRepo:     auditRepo,
+		tx:            tx,
+		policy:        withLoginPolicyDefaults(policy),
+		issuer:        issuer,
+	}
+}
+
+// Generates a new secret for the user to set up their authenticator app with
+// Two-factor authentication is only enabled once a first code from the app is confirmed
+func (s *TwoFactorService) Enroll(ctx context.Context, userId string) (_ *domain.TOTPEnrollment, err error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Enroll")
+	defer func() { span.End(err) }()
+	user, err := s.userRepo.FindByID(ctx, userId)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve user")
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate TOTP secret")
+	}
+	err = s.twoFactorRepo.InsertTOTP(ctx, &domain.UserTOTP{UserID: userId, Secret: secret})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to store TOTP secret")
+	}
+	return &domain.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Enables two-factor authentication with the first code from the authenticator app
+// Returns the recovery codes, which are only stored hashed, so they can't be shown again
+func (s *TwoFactorService) Confirm(ctx context.Context, userId string, code string, ip string) (_ []string, err error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Confirm")
+	defer func() { span.End(err) }()
+	var codes []string
+	err = s.tx.TxContext(ctx, func(ctx context.Context) error {
+		userTOTP, err := s.findTOTP(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if userTOTP.IsConfirmed() {
+			return domain.ErrTwoFactorEnabled
+		}
+		err = useTOTPCode(ctx, s.twoFactorRepo, userTOTP, code)
+		if err != nil {
+			return err
+		}
+		err = s.twoFactorRepo.ConfirmTOTP(ctx, userId)
+		if err != nil {
+			return errors.Wrap(err, "Failed to confirm TOTP")
+		}
+		codes, err = s.replaceRecoveryCodes(ctx, userId)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, domain.AuthTwoFactorEnabled, userId, ip)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disables two-factor authentication, which requires a code from the app or a recovery code
+// Returns a *domain.LoginBlockedError while the logins to the account or from the IP are refused
+func (s *TwoFactorService) Disable(ctx context.Context, userId string, code string, ip string) (err error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Disable")
+	defer func() { span.End(err) }()
+	return s.verifiedAttempt(ctx, userId, ip, func(ctx context.Context, userTOTP *domain.UserTOTP) error {
+		_, err := verifySecondFactor(ctx, s.twoFactorRepo, userTOTP, code)
+		if err != nil {
+			return err
+		}
+		err = s.twoFactorRepo.DeleteTOTP(ctx, userId)
+		if err != nil {
+			return errors.Wrap(err, "Failed to delete TOTP")
+		}
+		return s.record(ctx, domain.AuthTwoFactorDisabled, userId, ip)
+	})
+}
+
+// Replaces the user's recovery codes, e.g. when they ran out of them, which requires a code from the app
+// Returns a *domain.LoginBlockedError while the logins to the account or from the IP are refused
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userId string, code string, ip string) (_ []string, err error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.RegenerateRecoveryCodes")
+	defer func() { span.End(err) }()
+	var codes []string
+	err = s.verifiedAttempt(ctx, userId, ip, func(ctx context.Context, userTOTP *domain.UserTOTP) error {
+		err := useTOTPCode(ctx, s.twoFactorRepo, userTOTP, code)
+		if err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(ctx, userId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Runs the change requiring a second factor as an attempt to authenticate as the user, refused during a lockout
+// A wrong code is recorded as a failed login, as when completing a login
+func (s *TwoFactorService) verifiedAttempt(ctx context.Context, userId string, ip string,
+	change func(ctx context.Context, userTOTP *domain.UserTOTP) error) error {
+	user, err := s.userRepo.FindByID(ctx, userId)
+	if err != nil {
+		return errors.Wrap(err, "Failed to retrieve user")
+	}
+	return lockedAttempt(ctx, s.tx, s.auditRepo, user.Email, func(ctx context.Context) error {
+		err := checkLoginBlocked(ctx, s.auditRepo, s.policy, user.Email, ip)
+		if err != nil {
+			return err
+		}
+		userTOTP, err := s.findConfirmedTOTP(ctx, userId)
+		if err != nil {
+			return err
+		}
+		err = change(ctx, userTOTP)
+		if err == domain.ErrInvalidTwoFactorCode {
+			recordErr := recordAuthEvent(ctx, s.auditRepo, domain.AuthLoginFailed, user.Email, &user.ID, nil, ip)
+			if recordErr != nil {
+				return recordErr
+			}
+		}
+		return err
+	})
+}
+
+func (s *TwoFactorService) IsEnabled(ctx context.Context, userId string) (_ bool, err error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.IsEnabled")
+	defer func() { span.End(err) }()
+	return isTwoFactorEnabled(ctx, s.twoFactorRepo, userId)
+}
+
+// Returns how many unused recovery codes the user has left
+func (s *TwoFactorService) CountRecoveryCodes(ctx context.Context, userId string) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.CountRecoveryCodes")
+	defer func() { span.End(err) }()
+	count, err := s.twoFactorRepo.CountRecoveryCodes(ctx, userId)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to count recovery codes")
+	}
+	return count, nil
+}
+
+func (s *TwoFactorService) findTOTP(ctx context.Context, userId string) (*domain.UserTOTP, error) {
+	userTOTP, err := s.twoFactorRepo.FindTOTP(ctx, userId)
+	if err == repo.ErrTOTPNotFound {
+		return nil, domain.ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve TOTP")
+	}
+	return userTOTP, nil
+}
+
+func (s *TwoFactorService) findConfirmedTOTP(ctx context.Context, userId string) (*domain.UserTOTP, error) {
+	userTOTP, err := s.findTOTP(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !userTOTP.IsConfirmed() {
+		return nil, domain.ErrTwoFactorNotEnrolled
+	}
+	return userTOTP, nil
+}
+
+func (s *TwoFactorService) replaceRecoveryCodes(ctx context.Context, userId string) ([]string, error) {
+	codes := make([]string, domain.RecoveryCodeCount)
+	codeHashes := make([]string, domain.RecoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to generate recovery code")
+		}
+		codes[i] = code
+		codeHashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+	err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userId, codeHashes)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to store recovery codes")
+	}
+	return codes, nil
+}
+
+func (s *TwoFactorService) record(ctx context.Context, eventType domain.AuthEventType, userId string, ip string) error {
+	user, err := s.userRepo.FindByID(ctx, userId)
+	if err != nil {
+		return errors.Wrap(err, "Failed to retrieve user")
+	}
+	return recordAuthEvent(ctx, s.auditRepo, eventType, user.Email, &user.ID, nil, ip)
+}
+
+// Whether the user confirmed an authenticator app, so the logins require a second factor
+func isTwoFactorEnabled(ctx context.Context, twoFactorRepo *repo.TwoFactorRepository, userId string) (bool, error) {
+	userTOTP, err := twoFactorRepo.FindTOTP(ctx, userId)
+	if err == repo.ErrTOTPNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to retrieve TOTP")
+	}
+	return userTOTP.IsConfirmed(), nil
+}
+
+// Checks the code from the authenticator app, or else uses up the matching recovery code
+// Returns whether a recovery code was used, or domain.ErrInvalidTwoFactorCode if neither matches
+func verifySecondFactor(ctx context.Context, twoFactorRepo *repo.TwoFactorRepository, userTOTP *domain.UserTOTP, code string) (bool, error) {
+	err := useTOTPCode(ctx, twoFactorRepo, userTOTP, code)
+	if err != domain.ErrInvalidTwoFactorCode {
+		return false, err
+	}
+	used, err := twoFactorRepo.UseRecoveryCode(ctx, userTOTP.UserID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to use recovery code")
+	}
+	if !used {
+		return false, domain.ErrInvalidTwoFactorCode
+	}
+	return true, nil
+}
+
+// Checks the code from the authenticator app, a code which was already accepted is refused
+func useTOTPCode(ctx context.Context, twoFactorRepo *repo.TwoFactorRepository, userTOTP *domain.UserTOTP, code string) error {
+	step, ok := totp.Validate(userTOTP.Secret, code, time.Now())
+	if !ok {
+		return domain.ErrInvalidTwoFactorCode
+	}
+	fresh, err := twoFactorRepo.UseTOTPStep(ctx, userTOTP.UserID, step)
+	if err != nil {
+		return errors.Wrap(err, "Failed to use TOTP code")
+	}
+	if !fresh {
+		return domain.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// Recovery codes are formatted as xxxxx-xxxxx, from 32 characters without look-alikes, so every byte maps to one evenly
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz123456789"
+
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, 10)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+	code := make([]byte, len(raw))
+	for i, b := range raw {
+		code[i] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+	}
+	return string(code[:5]) + "-" + string(code[5:]), nil
+}
+
+// Recovery codes are accepted regardless of case, spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -13,24 +13,50 @@ import (
 var _ ports.UserUsecase = (*UserService)(nil)
 
 type UserService struct {
-	userRepo *repo.UserRepository
+	userRepo   *repo.UserRepository
+	tokenRepo  *repo.UserTokenRepository
+	outboxRepo *repo.OutboxRepository
+	tx         ports.Transactor
+	notifier   ports.UserNotifier
 }
 
-func NewUserService(userRepo *repo.UserRepository) *UserService {
+func NewUserService(userRepo *repo.UserRepository, tokenRepo *repo.UserTokenRepository, outboxRepo *repo.OutboxRepository,
+	tx ports.Transactor, notifier ports.UserNotifier) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		tokenRepo:  tokenRepo,
+		outboxRepo: outboxRepo,
+		tx:         tx,
+		notifier:   notifier,
 	}
 }
 
-func (s *UserService) RegisterUser(ctx context.Context, user *domain.User) error {
-	err := s.userRepo.Insert(ctx, user)
+// Registers the user, and emails them a link to verify their email
+func (s *UserService) RegisterUser(ctx context.Context, user *domain.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.RegisterUser")
+	defer func() { span.End(err) }()
+	err = s.tx.TxContext(ctx, func(ctx context.Context) error {
+		err := s.userRepo.Insert(ctx, user)
+		if err != nil {
+			return errors.Wrap(err, "Failed to register user")
+		}
+		event, err := domain.NewUserRegisteredEvent(user)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create user registered event")
+		}
+		return recordEvents(ctx, s.outboxRepo, event)
+	})
 	if err != nil {
-		return errors.Wrap(err, "Failed to register user")
+		return err
 	}
+	// the user is registered either way, and can ask for the verification email again
+	s.sendEmailVerification(ctx, user)
 	return nil
 }
 
-func (s *UserService) FindByID(ctx context.Context, id string) (*domain.User, error) {
+func (s *UserService) FindByID(ctx context.Context, id string) (_ *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.FindByID")
+	defer func() { span.End(err) }()
 	user, err := s.userRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to retrieve user")
@@ -39,7 +65,9 @@ func (s *UserService) FindByID(ctx context.Context, id string) (*domain.User, er
 	return user, nil
 }
 
-func (s *UserService) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+func (s *UserService) FindByEmail(ctx context.Context, email string) (_ *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.FindByEmail")
+	defer func() { span.End(err) }()
 	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to retrieve user")
@@ -48,10 +76,145 @@ func (s *UserService) FindByEmail(ctx context.Context, email string) (*domain.Us
 	return user, nil
 }
 
-func (s *UserService) Update(ctx context.Context, egwUser *domain.User) error {
-	err := s.userRepo.Update(ctx, egwUser)
+func (s *UserService) Update(ctx context.Context, egwUser *domain.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Update")
+	defer func() { span.End(err) }()
+	err = s.userRepo.Update(ctx, egwUser)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func (s *UserService) Patch(ctx context.Context, id string, patch *domain.UserPatch) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Patch")
+	defer func() { span.End(err) }()
+	err = s.userRepo.Patch(ctx, id, patch)
+	if err != nil {
+		return errors.Wrap(err, "Failed to patch user")
+	}
+	return nil
+}
+
+// Emails the user a password reset link
+// Unknown emails are ignored, so the caller can't tell which emails are registered
+func (s *UserService) RequestPasswordReset(ctx context.Context, email string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.RequestPasswordReset")
+	defer func() { span.End(err) }()
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err == repo.ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to retrieve user")
+	}
+	token, err := issueToken(ctx, s.tokenRepo, user.ID, domain.TokenPasswordReset, domain.PasswordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+	err = s.notifier.SendPasswordReset(ctx, user, token)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send password reset email")
+	}
+	return nil
+}
+
+// Sets a new password using a reset token, revoking the user's sessions
+// Since the token was emailed to the user, it also verifies their email
+func (s *UserService) ResetPassword(ctx context.Context, token string, passwordHash string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.ResetPassword")
+	defer func() { span.End(err) }()
+	return s.tx.TxContext(ctx, func(ctx context.Context) error {
+		userToken, err := useToken(ctx, s.tokenRepo, token, domain.TokenPasswordReset)
+		if err != nil {
+			return err
+		}
+		_, err = s.userRepo.UpdatePassword(ctx, userToken.UserID, passwordHash)
+		if err != nil {
+			return errors.Wrap(err, "Failed to reset password")
+		}
+		err = s.userRepo.MarkEmailVerified(ctx, userToken.UserID)
+		if err != nil {
+			return errors.Wrap(err, "Failed to verify email")
+		}
+		// other reset links sent before are no longer valid
+		return s.tokenRepo.DeleteUserTokens(ctx, userToken.UserID, domain.TokenPasswordReset)
+	})
+}
+
+// Sets a new password for the user, revoking all their sessions
+// Returns the new session version, for the caller to issue a new session with
+func (s *UserService) ChangePassword(ctx context.Context, id string, passwordHash string) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.ChangePassword")
+	defer func() { span.End(err) }()
+	sessionVersion, err := s.userRepo.UpdatePassword(ctx, id, passwordHash)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to change password")
+	}
+	return sessionVersion, nil
+}
+
+// Emails the user a new link to verify their email, unless it is already verified
+func (s *UserService) RequestEmailVerification(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.RequestEmailVerification")
+	defer func() { span.End(err) }()
+	user, err := s.userRepo.FindByID(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to retrieve user")
+	}
+	if user.IsVerified() {
+		return nil
+	}
+	return s.sendEmailVerification(ctx, user)
+}
+
+func (s *UserService) VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.VerifyEmail")
+	defer func() { span.End(err) }()
+	return s.tx.TxContext(ctx, func(ctx context.Context) error {
+		userToken, err := useToken(ctx, s.tokenRepo, token, domain.TokenEmailVerification)
+		if err != nil {
+			return err
+		}
+		err = s.userRepo.MarkEmailVerified(ctx, userToken.UserID)
+		if err != nil {
+			return errors.Wrap(err, "Failed to verify email")
+		}
+		return s.tokenRepo.DeleteUserTokens(ctx, userToken.UserID, domain.TokenEmailVerification)
+	})
+}
+
+// Whether a session of the given version is still valid, i.e. it wasn't revoked since it was issued
+func (s *UserService) IsSessionValid(ctx context.Context, id string, sessionVersion int) (bool, error) {
+	_, valid, err := s.CheckSession(ctx, id, sessionVersion)
+	return valid, err
+}
+
+// Whether a session of the given version is still valid, and the current role of the user
+func (s *UserService) CheckSession(ctx context.Context, id string, sessionVersion int) (_ string, _ bool, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.CheckSession")
+	defer func() { span.End(err) }()
+	user, err := s.userRepo.FindByID(ctx, id)
+	if err == repo.ErrUserNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, errors.Wrap(err, "Failed to retrieve user")
+	}
+	if user.SessionVersion != sessionVersion {
+		return "", false, nil
+	}
+	return user.Role, true, nil
+}
+
+func (s *UserService) sendEmailVerification(ctx context.Context, user *domain.User) error {
+	token, err := issueToken(ctx, s.tokenRepo, user.ID, domain.TokenEmailVerification, domain.EmailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+	err = s.notifier.SendEmailVerification(ctx, user, token)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send verification email")
+	}
+	return nil
+}
@@ -16,8 +16,25 @@ import (
 // returns the current testing context
 type UserSuite struct {
 	suite.Suite
-	userRep *repo.UserRepository
-	userSvc *UserService
+	userRep  *repo.UserRepository
+	userSvc  *UserService
+	notifier *recordingNotifier
+}
+
+// keeps the last token sent to each user, instead of emailing it
+type recordingNotifier struct {
+	resetTokens        map[string]string
+	verificationTokens map[string]string
+}
+
+func (n *recordingNotifier) SendPasswordReset(_ context.Context, user *domain.User, token string) error {
+	n.resetTokens[user.Email] = token
+	return nil
+}
+
+func (n *recordingNotifier) SendEmailVerification(_ context.Context, user *domain.User, token string) error {
+	n.verificationTokens[user.Email] = token
+	return nil
 }
 
 func (suite *UserSuite) SetupTest() {
@@ -31,7 +48,8 @@ func (suite *UserSuite) SetupSuite() {
 
 	app := testutil.InitTestApp()
 	suite.userRep = repo.NewUserRepository(app.DB)
-	suite.userSvc = NewUserService(suite.userRep)
+	suite.notifier = &recordingNotifier{resetTokens: map[string]string{}, verificationTokens: map[string]string{}}
+	suite.userSvc = NewUserService(suite.userRep, repo.NewUserTokenRepository(app.DB), repo.NewOutboxRepository(app.DB), app.DB, suite.notifier)
 }
 
 // In order for 'go test' to run this suite, we need to create
@@ -69,3 +87,49 @@ func (suite *UserSuite) TestCannotRegisterWithExistingEmail() {
 
 	assert.ErrorIs(suite.T(), err, repo.ErrDuplicateEmail)
 }
+
+func (suite *UserSuite) TestPasswordReset() {
+	userEmail := "reset@provider.com"
+	err := suite.userSvc.RegisterUser(context.TODO(), &domain.User{Email: userEmail, PasswordHash: "old hash"})
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	registered, _ := suite.userSvc.FindByEmail(context.TODO(), userEmail)
+
+	// unknown emails are ignored, without an error
+	assert.Nil(suite.T(), suite.userSvc.RequestPasswordReset(context.TODO(), "unknown@provider.com"))
+	assert.Nil(suite.T(), suite.userSvc.RequestPasswordReset(context.TODO(), userEmail))
+	token := suite.notifier.resetTokens[userEmail]
+	assert.NotEmpty(suite.T(), token)
+
+	err = suite.userSvc.ResetPassword(context.TODO(), token, "new hash")
+	assert.Nil(suite.T(), err)
+	user, _ := suite.userSvc.FindByEmail(context.TODO(), userEmail)
+	assert.Equal(suite.T(), "new hash", user.PasswordHash)
+	assert.True(suite.T(), user.IsVerified())
+
+	// the token can't be used twice, and the earlier sessions are revoked
+	err = suite.userSvc.ResetPassword(context.TODO(), token, "other hash")
+	assert.ErrorIs(suite.T(), err, domain.ErrInvalidToken)
+	valid, _ := suite.userSvc.IsSessionValid(context.TODO(), user.ID, registered.SessionVersion)
+	assert.False(suite.T(), valid)
+}
+
+func (suite *UserSuite) TestEmailVerification() {
+	userEmail := "verify@provider.com"
+	err := suite.userSvc.RegisterUser(context.TODO(), &domain.User{Email: userEmail})
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	user, _ := suite.userSvc.FindByEmail(context.TODO(), userEmail)
+	assert.False(suite.T(), user.IsVerified())
+
+	// unknown tokens are rejected
+	err = suite.userSvc.VerifyEmail(context.TODO(), "not-a-token")
+	assert.ErrorIs(suite.T(), err, domain.ErrInvalidToken)
+
+	err = suite.userSvc.VerifyEmail(context.TODO(), suite.notifier.verificationTokens[userEmail])
+	assert.Nil(suite.T(), err)
+	user, _ = suite.userSvc.FindByEmail(context.TODO(), userEmail)
+	assert.True(suite.T(), user.IsVerified())
+}
@@ -0,0 +1,102 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
+	"github.com/pkg/errors"
+)
+
+var _ ports.WebhookUsecase = (*WebhookService)(nil)
+
+type WebhookService struct {
+	webhookRepo *repo.WebhookRepository
+	urlChecker  ports.WebhookURLChecker
+}
+
+func NewWebhookService(webhookRepo *repo.WebhookRepository, urlChecker ports.WebhookURLChecker) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		urlChecker:  urlChecker,
+	}
+}
+
+// Stores the subscription, generating a signing secret for it when none is given
+// Returns a ValidationError when the URL points into the internal network
+func (s *WebhookService) CreateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateSubscription")
+	defer func() { span.End(err) }()
+	err = s.urlChecker.CheckURL(ctx, subscription.URL)
+	if err != nil {
+		return domain.NewValidationError("invalid webhook URL", domain.FieldError{Field: "url", Message: err.Error()})
+	}
+	if len(subscription.Secret) == 0 {
+		secret, err := generateSecret()
+		if err != nil {
+			return errors.Wrap(err, "Failed to generate webhook secret")
+		}
+		subscription.Secret = secret
+	}
+	subscription.Active = true
+	err = s.webhookRepo.InsertSubscription(ctx, subscription)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create webhook subscription")
+	}
+	return nil
+}
+
+func (s *WebhookService) FindSubscriptionById(ctx context.Context, id string) (_ *domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.FindSubscriptionById")
+	defer func() { span.End(err) }()
+	subscription, err := s.webhookRepo.FindSubscriptionById(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve webhook subscription")
+	}
+	return subscription, nil
+}
+
+func (s *WebhookService) FindSubscriptionsByUser(ctx context.Context, userId string) (_ *[]domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.FindSubscriptionsByUser")
+	defer func() { span.End(err) }()
+	subscriptions, err := s.webhookRepo.FindSubscriptionsByUser(ctx, userId)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve webhook subscriptions")
+	}
+	return subscriptions, nil
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id string) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteSubscription")
+	defer func() { span.End(err) }()
+	rows, err := s.webhookRepo.DeleteSubscription(ctx, id)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to delete webhook subscription")
+	}
+	if rows == 0 {
+		return 0, domain.NewNotFoundError("webhook subscription")
+	}
+	return rows, nil
+}
+
+func (s *WebhookService) FindDeliveries(ctx context.Context, subscriptionId string, status domain.WebhookDeliveryStatus, limit int) (_ *[]domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.FindDeliveries")
+	defer func() { span.End(err) }()
+	deliveries, err := s.webhookRepo.FindDeliveries(ctx, subscriptionId, status, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve webhook deliveries")
+	}
+	return deliveries, nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
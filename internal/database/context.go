@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"sync/atomic"
 )
 
 // ctxKey is an unexported type for context keys defined in this package.
@@ -12,6 +13,15 @@ type ctxKey int
 // clients use database.NewContext and database.FromContext instead of using this key directly.
 var txKey ctxKey
 
+// primaryKey marks the Contexts whose reads are made on the primary.
+const primaryKey ctxKey = 1
+
+// counterKey is the key for the *QueryCounter of a Context.
+const counterKey ctxKey = 2
+
+// commitHooksKey is the key for the *commitHooks of the transaction of a Context.
+const commitHooksKey ctxKey = 3
+
 // NewContext returns a new Context with provided *sqlx.Tx.
 func NewContext(ctx context.Context, tx *Tx) context.Context {
 	return context.WithValue(ctx, txKey, tx)
@@ -23,3 +33,63 @@ func FromContext(ctx context.Context) (*Tx, bool) {
 
 	return tx, ok
 }
+
+// WithPrimary returns a new Context whose reads are made on the primary, not on a replica.
+// For the reads which must see the writes just made, as the replicas lag behind.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// IsPrimary reports whether the reads made with ctx are made on the primary, as asked with WithPrimary.
+func IsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey).(bool)
+	return primary
+}
+
+// QueryCounter counts the database calls made with a Context, e.g. for tests to catch N+1 queries.
+type QueryCounter struct {
+	n int64
+}
+
+// Count returns the number of calls made so far.
+func (c *QueryCounter) Count() int {
+	return int(atomic.LoadInt64(&c.n))
+}
+
+// WithQueryCounter returns a new Context counting the database calls made with it, or its children.
+func WithQueryCounter(ctx context.Context) (context.Context, *QueryCounter) {
+	counter := &QueryCounter{}
+	return context.WithValue(ctx, counterKey, counter), counter
+}
+
+func countQuery(ctx context.Context) {
+	if counter, ok := ctx.Value(counterKey).(*QueryCounter); ok {
+		atomic.AddInt64(&counter.n, 1)
+	}
+}
+
+// Functions run once the transaction is committed, in the order they were added
+type commitHooks struct {
+	fns []func()
+}
+
+func withCommitHooks(ctx context.Context) (context.Context, *commitHooks) {
+	hooks := &commitHooks{}
+	return context.WithValue(ctx, commitHooksKey, hooks), hooks
+}
+
+func (h *commitHooks) run() {
+	for _, fn := range h.fns {
+		fn()
+	}
+}
+
+// AfterCommit runs fn once the transaction started with TxContext for ctx is committed, and not at all
+// if it's rolled back. Without a transaction fn is run right away.
+func AfterCommit(ctx context.Context, fn func()) {
+	if hooks, ok := ctx.Value(commitHooksKey).(*commitHooks); ok {
+		hooks.fns = append(hooks.fns, fn)
+		return
+	}
+	fn()
+}
@@ -3,11 +3,18 @@ package database
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"database/sql/driver"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/metrics"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/tracing"
 	"github.com/pkg/errors"
 )
 
@@ -17,18 +24,109 @@ type Tx = sqlx.Tx
 
 type DB struct {
 	db *sqlx.DB
+	// read replicas, the reads outside of a transaction are spread over them
+	replicas []*sqlx.DB
+	next     uint32
 }
 
 func NewDB(cfg config.DatabaseConfig) (*DB, error) {
-	connString := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s search_path=%s sslmode=disable",
-		cfg.Host, cfg.Port, cfg.User, cfg.Pass, cfg.Name, cfg.Schema)
-
-	db, err := sqlx.Connect("postgres", connString)
+	db, err := sqlx.Connect("postgres", connString(cfg))
 	if err != nil {
 		return nil, errors.Wrap(err, "open postgres conn")
 	}
+	configurePool(db, cfg)
+
+	// the replicas are connected to lazily, the server starts while they're down
+	replicas := make([]*sqlx.DB, 0, len(cfg.Replicas))
+	for i, dsn := range cfg.Replicas {
+		replicaConnString, err := replicaConnString(cfg, dsn)
+		if err != nil {
+			db.Close()
+			return nil, errors.Wrapf(err, "replica %d", i)
+		}
+		replica, err := sqlx.Open("postgres", replicaConnString)
+		if err != nil {
+			db.Close()
+			return nil, errors.Wrapf(err, "open replica %d", i)
+		}
+		configurePool(replica, cfg)
+		replicas = append(replicas, replica)
+	}
+
+	return &DB{db: db, replicas: replicas}, nil
+}
 
-	return &DB{db: db}, nil
+// The settings of the connections, in the key=value format of lib/pq
+func connString(cfg config.DatabaseConfig) string {
+	params := []string{
+		connParam("host", cfg.Host),
+		connParam("port", strconv.Itoa(cfg.Port)),
+		connParam("user", cfg.User),
+		connParam("password", cfg.Pass),
+		connParam("dbname", cfg.Name),
+	}
+	params = append(params, sessionParams(cfg)...)
+	return strings.Join(params, " ")
+}
+
+// The settings the replicas share with the primary: TLS, unless their DSN sets it, and the session parameters
+func replicaConnString(cfg config.DatabaseConfig, dsn string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		dsn, err = pq.ParseURL(dsn)
+		if err != nil {
+			return "", errors.Wrap(err, "parse dsn")
+		}
+	}
+	// lib/pq keeps the last value of a parameter, so the ones of the DSN come last
+	return strings.Join(append(sessionParams(cfg), dsn), " "), nil
+}
+
+func sessionParams(cfg config.DatabaseConfig) []string {
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	params := []string{
+		connParam("search_path", cfg.Schema),
+		connParam("sslmode", sslMode),
+	}
+	if cfg.SSLRootCert != "" {
+		params = append(params, connParam("sslrootcert", cfg.SSLRootCert))
+	}
+	if cfg.SSLCert != "" {
+		params = append(params, connParam("sslcert", cfg.SSLCert), connParam("sslkey", cfg.SSLKey))
+	}
+	// unknown parameters are set by lib/pq as run-time parameters of the session
+	if cfg.StatementTimeout > 0 {
+		params = append(params, connParam("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)))
+	}
+	if cfg.ApplicationName != "" {
+		params = append(params, connParam("application_name", cfg.ApplicationName))
+	}
+	return params
+}
+
+// Quotes the value, so passwords with spaces or quotes don't break the connection string
+func connParam(key string, value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return key + "='" + value + "'"
+}
+
+func configurePool(db *sqlx.DB, cfg config.DatabaseConfig) {
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
 }
 
 func (db *DB) TxContext(ctx context.Context, fn func(ctx context.Context) error) (err error) {
@@ -39,9 +137,13 @@ func (db *DB) TxContext(ctx context.Context, fn func(ctx context.Context) error)
 	}
 
 	ctx = NewContext(ctx, tx)
+	ctx, hooks := withCommitHooks(ctx)
 
 	defer func() {
 		err = TxHandler(tx, err, recover())
+		if err == nil {
+			hooks.run()
+		}
 	}()
 
 	return fn(ctx)
@@ -61,9 +163,13 @@ func (db *DB) TxContextSerializable(ctx context.Context, fn func(ctx context.Con
 	}
 
 	ctx = NewContext(ctx, tx)
+	ctx, hooks := withCommitHooks(ctx)
 
 	defer func() {
 		err = TxHandler(tx, err, recover())
+		if err == nil {
+			hooks.run()
+		}
 	}()
 
 	return fn(ctx)
@@ -78,19 +184,85 @@ func (db *DB) BeginTx(ctx context.Context) (*Tx, error) {
 	return db.db.BeginTxx(ctx, nil)
 }
 
-func (db *DB) Get(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+// Closes the connections of the pools, once the queries using them are done
+func (db *DB) Close() error {
+	err := db.db.Close()
+	for _, replica := range db.replicas {
+		if rerr := replica.Close(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// Checks the database is reachable, a health check
+func (db *DB) Ping(ctx context.Context) error {
+	return db.db.PingContext(ctx)
+}
+
+// Health checks of the replicas, in the order of the config
+// A replica which is down only slows the reads, they go to the primary instead
+func (db *DB) ReplicaChecks() []func(ctx context.Context) error {
+	if db == nil {
+		return nil
+	}
+	checks := make([]func(ctx context.Context) error, len(db.replicas))
+	for i, replica := range db.replicas {
+		checks[i] = replica.PingContext
+	}
+	return checks
+}
+
+func (db *DB) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	ctx, done := startQuery(ctx, "select", query)
+	defer func() { done(err) }()
+	if replica := db.replica(ctx, query); replica != nil {
+		if err = replica.SelectContext(ctx, dest, query, args...); !isConnError(err) {
+			return err
+		}
+	}
+	return db.executor(ctx).SelectContext(ctx, dest, query, args...)
+}
+
+func (db *DB) Get(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	ctx, done := startQuery(ctx, "get", query)
+	defer func() { done(err) }()
+	if replica := db.replica(ctx, query); replica != nil {
+		if err = replica.GetContext(ctx, dest, query, args...); !isConnError(err) {
+			return err
+		}
+	}
 	return db.executor(ctx).GetContext(ctx, dest, query, args...)
 }
 
-func (db *DB) Query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+// The duration measured is until the first rows are returned, reading the rest isn't included
+func (db *DB) Query(ctx context.Context, query string, args ...interface{}) (rows *Rows, err error) {
+	ctx, done := startQuery(ctx, "query", query)
+	defer func() { done(err) }()
+	if replica := db.replica(ctx, query); replica != nil {
+		if rows, err = replica.QueryxContext(ctx, query, args...); !isConnError(err) {
+			return rows, err
+		}
+	}
 	return db.executor(ctx).QueryxContext(ctx, query, args...)
 }
 
 func (db *DB) QueryRow(ctx context.Context, query string, args ...interface{}) *Row {
-	return db.executor(ctx).QueryRowxContext(ctx, query, args...)
+	ctx, done := startQuery(ctx, "query_row", query)
+	if replica := db.replica(ctx, query); replica != nil {
+		if row := replica.QueryRowxContext(ctx, query, args...); !isConnError(row.Err()) {
+			done(row.Err())
+			return row
+		}
+	}
+	row := db.executor(ctx).QueryRowxContext(ctx, query, args...)
+	done(row.Err())
+	return row
 }
 
-func (db *DB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+func (db *DB) Exec(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	ctx, done := startQuery(ctx, "exec", query)
+	defer func() { done(err) }()
 	return db.executor(ctx).ExecContext(ctx, query, args...)
 }
 
@@ -98,6 +270,67 @@ func (db *DB) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
 	return db.executor(ctx).PrepareContext(ctx, query)
 }
 
+// Statistics of the connection pool, exposed as metrics
+func (db *DB) Stats() sql.DBStats {
+	return db.db.Stats()
+}
+
+// Counts and times a database call, in a span and in the query durations metric
+// The returned function ends it, sql.ErrNoRows isn't counted as a failure
+func startQuery(ctx context.Context, operation string, query string) (context.Context, func(err error)) {
+	countQuery(ctx)
+	start := time.Now()
+	ctx, endSpan := tracing.StartQuery(ctx, operation, query)
+	return ctx, func(err error) {
+		outcome := "ok"
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			outcome = "error"
+		}
+		metrics.DBQueryDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+		endSpan(err)
+	}
+}
+
+// The replica the query is made on: the next one, if the query only reads, outside of a transaction,
+// and the primary isn't asked for with WithPrimary. Nil when it's made on the primary
+func (db *DB) replica(ctx context.Context, query string) *sqlx.DB {
+	if len(db.replicas) == 0 || !isReadOnly(query) {
+		return nil
+	}
+	if _, ok := FromContext(ctx); ok {
+		return nil
+	}
+	if IsPrimary(ctx) {
+		return nil
+	}
+	n := atomic.AddUint32(&db.next, 1)
+	return db.replicas[int(n)%len(db.replicas)]
+}
+
+// Whether the query only reads: a SELECT which doesn't lock the rows
+// Anything else, e.g. INSERT ... RETURNING, or a WITH which may modify data, is made on the primary
+func isReadOnly(query string) bool {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if !strings.HasPrefix(q, "select") {
+		return false
+	}
+	for _, lock := range []string{"for update", "for no key update", "for share", "for key share", "nextval("} {
+		if strings.Contains(q, lock) {
+			return false
+		}
+	}
+	return true
+}
+
+// Whether the query failed for lack of a connection, in which case it's retried on the primary
+func isConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
+
 func (db *DB) executor(ctx context.Context) dbExecutor {
 	if tx, ok := FromContext(ctx); ok {
 		return tx
@@ -113,4 +346,5 @@ type dbExecutor interface {
 	sqlx.QueryerContext
 	sqlx.PreparerContext
 	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 }
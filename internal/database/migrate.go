@@ -8,10 +8,14 @@ import (
 	"path/filepath"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
 	"github.com/pkg/errors"
 )
 
+// Postgres error code of queries on tables which don't exist
+const undefinedTable = "42P01"
+
 type MigrationProcess struct {
 	db *DB
 
@@ -49,11 +53,64 @@ func (m *MigrationProcess) Migrate(migrationsDir string) (err error) {
 			}
 		}
 
+		// the table is created by one of the migrations, so the applied ones are recorded once all ran
+		for _, file := range files {
+			_, err = m.db.Exec(ctx, `INSERT INTO hex_fwk.schema_migration (name) VALUES ($1) ON CONFLICT DO NOTHING`, file.Name())
+			if err != nil {
+				return errors.Wrap(err, "record migration")
+			}
+		}
+
 		m.logger.Info("migration done")
 		return nil
 	})
 }
 
+// Returns the migrations from the given directory which weren't applied to the database yet
+func (m *MigrationProcess) Pending(ctx context.Context, migrationsDir string) ([]string, error) {
+	files, err := findMigrationFiles(migrationsDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "find migrations")
+	}
+
+	var applied []string
+	// a replica may not have the latest migrations yet
+	err = m.db.Select(WithPrimary(ctx), &applied, `SELECT name FROM hex_fwk.schema_migration`)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == undefinedTable {
+		// migrated before the applied migrations were recorded
+		applied = nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "select applied migrations")
+	}
+	done := map[string]bool{}
+	for _, name := range applied {
+		done[name] = true
+	}
+
+	var pending []string
+	for _, file := range files {
+		if !done[file.Name()] {
+			pending = append(pending, file.Name())
+		}
+	}
+	return pending, nil
+}
+
+// Health check failing while migrations from the given directory are pending
+func (m *MigrationProcess) CheckCurrent(migrationsDir string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		pending, err := m.Pending(ctx, migrationsDir)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations, first %s", len(pending), pending[0])
+		}
+		return nil
+	}
+}
+
 // Given a path to the folder containing all the migration files, return the list of all migrations in the directory
 func findMigrationFiles(dir string) ([]os.FileInfo, error) {
 	files, err := ioutil.ReadDir(dir)
@@ -0,0 +1,341 @@
+package errorreport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+	"github.com/pkg/errors"
+)
+
+// Breadcrumbs kept per request, when the config doesn't say
+const DefaultMaxBreadcrumbs = 50
+
+// Value the scrubbed headers and query parameters are replaced with
+const filtered = "[Filtered]"
+
+// Headers carrying credentials, never sent in the reports
+var defaultScrubbedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Query parameters carrying credentials, e.g. the tokens of the links sent by email
+var scrubbedParams = []string{"token", "password", "secret"}
+
+// Deepest frames kept in the stack of a report
+const maxStackDepth = 64
+
+// Capturer reports the requests which panicked or were answered with a 5xx, with what is known about them:
+// the request and its id, the user, the release, and the breadcrumbs left while it was served
+type Capturer struct {
+	reporter       ports.ErrorReporter
+	release        string
+	environment    string
+	maxBreadcrumbs int
+	scrubbed       map[string]bool
+	logger         log.Logger
+}
+
+func NewCapturer(reporter ports.ErrorReporter, cfg config.ErrorReportingConfig, logger log.Logger) *Capturer {
+	if cfg.MaxBreadcrumbs <= 0 {
+		cfg.MaxBreadcrumbs = DefaultMaxBreadcrumbs
+	}
+	if logger == nil {
+		logger = log.NewNilLogger()
+	}
+	scrubbed := map[string]bool{}
+	for _, header := range append(defaultScrubbedHeaders, cfg.ScrubHeaders...) {
+		scrubbed[http.CanonicalHeaderKey(header)] = true
+	}
+	return &Capturer{
+		reporter:       reporter,
+		release:        cfg.Release,
+		environment:    cfg.Environment,
+		maxBreadcrumbs: cfg.MaxBreadcrumbs,
+		scrubbed:       scrubbed,
+		logger:         logger,
+	}
+}
+
+// Filter capturing the failures of the requests, it has to come after log.RequestIDFilter
+// Panics are reported then passed on, so they're still answered by the recover handler of the container
+func (c *Capturer) Filter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	trail := &trail{max: c.maxBreadcrumbs}
+	ctx := context.WithValue(req.Request.Context(), trailCtxKey, trail)
+	// the messages logged while serving the request are its breadcrumbs
+	ctx = log.NewContext(ctx, &breadcrumbLogger{Logger: log.FromContext(ctx), trail: trail})
+	req.Request = req.Request.WithContext(ctx)
+
+	recorder := &errorRecorder{ResponseWriter: resp.ResponseWriter}
+	resp.ResponseWriter = recorder
+
+	completed := false
+	defer func() {
+		resp.ResponseWriter = recorder.ResponseWriter
+		if completed {
+			return
+		}
+		r := recover()
+		if r == nil {
+			return
+		}
+		report := c.newReport(req, trail, domain.ErrorLevelFatal)
+		report.Type = "panic"
+		report.Message = fmt.Sprint(r)
+		report.Stack = panicStack()
+		c.report(report)
+		panic(r)
+	}()
+	chain.ProcessFilter(req, resp)
+	completed = true
+
+	if status := resp.StatusCode(); status >= http.StatusInternalServerError {
+		report := c.newReport(req, trail, domain.ErrorLevelError)
+		if recorder.err != nil {
+			report.Type = errorType(recorder.err)
+			report.Message = recorder.err.Error()
+			report.Stack = errorStack(recorder.err)
+		} else {
+			report.Type = http.StatusText(status)
+			report.Message = fmt.Sprintf("%s %s answered %d", req.Request.Method, req.Request.URL.Path, status)
+		}
+		c.report(report)
+	}
+}
+
+func (c *Capturer) newReport(req *restful.Request, trail *trail, level string) *domain.ErrorReport {
+	ctx := req.Request.Context()
+	userID, _ := log.UserFrom(ctx)
+	return &domain.ErrorReport{
+		ID:    newEventID(),
+		Time:  time.Now(),
+		Level: level,
+		Request: domain.ErrorRequest{
+			Method:  req.Request.Method,
+			URL:     requestURL(req.Request),
+			Route:   req.SelectedRoutePath(),
+			Headers: c.scrub(req.Request.Header),
+		},
+		RequestID:   log.RequestIDFrom(ctx),
+		UserID:      userID,
+		Release:     c.release,
+		Environment: c.environment,
+		Breadcrumbs: trail.list(),
+	}
+}
+
+func (c *Capturer) report(report *domain.ErrorReport) {
+	if err := c.reporter.Report(context.Background(), report); err != nil {
+		c.logger.Error("error reporting error", "report", report.ID, "err", err)
+	}
+}
+
+func (c *Capturer) scrub(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if c.scrubbed[http.CanonicalHeaderKey(name)] {
+			headers[name] = filtered
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path}
+	query := r.URL.Query()
+	for _, param := range scrubbedParams {
+		if _, ok := query[param]; ok {
+			query.Set(param, filtered)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Leaves a breadcrumb in the trail of the request ctx belongs to, shown in its report if it fails
+// Outside of a request, it's dropped
+func AddBreadcrumb(ctx context.Context, category string, message string) {
+	if trail, ok := ctx.Value(trailCtxKey).(*trail); ok {
+		trail.add(category, "info", message)
+	}
+}
+
+type ctxKey int
+
+const trailCtxKey ctxKey = 0
+
+// Breadcrumbs of a request, only the latest max are kept
+type trail struct {
+	mu          sync.Mutex
+	max         int
+	breadcrumbs []domain.Breadcrumb
+}
+
+func (t *trail) add(category string, level string, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.breadcrumbs = append(t.breadcrumbs, domain.Breadcrumb{Time: time.Now(), Category: category, Level: level, Message: message})
+	if len(t.breadcrumbs) > t.max {
+		t.breadcrumbs = t.breadcrumbs[len(t.breadcrumbs)-t.max:]
+	}
+}
+
+func (t *trail) list() []domain.Breadcrumb {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]domain.Breadcrumb{}, t.breadcrumbs...)
+}
+
+// Logger leaving a breadcrumb for every message, before passing it on
+type breadcrumbLogger struct {
+	log.Logger
+	trail  *trail
+	fields []interface{}
+}
+
+func (l *breadcrumbLogger) record(level string, msg string, fields []interface{}) {
+	var b strings.Builder
+	b.WriteString(msg)
+	all := append(append([]interface{}{}, l.fields...), fields...)
+	for i := 0; i+1 < len(all); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", all[i], all[i+1])
+	}
+	l.trail.add("log", level, b.String())
+}
+
+func (l *breadcrumbLogger) Debug(msg string, fields ...interface{}) {
+	l.record("debug", msg, fields)
+	l.Logger.Debug(msg, fields...)
+}
+
+func (l *breadcrumbLogger) Info(msg string, fields ...interface{}) {
+	l.record("info", msg, fields)
+	l.Logger.Info(msg, fields...)
+}
+
+func (l *breadcrumbLogger) Infof(msg string, fields ...interface{}) {
+	l.record("info", fmt.Sprintf(msg, fields...), nil)
+	l.Logger.Infof(msg, fields...)
+}
+
+func (l *breadcrumbLogger) Warn(msg string, fields ...interface{}) {
+	l.record("warning", msg, fields)
+	l.Logger.Warn(msg, fields...)
+}
+
+func (l *breadcrumbLogger) Error(msg string, fields ...interface{}) {
+	l.record("error", msg, fields)
+	l.Logger.Error(msg, fields...)
+}
+
+func (l *breadcrumbLogger) Errorf(msg string, fields ...interface{}) {
+	l.record("error", fmt.Sprintf(msg, fields...), nil)
+	l.Logger.Errorf(msg, fields...)
+}
+
+func (l *breadcrumbLogger) With(fields ...interface{}) log.Logger {
+	return &breadcrumbLogger{
+		Logger: l.Logger.With(fields...),
+		trail:  l.trail,
+		fields: append(append([]interface{}{}, l.fields...), fields...),
+	}
+}
+
+var _ response.ErrorRecorder = (*errorRecorder)(nil)
+
+// Passes the response through, keeping the error it was written for
+type errorRecorder struct {
+	http.ResponseWriter
+	err error
+}
+
+func (r *errorRecorder) RecordError(err error) {
+	r.err = err
+}
+
+func (r *errorRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// The type of the innermost error, the one the others wrap
+func errorType(err error) string {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = inner
+	}
+}
+
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+// The stack recorded by the innermost error wrapped with pkg/errors, closest to where it happened
+func errorStack(err error) []domain.StackFrame {
+	var deepest stackTracer
+	for ; err != nil; err = errors.Unwrap(err) {
+		if st, ok := err.(stackTracer); ok {
+			deepest = st
+		}
+	}
+	if deepest == nil {
+		return nil
+	}
+	trace := deepest.StackTrace()
+	pcs := make([]uintptr, len(trace))
+	for i, frame := range trace {
+		pcs[i] = uintptr(frame)
+	}
+	return stackFrames(pcs)
+}
+
+// The stack of the panic being recovered, from the call which panicked
+func panicStack() []domain.StackFrame {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+	stack := stackFrames(pcs[:n])
+	// the frames of the runtime raising the panic aren't of interest
+	for len(stack) > 0 && strings.HasPrefix(stack[0].Function, "runtime.") {
+		stack = stack[1:]
+	}
+	return stack
+}
+
+func stackFrames(pcs []uintptr) []domain.StackFrame {
+	var stack []domain.StackFrame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, domain.StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		if !more || len(stack) == maxStackDepth {
+			return stack
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package errorreport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CaptureSuite struct {
+	suite.Suite
+	reporter    *MemoryReporter
+	wsContainer *restful.Container
+}
+
+func (suite *CaptureSuite) SetupTest() {
+	suite.reporter = NewMemoryReporter()
+	cfg := config.ErrorReportingConfig{MaxBreadcrumbs: 2, ScrubHeaders: []string{"X-Session"}, Release: "1.2.3", Environment: "test"}
+
+	suite.wsContainer = restful.NewContainer()
+	suite.wsContainer.DoNotRecover(false)
+	suite.wsContainer.RecoverHandler(func(i interface{}, w http.ResponseWriter) {
+		response.WriteError(w, response.NewInternalServerError("an error occured"))
+	})
+	suite.wsContainer.Filter(log.RequestIDFilter(nil))
+	suite.wsContainer.Filter(NewCapturer(suite.reporter, cfg, nil).Filter)
+
+	ws := new(restful.WebService)
+	ws.Path("/item")
+	ws.Filter(func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		// as done by the authentication filters
+		req.Request = req.Request.WithContext(log.WithUser(req.Request.Context(), "user-1", "testy@email.com"))
+		chain.ProcessFilter(req, resp)
+	})
+	ws.Route(ws.GET("/{id}").To(func(req *restful.Request, resp *restful.Response) {
+		ctx := req.Request.Context()
+		log.FromContext(ctx).Info("reading item", "id", req.PathParameter("id"))
+		AddBreadcrumb(ctx, "db", "SELECT * FROM item")
+		AddBreadcrumb(ctx, "cache", "item missed")
+		switch req.PathParameter("id") {
+		case "panic":
+			var items map[string]int
+			items["boom"]++
+		case "missing":
+			response.WriteError(resp, &domain.NotFoundError{})
+		default:
+			response.WriteError(resp, errors.Wrap(errors.New("connection refused"), "find item"))
+		}
+	}))
+	suite.wsContainer.Add(ws)
+}
+
+func TestCaptureTestSuite(t *testing.T) {
+	suite.Run(t, new(CaptureSuite))
+}
+
+func (suite *CaptureSuite) serve(path string) *httptest.ResponseRecorder {
+	httpRequest, _ := http.NewRequest("GET", path, nil)
+	httpRequest.Header.Set("Authorization", "Bearer secret")
+	httpRequest.Header.Set("X-Session", "secret")
+	httpRequest.Header.Set("User-Agent", "test")
+	httpRequest.Header.Set(log.RequestIDHeader, "req-1")
+	responseRec := httptest.NewRecorder()
+	suite.wsContainer.ServeHTTP(responseRec, httpRequest)
+	return responseRec
+}
+
+func (suite *CaptureSuite) TestErrorsAnsweredWith500AreReported() {
+	responseRec := suite.serve("/item/42?token=abc&page=2")
+	assert.Equal(suite.T(), http.StatusInternalServerError, responseRec.Code)
+
+	reported := suite.reporter.Reported()
+	if !assert.Len(suite.T(), reported, 1) {
+		return
+	}
+	report := reported[0]
+	assert.Equal(suite.T(), domain.ErrorLevelError, report.Level)
+	assert.Equal(suite.T(), "*errors.fundamental", report.Type)
+	assert.Equal(suite.T(), "find item: connection refused", report.Message)
+	if assert.NotEmpty(suite.T(), report.Stack) {
+		assert.Contains(suite.T(), report.Stack[0].Function, "SetupTest")
+	}
+
+	assert.Equal(suite.T(), "GET", report.Request.Method)
+	assert.Equal(suite.T(), "/item/{id}", report.Request.Route)
+	assert.Equal(suite.T(), "http:///item/42?page=2&token=%5BFiltered%5D", report.Request.URL)
+	assert.Equal(suite.T(), "[Filtered]", report.Request.Headers["Authorization"])
+	assert.Equal(suite.T(), "[Filtered]", report.Request.Headers["X-Session"])
+	assert.Equal(suite.T(), "test", report.Request.Headers["User-Agent"])
+	assert.Equal(suite.T(), "req-1", report.RequestID)
+	assert.Equal(suite.T(), "user-1", report.UserID)
+	assert.Equal(suite.T(), "1.2.3", report.Release)
+	assert.Equal(suite.T(), "test", report.Environment)
+
+	// only the latest breadcrumbs are kept
+	if assert.Len(suite.T(), report.Breadcrumbs, 2) {
+		assert.Equal(suite.T(), "SELECT * FROM item", report.Breadcrumbs[0].Message)
+		assert.Equal(suite.T(), "item missed", report.Breadcrumbs[1].Message)
+	}
+}
+
+func (suite *CaptureSuite) TestPanicsAreReported() {
+	responseRec := suite.serve("/item/panic")
+	// still answered by the recover handler
+	assert.Equal(suite.T(), http.StatusInternalServerError, responseRec.Code)
+	assert.Equal(suite.T(), response.MIME_PROBLEM_JSON, responseRec.Header().Get("Content-Type"))
+
+	reported := suite.reporter.Reported()
+	if !assert.Len(suite.T(), reported, 1) {
+		return
+	}
+	report := reported[0]
+	assert.Equal(suite.T(), domain.ErrorLevelFatal, report.Level)
+	assert.Equal(suite.T(), "panic", report.Type)
+	assert.Equal(suite.T(), "assignment to entry in nil map", report.Message)
+	if assert.NotEmpty(suite.T(), report.Stack) {
+		// starts at the statement which panicked
+		assert.Contains(suite.T(), report.Stack[0].Function, "SetupTest")
+	}
+	assert.Equal(suite.T(), "user-1", report.UserID)
+}
+
+func (suite *CaptureSuite) TestClientErrorsAreNotReported() {
+	responseRec := suite.serve("/item/missing")
+	assert.Equal(suite.T(), http.StatusNotFound, responseRec.Code)
+	assert.Empty(suite.T(), suite.reporter.Reported())
+}
+
+func (suite *CaptureSuite) TestReporterOfTheConfig() {
+	reporter, err := NewReporter(config.ErrorReportingConfig{})
+	assert.Nil(suite.T(), err)
+	assert.IsType(suite.T(), NopReporter{}, reporter)
+
+	reporter, err = NewReporter(config.ErrorReportingConfig{DSN: "https://key@sentry.example.com/42"})
+	assert.Nil(suite.T(), err)
+	assert.IsType(suite.T(), &SentryReporter{}, reporter)
+
+	_, err = NewReporter(config.ErrorReportingConfig{Driver: "rollbar"})
+	assert.NotNil(suite.T(), err)
+}
@@ -0,0 +1,52 @@
+package errorreport
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/pkg/errors"
+)
+
+var _ ports.ErrorReporter = (*FileReporter)(nil)
+
+// FileReporter appends the reports to a file, one JSON object per line
+// Meant for local runs, where there is no Sentry project to report to
+type FileReporter struct {
+	mu   sync.Mutex
+	file string
+}
+
+func NewFileReporter(file string) *FileReporter {
+	return &FileReporter{
+		file: file,
+	}
+}
+
+func (r *FileReporter) Report(_ context.Context, report *domain.ErrorReport) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return errors.Wrap(err, "marshal report")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err = os.MkdirAll(filepath.Dir(r.file), 0755)
+	if err != nil {
+		return errors.Wrap(err, "create reports dir")
+	}
+	f, err := os.OpenFile(r.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "open reports file")
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return errors.Wrap(err, "write report")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package errorreport
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+)
+
+var _ ports.ErrorReporter = (*MemoryReporter)(nil)
+
+// MemoryReporter keeps the reports, so tests can check them
+type MemoryReporter struct {
+	mu       sync.Mutex
+	reported []domain.ErrorReport
+}
+
+func NewMemoryReporter() *MemoryReporter {
+	return &MemoryReporter{}
+}
+
+func (r *MemoryReporter) Report(_ context.Context, report *domain.ErrorReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reported = append(r.reported, *report)
+	return nil
+}
+
+// Returns the reports received so far
+func (r *MemoryReporter) Reported() []domain.ErrorReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.ErrorReport{}, r.reported...)
+}
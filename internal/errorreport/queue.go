@@ -0,0 +1,95 @@
+package errorreport
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+)
+
+var _ ports.ErrorReporter = (*Queue)(nil)
+
+// Returned when an error is reported while the queue is full
+var ErrQueueFull = errors.New("error report queue is full")
+
+// Queue size used when none is configured
+const DefaultQueueSize = 100
+
+// Queue is a reporter which accepts reports right away, and sends them in the background through the wrapped reporter
+// so the failed requests aren't slowed down further. Reports are tried once, a burst of errors shouldn't pile up retries
+type Queue struct {
+	reporter ports.ErrorReporter
+	reports  chan domain.ErrorReport
+	logger   log.Logger
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewQueue(reporter ports.ErrorReporter, size int, logger log.Logger) *Queue {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	return &Queue{
+		reporter: reporter,
+		reports:  make(chan domain.ErrorReport, size),
+		logger:   logger,
+	}
+}
+
+// Queues the report, returns ErrQueueFull instead of waiting for room in the queue
+func (q *Queue) Report(_ context.Context, report *domain.ErrorReport) error {
+	select {
+	case q.reports <- *report:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Starts sending the queued reports, until Stop is called
+func (q *Queue) Start() {
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+	go func() {
+		defer close(q.done)
+		for {
+			select {
+			case <-q.stop:
+				q.drain()
+				return
+			case report := <-q.reports:
+				q.send(&report)
+			}
+		}
+	}()
+}
+
+// Stops the queue, after sending the reports still in it
+func (q *Queue) Stop() {
+	if q.stop == nil {
+		return
+	}
+	close(q.stop)
+	<-q.done
+}
+
+func (q *Queue) drain() {
+	for {
+		select {
+		case report := <-q.reports:
+			q.send(&report)
+		default:
+			return
+		}
+	}
+}
+
+func (q *Queue) send(report *domain.ErrorReport) {
+	err := q.reporter.Report(context.Background(), report)
+	if err != nil {
+		q.logger.Error("error sending error report", "report", report.ID, "err", err)
+	}
+}
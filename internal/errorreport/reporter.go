@@ -0,0 +1,58 @@
+// Package errorreport captures the panics and 5xx errors of the requests, and sends them to Sentry or keeps them locally
+package errorreport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+)
+
+const (
+	DriverSentry = "sentry"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+	DriverNone   = "none"
+)
+
+// File the file driver writes to, when none is configured
+const DefaultFile = "logs/errors.json"
+
+// Creates the reporter chosen by the config
+// Without a driver, the errors are sent to Sentry when a DSN is configured, and dropped otherwise
+func NewReporter(cfg config.ErrorReportingConfig) (ports.ErrorReporter, error) {
+	driver := cfg.Driver
+	if driver == "" {
+		driver = DriverNone
+		if cfg.DSN != "" {
+			driver = DriverSentry
+		}
+	}
+
+	switch driver {
+	case DriverSentry:
+		return NewSentryReporter(cfg.DSN)
+	case DriverFile:
+		file := cfg.File
+		if len(file) == 0 {
+			file = DefaultFile
+		}
+		return NewFileReporter(file), nil
+	case DriverMemory:
+		return NewMemoryReporter(), nil
+	case DriverNone:
+		return NopReporter{}, nil
+	}
+	return nil, fmt.Errorf("unknown error reporting driver %q", cfg.Driver)
+}
+
+var _ ports.ErrorReporter = NopReporter{}
+
+// NopReporter drops the reports
+type NopReporter struct{}
+
+func (NopReporter) Report(_ context.Context, _ *domain.ErrorReport) error {
+	return nil
+}
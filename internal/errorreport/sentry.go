@@ -0,0 +1,211 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/pkg/errors"
+)
+
+var _ ports.ErrorReporter = (*SentryReporter)(nil)
+
+// Name and version the reports are sent with
+const sentryClient = "hexfwk/1.0"
+
+// How long the Sentry server has to accept a report
+const sentryTimeout = 10 * time.Second
+
+// SentryReporter sends the reports to the store endpoint of a Sentry project, or of a server speaking its protocol
+type SentryReporter struct {
+	endpoint  string
+	publicKey string
+	secretKey string
+	client    *http.Client
+}
+
+// Creates a reporter for the project of the DSN, e.g. https://<key>@o1.ingest.sentry.io/<project>
+func NewSentryReporter(dsn string) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse sentry dsn")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("sentry dsn must be an http or https URL, got %q", u.Scheme)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("sentry dsn has no public key")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if projectID == "" {
+		return nil, errors.New("sentry dsn has no project id")
+	}
+	secretKey, _ := u.User.Password()
+
+	return &SentryReporter{
+		// the DSN may point at a Sentry hosted under a path
+		endpoint:  fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path[:slash], projectID),
+		publicKey: u.User.Username(),
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: sentryTimeout},
+	}, nil
+}
+
+func (r *SentryReporter) Report(ctx context.Context, report *domain.ErrorReport) error {
+	body, err := json.Marshal(newSentryEvent(report))
+	if err != nil {
+		return errors.Wrap(err, "marshal sentry event")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "create sentry request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", r.authHeader())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send sentry event")
+	}
+	defer resp.Body.Close()
+	// the body is read, so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// 429 when the project is over its quota, the report is dropped like any other failed one
+		return fmt.Errorf("sentry answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (r *SentryReporter) authHeader() string {
+	header := fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, r.publicKey)
+	if r.secretKey != "" {
+		header += ", sentry_secret=" + r.secretKey
+	}
+	return header
+}
+
+// The event of the Sentry store endpoint, only the attributes the reports have are sent
+type sentryEvent struct {
+	EventID     string             `json:"event_id"`
+	Timestamp   string             `json:"timestamp"`
+	Level       string             `json:"level"`
+	Platform    string             `json:"platform"`
+	Message     string             `json:"message,omitempty"`
+	Transaction string             `json:"transaction,omitempty"`
+	Release     string             `json:"release,omitempty"`
+	Environment string             `json:"environment,omitempty"`
+	Exception   *sentryValues      `json:"exception,omitempty"`
+	Request     *sentryRequest     `json:"request,omitempty"`
+	User        *sentryUser        `json:"user,omitempty"`
+	Tags        map[string]string  `json:"tags,omitempty"`
+	Breadcrumbs *sentryBreadcrumbs `json:"breadcrumbs,omitempty"`
+}
+
+type sentryValues struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+}
+
+type sentryRequest struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+type sentryUser struct {
+	ID string `json:"id"`
+}
+
+type sentryBreadcrumbs struct {
+	Values []sentryBreadcrumb `json:"values"`
+}
+
+type sentryBreadcrumb struct {
+	Timestamp string `json:"timestamp"`
+	Category  string `json:"category"`
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+}
+
+func newSentryEvent(report *domain.ErrorReport) *sentryEvent {
+	event := &sentryEvent{
+		EventID:     report.ID,
+		Timestamp:   report.Time.UTC().Format(time.RFC3339Nano),
+		Level:       report.Level,
+		Platform:    "go",
+		Transaction: report.Request.Route,
+		Release:     report.Release,
+		Environment: report.Environment,
+		Exception: &sentryValues{Values: []sentryException{{
+			Type:  report.Type,
+			Value: report.Message,
+		}}},
+	}
+
+	if len(report.Stack) > 0 {
+		// Sentry lists the frames oldest first
+		frames := make([]sentryFrame, len(report.Stack))
+		for i, frame := range report.Stack {
+			frames[len(frames)-1-i] = sentryFrame{Function: frame.Function, AbsPath: frame.File, Lineno: frame.Line}
+		}
+		event.Exception.Values[0].Stacktrace = &sentryStacktrace{Frames: frames}
+	}
+
+	if report.Request.Method != "" {
+		event.Request = &sentryRequest{
+			Method:  report.Request.Method,
+			URL:     report.Request.URL,
+			Headers: report.Request.Headers,
+		}
+		if u, err := url.Parse(report.Request.URL); err == nil {
+			event.Request.URL = u.Scheme + "://" + u.Host + u.Path
+			event.Request.QueryString = u.RawQuery
+		}
+	}
+	if report.UserID != "" {
+		event.User = &sentryUser{ID: report.UserID}
+	}
+	if report.RequestID != "" {
+		event.Tags = map[string]string{"request_id": report.RequestID}
+	}
+	if len(report.Breadcrumbs) > 0 {
+		event.Breadcrumbs = &sentryBreadcrumbs{}
+		for _, b := range report.Breadcrumbs {
+			event.Breadcrumbs.Values = append(event.Breadcrumbs.Values, sentryBreadcrumb{
+				Timestamp: b.Time.UTC().Format(time.RFC3339Nano),
+				Category:  b.Category,
+				Level:     b.Level,
+				Message:   b.Message,
+			})
+		}
+	}
+	return event
+}
@@ -0,0 +1,147 @@
+package errorreport
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// A request received by the stand-in of the Sentry ingest endpoint
+type ingested struct {
+	path  string
+	auth  string
+	event map[string]interface{}
+}
+
+type SentrySuite struct {
+	suite.Suite
+	server   *httptest.Server
+	status   int
+	ingested chan ingested
+}
+
+func (suite *SentrySuite) SetupTest() {
+	suite.status = http.StatusOK
+	suite.ingested = make(chan ingested, 10)
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		in := ingested{path: r.URL.Path, auth: r.Header.Get("X-Sentry-Auth")}
+		json.Unmarshal(body, &in.event)
+		suite.ingested <- in
+		w.WriteHeader(suite.status)
+	}))
+}
+
+func (suite *SentrySuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func TestSentryTestSuite(t *testing.T) {
+	suite.Run(t, new(SentrySuite))
+}
+
+func (suite *SentrySuite) dsn() string {
+	return "http://public@" + suite.server.Listener.Addr().String() + "/sentry/42"
+}
+
+func (suite *SentrySuite) TestReportIsSentAsASentryEvent() {
+	reporter, err := NewSentryReporter(suite.dsn())
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	err = reporter.Report(context.Background(), &domain.ErrorReport{
+		ID:      "0123456789abcdef0123456789abcdef",
+		Time:    at,
+		Level:   domain.ErrorLevelFatal,
+		Type:    "panic",
+		Message: "assignment to entry in nil map",
+		Stack: []domain.StackFrame{
+			{Function: "order.CreateOrder", File: "/src/order/http.go", Line: 12},
+			{Function: "restful.dispatch", File: "/src/restful/container.go", Line: 300},
+		},
+		Request: domain.ErrorRequest{
+			Method:  "POST",
+			URL:     "http://localhost/v1/order?page=2",
+			Route:   "/v1/order",
+			Headers: map[string]string{"Authorization": "[Filtered]"},
+		},
+		RequestID:   "req-1",
+		UserID:      "user-1",
+		Release:     "1.2.3",
+		Environment: "prod",
+		Breadcrumbs: []domain.Breadcrumb{{Time: at, Category: "log", Level: "info", Message: "creating order"}},
+	})
+	assert.Nil(suite.T(), err)
+
+	in := <-suite.ingested
+	assert.Equal(suite.T(), "/sentry/api/42/store/", in.path)
+	assert.Equal(suite.T(), "Sentry sentry_version=7, sentry_client=hexfwk/1.0, sentry_key=public", in.auth)
+
+	event := in.event
+	assert.Equal(suite.T(), "0123456789abcdef0123456789abcdef", event["event_id"])
+	assert.Equal(suite.T(), "2026-03-01T12:00:00Z", event["timestamp"])
+	assert.Equal(suite.T(), "fatal", event["level"])
+	assert.Equal(suite.T(), "go", event["platform"])
+	assert.Equal(suite.T(), "/v1/order", event["transaction"])
+	assert.Equal(suite.T(), "1.2.3", event["release"])
+	assert.Equal(suite.T(), "prod", event["environment"])
+	assert.Equal(suite.T(), map[string]interface{}{"id": "user-1"}, event["user"])
+	assert.Equal(suite.T(), map[string]interface{}{"request_id": "req-1"}, event["tags"])
+	assert.Equal(suite.T(), map[string]interface{}{
+		"method":       "POST",
+		"url":          "http://localhost/v1/order",
+		"query_string": "page=2",
+		"headers":      map[string]interface{}{"Authorization": "[Filtered]"},
+	}, event["request"])
+
+	exception := event["exception"].(map[string]interface{})["values"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(suite.T(), "panic", exception["type"])
+	assert.Equal(suite.T(), "assignment to entry in nil map", exception["value"])
+	frames := exception["stacktrace"].(map[string]interface{})["frames"].([]interface{})
+	// oldest first
+	assert.Equal(suite.T(), "restful.dispatch", frames[0].(map[string]interface{})["function"])
+	assert.Equal(suite.T(), "order.CreateOrder", frames[1].(map[string]interface{})["function"])
+
+	breadcrumbs := event["breadcrumbs"].(map[string]interface{})["values"].([]interface{})
+	assert.Equal(suite.T(), "creating order", breadcrumbs[0].(map[string]interface{})["message"])
+}
+
+func (suite *SentrySuite) TestRefusedReportsFail() {
+	suite.status = http.StatusTooManyRequests
+	reporter, err := NewSentryReporter(suite.dsn())
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	err = reporter.Report(context.Background(), &domain.ErrorReport{ID: "1", Time: time.Now()})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *SentrySuite) TestInvalidDSNs() {
+	for _, dsn := range []string{"", "ftp://key@host/1", "https://host/1", "https://key@host/"} {
+		_, err := NewSentryReporter(dsn)
+		assert.NotNil(suite.T(), err, dsn)
+	}
+}
+
+func (suite *SentrySuite) TestQueueSendsTheReportsLeftWhenStopped() {
+	reporter, err := NewSentryReporter(suite.dsn())
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	queue := NewQueue(reporter, 1, nil)
+	assert.Nil(suite.T(), queue.Report(context.Background(), &domain.ErrorReport{ID: "1", Time: time.Now()}))
+	assert.Equal(suite.T(), ErrQueueFull, queue.Report(context.Background(), &domain.ErrorReport{ID: "2", Time: time.Now()}))
+
+	queue.Start()
+	queue.Stop()
+	assert.Equal(suite.T(), "1", (<-suite.ingested).event["event_id"])
+}
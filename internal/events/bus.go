@@ -0,0 +1,69 @@
+// Adapters publishing domain events from the outbox, and the relay feeding them
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/pkg/errors"
+)
+
+var _ ports.EventPublisher = (*Bus)(nil)
+
+// Handles a single event, returning an error makes the relay retry the event later
+type Handler func(ctx context.Context, event *domain.Event) error
+
+// Subscription is a handler subscribed under a name, the relay records which subscriptions handled each event
+// Names must be unique among the subscriptions to an event type, and stay the same across restarts
+type Subscription struct {
+	Name    string
+	Handler Handler
+}
+
+// Bus is an in-process publisher, which passes events on to the handlers subscribed to them
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions map[domain.EventType][]Subscription
+	all           []Subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscriptions: make(map[domain.EventType][]Subscription),
+	}
+}
+
+// Subscribes the handler to events of the given type
+func (b *Bus) Subscribe(name string, eventType domain.EventType, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[eventType] = append(b.subscriptions[eventType], Subscription{Name: name, Handler: handler})
+}
+
+// Subscribes the handler to all events
+func (b *Bus) SubscribeAll(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, Subscription{Name: name, Handler: handler})
+}
+
+// Returns the subscriptions to events of the given type, the ones to all events first
+func (b *Bus) Subscriptions(eventType domain.EventType) []Subscription {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append(append([]Subscription{}, b.all...), b.subscriptions[eventType]...)
+}
+
+// Calls every handler subscribed to the event, and returns the first error they return
+func (b *Bus) Publish(ctx context.Context, event *domain.Event) error {
+	var firstErr error
+	for _, subscription := range b.Subscriptions(event.Type) {
+		err := subscription.Handler(ctx, event)
+		if err != nil && firstErr == nil {
+			firstErr = errors.Wrap(err, "handle "+string(event.Type))
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,31 @@
+package events
+
+import (
+	"context"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+)
+
+var _ ports.EventPublisher = (*LogPublisher)(nil)
+
+// LogPublisher writes every event to the log, useful for local runs and debugging
+type LogPublisher struct {
+	logger log.Logger
+}
+
+func NewLogPublisher(logger log.Logger) *LogPublisher {
+	return &LogPublisher{
+		logger: logger,
+	}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, event *domain.Event) error {
+	p.logger.Info("event published",
+		"id", event.ID,
+		"type", event.Type,
+		"aggregate_id", event.AggregateID,
+		"payload", string(event.Payload))
+	return nil
+}
@@ -0,0 +1,33 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/metrics"
+)
+
+var _ ports.EventPublisher = (*MetricsPublisher)(nil)
+
+// MetricsPublisher counts the events, and the business facts they tell of, e.g. the products running out of stock
+// Counted once the events are committed, so rolled back changes aren't, but again when an event is redelivered
+type MetricsPublisher struct{}
+
+func NewMetricsPublisher() *MetricsPublisher {
+	return &MetricsPublisher{}
+}
+
+func (p *MetricsPublisher) Publish(_ context.Context, event *domain.Event) error {
+	metrics.EventsPublished.WithLabelValues(string(event.Type)).Inc()
+	switch event.Type {
+	case domain.EventProductStockLow:
+		var payload domain.ProductStockLowPayload
+		// a payload which can't be read isn't worth failing the delivery to the other subscribers
+		if err := json.Unmarshal(event.Payload, &payload); err == nil && payload.Quantity <= 0 {
+			metrics.StockOuts.Inc()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/pkg/errors"
+)
+
+// Used for retrying the events which fail to publish
+const (
+	DefaultMaxAttempts = 10
+	DefaultBackoff     = 5 * time.Second
+	DefaultMaxBackoff  = time.Hour
+)
+
+// Relay periodically publishes the events stored in the outbox to the bus subscriptions
+// Events are delivered at least once: an event whose publishing fails stays in the outbox, and is retried with
+// exponential backoff until it runs out of attempts and is dead-lettered
+type Relay struct {
+	outbox      ports.OutboxRepo
+	tx          ports.Transactor
+	bus         *Bus
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	logger      log.Logger
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewRelay(outbox ports.OutboxRepo, tx ports.Transactor, bus *Bus, interval time.Duration, batchSize int, logger log.Logger) *Relay {
+	return &Relay{
+		outbox:      outbox,
+		tx:          tx,
+		bus:         bus,
+		interval:    interval,
+		batchSize:   batchSize,
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+		logger:      logger,
+	}
+}
+
+// Starts relaying in the background, until Stop is called
+func (r *Relay) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				// keep going while full batches come back, so a backlog drains quickly
+				for {
+					relayed, err := r.RelayBatch(context.Background())
+					if err != nil {
+						r.logger.Error("error relaying outbox events", "err", err)
+					}
+					if err != nil || relayed < r.batchSize {
+						break
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stops the relay, and waits for a running batch to finish
+func (r *Relay) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+// Relays one batch of due events, oldest first
+// An event which fails to publish is retried later, and the events after it are published meanwhile
+// Returns the number of relayed events, whether they were published or not
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	events, err := r.outbox.FindDueEvents(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	relayed := 0
+	for i := range *events {
+		event := &(*events)[i]
+		ok, err := r.relay(ctx, event)
+		if err != nil {
+			return relayed, err
+		}
+		if ok {
+			relayed++
+		}
+	}
+	return relayed, nil
+}
+
+// Hands the event to each subscription which didn't handle it yet, then marks it published, or failed if any of them failed
+// Each subscription handles the event in a transaction of its own, which also records it did, so a failing subscription
+// neither undoes the work of the others nor makes them handle the event twice
+// Returns false when another relay holds the event
+func (r *Relay) relay(ctx context.Context, event *domain.Event) (bool, error) {
+	var failure error
+	for _, subscription := range r.bus.Subscriptions(event.Type) {
+		if contains(event.DeliveredTo, subscription.Name) {
+			continue
+		}
+		locked := false
+		var handlerErr error
+		err := r.tx.TxContext(ctx, func(ctx context.Context) error {
+			deliveredTo, ok, err := r.outbox.LockEvent(ctx, event.ID)
+			if err != nil || !ok {
+				return err
+			}
+			locked = true
+			if contains(deliveredTo, subscription.Name) {
+				return nil
+			}
+			handlerErr = subscription.Handler(ctx, event)
+			if handlerErr != nil {
+				return handlerErr
+			}
+			return r.outbox.MarkEventDelivered(ctx, event.ID, subscription.Name)
+		})
+		if handlerErr != nil {
+			if failure == nil {
+				failure = errors.Wrap(handlerErr, subscription.Name)
+			}
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !locked {
+			return false, nil
+		}
+		event.DeliveredTo = append(event.DeliveredTo, subscription.Name)
+	}
+
+	if failure != nil {
+		return true, r.fail(ctx, event, failure)
+	}
+	return true, r.outbox.MarkEventPublished(ctx, event.ID)
+}
+
+// Schedules the next attempt of the event, or dead-letters it after the last one
+func (r *Relay) fail(ctx context.Context, event *domain.Event, failure error) error {
+	attempts := event.Attempts + 1
+	if attempts >= r.maxAttempts {
+		r.logger.Error("outbox event dead-lettered", "id", event.ID, "type", event.Type, "err", failure)
+		return r.outbox.MarkEventDead(ctx, event.ID, failure.Error())
+	}
+	r.logger.Warn("error publishing event", "id", event.ID, "type", event.Type, "attempts", attempts, "err", failure)
+	return r.outbox.MarkEventFailed(ctx, event.ID, failure.Error(), time.Now().Add(r.Backoff(attempts)))
+}
+
+// Returns how long to wait before the next attempt, after the given number of failed ones
+func (r *Relay) Backoff(attempts int) time.Duration {
+	backoff := r.backoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= r.maxBackoff {
+			return r.maxBackoff
+		}
+	}
+	return backoff
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// in-memory outbox, standing in for the database repository
+type memoryOutbox struct {
+	events    []domain.Event
+	published map[string]bool
+	failed    map[string]string
+	retryAt   map[string]time.Time
+	dead      map[string]bool
+}
+
+func (m *memoryOutbox) InsertEvent(_ context.Context, event *domain.Event) error {
+	m.events = append(m.events, *event)
+	return nil
+}
+
+func (m *memoryOutbox) find(id string) *domain.Event {
+	for i := range m.events {
+		if m.events[i].ID == id {
+			return &m.events[i]
+		}
+	}
+	return nil
+}
+
+func (m *memoryOutbox) FindDueEvents(_ context.Context, limit int) (*[]domain.Event, error) {
+	var events []domain.Event
+	for _, event := range m.events {
+		due := !m.retryAt[event.ID].After(time.Now())
+		if !m.published[event.ID] && !m.dead[event.ID] && due && len(events) < limit {
+			event.DeliveredTo = append([]string{}, event.DeliveredTo...)
+			events = append(events, event)
+		}
+	}
+	return &events, nil
+}
+
+func (m *memoryOutbox) LockEvent(_ context.Context, id string) ([]string, bool, error) {
+	if m.published[id] || m.dead[id] {
+		return nil, false, nil
+	}
+	return m.find(id).DeliveredTo, true, nil
+}
+
+func (m *memoryOutbox) MarkEventDelivered(_ context.Context, id string, subscriber string) error {
+	event := m.find(id)
+	event.DeliveredTo = append(event.DeliveredTo, subscriber)
+	return nil
+}
+
+func (m *memoryOutbox) MarkEventPublished(_ context.Context, id string) error {
+	m.find(id).Attempts++
+	m.published[id] = true
+	return nil
+}
+
+func (m *memoryOutbox) MarkEventFailed(_ context.Context, id string, reason string, nextAttemptAt time.Time) error {
+	m.find(id).Attempts++
+	m.failed[id] = reason
+	m.retryAt[id] = nextAttemptAt
+	return nil
+}
+
+func (m *memoryOutbox) MarkEventDead(_ context.Context, id string, reason string) error {
+	m.find(id).Attempts++
+	m.failed[id] = reason
+	m.dead[id] = true
+	return nil
+}
+
+// makes the failed events due again
+func (m *memoryOutbox) rewind() {
+	m.retryAt = map[string]time.Time{}
+}
+
+type noTx struct{}
+
+func (noTx) TxContext(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type RelaySuite struct {
+	suite.Suite
+	outbox *memoryOutbox
+	bus    *Bus
+	relay  *Relay
+}
+
+func (suite *RelaySuite) SetupTest() {
+	suite.outbox = &memoryOutbox{published: map[string]bool{}, failed: map[string]string{}, retryAt: map[string]time.Time{}, dead: map[string]bool{}}
+	suite.bus = NewBus()
+	suite.relay = NewRelay(suite.outbox, noTx{}, suite.bus, 0, 10, log.NewNilLogger())
+
+	for _, id := range []string{"1", "2", "3"} {
+		event, _ := domain.NewEvent(domain.EventOrderCreated, id, domain.OrderCreatedPayload{OrderID: id})
+		event.ID = id
+		suite.outbox.InsertEvent(context.TODO(), event)
+	}
+}
+
+func TestRelayTestSuite(t *testing.T) {
+	suite.Run(t, new(RelaySuite))
+}
+
+func (suite *RelaySuite) TestPublishesEventsInOrder() {
+	var received []string
+	suite.bus.Subscribe("orders", domain.EventOrderCreated, func(_ context.Context, event *domain.Event) error {
+		var payload domain.OrderCreatedPayload
+		event.DecodePayload(&payload)
+		received = append(received, payload.OrderID)
+		return nil
+	})
+	// subscribers to other event types are not called
+	suite.bus.Subscribe("users", domain.EventUserRegistered, func(_ context.Context, _ *domain.Event) error {
+		suite.T().Fatal("unexpected event")
+		return nil
+	})
+
+	published, err := suite.relay.RelayBatch(context.TODO())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, published)
+	assert.Equal(suite.T(), []string{"1", "2", "3"}, received)
+
+	// published events are not relayed again
+	published, _ = suite.relay.RelayBatch(context.TODO())
+	assert.Equal(suite.T(), 0, published)
+}
+
+func (suite *RelaySuite) TestSkipsFailedEvent() {
+	suite.bus.SubscribeAll("all", func(_ context.Context, event *domain.Event) error {
+		if event.ID == "2" {
+			return errors.New("subscriber down")
+		}
+		return nil
+	})
+
+	relayed, err := suite.relay.RelayBatch(context.TODO())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, relayed)
+	assert.True(suite.T(), suite.outbox.published["1"])
+	assert.False(suite.T(), suite.outbox.published["2"])
+	assert.True(suite.T(), suite.outbox.published["3"])
+	assert.Contains(suite.T(), suite.outbox.failed["2"], "subscriber down")
+
+	// the failed event waits for its next attempt
+	assert.True(suite.T(), suite.outbox.retryAt["2"].After(time.Now()))
+	relayed, _ = suite.relay.RelayBatch(context.TODO())
+	assert.Equal(suite.T(), 0, relayed)
+}
+
+func (suite *RelaySuite) TestRetriesOnlyFailedSubscribers() {
+	calls := map[string]int{}
+	down := true
+	suite.bus.SubscribeAll("metrics", func(_ context.Context, event *domain.Event) error {
+		calls["metrics"]++
+		return nil
+	})
+	suite.bus.Subscribe("mail", domain.EventOrderCreated, func(_ context.Context, event *domain.Event) error {
+		calls["mail"]++
+		if down {
+			return errors.New("mail server down")
+		}
+		return nil
+	})
+
+	suite.relay.RelayBatch(context.TODO())
+	assert.Equal(suite.T(), map[string]int{"metrics": 3, "mail": 3}, calls)
+	assert.Equal(suite.T(), []string{"metrics"}, suite.outbox.find("1").DeliveredTo)
+
+	down = false
+	suite.outbox.rewind()
+	relayed, err := suite.relay.RelayBatch(context.TODO())
+
+	// the subscriber which handled the events already isn't called again
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, relayed)
+	assert.Equal(suite.T(), map[string]int{"metrics": 3, "mail": 6}, calls)
+	assert.True(suite.T(), suite.outbox.published["1"])
+}
+
+func (suite *RelaySuite) TestDeadLettersAfterLastAttempt() {
+	suite.bus.SubscribeAll("all", func(_ context.Context, event *domain.Event) error {
+		return errors.New("subscriber down")
+	})
+
+	for i := 0; i < DefaultMaxAttempts; i++ {
+		suite.outbox.rewind()
+		suite.relay.RelayBatch(context.TODO())
+	}
+
+	assert.True(suite.T(), suite.outbox.dead["1"])
+	assert.Equal(suite.T(), DefaultMaxAttempts, suite.outbox.find("1").Attempts)
+
+	// dead events aren't relayed anymore
+	suite.outbox.rewind()
+	relayed, _ := suite.relay.RelayBatch(context.TODO())
+	assert.Equal(suite.T(), 0, relayed)
+}
+
+func (suite *RelaySuite) TestBackoff() {
+	assert.Equal(suite.T(), DefaultBackoff, suite.relay.Backoff(1))
+	assert.Equal(suite.T(), 4*DefaultBackoff, suite.relay.Backoff(3))
+	assert.Equal(suite.T(), DefaultMaxBackoff, suite.relay.Backoff(20))
+}
@@ -0,0 +1,155 @@
+package apikey
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/openapi"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/params"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/request"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+)
+
+type ApiKeyHttpHandler struct {
+	apiKeySvc ports.ApiKeyUsecase
+}
+
+func NewApiKeyHandler(apiKeySvc ports.ApiKeyUsecase, wsCont *restful.Container) *ApiKeyHttpHandler {
+	httpHandler := &ApiKeyHttpHandler{
+		apiKeySvc: apiKeySvc,
+	}
+
+	// API keys are only managed by admins
+	apiversion.Register(wsCont, "/admin/api-key", func(v apiversion.Version, ws *restful.WebService) {
+		ws.Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON).
+			Filter(auth.AuthJWT).Filter(auth.RequireRole(domain.RoleAdmin))
+		idParam := ws.PathParameter("id", "ID of the API key")
+
+		ws.Route(ws.GET("").To(httpHandler.GetApiKeys).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("List the API keys").
+			Writes([]ApiKeyModel{}).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil))
+		ws.Route(ws.GET("/{id}").To(httpHandler.GetApiKey).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Get an API key").
+			Param(idParam).
+			Writes(ApiKeyModel{}).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil).
+			Returns(http.StatusNotFound, "API key doesn't exist", nil))
+		ws.Route(ws.POST("").To(httpHandler.CreateApiKey).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Create an API key").
+			Notes("The key is only returned here, it can't be retrieved later").
+			Reads(ApiKeyRequest{}).
+			Writes(ApiKeyModel{}).
+			Returns(http.StatusCreated, "API key created", nil).
+			Returns(http.StatusBadRequest, "Invalid API key", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil))
+		ws.Route(ws.DELETE("/{id}").To(httpHandler.RevokeApiKey).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Revoke an API key").
+			Param(idParam).
+			Returns(http.StatusNoContent, "API key revoked", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil).
+			Returns(http.StatusNotFound, "API key doesn't exist", nil))
+	})
+
+	return httpHandler
+}
+
+func (e *ApiKeyHttpHandler) GetApiKeys(req *restful.Request, resp *restful.Response) {
+	keys, err := e.apiKeySvc.GetAllApiKeys(req.Request.Context())
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	retKeys := []ApiKeyModel{}
+	for _, key := range *keys {
+		var retKey ApiKeyModel
+		retKey.FromDomain(&key)
+		retKeys = append(retKeys, retKey)
+	}
+	resp.WriteAsJson(retKeys)
+}
+
+func (e *ApiKeyHttpHandler) GetApiKey(req *restful.Request, resp *restful.Response) {
+	key, err := e.apiKeySvc.FindApiKeyById(req.Request.Context(), req.PathParameter("id"))
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	var retKey *ApiKeyModel = &ApiKeyModel{}
+	retKey.FromDomain(key)
+	resp.WriteAsJson(retKey)
+}
+
+// Creates a key with the given scopes
+// The response carries the key itself, which isn't returned again afterwards
+func (e *ApiKeyHttpHandler) CreateApiKey(req *restful.Request, resp *restful.Response) {
+	adminId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(adminId) == 0 {
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
+		return
+	}
+	var reqData ApiKeyRequest
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+
+	var fields []domain.FieldError
+	if len(strings.TrimSpace(reqData.Name)) == 0 {
+		fields = append(fields, domain.FieldError{Field: "name", Message: "is required"})
+	}
+	for _, scope := range reqData.Scopes {
+		if !domain.ApiKeyScope(scope).IsValid() {
+			fields = append(fields, domain.FieldError{Field: "scopes", Message: "unknown scope " + scope})
+		}
+	}
+	if reqData.ExpiresAt != nil && !reqData.ExpiresAt.After(time.Now()) {
+		fields = append(fields, domain.FieldError{Field: "expiresAt", Message: "must be in the future"})
+	}
+	if len(fields) > 0 {
+		response.WriteError(resp, domain.NewValidationError("invalid request body", fields...))
+		return
+	}
+
+	var key *ApiKeyModel = &ApiKeyModel{}
+	key.Name = strings.TrimSpace(reqData.Name)
+	key.Scopes = reqData.Scopes
+	key.ExpiresAt = reqData.ExpiresAt
+	created := key.ToDomain()
+	created.CreatedBy = adminId
+	rawKey, err := e.apiKeySvc.CreateApiKey(req.Request.Context(), created)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	key.FromDomain(created)
+	key.Key = rawKey
+	resp.WriteHeaderAndJson(http.StatusCreated, key, restful.MIME_JSON)
+}
+
+// Revokes the key, requests made with it are refused from then on
+func (e *ApiKeyHttpHandler) RevokeApiKey(req *restful.Request, resp *restful.Response) {
+	id := req.PathParameter("id")
+	err := e.apiKeySvc.RevokeApiKey(req.Request.Context(), id)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
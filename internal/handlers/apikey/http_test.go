@@ -0,0 +1,186 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/app"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/usecases"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/product"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+var testApp *app.App
+
+type HttpSuite struct {
+	suite.Suite
+	apiKeyHttpSvc ApiKeyHttpHandler
+	wsContainer   *restful.Container
+	userRep       *repo.UserRepository
+	productSvc    *usecases.ProductService
+	categorySvc   *usecases.CategoryService
+}
+
+func (suite *HttpSuite) SetupTest() {
+}
+
+func (suite *HttpSuite) TearDownTest() {
+	testutil.CleanUpTables(*testApp.DB)
+}
+
+func (suite *HttpSuite) SetupSuite() {
+
+	testApp = testutil.InitTestApp()
+	suite.wsContainer = restful.NewContainer()
+	http.Handle("/", suite.wsContainer)
+
+	suite.userRep = repo.NewUserRepository(testApp.DB)
+	realApiKeySvc := usecases.NewApiKeyService(repo.NewApiKeyRepository(testApp.DB))
+	auth.SetApiKeyAuthenticator(realApiKeySvc.Authenticate)
+	auth.SetSessionChecker(usecases.NewUserService(suite.userRep, repo.NewUserTokenRepository(testApp.DB),
+		repo.NewOutboxRepository(testApp.DB), testApp.DB, nil).CheckSession)
+	suite.apiKeyHttpSvc = *NewApiKeyHandler(realApiKeySvc, suite.wsContainer)
+
+	// the stock endpoint is called with the keys
+	suite.categorySvc = usecases.NewCategoryService(repo.NewCategoryRepository(testApp.DB))
+	suite.productSvc = usecases.NewProductService(repo.NewProductRepository(testApp.DB), repo.NewOutboxRepository(testApp.DB), testApp.DB)
+	product.NewProductHandler(suite.productSvc, suite.categorySvc, suite.wsContainer)
+}
+
+func (suite *HttpSuite) TearDownSuite() {
+	auth.SetApiKeyAuthenticator(nil)
+	auth.SetSessionChecker(nil)
+}
+
+func TestApiKeyTestSuite(t *testing.T) {
+	suite.Run(t, new(HttpSuite))
+}
+
+// Registers a user with the role, and returns a JWT for them
+func (suite *HttpSuite) createUser(email string, role string) string {
+	user := &domain.User{Email: email, Name: "Name", Surname: "Surname", PasswordHash: "hash"}
+	err := suite.userRep.Insert(context.TODO(), user)
+	if err != nil {
+		suite.T().Fatalf("Error creating test user: %s", err)
+	}
+	testutil.SetUserRole(*testApp.DB, user.ID, role)
+	token, err := auth.CreateJWT(user.Email, user.ID, role, user.SessionVersion)
+	if err != nil {
+		suite.T().Fatalf("Error creating test token: %s", err)
+	}
+	return token
+}
+
+func (suite *HttpSuite) createProduct() int64 {
+	cId, err := suite.categorySvc.CreateCategory(context.TODO(), &domain.Category{Name: "test"})
+	if err != nil {
+		suite.T().Fatalf("Error creating test category: %s", err)
+	}
+	pId, err := suite.productSvc.CreateProduct(context.TODO(), &domain.Product{
+		Name: "product", Price: 10, Quantity: 5, Category: &domain.Category{Id: int(cId)},
+	})
+	if err != nil {
+		suite.T().Fatalf("Error creating test product: %s", err)
+	}
+	return pId
+}
+
+func (suite *HttpSuite) TestOnlyAdminsManageKeys() {
+	token := suite.createUser("user@email.com", domain.RoleUser)
+	postData := ApiKeyRequest{Name: "inventory", Scopes: []string{string(domain.ScopeWriteStock)}}
+
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/admin/api-key", postData, &token)
+	assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "GET", "/v1/admin/api-key", nil, nil)
+	assert.Equal(suite.T(), http.StatusUnauthorized, responseRec.Code)
+}
+
+func (suite *HttpSuite) TestCreateApiKeyValidation() {
+	token := suite.createUser("admin@email.com", domain.RoleAdmin)
+	past := time.Now().Add(-time.Hour)
+
+	for _, postData := range []ApiKeyRequest{
+		{Name: "", Scopes: []string{string(domain.ScopeWriteStock)}},
+		{Name: "inventory"},
+		{Name: "inventory", Scopes: []string{"everything"}},
+		{Name: "inventory", Scopes: []string{string(domain.ScopeWriteStock)}, ExpiresAt: &past},
+	} {
+		responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/admin/api-key", postData, &token)
+		assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code)
+	}
+}
+
+// Test the lifecycle of a key: it's created, used on the stock endpoint, and no longer works once revoked
+func (suite *HttpSuite) TestApiKeyLifecycle() {
+	token := suite.createUser("admin@email.com", domain.RoleAdmin)
+	postData := ApiKeyRequest{Name: "inventory", Scopes: []string{string(domain.ScopeWriteStock)}}
+
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/admin/api-key", postData, &token)
+	assert.Equal(suite.T(), http.StatusCreated, responseRec.Code)
+	var created ApiKeyModel
+	err := json.Unmarshal(responseRec.Body.Bytes(), &created)
+	if err != nil {
+		suite.T().Fatalf("Error unmarshalling API key response: %s", err)
+	}
+	assert.NotEmpty(suite.T(), created.ID)
+	assert.NotEmpty(suite.T(), created.Key)
+	assert.Equal(suite.T(), created.Prefix, created.Key[:len(created.Prefix)])
+
+	// the key itself is only shown once
+	responseRec = testutil.MakeRequest(suite.wsContainer, "GET", "/v1/admin/api-key/"+created.ID, nil, &token)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var found ApiKeyModel
+	json.Unmarshal(responseRec.Body.Bytes(), &found)
+	assert.Empty(suite.T(), found.Key)
+	assert.Equal(suite.T(), []string{string(domain.ScopeWriteStock)}, found.Scopes)
+
+	pId := suite.createProduct()
+	stockPath := "/v1/product/" + strconv.FormatInt(pId, 10) + "/stock"
+	headers := map[string]string{"Content-Type": restful.MIME_JSON, "Authorization": "ApiKey " + created.Key, "If-Match": "*"}
+	responseRec = testutil.MakeRawRequest(suite.wsContainer, "PUT", stockPath, []byte(`{"Quantity": 42}`), headers)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var updated product.ProductModel
+	json.Unmarshal(responseRec.Body.Bytes(), &updated)
+	assert.Equal(suite.T(), 42, updated.Quantity)
+
+	responseRec = testutil.MakeRawRequest(suite.wsContainer, "PUT", stockPath, []byte(`{"Quantity": -1}`), headers)
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code)
+
+	// its use was recorded
+	responseRec = testutil.MakeRequest(suite.wsContainer, "GET", "/v1/admin/api-key/"+created.ID, nil, &token)
+	json.Unmarshal(responseRec.Body.Bytes(), &found)
+	assert.NotNil(suite.T(), found.LastUsedAt)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "DELETE", "/v1/admin/api-key/"+created.ID, nil, &token)
+	assert.Equal(suite.T(), http.StatusNoContent, responseRec.Code)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "DELETE", "/v1/admin/api-key/"+created.ID, nil, &token)
+	assert.Equal(suite.T(), http.StatusNotFound, responseRec.Code)
+
+	responseRec = testutil.MakeRawRequest(suite.wsContainer, "PUT", stockPath, []byte(`{"Quantity": 7}`), headers)
+	assert.Equal(suite.T(), http.StatusUnauthorized, responseRec.Code)
+}
+
+// Test that keys without the scope can't change the stock
+func (suite *HttpSuite) TestStockRequiresScope() {
+	token := suite.createUser("admin@email.com", domain.RoleAdmin)
+	postData := ApiKeyRequest{Name: "reporting", Scopes: []string{string(domain.ScopeReadOrders)}}
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/admin/api-key", postData, &token)
+	var created ApiKeyModel
+	json.Unmarshal(responseRec.Body.Bytes(), &created)
+
+	pId := suite.createProduct()
+	headers := map[string]string{"Content-Type": restful.MIME_JSON, "Authorization": "ApiKey " + created.Key, "If-Match": "*"}
+	responseRec = testutil.MakeRawRequest(suite.wsContainer, "PUT", "/v1/product/"+strconv.FormatInt(pId, 10)+"/stock", []byte(`{"Quantity": 42}`), headers)
+	assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
+}
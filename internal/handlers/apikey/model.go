@@ -0,0 +1,60 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+)
+
+type ApiKeyModel struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"createdBy"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	// Only returned once, when the key is created
+	Key string `json:"key,omitempty"`
+}
+
+func (e *ApiKeyModel) FromDomain(key *domain.ApiKey) {
+	if e == nil || key == nil {
+		return
+	}
+	e.ID = key.ID
+	e.Name = key.Name
+	e.Prefix = key.Prefix
+	e.Scopes = []string{}
+	for _, scope := range key.Scopes {
+		e.Scopes = append(e.Scopes, string(scope))
+	}
+	e.CreatedBy = key.CreatedBy
+	e.ExpiresAt = key.ExpiresAt
+	e.LastUsedAt = key.LastUsedAt
+	e.RevokedAt = key.RevokedAt
+	e.CreatedAt = key.CreatedAt
+}
+
+func (e *ApiKeyModel) ToDomain() *domain.ApiKey {
+	if e == nil {
+		return &domain.ApiKey{}
+	}
+	var scopes []domain.ApiKeyScope
+	for _, scope := range e.Scopes {
+		scopes = append(scopes, domain.ApiKeyScope(scope))
+	}
+	return &domain.ApiKey{
+		ID:         e.ID,
+		Name:       e.Name,
+		Prefix:     e.Prefix,
+		Scopes:     scopes,
+		CreatedBy:  e.CreatedBy,
+		ExpiresAt:  e.ExpiresAt,
+		LastUsedAt: e.LastUsedAt,
+		RevokedAt:  e.RevokedAt,
+		CreatedAt:  e.CreatedAt,
+	}
+}
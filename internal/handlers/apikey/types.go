@@ -0,0 +1,15 @@
+package apikey
+
+import "time"
+
+type Response struct {
+	ID   string
+	Name string
+}
+
+type ApiKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=255"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+	// Optional, the key doesn't expire when not provided
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
@@ -1,13 +1,16 @@
 package category
 
 import (
-	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/emicklei/go-restful/v3"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/patch"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/request"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
 )
 
 type CategoryHttpHandler struct {
@@ -19,17 +22,64 @@ func NewCategoryHandler(categorySvc ports.CategoryUsecase, wsCont *restful.Conta
 		categorySvc: categorySvc,
 	}
 
-	ws := new(restful.WebService)
+	apiversion.Register(wsCont, "/category", func(v apiversion.Version, ws *restful.WebService) {
+		ws.Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
 
-	ws.Path("/category").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+		var model, models interface{} = CategoryModel{}, []CategoryModel{}
+		if v >= apiversion.V2 {
+			model, models = CategoryModelV2{}, []CategoryModelV2{}
+		}
 
-	ws.Route(ws.GET("").To(httpHandler.GetCategories))
-	ws.Route(ws.GET("/{id}").To(httpHandler.GetCategory))
-	ws.Route(ws.POST("").To(httpHandler.CreateCategory))
-	ws.Route(ws.DELETE("/{id}").To(httpHandler.DeleteCategory))
-	ws.Route(ws.PUT("/{id}").To(httpHandler.UpdateCategory))
+		idParam := ws.PathParameter("id", "ID of the category").DataType("integer")
+		ifMatchParam := ws.HeaderParameter("If-Match", "ETag of the category, the request fails with 412 if it was modified since")
 
-	wsCont.Add(ws)
+		ws.Route(ws.GET("").To(httpHandler.GetCategories).
+			Doc("List the categories").
+			Writes(models).
+			Returns(http.StatusOK, "OK", nil))
+		ws.Route(ws.GET("/{id}").To(httpHandler.GetCategory).
+			Doc("Get a category").
+			Param(idParam).
+			Writes(model).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusNotFound, "Category doesn't exist", nil))
+		ws.Route(ws.POST("").To(httpHandler.CreateCategory).
+			Doc("Create a category").
+			Reads(CategoryRequest{}).
+			Writes(Response{}).
+			Returns(http.StatusOK, "Category created", nil).
+			Returns(http.StatusBadRequest, "Invalid category", nil))
+		ws.Route(ws.DELETE("/{id}").To(httpHandler.DeleteCategory).
+			Doc("Delete a category").
+			Param(idParam).Param(ifMatchParam).
+			Writes(Response{}).
+			Returns(http.StatusOK, "Category deleted", nil).
+			Returns(http.StatusNotFound, "Category doesn't exist", nil).
+			Returns(http.StatusPreconditionFailed, "Category was modified", nil).
+			Returns(http.StatusPreconditionRequired, "If-Match header missing", nil))
+		ws.Route(ws.PUT("/{id}").To(httpHandler.UpdateCategory).
+			Doc("Replace a category").
+			Param(idParam).Param(ifMatchParam).
+			Reads(CategoryRequest{}).
+			Writes(Response{}).
+			Returns(http.StatusOK, "Category updated", nil).
+			Returns(http.StatusBadRequest, "Invalid category", nil).
+			Returns(http.StatusNotFound, "Category doesn't exist", nil).
+			Returns(http.StatusPreconditionFailed, "Category was modified", nil).
+			Returns(http.StatusPreconditionRequired, "If-Match header missing", nil))
+		ws.Route(ws.PATCH("/{id}").To(httpHandler.PatchCategory).Consumes(patch.MIME_MERGE_PATCH, patch.MIME_JSON_PATCH).
+			Doc("Update some fields of a category").
+			Notes("Takes a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) of the category").
+			Param(idParam).Param(ifMatchParam).
+			Reads(model).
+			Writes(model).
+			Returns(http.StatusOK, "Category updated", nil).
+			Returns(http.StatusBadRequest, "Invalid patch or patched category", nil).
+			Returns(http.StatusNotFound, "Category doesn't exist", nil).
+			Returns(http.StatusPreconditionFailed, "Category was modified", nil).
+			Returns(http.StatusPreconditionRequired, "If-Match header missing", nil))
+
+	})
 
 	return httpHandler
 }
@@ -38,15 +88,13 @@ func (e *CategoryHttpHandler) GetCategories(req *restful.Request, resp *restful.
 	ctx := req.Request.Context()
 	categories, err := e.categorySvc.GetAllCategories(ctx)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("error retrieving categories"))
+		response.WriteError(resp, err)
 		return
 	}
-	var retCategories []CategoryModel
-	var retCategory *CategoryModel = &CategoryModel{}
-
-	for _, category := range *categories {
-		retCategory.FromDomain(&category)
-		retCategories = append(retCategories, *retCategory)
+	v := apiversion.FromRequest(req)
+	var retCategories []versionedModel
+	for i := range *categories {
+		retCategories = append(retCategories, modelOf(v, &(*categories)[i]))
 	}
 	resp.WriteAsJson(retCategories)
 }
@@ -54,34 +102,32 @@ func (e *CategoryHttpHandler) GetCategories(req *restful.Request, resp *restful.
 func (e *CategoryHttpHandler) GetCategory(req *restful.Request, resp *restful.Response) {
 	id, err := getId(req, resp)
 	if err != nil {
-		resp.WriteError(http.StatusBadRequest, errors.New("invalid category id"))
+		response.WriteError(resp, response.NewBadRequestError("invalid category id"))
 		return
 	}
 	category, err := e.categorySvc.FindCategoryById(req.Request.Context(), id)
 	if err != nil {
-		resp.WriteError(http.StatusNotFound, errors.New("category doesn't exist"))
+		response.WriteError(resp, err)
 		return
 	}
-	var retCategory *CategoryModel = &CategoryModel{}
-	retCategory.FromDomain(category)
-	resp.WriteAsJson(retCategory)
+	response.SetETag(resp, category.Version)
+	resp.WriteAsJson(modelOf(apiversion.FromRequest(req), category))
 }
 
 func (e *CategoryHttpHandler) CreateCategory(req *restful.Request, resp *restful.Response) {
 	var reqData CategoryRequest
-	req.ReadEntity(&reqData)
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
 
 	var category *CategoryModel = &CategoryModel{}
 	category.Name = reqData.Name
 
-	if len(category.Name) < 1 {
-		resp.WriteError(http.StatusBadRequest, errors.New("name not provided"))
-		return
-	}
 	categoryId, err := e.categorySvc.CreateCategory(req.Request.Context(), category.ToDomain())
 
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("error creating category"))
+		response.WriteError(resp, err)
 		return
 	}
 	resp.WriteAsJson(Response{ID: categoryId, Name: category.Name})
@@ -90,16 +136,17 @@ func (e *CategoryHttpHandler) CreateCategory(req *restful.Request, resp *restful
 func (e *CategoryHttpHandler) DeleteCategory(req *restful.Request, resp *restful.Response) {
 	id, err := getId(req, resp)
 	if err != nil {
-		resp.WriteError(http.StatusBadRequest, errors.New("invalid category id"))
+		response.WriteError(resp, response.NewBadRequestError("invalid category id"))
 		return
 	}
-	rows, err := e.categorySvc.DeleteCategory(req.Request.Context(), id)
+	version, err := request.IfMatchVersion(req.Request)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("an error occured"))
+		response.WriteError(resp, err)
 		return
 	}
-	if rows == 0 {
-		resp.WriteError(http.StatusNotFound, errors.New("category doesn't exist"))
+	rows, err := e.categorySvc.DeleteCategory(req.Request.Context(), id, version)
+	if err != nil {
+		response.WriteError(resp, err)
 		return
 	}
 	resp.WriteAsJson(Response{ID: rows, Name: "category deleted"})
@@ -108,23 +155,97 @@ func (e *CategoryHttpHandler) DeleteCategory(req *restful.Request, resp *restful
 func (e *CategoryHttpHandler) UpdateCategory(req *restful.Request, resp *restful.Response) {
 	id, err := getId(req, resp)
 	if err != nil {
-		resp.WriteError(http.StatusBadRequest, errors.New("invalid category id"))
+		response.WriteError(resp, response.NewBadRequestError("invalid category id"))
+		return
+	}
+	version, err := request.IfMatchVersion(req.Request)
+	if err != nil {
+		response.WriteError(resp, err)
 		return
 	}
 	var categoryReq CategoryRequest
-	req.ReadEntity(&categoryReq)
-	dataCategory := &domain.Category{Name: categoryReq.Name}
+	if err := request.ReadBody(req.Request, &categoryReq); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	dataCategory := &domain.Category{Name: categoryReq.Name, Version: version}
 	updated, err := e.categorySvc.UpdateCategory(req.Request.Context(), dataCategory, id)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("an error occured"))
+		response.WriteError(resp, err)
 		return
 	}
-	if updated == 0 {
-		resp.WriteError(http.StatusNotFound, errors.New("category doesn't exist"))
+	response.SetETag(resp, dataCategory.Version)
+	resp.WriteAsJson(Response{ID: updated, Name: dataCategory.Name})
+
+}
+
+// Applies a JSON Merge Patch or JSON Patch to the category, updating only the changed fields
+func (e *CategoryHttpHandler) PatchCategory(req *restful.Request, resp *restful.Response) {
+	id, err := getId(req, resp)
+	if err != nil {
+		response.WriteError(resp, response.NewBadRequestError("invalid category id"))
 		return
 	}
-	resp.WriteAsJson(Response{ID: updated, Name: dataCategory.Name})
+	version, err := request.IfMatchVersion(req.Request)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	ctx := req.Request.Context()
+	current, err := e.categorySvc.FindCategoryById(ctx, id)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	if version != 0 && version != current.Version {
+		response.WriteError(resp, response.NewPreconditionFailedError("category was modified"))
+		return
+	}
+
+	// the patch is in the shape of the category in the version
+	v := apiversion.FromRequest(req)
+	var original *CategoryModel = &CategoryModel{}
+	original.FromDomain(current)
+	patchedModel := newModel(v)
+	err = patch.Apply(req.Request, modelOf(v, current), patchedModel)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	var patched *CategoryModel = &CategoryModel{}
+	patched.FromDomain(patchedModel.ToDomain())
+	if patched.Id != original.Id || patched.Version != original.Version ||
+		!patched.CreatedAt.Equal(original.CreatedAt) || !patched.UpdatedAt.Equal(original.UpdatedAt) {
+		response.WriteError(resp, response.NewBadRequestError("read-only category fields cannot be patched"))
+		return
+	}
+
+	// the patched category must pass the same checks as a created one
+	reqData := CategoryRequest{Name: patched.Name}
+	if err := request.Validate(&reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+
+	changes := &domain.CategoryPatch{Version: current.Version}
+	if reqData.Name != original.Name {
+		changes.Name = &reqData.Name
+	}
+	if !changes.IsEmpty() {
+		_, err = e.categorySvc.PatchCategory(ctx, id, changes)
+		if err != nil {
+			response.WriteError(resp, err)
+			return
+		}
+		current, err = e.categorySvc.FindCategoryById(ctx, id)
+		if err != nil {
+			response.WriteError(resp, err)
+			return
+		}
+	}
 
+	response.SetETag(resp, current.Version)
+	resp.WriteAsJson(modelOf(v, current))
 }
 
 func getId(req *restful.Request, resp *restful.Response) (int64, error) {
@@ -56,7 +56,7 @@ func (suite *HttpSuite) TestGetCategories() {
 	if err != nil {
 		suite.T().Fatalf("Error creating test category: %s", err)
 	}
-	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", "/category", nil, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", "/v1/category", nil, nil)
 	var response []CategoryModel
 	err = json.Unmarshal(responseRec.Body.Bytes(), &response)
 	if err != nil {
@@ -74,7 +74,7 @@ func (suite *HttpSuite) TestGetCategory() {
 	if err != nil {
 		suite.T().Fatalf("Error creating test category: %s", err)
 	}
-	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", "/category/"+strconv.Itoa(int(id)), nil, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", "/v1/category/"+strconv.Itoa(int(id)), nil, nil)
 	var response CategoryModel
 	err = json.Unmarshal(responseRec.Body.Bytes(), &response)
 	if err != nil {
@@ -86,7 +86,7 @@ func (suite *HttpSuite) TestGetCategory() {
 
 func (suite *HttpSuite) TestCreateCategory() {
 	categoryName := "test"
-	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/category", CategoryRequest{Name: categoryName}, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/category", CategoryRequest{Name: categoryName}, nil)
 	var createResponse Response
 	err := json.Unmarshal(responseRec.Body.Bytes(), &createResponse)
 	if err != nil {
@@ -103,7 +103,13 @@ func (suite *HttpSuite) TestUpdateCategory() {
 		suite.T().Fatalf("Error creating test category: %s", err)
 	}
 	updateName := "updated"
-	responseRec := testutil.MakeRequest(suite.wsContainer, "PUT", "/category/"+strconv.Itoa(int(id)), CategoryRequest{Name: updateName}, nil)
+	path := "/v1/category/" + strconv.Itoa(int(id))
+	responseRec := testutil.MakeRequest(suite.wsContainer, "PUT", path, CategoryRequest{Name: updateName}, nil)
+	assert.Equal(suite.T(), http.StatusPreconditionRequired, responseRec.Code)
+
+	body, _ := json.Marshal(CategoryRequest{Name: updateName})
+	responseRec = testutil.MakeRawRequest(suite.wsContainer, "PUT", path, body,
+		map[string]string{"Content-Type": restful.MIME_JSON, "If-Match": `"1"`})
 	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
 	var updateResponse Response
 	err = json.Unmarshal(responseRec.Body.Bytes(), &updateResponse)
@@ -122,7 +128,8 @@ func (suite *HttpSuite) TestDeleteCategory() {
 	if err != nil {
 		suite.T().Fatalf("Error creating test category: %s", err)
 	}
-	responseRec := testutil.MakeRequest(suite.wsContainer, "DELETE", "/category/"+strconv.Itoa(int(id)), nil, nil)
+	responseRec := testutil.MakeRawRequest(suite.wsContainer, "DELETE", "/v1/category/"+strconv.Itoa(int(id)), nil,
+		map[string]string{"If-Match": "*"})
 	var response Response
 	err = json.Unmarshal(responseRec.Body.Bytes(), &response)
 	if err != nil {
@@ -134,3 +141,34 @@ func (suite *HttpSuite) TestDeleteCategory() {
 	assert.Equal(suite.T(), message, response.Name)
 	assert.Equal(suite.T(), rows, response.ID)
 }
+
+func (suite *HttpSuite) TestPatchCategory() {
+	id, err := suite.categoryHttpSvc.categorySvc.CreateCategory(context.TODO(), &domain.Category{
+		Name: "test",
+	})
+	if err != nil {
+		suite.T().Fatalf("Error creating test category: %s", err)
+	}
+	path := "/v1/category/" + strconv.Itoa(int(id))
+
+	responseRec := testutil.MakeRawRequest(suite.wsContainer, "PATCH", path, []byte(`{"categoryName":"patched"}`),
+		map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": `"1"`})
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var response CategoryModel
+	err = json.Unmarshal(responseRec.Body.Bytes(), &response)
+	if err != nil {
+		suite.T().Fatalf("Error unmarshalling category response: %s", err)
+	}
+	assert.Equal(suite.T(), "patched", response.Name)
+	assert.Equal(suite.T(), 2, response.Version)
+
+	// patching a read-only field is rejected
+	responseRec = testutil.MakeRawRequest(suite.wsContainer, "PATCH", path, []byte(`[{"op":"replace","path":"/categoryId","value":999}]`),
+		map[string]string{"Content-Type": "application/json-patch+json", "If-Match": "*"})
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code)
+
+	// an empty name fails the same check as on create
+	responseRec = testutil.MakeRawRequest(suite.wsContainer, "PATCH", path, []byte(`{"categoryName":""}`),
+		map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": "*"})
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code)
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
 )
 
 type CategoryModel struct {
@@ -11,6 +12,7 @@ type CategoryModel struct {
 	Name      string    `json:"categoryName"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int       `json:"version"`
 }
 
 func (e *CategoryModel) FromDomain(category *domain.Category) {
@@ -21,6 +23,7 @@ func (e *CategoryModel) FromDomain(category *domain.Category) {
 	e.Name = category.Name
 	e.UpdatedAt = category.UpdatedAt
 	e.CreatedAt = category.CreatedAt
+	e.Version = category.Version
 }
 
 func (e *CategoryModel) ToDomain() *domain.Category {
@@ -33,5 +36,59 @@ func (e *CategoryModel) ToDomain() *domain.Category {
 		Name:      e.Name,
 		CreatedAt: e.CreatedAt,
 		UpdatedAt: e.UpdatedAt,
+		Version:   e.Version,
 	}
 }
+
+// Category as returned by the v2 routes, with its fields named like in the other resources
+type CategoryModelV2 struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int       `json:"version"`
+}
+
+func (e *CategoryModelV2) FromDomain(category *domain.Category) {
+	if e == nil || category == nil {
+		return
+	}
+	e.ID = category.Id
+	e.Name = category.Name
+	e.CreatedAt = category.CreatedAt
+	e.UpdatedAt = category.UpdatedAt
+	e.Version = category.Version
+}
+
+func (e *CategoryModelV2) ToDomain() *domain.Category {
+	if e == nil {
+		return &domain.Category{}
+	}
+	return &domain.Category{
+		Id:        e.ID,
+		Name:      e.Name,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+		Version:   e.Version,
+	}
+}
+
+// The shapes of a category in the API versions
+type versionedModel interface {
+	FromDomain(category *domain.Category)
+	ToDomain() *domain.Category
+}
+
+func newModel(v apiversion.Version) versionedModel {
+	if v >= apiversion.V2 {
+		return &CategoryModelV2{}
+	}
+	return &CategoryModel{}
+}
+
+// The category in the shape of the API version
+func modelOf(v apiversion.Version, category *domain.Category) versionedModel {
+	model := newModel(v)
+	model.FromDomain(category)
+	return model
+}
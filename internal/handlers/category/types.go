@@ -6,5 +6,5 @@ type Response struct {
 }
 
 type CategoryRequest struct {
-	Name string
+	Name string `validate:"required,max=75"`
 }
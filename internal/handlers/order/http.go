@@ -5,10 +5,16 @@ import (
 	"net/http"
 
 	"github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/user"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/metrics"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/openapi"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/params"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/request"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
 )
 
 type OrderHttpHandler struct {
@@ -26,24 +32,98 @@ func NewOrderHandler(orderSvc ports.OrderUsecase, productSvc ports.ProductUsecas
 		userSvc:     userSvc,
 	}
 
-	ws := new(restful.WebService)
-	ws.Path("/order").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
-	ws.Route(ws.POST("/").To(httpHandler.CreateOrder).Filter(auth.AuthJWT))
-	ws.Route(ws.PUT("/").To(httpHandler.UpdateOrderStatus).Filter(auth.AuthJWT))
-	ws.Route(ws.DELETE("/").To(httpHandler.DeleteOrder))
-	ws.Route(ws.GET("/pdf").To(httpHandler.GeneratePdf))
+	apiversion.Register(wsCont, "/order", func(v apiversion.Version, ws *restful.WebService) {
+		ws.Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+		var model interface{} = OrderModel{}
+		if v >= apiversion.V2 {
+			model = OrderModelV2{}
+		}
+		ifMatchParam := ws.HeaderParameter("If-Match", "ETag of the order, the request fails with 412 if it was modified since")
 
-	wsCont.Add(ws)
+		ws.Route(ws.GET("/{id}").To(httpHandler.GetOrder).Filter(auth.Authenticate).
+			Do(openapi.Secured(openapi.BearerAuth, openapi.ApiKeyAuth)).
+			Doc("Get an order").
+			Notes("Users get their own orders, API keys with the "+string(domain.ScopeReadOrders)+" scope any order").
+			Param(ws.PathParameter("id", "ID of the order")).
+			Writes(model).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusNotFound, "Order doesn't exist", nil))
+		ws.Route(ws.POST("/").To(httpHandler.CreateOrder).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Order products").
+			Notes("Only users who verified their email can order").
+			Reads(OrderRequest{}).
+			Writes(model).
+			Returns(http.StatusOK, "Order created", nil).
+			Returns(http.StatusBadRequest, "Invalid order", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Email not verified", nil).
+			Returns(http.StatusConflict, "Not enough products in stock", nil))
+		ws.Route(ws.PUT("/").To(httpHandler.UpdateOrderStatus).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Change the status of an order").
+			Param(ifMatchParam).
+			Reads(OrderRequest{}).
+			Writes(model).
+			Returns(http.StatusOK, "Order updated", nil).
+			Returns(http.StatusBadRequest, "Invalid status", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusNotFound, "Order doesn't exist", nil).
+			Returns(http.StatusPreconditionFailed, "Order was modified", nil).
+			Returns(http.StatusPreconditionRequired, "If-Match header missing", nil))
+		ws.Route(ws.DELETE("/").To(httpHandler.DeleteOrder).
+			Doc("Delete an order").
+			Param(ifMatchParam).
+			Reads(OrderRequest{}).
+			Writes(model).
+			Returns(http.StatusOK, "Order deleted", nil).
+			Returns(http.StatusNotFound, "Order doesn't exist", nil).
+			Returns(http.StatusPreconditionFailed, "Order was modified", nil).
+			Returns(http.StatusPreconditionRequired, "If-Match header missing", nil))
+		ws.Route(ws.GET("/pdf").To(httpHandler.GeneratePdf).
+			Doc("Generate the PDF of an order").
+			Reads(OrderRequest{}).
+			Writes(model).
+			Returns(http.StatusOK, "PDF generated", nil))
+	})
 
 	return httpHandler
 }
 
+func (e *OrderHttpHandler) GetOrder(req *restful.Request, res *restful.Response) {
+	reqId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(reqId) == 0 {
+		response.WriteError(res, response.NewBadRequestError("no id found for user"))
+		return
+	}
+	found, err := e.orderSvc.FindOrderById(req.Request.Context(), req.PathParameter("id"))
+	if err != nil {
+		response.WriteError(res, err)
+		return
+	}
+	// users can only see their own orders, API keys with the orders:read scope and admins can see all of them
+	if (found.User == nil || found.User.ID != reqId) && !auth.HasScope(req.Request, domain.ScopeReadOrders) {
+		response.WriteError(res, domain.NewNotFoundError("order"))
+		return
+	}
+	response.SetETag(res, found.Version)
+	e.writeOrder(req, res, found)
+}
+
 func (e *OrderHttpHandler) CreateOrder(req *restful.Request, res *restful.Response) {
 	var reqData OrderRequest
-	req.ReadEntity(&reqData)
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(res, err)
+		return
+	}
+	if reqData.Products == nil {
+		response.WriteError(res, domain.NewValidationError("invalid request body", domain.FieldError{Field: "product_items", Message: "is required"}))
+		return
+	}
 	reqId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
 	if err != nil || len(reqId) == 0 {
-		res.WriteError(http.StatusBadRequest, errors.New("no id found for user"))
+		response.WriteError(res, response.NewBadRequestError("no id found for user"))
 		return
 	}
 
@@ -52,29 +132,43 @@ func (e *OrderHttpHandler) CreateOrder(req *restful.Request, res *restful.Respon
 	order.Status = reqData.Status
 	order.ProductItems = reqData.Products
 	created, err := e.orderSvc.CreateOrder(req.Request.Context(), order.ToDomain())
+	var insufficientStock *domain.InsufficientStockError
+	if errors.As(err, &insufficientStock) {
+		metrics.OrdersOutOfStock.Inc()
+	}
 	if err != nil {
-		res.WriteError(http.StatusInternalServerError, err)
+		response.WriteError(res, err)
 		return
 	}
-	order.FromDomain(created)
-	res.WriteAsJson(order)
+	// counted here rather than from the order.created event, which may be delivered more than once
+	metrics.OrdersCreated.Inc()
+	response.SetETag(res, created.Version)
+	e.writeOrder(req, res, created)
 }
 
 func (e *OrderHttpHandler) UpdateOrderStatus(req *restful.Request, res *restful.Response) {
 	var reqData OrderRequest
-	req.ReadEntity(&reqData)
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(res, err)
+		return
+	}
 	reqId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
 	if err != nil || len(reqId) == 0 {
-		res.WriteError(http.StatusBadRequest, errors.New("no id found for user"))
+		response.WriteError(res, response.NewBadRequestError("no id found for user"))
+		return
+	}
+	version, err := request.IfMatchVersion(req.Request)
+	if err != nil {
+		response.WriteError(res, err)
 		return
 	}
 	toUpdate, err := e.orderSvc.FindOrderById(req.Request.Context(), reqData.ID)
 	if err != nil {
-		res.WriteError(http.StatusInternalServerError, err)
+		response.WriteError(res, err)
 		return
 	}
 	if toUpdate.ID != reqData.ID {
-		res.WriteError(http.StatusBadRequest, errors.New("user cannot edit other user's order"))
+		response.WriteError(res, response.NewBadRequestError("user cannot edit other user's order"))
 		return
 	}
 	var order *OrderModel = &OrderModel{}
@@ -82,43 +176,71 @@ func (e *OrderHttpHandler) UpdateOrderStatus(req *restful.Request, res *restful.
 	order.Status = reqData.Status
 	order.ProductItems = reqData.Products
 	order.User.ID = reqId
+	order.Version = version
 	updated, err := e.orderSvc.UpdateOrderStatus(req.Request.Context(), order.ToDomain())
 	if err != nil {
-		res.WriteError(http.StatusInternalServerError, errors.New("error updating order"))
+		response.WriteError(res, err)
 		return
 	}
-	order.FromDomain(updated)
-	res.WriteAsJson(order)
+	response.SetETag(res, updated.Version)
+	e.writeOrder(req, res, updated)
 }
 
 func (e *OrderHttpHandler) DeleteOrder(req *restful.Request, res *restful.Response) {
+	version, err := request.IfMatchVersion(req.Request)
+	if err != nil {
+		response.WriteError(res, err)
+		return
+	}
 	var reqData OrderRequest
-	req.ReadEntity(&reqData)
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(res, err)
+		return
+	}
 	var order *OrderModel = &OrderModel{}
 	order.ID = reqData.ID
 	order.ProductItems = reqData.Products
 	order.Status = reqData.Status
-	err := e.orderSvc.DeleteOrder(req.Request.Context(), order.ToDomain())
+	order.Version = version
+	toDelete := order.ToDomain()
+	err = e.orderSvc.DeleteOrder(req.Request.Context(), toDelete)
 	if err != nil {
-		res.WriteError(http.StatusInternalServerError, errors.New("error deleting order"))
+		response.WriteError(res, err)
 		return
 	}
-	res.WriteAsJson(order)
+	e.writeOrder(req, res, toDelete)
 }
 
 func (e *OrderHttpHandler) GeneratePdf(req *restful.Request, res *restful.Response) {
 	var reqData OrderRequest
-	req.ReadEntity(&reqData)
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(res, err)
+		return
+	}
 	var order *OrderModel = &OrderModel{}
 	order.ID = reqData.ID
 	order.User = user.UserModel{}
 	order.User.ID = reqData.UserId
 	order.Status = reqData.Status
 	order.ProductItems = reqData.Products
-	err := e.orderSvc.GeneratePdf(req.Request.Context(), order.ToDomain())
+	toGenerate := order.ToDomain()
+	err := e.orderSvc.GeneratePdf(req.Request.Context(), toGenerate)
 	if err != nil {
-		res.WriteError(http.StatusInternalServerError, err)
+		response.WriteError(res, err)
+		return
+	}
+	e.writeOrder(req, res, toGenerate)
+}
+
+// Writes the order in the shape of the version of the route, v2 orders carry their totals
+func (e *OrderHttpHandler) writeOrder(req *restful.Request, res *restful.Response, order *domain.Order) {
+	if apiversion.FromRequest(req) < apiversion.V2 {
+		var model *OrderModel = &OrderModel{}
+		model.FromDomain(order)
+		res.WriteAsJson(model)
 		return
 	}
-	res.WriteAsJson(order)
+	var model *OrderModelV2 = &OrderModelV2{}
+	model.FromDomain(order)
+	res.WriteAsJson(model)
 }
@@ -75,7 +75,7 @@ package order
 // 	}
 // 	orderStatus := ""
 // 	orderProducts := &[]OrderedProductModel{{ProductId: pId, Quantity: 10}}
-// 	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/order", OrderRequest{Status: orderStatus, Products: orderProducts}, nil)
+// 	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/order", OrderRequest{Status: orderStatus, Products: orderProducts}, nil)
 // 	var response *domain.Order
 // 	err = json.Unmarshal(responseRec.Body.Bytes(), &response)
 // 	if err != nil {
@@ -129,7 +129,7 @@ package order
 // 	updateStatus := "PENDING"
 // 	updateProducts := &[]OrderedProductModel{{ProductId: pId, Quantity: 10}}
 
-// 	responseRec := testutil.MakeRequest(suite.wsContainer, "PUT", "/order/status", OrderRequest{
+// 	responseRec := testutil.MakeRequest(suite.wsContainer, "PUT", "/v1/order/status", OrderRequest{
 // 		ID:       updateID,
 // 		Status:   updateStatus,
 // 		Products: updateProducts,
@@ -194,7 +194,7 @@ package order
 // 	deleteStatus := "CREATED"
 // 	deleteProducts := &[]OrderedProductModel{{ProductId: pId, Quantity: 10}}
 
-// 	responseRec := testutil.MakeRequest(suite.wsContainer, "DELETE", "/order", OrderRequest{
+// 	responseRec := testutil.MakeRequest(suite.wsContainer, "DELETE", "/v1/order", OrderRequest{
 // 		ID:       deleteID,
 // 		Status:   deleteStatus,
 // 		Products: deleteProducts,
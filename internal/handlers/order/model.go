@@ -14,11 +14,12 @@ type OrderModel struct {
 	User         user.UserModel         `json:"user"`
 	CreatedAt    time.Time              `json:"createdAt"`
 	UpdatedAt    time.Time              `json:"updatedAt"`
+	Version      int                    `json:"version"`
 }
 
 type OrderedProductModel struct {
-	ProductId int64 `json:"productId"`
-	Quantity  int   `json:"quantity"`
+	ProductId int64 `json:"productId" validate:"gt=0"`
+	Quantity  int   `json:"quantity" validate:"gt=0"`
 }
 
 func (e *OrderModel) FromDomain(order *domain.Order) {
@@ -29,6 +30,7 @@ func (e *OrderModel) FromDomain(order *domain.Order) {
 	e.Status = order.Status
 	e.CreatedAt = order.CreatedAt
 	e.UpdatedAt = order.UpdatedAt
+	e.Version = order.Version
 	var products []OrderedProductModel = []OrderedProductModel{}
 	for _, item := range *order.ProductItems {
 		orderedProduct := OrderedProductModel{}
@@ -54,6 +56,7 @@ func (e *OrderModel) ToDomain() *domain.Order {
 		Status:       e.Status,
 		CreatedAt:    e.CreatedAt,
 		UpdatedAt:    e.UpdatedAt,
+		Version:      e.Version,
 		User:         e.User.ToDomain(),
 		ProductItems: &products,
 	}
@@ -75,3 +78,54 @@ func (e *OrderedProductModel) ToDomain() *domain.OrderedProduct {
 		ProductId: e.ProductId,
 	}
 }
+
+// Order as returned by the v2 routes, in camelCase and with the totals embedded
+type OrderModelV2 struct {
+	ID           string                  `json:"id"`
+	Status       string                  `json:"status"`
+	UserID       string                  `json:"userId"`
+	ProductItems []OrderedProductModelV2 `json:"productItems"`
+	// Sum of the quantities of the items
+	ItemCount int       `json:"itemCount"`
+	Total     float32   `json:"total"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int       `json:"version"`
+}
+
+type OrderedProductModelV2 struct {
+	ProductID int64   `json:"productId"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float32 `json:"unitPrice"`
+	Total     float32 `json:"total"`
+}
+
+// The totals are computed from the prices the products were ordered at
+func (e *OrderModelV2) FromDomain(order *domain.Order) {
+	if e == nil || order == nil {
+		return
+	}
+	e.ID = order.ID
+	e.Status = order.Status
+	if order.User != nil {
+		e.UserID = order.User.ID
+	}
+	e.CreatedAt = order.CreatedAt
+	e.UpdatedAt = order.UpdatedAt
+	e.Version = order.Version
+	e.ProductItems = []OrderedProductModelV2{}
+	if order.ProductItems == nil {
+		return
+	}
+	for _, item := range *order.ProductItems {
+		line := OrderedProductModelV2{
+			ProductID: item.ProductId,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			Total:     item.UnitPrice * float32(item.Quantity),
+		}
+		e.ProductItems = append(e.ProductItems, line)
+		e.ItemCount += line.Quantity
+		e.Total += line.Total
+	}
+}
@@ -19,7 +19,7 @@ type OrderedProduct struct {
 
 type OrderRequest struct {
 	ID       string
-	Status   string
+	Status   string `validate:"omitempty,oneof=CREATED PENDING COMPLETED CLOSED"`
 	UserId   string
-	Products *[]OrderedProductModel `json:"product_items"`
+	Products *[]OrderedProductModel `json:"product_items" validate:"omitempty,min=1,dive"`
 }
@@ -1,7 +1,6 @@
 package product
 
 import (
-	"errors"
 	"net/http"
 	"strconv"
 
@@ -9,6 +8,12 @@ import (
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/category"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/openapi"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/patch"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/request"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
 )
 
 type ProductHttpHandler struct {
@@ -22,17 +27,80 @@ func NewProductHandler(productSvc ports.ProductUsecase, categorySvc ports.Catego
 		categorySvc: categorySvc,
 	}
 
-	ws := new(restful.WebService)
+	apiversion.Register(wsCont, "/product", func(v apiversion.Version, ws *restful.WebService) {
+		ws.Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
 
-	ws.Path("/product").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+		var model, models interface{} = ProductModel{}, []ProductModel{}
+		if v >= apiversion.V2 {
+			model, models = ProductModelV2{}, []ProductModelV2{}
+		}
 
-	ws.Route(ws.GET("").To(httpHandler.GetProducts))
-	ws.Route(ws.GET("/{id}").To(httpHandler.GetProduct))
-	ws.Route(ws.POST("").To(httpHandler.CreateProduct))
-	ws.Route(ws.DELETE("/{id}").To(httpHandler.DeleteProduct))
-	ws.Route(ws.PUT("/{id}").To(httpHandler.UpdateProduct))
+		idParam := ws.PathParameter("id", "ID of the product").DataType("integer")
+		ifMatchParam := ws.HeaderParameter("If-Match", "ETag of the product, the request fails with 412 if it was modified since")
 
-	wsCont.Add(ws)
+		ws.Route(ws.GET("").To(httpHandler.GetProducts).
+			Doc("List the products").
+			Writes(models).
+			Returns(http.StatusOK, "OK", nil))
+		ws.Route(ws.GET("/{id}").To(httpHandler.GetProduct).
+			Doc("Get a product").
+			Param(idParam).
+			Writes(model).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusNotFound, "Product doesn't exist", nil))
+		ws.Route(ws.POST("").To(httpHandler.CreateProduct).
+			Doc("Create a product").
+			Reads(ProductRequest{}).
+			Writes(Response{}).
+			Returns(http.StatusOK, "Product created", nil).
+			Returns(http.StatusBadRequest, "Invalid product", nil).
+			Returns(http.StatusNotFound, "Category doesn't exist", nil))
+		ws.Route(ws.DELETE("/{id}").To(httpHandler.DeleteProduct).
+			Doc("Delete a product").
+			Param(idParam).Param(ifMatchParam).
+			Writes(Response{}).
+			Returns(http.StatusOK, "Product deleted", nil).
+			Returns(http.StatusNotFound, "Product doesn't exist", nil).
+			Returns(http.StatusPreconditionFailed, "Product was modified", nil).
+			Returns(http.StatusPreconditionRequired, "If-Match header missing", nil))
+		ws.Route(ws.PUT("/{id}").To(httpHandler.UpdateProduct).
+			Doc("Replace a product").
+			Param(idParam).Param(ifMatchParam).
+			Reads(ProductRequest{}).
+			Writes(Response{}).
+			Returns(http.StatusOK, "Product updated", nil).
+			Returns(http.StatusBadRequest, "Invalid product", nil).
+			Returns(http.StatusNotFound, "Product or category doesn't exist", nil).
+			Returns(http.StatusPreconditionFailed, "Product was modified", nil).
+			Returns(http.StatusPreconditionRequired, "If-Match header missing", nil))
+		ws.Route(ws.PATCH("/{id}").To(httpHandler.PatchProduct).Consumes(patch.MIME_MERGE_PATCH, patch.MIME_JSON_PATCH).
+			Doc("Update some fields of a product").
+			Notes("Takes a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) of the product").
+			Param(idParam).Param(ifMatchParam).
+			Reads(model).
+			Writes(model).
+			Returns(http.StatusOK, "Product updated", nil).
+			Returns(http.StatusBadRequest, "Invalid patch or patched product", nil).
+			Returns(http.StatusNotFound, "Product or category doesn't exist", nil).
+			Returns(http.StatusPreconditionFailed, "Product was modified", nil).
+			Returns(http.StatusPreconditionRequired, "If-Match header missing", nil))
+		ws.Route(ws.PUT("/{id}/stock").To(httpHandler.UpdateStock).
+			Filter(auth.Authenticate).Filter(auth.RequireScope(domain.ScopeWriteStock)).
+			Do(openapi.Secured(openapi.BearerAuth, openapi.ApiKeyAuth)).
+			Doc("Set the quantity of a product in stock").
+			Notes("For the inventory systems, needs an API key with the "+string(domain.ScopeWriteStock)+" scope").
+			Param(idParam).Param(ifMatchParam).
+			Reads(StockRequest{}).
+			Writes(model).
+			Returns(http.StatusOK, "Stock updated", nil).
+			Returns(http.StatusBadRequest, "Invalid quantity", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Missing the scope", nil).
+			Returns(http.StatusNotFound, "Product doesn't exist", nil).
+			Returns(http.StatusPreconditionFailed, "Product was modified", nil).
+			Returns(http.StatusPreconditionRequired, "If-Match header missing", nil))
+
+	})
 
 	return httpHandler
 }
@@ -41,15 +109,13 @@ func (e *ProductHttpHandler) GetProducts(req *restful.Request, resp *restful.Res
 	ctx := req.Request.Context()
 	products, err := e.productSvc.GetAllProducts(ctx)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("error retrieving products"))
+		response.WriteError(resp, err)
 		return
 	}
-	var retProducts []ProductModel
-	var retProduct *ProductModel = &ProductModel{Category: &category.CategoryModel{}}
-
-	for _, product := range *products {
-		retProduct.FromDomain(&product)
-		retProducts = append(retProducts, *retProduct)
+	v := apiversion.FromRequest(req)
+	var retProducts []versionedModel
+	for i := range *products {
+		retProducts = append(retProducts, modelOf(v, &(*products)[i]))
 	}
 	resp.WriteAsJson(retProducts)
 }
@@ -57,22 +123,24 @@ func (e *ProductHttpHandler) GetProducts(req *restful.Request, resp *restful.Res
 func (e *ProductHttpHandler) GetProduct(req *restful.Request, resp *restful.Response) {
 	id, err := getId(req, resp)
 	if err != nil {
-		resp.WriteError(http.StatusBadRequest, errors.New("invalid product id"))
+		response.WriteError(resp, response.NewBadRequestError("invalid product id"))
 		return
 	}
 	product, err := e.productSvc.FindProductById(req.Request.Context(), id)
 	if err != nil {
-		resp.WriteError(http.StatusNotFound, errors.New("product doesn't exist"))
+		response.WriteError(resp, err)
 		return
 	}
-	var retProduct *ProductModel = &ProductModel{Category: &category.CategoryModel{}}
-	retProduct.FromDomain(product)
-	resp.WriteAsJson(retProduct)
+	response.SetETag(resp, product.Version)
+	resp.WriteAsJson(modelOf(apiversion.FromRequest(req), product))
 }
 
 func (e *ProductHttpHandler) CreateProduct(req *restful.Request, resp *restful.Response) {
 	var reqData ProductRequest
-	req.ReadEntity(&reqData)
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
 
 	var product *ProductModel = &ProductModel{}
 	product.Name = reqData.Name
@@ -82,7 +150,7 @@ func (e *ProductHttpHandler) CreateProduct(req *restful.Request, resp *restful.R
 	product.Price = reqData.Price
 	userCategory, err := e.categorySvc.FindCategoryById(req.Request.Context(), int64(reqData.Category.Id))
 	if err != nil {
-		resp.WriteError(http.StatusNotFound, errors.New("category doesn't exist"))
+		response.WriteError(resp, err)
 		return
 	}
 	var category *category.CategoryModel = &category.CategoryModel{}
@@ -90,7 +158,7 @@ func (e *ProductHttpHandler) CreateProduct(req *restful.Request, resp *restful.R
 	product.Category = category
 	id, err := e.productSvc.CreateProduct(req.Request.Context(), product.ToDomain())
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("error creating product"))
+		response.WriteError(resp, err)
 		return
 	}
 	resp.WriteAsJson(Response{ID: id, Message: "product created"})
@@ -99,16 +167,17 @@ func (e *ProductHttpHandler) CreateProduct(req *restful.Request, resp *restful.R
 func (e *ProductHttpHandler) DeleteProduct(req *restful.Request, resp *restful.Response) {
 	id, err := getId(req, resp)
 	if err != nil {
-		resp.WriteError(http.StatusBadRequest, errors.New("invalid product id"))
+		response.WriteError(resp, response.NewBadRequestError("invalid product id"))
 		return
 	}
-	deleted, err := e.productSvc.DeleteProduct(req.Request.Context(), id)
+	version, err := request.IfMatchVersion(req.Request)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("an error occured"))
+		response.WriteError(resp, err)
 		return
 	}
-	if deleted == 0 {
-		resp.WriteError(http.StatusNotFound, errors.New("product doesn't exist"))
+	deleted, err := e.productSvc.DeleteProduct(req.Request.Context(), id, version)
+	if err != nil {
+		response.WriteError(resp, err)
 		return
 	}
 	resp.WriteAsJson(Response{ID: deleted, Message: "product deleted"})
@@ -117,28 +186,165 @@ func (e *ProductHttpHandler) DeleteProduct(req *restful.Request, resp *restful.R
 func (e *ProductHttpHandler) UpdateProduct(req *restful.Request, resp *restful.Response) {
 	id, err := getId(req, resp)
 	if err != nil {
-		resp.WriteError(http.StatusBadRequest, errors.New("invalid product id"))
+		response.WriteError(resp, response.NewBadRequestError("invalid product id"))
+		return
+	}
+	version, err := request.IfMatchVersion(req.Request)
+	if err != nil {
+		response.WriteError(resp, err)
 		return
 	}
 	var productReq ProductRequest
-	req.ReadEntity(&productReq)
+	if err := request.ReadBody(req.Request, &productReq); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
 	userCategory, err := e.categorySvc.FindCategoryById(req.Request.Context(), int64(productReq.Category.Id))
 	if err != nil {
-		resp.WriteError(http.StatusNotFound, errors.New("category doesn't exist"))
+		response.WriteError(resp, err)
 		return
 	}
 	dataProduct := &domain.Product{Name: productReq.Name, ShortDescription: productReq.ShortDescription, Description: productReq.Description,
-		Quantity: productReq.Quantity, Price: productReq.Price, Category: userCategory}
+		Quantity: productReq.Quantity, Price: productReq.Price, Category: userCategory, Version: version}
 	updated, err := e.productSvc.UpdateProduct(req.Request.Context(), dataProduct, id)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("an error occured"))
+		response.WriteError(resp, err)
 		return
 	}
-	if updated == 0 {
-		resp.WriteError(http.StatusNotFound, errors.New("product doesn't exist"))
+	response.SetETag(resp, dataProduct.Version)
+	resp.WriteAsJson(Response{ID: updated, Message: "product updated"})
+}
+
+// Applies a JSON Merge Patch or JSON Patch to the product, updating only the changed fields
+func (e *ProductHttpHandler) PatchProduct(req *restful.Request, resp *restful.Response) {
+	id, err := getId(req, resp)
+	if err != nil {
+		response.WriteError(resp, response.NewBadRequestError("invalid product id"))
 		return
 	}
-	resp.WriteAsJson(Response{ID: updated, Message: "product updated"})
+	version, err := request.IfMatchVersion(req.Request)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	ctx := req.Request.Context()
+	current, err := e.productSvc.FindProductById(ctx, id)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	if version != 0 && version != current.Version {
+		response.WriteError(resp, response.NewPreconditionFailedError("product was modified"))
+		return
+	}
+
+	// the patch is in the shape of the product in the version
+	v := apiversion.FromRequest(req)
+	var original *ProductModel = &ProductModel{}
+	original.FromDomain(current)
+	patchedModel := newModel(v)
+	err = patch.Apply(req.Request, modelOf(v, current), patchedModel)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	var patched *ProductModel = &ProductModel{}
+	patched.FromDomain(patchedModel.ToDomain())
+	if patched.ID != original.ID || patched.Version != original.Version ||
+		!patched.CreatedAt.Equal(original.CreatedAt) || !patched.UpdatedAt.Equal(original.UpdatedAt) {
+		response.WriteError(resp, response.NewBadRequestError("read-only product fields cannot be patched"))
+		return
+	}
+
+	// the patched product must pass the same checks as a created one
+	reqData := ProductRequest{Name: patched.Name, ShortDescription: patched.ShortDescription, Description: patched.Description,
+		Price: patched.Price, Quantity: patched.Quantity, Category: patched.Category}
+	if err := request.Validate(&reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	if reqData.Category.Id != original.Category.Id {
+		_, err = e.categorySvc.FindCategoryById(ctx, int64(reqData.Category.Id))
+		if err != nil {
+			response.WriteError(resp, err)
+			return
+		}
+	}
+
+	changes := productChanges(original, &reqData)
+	changes.Version = current.Version
+	if !changes.IsEmpty() {
+		_, err = e.productSvc.PatchProduct(ctx, id, changes)
+		if err != nil {
+			response.WriteError(resp, err)
+			return
+		}
+		current, err = e.productSvc.FindProductById(ctx, id)
+		if err != nil {
+			response.WriteError(resp, err)
+			return
+		}
+	}
+
+	response.SetETag(resp, current.Version)
+	resp.WriteAsJson(modelOf(v, current))
+}
+
+// Sets the quantity in stock, for the inventory systems calling with an API key with the stock:write scope
+func (e *ProductHttpHandler) UpdateStock(req *restful.Request, resp *restful.Response) {
+	id, err := getId(req, resp)
+	if err != nil {
+		response.WriteError(resp, response.NewBadRequestError("invalid product id"))
+		return
+	}
+	version, err := request.IfMatchVersion(req.Request)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	var reqData StockRequest
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+
+	ctx := req.Request.Context()
+	_, err = e.productSvc.PatchProduct(ctx, id, &domain.ProductPatch{Quantity: reqData.Quantity, Version: version})
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	current, err := e.productSvc.FindProductById(ctx, id)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	response.SetETag(resp, current.Version)
+	resp.WriteAsJson(modelOf(apiversion.FromRequest(req), current))
+}
+
+// Collects the fields of the request which differ from the original product
+func productChanges(original *ProductModel, reqData *ProductRequest) *domain.ProductPatch {
+	changes := &domain.ProductPatch{}
+	if reqData.Name != original.Name {
+		changes.Name = &reqData.Name
+	}
+	if reqData.ShortDescription != original.ShortDescription {
+		changes.ShortDescription = &reqData.ShortDescription
+	}
+	if reqData.Description != original.Description {
+		changes.Description = &reqData.Description
+	}
+	if reqData.Price != original.Price {
+		changes.Price = &reqData.Price
+	}
+	if reqData.Quantity != original.Quantity {
+		changes.Quantity = &reqData.Quantity
+	}
+	if reqData.Category.Id != original.Category.Id {
+		changes.CategoryId = &reqData.Category.Id
+	}
+	return changes
 }
 
 func getId(req *restful.Request, resp *restful.Response) (int64, error) {
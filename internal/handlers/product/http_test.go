@@ -1,9 +1,11 @@
 package product
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"testing"
 
@@ -40,7 +42,7 @@ func (suite *HttpSuite) SetupSuite() {
 	realCategoryRep := repo.NewCategoryRepository(testApp.DB)
 	realCategorySvc := usecases.NewCategoryService(realCategoryRep)
 	realProductRep := repo.NewProductRepository(testApp.DB)
-	realProductSvc := usecases.NewProductService(realProductRep)
+	realProductSvc := usecases.NewProductService(realProductRep, repo.NewOutboxRepository(testApp.DB), testApp.DB)
 	suite.productHttpSvc = *NewProductHandler(realProductSvc, realCategorySvc, suite.wsContainer)
 }
 
@@ -73,7 +75,7 @@ func (suite *HttpSuite) TestGetProducts() {
 	if err != nil {
 		suite.T().Fatalf("Error creating test product: %s", err)
 	}
-	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", "/product", nil, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", "/v1/product", nil, nil)
 	var response []ProductModel
 	err = json.Unmarshal(responseRec.Body.Bytes(), &response)
 	if err != nil {
@@ -107,7 +109,7 @@ func (suite *HttpSuite) TestGetProduct() {
 	if err != nil {
 		suite.T().Fatalf("Error creating test product: %s", err)
 	}
-	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", "/product/"+strconv.Itoa(int(pId)), nil, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", "/v1/product/"+strconv.Itoa(int(pId)), nil, nil)
 	var response ProductModel
 	err = json.Unmarshal(responseRec.Body.Bytes(), &response)
 	if err != nil {
@@ -121,6 +123,35 @@ func (suite *HttpSuite) TestGetProduct() {
 	assert.Equal(suite.T(), productShortDescription, response.ShortDescription)
 	assert.Equal(suite.T(), productQuantity, response.Quantity)
 }
+func (suite *HttpSuite) TestGetProductV2() {
+	cId, err := suite.productHttpSvc.categorySvc.CreateCategory(context.TODO(), &domain.Category{
+		Name: "test",
+	})
+	if err != nil {
+		suite.T().Fatalf("Error creating test category: %s", err)
+	}
+	pId, err := suite.productHttpSvc.productSvc.CreateProduct(context.TODO(), &domain.Product{
+		Name:     "test",
+		Price:    100.0,
+		Quantity: 1,
+		Category: &domain.Category{Id: int(cId)},
+	})
+	if err != nil {
+		suite.T().Fatalf("Error creating test product: %s", err)
+	}
+	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", "/v2/product/"+strconv.Itoa(int(pId)), nil, nil)
+	var response map[string]interface{}
+	err = json.Unmarshal(responseRec.Body.Bytes(), &response)
+	if err != nil {
+		suite.T().Fatalf("Error unmarshalling product response: %s", err)
+	}
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	assert.Equal(suite.T(), float64(pId), response["id"])
+	assert.NotContains(suite.T(), response, "productId")
+	if assert.Contains(suite.T(), response, "category") {
+		assert.Equal(suite.T(), float64(cId), response["category"].(map[string]interface{})["id"])
+	}
+}
 func (suite *HttpSuite) TestCreateProduct() {
 	categoryName := "test"
 	cId, err := suite.productHttpSvc.categorySvc.CreateCategory(context.TODO(), &domain.Category{
@@ -135,7 +166,7 @@ func (suite *HttpSuite) TestCreateProduct() {
 	productPrice := float32(100.0)
 	productQuantity := 1
 	productCategory := &domain.Category{Id: int(cId)}
-	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/product", &domain.Product{
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/product", &domain.Product{
 		Name:             productName,
 		ShortDescription: productShortDescription,
 		Description:      productDescription,
@@ -190,14 +221,16 @@ func (suite *HttpSuite) TestUpdateProduct() {
 	updateQuantity := 2
 	updateCategory := &domain.Category{Id: int(uCId)}
 
-	responseRec := testutil.MakeRequest(suite.wsContainer, "PUT", "/product/"+strconv.Itoa(int(pId)), domain.Product{
+	body, _ := json.Marshal(domain.Product{
 		Name:             updateName,
 		ShortDescription: updateShortDescription,
 		Description:      updateDescription,
 		Price:            updatePrice,
 		Quantity:         updateQuantity,
 		Category:         updateCategory,
-	}, nil)
+	})
+	responseRec := testutil.MakeRawRequest(suite.wsContainer, "PUT", "/v1/product/"+strconv.Itoa(int(pId)), body,
+		map[string]string{"Content-Type": restful.MIME_JSON, "If-Match": `"1"`})
 	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
 	var response Response
 	err = json.Unmarshal(responseRec.Body.Bytes(), &response)
@@ -234,7 +267,11 @@ func (suite *HttpSuite) TestDeleteProduct() {
 	if err != nil {
 		suite.T().Fatalf("Error creating test product: %s", err)
 	}
-	responseRec := testutil.MakeRequest(suite.wsContainer, "DELETE", "/product/"+strconv.Itoa(int(pId)), nil, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "DELETE", "/v1/product/"+strconv.Itoa(int(pId)), nil, nil)
+	assert.Equal(suite.T(), http.StatusPreconditionRequired, responseRec.Code)
+
+	responseRec = testutil.MakeRawRequest(suite.wsContainer, "DELETE", "/v1/product/"+strconv.Itoa(int(pId)), nil,
+		map[string]string{"If-Match": `"1"`})
 	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
 	message := "product deleted"
 	var response Response
@@ -246,3 +283,49 @@ func (suite *HttpSuite) TestDeleteProduct() {
 	rowsAffected := int64(1)
 	assert.Equal(suite.T(), rowsAffected, response.ID)
 }
+func (suite *HttpSuite) TestUpdateProductWithStaleVersion() {
+	cId, err := suite.productHttpSvc.categorySvc.CreateCategory(context.TODO(), &domain.Category{
+		Name: "test",
+	})
+	if err != nil {
+		suite.T().Fatalf("Error creating test category: %s", err)
+	}
+	pId, err := suite.productHttpSvc.productSvc.CreateProduct(context.TODO(), &domain.Product{
+		Name:             "test",
+		ShortDescription: "t",
+		Description:      "testing",
+		Price:            float32(100.0),
+		Quantity:         1,
+		Category:         &domain.Category{Id: int(cId)},
+	})
+	if err != nil {
+		suite.T().Fatalf("Error creating test product: %s", err)
+	}
+	path := "/v1/product/" + strconv.Itoa(int(pId))
+
+	// the ETag of a fresh product is its first version
+	responseRec := testutil.MakeRequest(suite.wsContainer, "GET", path, nil, nil)
+	etag := responseRec.Header().Get("ETag")
+	assert.Equal(suite.T(), `"1"`, etag)
+
+	update := domain.Product{Name: "test2", ShortDescription: "t2", Description: "testing2", Price: float32(200.0),
+		Quantity: 2, Category: &domain.Category{Id: int(cId)}}
+	send := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(update)
+		httpRequest, _ := http.NewRequest("PUT", path, bytes.NewReader(body))
+		httpRequest.Header.Set("Content-Type", restful.MIME_JSON)
+		httpRequest.Header.Set("If-Match", etag)
+		responseRec := httptest.NewRecorder()
+		suite.wsContainer.ServeHTTP(responseRec, httpRequest)
+		return responseRec
+	}
+
+	// first write with the ETag succeeds, and bumps the version
+	first := send()
+	assert.Equal(suite.T(), http.StatusOK, first.Code)
+	assert.Equal(suite.T(), `"2"`, first.Header().Get("ETag"))
+
+	// second write with the same, now stale, ETag is rejected
+	second := send()
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, second.Code)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/category"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
 )
 
 type ProductModel struct {
@@ -17,6 +18,7 @@ type ProductModel struct {
 	Category         *category.CategoryModel `json:"category"`
 	CreatedAt        time.Time               `json:"createdAt"`
 	UpdatedAt        time.Time               `json:"updatedAt"`
+	Version          int                     `json:"version"`
 }
 
 func (e *ProductModel) FromDomain(product *domain.Product) {
@@ -34,6 +36,7 @@ func (e *ProductModel) FromDomain(product *domain.Product) {
 	e.Category.FromDomain(product.Category)
 	e.CreatedAt = product.CreatedAt
 	e.UpdatedAt = product.UpdatedAt
+	e.Version = product.Version
 
 }
 
@@ -51,5 +54,75 @@ func (e *ProductModel) ToDomain() *domain.Product {
 		Category:         e.Category.ToDomain(),
 		CreatedAt:        e.CreatedAt,
 		UpdatedAt:        e.UpdatedAt,
+		Version:          e.Version,
 	}
 }
+
+// Product as returned by the v2 routes, with its id named like in the other resources
+type ProductModelV2 struct {
+	ID               int                       `json:"id"`
+	Name             string                    `json:"name"`
+	ShortDescription string                    `json:"shortDescription"`
+	Description      string                    `json:"description"`
+	Price            float32                   `json:"price"`
+	Quantity         int                       `json:"quantity"`
+	Category         *category.CategoryModelV2 `json:"category"`
+	CreatedAt        time.Time                 `json:"createdAt"`
+	UpdatedAt        time.Time                 `json:"updatedAt"`
+	Version          int                       `json:"version"`
+}
+
+func (e *ProductModelV2) FromDomain(product *domain.Product) {
+	if e == nil || product == nil {
+		return
+	}
+	e.ID = product.ProductId
+	e.Name = product.Name
+	e.ShortDescription = product.ShortDescription
+	e.Description = product.Description
+	e.Price = product.Price
+	e.Quantity = product.Quantity
+	e.Category = &category.CategoryModelV2{}
+	e.Category.FromDomain(product.Category)
+	e.CreatedAt = product.CreatedAt
+	e.UpdatedAt = product.UpdatedAt
+	e.Version = product.Version
+}
+
+func (e *ProductModelV2) ToDomain() *domain.Product {
+	if e == nil {
+		return &domain.Product{}
+	}
+	return &domain.Product{
+		ProductId:        e.ID,
+		Name:             e.Name,
+		ShortDescription: e.ShortDescription,
+		Description:      e.Description,
+		Price:            e.Price,
+		Quantity:         e.Quantity,
+		Category:         e.Category.ToDomain(),
+		CreatedAt:        e.CreatedAt,
+		UpdatedAt:        e.UpdatedAt,
+		Version:          e.Version,
+	}
+}
+
+// The shapes of a product in the API versions
+type versionedModel interface {
+	FromDomain(product *domain.Product)
+	ToDomain() *domain.Product
+}
+
+func newModel(v apiversion.Version) versionedModel {
+	if v >= apiversion.V2 {
+		return &ProductModelV2{}
+	}
+	return &ProductModel{}
+}
+
+// The product in the shape of the API version
+func modelOf(v apiversion.Version, product *domain.Product) versionedModel {
+	model := newModel(v)
+	model.FromDomain(product)
+	return model
+}
@@ -8,10 +8,15 @@ type Response struct {
 }
 
 type ProductRequest struct {
-	Name             string
+	Name             string `validate:"required,max=75"`
 	ShortDescription string
 	Description      string
-	Price            float32
-	Quantity         int
-	Category         *category.CategoryModel
+	Price            float32                 `validate:"gt=0"`
+	Quantity         int                     `validate:"gte=0"`
+	Category         *category.CategoryModel `validate:"required"`
+}
+
+type StockRequest struct {
+	// Pointer, so a missing quantity isn't taken for zero
+	Quantity *int `validate:"required,gte=0"`
 }
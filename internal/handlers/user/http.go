@@ -3,47 +3,205 @@ package user
 import (
 	"context"
 	"errors"
-	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/emicklei/go-restful/v3"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/metrics"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/openapi"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/params"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/patch"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/request"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Auth events returned by default, and at most
+const (
+	defaultAuthEventsLimit = 50
+	maxAuthEventsLimit     = 500
+)
+
 type UserHttpHandler struct {
-	userSvc ports.UserUsecase
+	userSvc      ports.UserUsecase
+	loginSvc     ports.LoginUsecase
+	twoFactorSvc ports.TwoFactorUsecase
 }
 
-func NewUserHandler(userSvc ports.UserUsecase, wsCont *restful.Container) *UserHttpHandler {
+func NewUserHandler(userSvc ports.UserUsecase, loginSvc ports.LoginUsecase, twoFactorSvc ports.TwoFactorUsecase,
+	wsCont *restful.Container) *UserHttpHandler {
 	httpHandler := &UserHttpHandler{
-		userSvc: userSvc,
+		userSvc:      userSvc,
+		loginSvc:     loginSvc,
+		twoFactorSvc: twoFactorSvc,
 	}
 
-	ws := new(restful.WebService)
+	apiversion.Register(wsCont, "/user", func(v apiversion.Version, ws *restful.WebService) {
+		ws.Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+
+		ws.Route(ws.POST("/register").To(httpHandler.RegisterUser).
+			Doc("Register a user").
+			Notes("Emails the user a link for verifying their email").
+			Reads(RegisterRequestData{}).
+			Writes(RegisterResponseData{}).
+			Returns(http.StatusOK, "User registered and logged in", nil).
+			Returns(http.StatusBadRequest, "Invalid user", nil))
+		ws.Route(ws.POST("/login").To(httpHandler.LoginUser).
+			Doc("Log in").
+			Notes("With two-factor authentication enabled, only a challenge is returned, for completing the login at /user/login/2fa").
+			Reads(LoginRequestData{}).
+			Writes(LoginResponseData{}).
+			Returns(http.StatusOK, "Logged in, or the two-factor challenge", nil).
+			Returns(http.StatusForbidden, "Wrong email or password", nil).
+			Returns(http.StatusTooManyRequests, "Too many failed logins, retry after the Retry-After seconds", nil))
+		ws.Route(ws.POST("/login/2fa").To(httpHandler.LoginTwoFactor).
+			Doc("Complete a login with a two-factor code").
+			Reads(LoginTwoFactorRequestData{}).
+			Writes(LoginResponseData{}).
+			Returns(http.StatusOK, "Logged in", nil).
+			Returns(http.StatusUnauthorized, "Invalid or expired challenge", nil).
+			Returns(http.StatusForbidden, "Wrong code", nil).
+			Returns(http.StatusTooManyRequests, "Too many failed logins, retry after the Retry-After seconds", nil))
+		ws.Route(ws.PUT("").To(httpHandler.UpdateUser).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Update the logged in user").
+			Reads(UpdateRequestData{}).
+			Writes(UserModel{}).
+			Returns(http.StatusOK, "User updated", nil).
+			Returns(http.StatusBadRequest, "Invalid user", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil))
+		ws.Route(ws.PATCH("").To(httpHandler.PatchUser).Filter(auth.AuthJWT).Consumes(patch.MIME_MERGE_PATCH, patch.MIME_JSON_PATCH).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Update some fields of the logged in user").
+			Notes("Takes a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) of the user, only the name, surname and locale can change").
+			Reads(UserModel{}).
+			Writes(UserModel{}).
+			Returns(http.StatusOK, "User updated", nil).
+			Returns(http.StatusBadRequest, "Invalid patch or patched user", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil))
+		ws.Route(ws.POST("/password/forgot").To(httpHandler.ForgotPassword).
+			Doc("Request a password reset").
+			Notes("Emails a reset link if the email belongs to a user, the response is the same either way").
+			Reads(ForgotPasswordRequestData{}).
+			Returns(http.StatusAccepted, "Accepted", nil).
+			Returns(http.StatusBadRequest, "Invalid email", nil))
+		ws.Route(ws.POST("/password/reset").To(httpHandler.ResetPassword).
+			Doc("Reset the password with the token from the email").
+			Reads(ResetPasswordRequestData{}).
+			Returns(http.StatusNoContent, "Password reset", nil).
+			Returns(http.StatusBadRequest, "Invalid or expired token, or weak password", nil))
+		ws.Route(ws.PUT("/password").To(httpHandler.ChangePassword).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Change the password of the logged in user").
+			Notes("Revokes the JWTs issued before, a new one is returned").
+			Reads(ChangePasswordRequestData{}).
+			Writes(ChangePasswordResponseData{}).
+			Returns(http.StatusOK, "Password changed", nil).
+			Returns(http.StatusBadRequest, "Weak password", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Wrong current password", nil).
+			Returns(http.StatusTooManyRequests, "Too many failed logins, retry after the Retry-After seconds", nil))
+		ws.Route(ws.POST("/email/verify").To(httpHandler.VerifyEmail).
+			Doc("Verify the email with the token from the email").
+			Reads(VerifyEmailRequestData{}).
+			Returns(http.StatusNoContent, "Email verified", nil).
+			Returns(http.StatusBadRequest, "Invalid or expired token", nil))
+		ws.Route(ws.POST("/email/verify/resend").To(httpHandler.ResendEmailVerification).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Send the logged in user a new verification email").
+			Returns(http.StatusAccepted, "Accepted", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil))
+		ws.Route(ws.GET("/2fa").To(httpHandler.GetTwoFactor).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Get the two-factor authentication status of the logged in user").
+			Writes(TwoFactorStatusResponseData{}).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil))
+		ws.Route(ws.POST("/2fa/enroll").To(httpHandler.EnrollTwoFactor).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Start enabling two-factor authentication").
+			Notes("Returns the secret for the authenticator app, enabled once confirmed with a code at /user/2fa/confirm").
+			Writes(TwoFactorEnrollResponseData{}).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusConflict, "Already enabled", nil))
+		ws.Route(ws.POST("/2fa/confirm").To(httpHandler.ConfirmTwoFactor).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Enable two-factor authentication with a code from the authenticator app").
+			Reads(TwoFactorCodeRequestData{}).
+			Writes(RecoveryCodesResponseData{}).
+			Returns(http.StatusOK, "Enabled, with the recovery codes", nil).
+			Returns(http.StatusBadRequest, "Wrong code", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusConflict, "Not enrolled, or already enabled", nil))
+		ws.Route(ws.POST("/2fa/disable").To(httpHandler.DisableTwoFactor).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Disable two-factor authentication").
+			Reads(TwoFactorCodeRequestData{}).
+			Returns(http.StatusNoContent, "Disabled", nil).
+			Returns(http.StatusBadRequest, "Wrong code", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusConflict, "Not enabled", nil).
+			Returns(http.StatusTooManyRequests, "Too many failed logins, retry after the Retry-After seconds", nil))
+		ws.Route(ws.POST("/2fa/recovery-codes").To(httpHandler.RegenerateRecoveryCodes).Filter(auth.AuthJWT).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Replace the recovery codes").
+			Reads(TwoFactorCodeRequestData{}).
+			Writes(RecoveryCodesResponseData{}).
+			Returns(http.StatusOK, "The new recovery codes", nil).
+			Returns(http.StatusBadRequest, "Wrong code", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusConflict, "Not enabled", nil).
+			Returns(http.StatusTooManyRequests, "Too many failed logins, retry after the Retry-After seconds", nil))
+	})
 
-	ws.Path("/user").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	// managing the other users' accounts, for admins only
+	apiversion.Register(wsCont, "/admin/user", func(v apiversion.Version, adminWs *restful.WebService) {
+		adminWs.Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON).
+			Filter(auth.AuthJWT).Filter(auth.RequireRole(domain.RoleAdmin))
 
-	ws.Route(ws.POST("/register").To(httpHandler.RegisterUser))
-	ws.Route(ws.POST("/login").To(httpHandler.LoginUser))
-	ws.Route(ws.PUT("").To(httpHandler.UpdateUser).Filter(auth.AuthJWT))
+		userIdParam := adminWs.PathParameter("id", "ID of the user")
 
-	wsCont.Add(ws)
+		adminWs.Route(adminWs.POST("/{id}/unlock").To(httpHandler.UnlockUser).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Unlock a user locked out after failed logins").
+			Param(userIdParam).
+			Returns(http.StatusNoContent, "Unlocked", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil))
+		adminWs.Route(adminWs.GET("/{id}/auth-events").To(httpHandler.GetAuthEvents).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("List the latest authentication events of a user").
+			Param(userIdParam).
+			Param(adminWs.QueryParameter("limit", "How many events, at most "+strconv.Itoa(maxAuthEventsLimit)).
+				DataType("integer").DefaultValue(strconv.Itoa(defaultAuthEventsLimit))).
+			Writes([]AuthEventModel{}).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusBadRequest, "Invalid limit", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil))
+	})
 
 	return httpHandler
 }
 
 func (e *UserHttpHandler) UpdateUser(req *restful.Request, resp *restful.Response) {
 	var a UpdateRequestData
-	req.ReadEntity(&a)
+	if err := request.ReadBody(req.Request, &a); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
 
 	// get user ID for update query
 	reqId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
 	if err != nil || len(reqId) == 0 {
-		resp.WriteError(http.StatusBadRequest, errors.New("no id found for user"))
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
 		return
 	}
 
@@ -52,7 +210,7 @@ func (e *UserHttpHandler) UpdateUser(req *restful.Request, resp *restful.Respons
 
 	err = e.userSvc.Update(ctx, dataUser)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("error updating user"))
+		response.WriteError(resp, err)
 		return
 	}
 
@@ -62,39 +220,108 @@ func (e *UserHttpHandler) UpdateUser(req *restful.Request, resp *restful.Respons
 	resp.WriteAsJson(retUser)
 }
 
+// Applies a JSON Merge Patch or JSON Patch to the logged in user, updating only the changed fields
+func (e *UserHttpHandler) PatchUser(req *restful.Request, resp *restful.Response) {
+	reqId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(reqId) == 0 {
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
+		return
+	}
+	ctx := req.Request.Context()
+	current, err := e.userSvc.FindByID(ctx, reqId)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+
+	var original *UserModel = &UserModel{}
+	original.FromDomain(current)
+	var patched UserModel
+	err = patch.Apply(req.Request, original, &patched)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	if patched.ID != original.ID || patched.Email != original.Email || patched.PasswordHash != original.PasswordHash ||
+		patched.Role != original.Role || !patched.CreatedAt.Equal(original.CreatedAt) ||
+		!sameTime(patched.EmailVerifiedAt, original.EmailVerifiedAt) {
+		response.WriteError(resp, response.NewBadRequestError("read-only user fields cannot be patched"))
+		return
+	}
+
+	reqData := UpdateRequestData{Name: patched.Name, Surname: patched.Surname}
+	if err := request.Validate(&reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	if patched.Locale != original.Locale && !domain.IsSupportedLocale(patched.Locale) {
+		response.WriteError(resp, domain.NewValidationError("invalid request body", domain.FieldError{Field: "locale", Message: "is not supported"}))
+		return
+	}
+
+	changes := &domain.UserPatch{}
+	if reqData.Name != original.Name {
+		changes.Name = &reqData.Name
+	}
+	if reqData.Surname != original.Surname {
+		changes.Surname = &reqData.Surname
+	}
+	if patched.Locale != original.Locale {
+		changes.Locale = &patched.Locale
+	}
+	if !changes.IsEmpty() {
+		err = e.userSvc.Patch(ctx, reqId, changes)
+		if err != nil {
+			response.WriteError(resp, err)
+			return
+		}
+		current, err = e.userSvc.FindByID(ctx, reqId)
+		if err != nil {
+			response.WriteError(resp, err)
+			return
+		}
+	}
+
+	var retUser *UserModel = &UserModel{}
+	retUser.FromDomain(current)
+	resp.WriteAsJson(retUser)
+}
+
 // Performs login or register
 func (e *UserHttpHandler) RegisterUser(req *restful.Request, resp *restful.Response) {
 	var reqData RegisterRequestData
-	req.ReadEntity(&reqData)
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
 
 	var user *UserModel = &UserModel{}
 	user.Email = reqData.Email
 	user.Name = reqData.Name
 	user.Surname = reqData.Surname
+	user.Locale = reqData.Locale
 
-	// todo: expand validation
-	if len(user.Email) == 0 {
-		resp.WriteError(http.StatusBadRequest, errors.New("no email provided"))
+	if len(user.Locale) > 0 && !domain.IsSupportedLocale(user.Locale) {
+		response.WriteError(resp, domain.NewValidationError("invalid request body", domain.FieldError{Field: "Locale", Message: "is not supported"}))
 		return
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(reqData.Password), 10)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("error registering"))
+		response.WriteError(resp, err)
 		return
 	}
 	user.PasswordHash = string(hashedPassword)
 
 	err = e.registerUser(req.Request.Context(), user)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("error registering"))
-		fmt.Println(err)
+		response.WriteError(resp, err)
 		return
 	}
 
-	authToken, err := auth.CreateJWT(user.Email, user.ID)
+	authToken, err := auth.CreateJWT(user.Email, user.ID, user.Role, 0)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("error creating jwt"))
+		response.WriteError(resp, err)
 		return
 	}
 
@@ -120,37 +347,359 @@ func (e *UserHttpHandler) registerUser(ctx context.Context, user *UserModel) err
 
 func (e *UserHttpHandler) LoginUser(req *restful.Request, resp *restful.Response) {
 	var reqData LoginRequestData
-	req.ReadEntity(&reqData)
-
-	if len(reqData.Email) == 0 || len(reqData.Password) == 0 {
-		resp.WriteError(http.StatusBadRequest, errors.New("bad login credentials"))
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
 		return
 	}
 
-	userData, err := e.userSvc.FindByEmail(req.Request.Context(), reqData.Email)
-	if err != nil {
-		resp.WriteError(http.StatusForbidden, errors.New("unauthorized"))
+	// the same response whether the email doesn't exist or the password is wrong, so emails can't be found out
+	result, err := e.loginSvc.Login(req.Request.Context(), reqData.Email, reqData.Password, request.ClientIP(req.Request))
+	if writeLoginError(resp, err) {
+		return
+	}
+	if result.User == nil {
+		resp.WriteAsJson(LoginResponseData{TwoFactorRequired: true, ChallengeToken: result.ChallengeToken})
 		return
 	}
+	e.writeLogin(resp, result.User)
+}
 
-	// check password
-	err = bcrypt.CompareHashAndPassword([]byte(userData.PasswordHash), []byte(reqData.Password))
-	if err != nil {
-		resp.WriteError(http.StatusForbidden, errors.New("unauthorized"))
+// Completes the login of a user with two-factor authentication enabled
+func (e *UserHttpHandler) LoginTwoFactor(req *restful.Request, resp *restful.Response) {
+	var reqData LoginTwoFactorRequestData
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
 		return
 	}
+	userData, err := e.loginSvc.CompleteLogin(req.Request.Context(), reqData.ChallengeToken, reqData.Code, request.ClientIP(req.Request))
+	if errors.Is(err, domain.ErrInvalidToken) {
+		response.WriteError(resp, response.NewUnauthorizedError("invalid or expired challenge, log in again").WithErrorCode(response.ErrCodeInvalidToken))
+		return
+	}
+	if writeLoginError(resp, err) {
+		return
+	}
+	e.writeLogin(resp, userData)
+}
+
+// Writes the error of a failed login, returns false if there was none
+func writeLoginError(resp *restful.Response, err error) bool {
+	if err == nil {
+		return false
+	}
+	var blocked *domain.LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		resp.AddHeader("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		metrics.LoginFailures.WithLabelValues(metrics.LoginBlocked).Inc()
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCredentials).Inc()
+	case errors.Is(err, domain.ErrInvalidTwoFactorCode):
+		metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCode).Inc()
+	}
+	response.WriteError(resp, err)
+	return true
+}
 
-	authToken, err := auth.CreateJWT(userData.Email, userData.ID)
+func (e *UserHttpHandler) writeLogin(resp *restful.Response, userData *domain.User) {
+	authToken, err := auth.CreateJWT(userData.Email, userData.ID, userData.Role, userData.SessionVersion)
 	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, errors.New("error creating jwt"))
+		response.WriteError(resp, err)
 		return
 	}
 
 	// send user + token back
 	var user *UserModel = &UserModel{}
 	user.FromDomain(userData)
-	respData := RegisterResponseData{AuthToken: authToken, User: *user}
+	resp.WriteAsJson(LoginResponseData{AuthToken: authToken, User: *user})
+}
+
+// Emails a password reset link, if the email belongs to a user
+// Responds the same either way, so registered emails can't be found out
+func (e *UserHttpHandler) ForgotPassword(req *restful.Request, resp *restful.Response) {
+	var reqData ForgotPasswordRequestData
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	err := e.userSvc.RequestPasswordReset(req.Request.Context(), reqData.Email)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+// Sets a new password, using the token from the password reset email
+func (e *UserHttpHandler) ResetPassword(req *restful.Request, resp *restful.Response) {
+	var reqData ResetPasswordRequestData
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(reqData.Password), 10)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	err = e.userSvc.ResetPassword(req.Request.Context(), reqData.Token, string(hashedPassword))
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// Changes the logged in user's password, which requires the current one
+// All the user's sessions are revoked, the response carries a new JWT for the current one
+func (e *UserHttpHandler) ChangePassword(req *restful.Request, resp *restful.Response) {
+	reqId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(reqId) == 0 {
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
+		return
+	}
+	var reqData ChangePasswordRequestData
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	ctx := req.Request.Context()
+	userData, err := e.userSvc.FindByID(ctx, reqId)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	// a wrong current password counts as a failed login, so it can't be guessed with a stolen JWT
+	err = e.loginSvc.VerifyPassword(ctx, reqId, reqData.CurrentPassword, request.ClientIP(req.Request))
+	if errors.Is(err, domain.ErrInvalidCredentials) {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCredentials).Inc()
+		response.WriteError(resp, response.NewForbiddenError("wrong current password").WithErrorCode(response.ErrCodeInvalidCredentials))
+		return
+	}
+	if writeLoginError(resp, err) {
+		return
+	}
 
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(reqData.NewPassword), 10)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	sessionVersion, err := e.userSvc.ChangePassword(ctx, reqId, string(hashedPassword))
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	authToken, err := auth.CreateJWT(userData.Email, userData.ID, userData.Role, sessionVersion)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	resp.WriteAsJson(ChangePasswordResponseData{AuthToken: authToken})
+}
+
+// Verifies the user's email, using the token from the verification email
+func (e *UserHttpHandler) VerifyEmail(req *restful.Request, resp *restful.Response) {
+	var reqData VerifyEmailRequestData
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	err := e.userSvc.VerifyEmail(req.Request.Context(), reqData.Token)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// Sends the logged in user a new verification email
+func (e *UserHttpHandler) ResendEmailVerification(req *restful.Request, resp *restful.Response) {
+	reqId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(reqId) == 0 {
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
+		return
+	}
+	err = e.userSvc.RequestEmailVerification(req.Request.Context(), reqId)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+func sameTime(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// Lifts the user's lockout after failed logins
+func (e *UserHttpHandler) UnlockUser(req *restful.Request, resp *restful.Response) {
+	adminId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(adminId) == 0 {
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
+		return
+	}
+	ctx := req.Request.Context()
+	userId := req.PathParameter("id")
+	if _, err := e.userSvc.FindByID(ctx, userId); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	err = e.loginSvc.Unlock(ctx, userId, adminId, request.ClientIP(req.Request))
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// Returns the user's logins and other auth events, newest first
+func (e *UserHttpHandler) GetAuthEvents(req *restful.Request, resp *restful.Response) {
+	limit := defaultAuthEventsLimit
+	if limitS := req.QueryParameter("limit"); len(limitS) > 0 {
+		var err error
+		limit, err = strconv.Atoi(limitS)
+		if err != nil || limit < 1 || limit > maxAuthEventsLimit {
+			response.WriteError(resp, response.NewBadRequestError("invalid limit"))
+			return
+		}
+	}
+	ctx := req.Request.Context()
+	userId := req.PathParameter("id")
+	if _, err := e.userSvc.FindByID(ctx, userId); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+
+	events, err := e.loginSvc.FindAuthEvents(ctx, userId, limit)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	retEvents := []AuthEventModel{}
+	for _, event := range *events {
+		var retEvent AuthEventModel
+		retEvent.FromDomain(&event)
+		retEvents = append(retEvents, retEvent)
+	}
+	resp.WriteAsJson(retEvents)
+}
+
+// Returns whether the logged in user has two-factor authentication enabled
+func (e *UserHttpHandler) GetTwoFactor(req *restful.Request, resp *restful.Response) {
+	userId, ok := currentUserId(req, resp)
+	if !ok {
+		return
+	}
+	ctx := req.Request.Context()
+	enabled, err := e.twoFactorSvc.IsEnabled(ctx, userId)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	respData := TwoFactorStatusResponseData{Enabled: enabled}
+	if enabled {
+		respData.RecoveryCodesLeft, err = e.twoFactorSvc.CountRecoveryCodes(ctx, userId)
+		if err != nil {
+			response.WriteError(resp, err)
+			return
+		}
+	}
 	resp.WriteAsJson(respData)
+}
+
+// Generates the secret for the user's authenticator app, which is confirmed with a first code
+func (e *UserHttpHandler) EnrollTwoFactor(req *restful.Request, resp *restful.Response) {
+	userId, ok := currentUserId(req, resp)
+	if !ok {
+		return
+	}
+	enrollment, err := e.twoFactorSvc.Enroll(req.Request.Context(), userId)
+	if e.writeTwoFactorError(resp, err) {
+		return
+	}
+	resp.WriteAsJson(TwoFactorEnrollResponseData{Secret: enrollment.Secret, URI: enrollment.URI})
+}
+
+// Enables two-factor authentication with the first code from the authenticator app, returns the recovery codes
+func (e *UserHttpHandler) ConfirmTwoFactor(req *restful.Request, resp *restful.Response) {
+	userId, code, ok := twoFactorCodeRequest(req, resp)
+	if !ok {
+		return
+	}
+	codes, err := e.twoFactorSvc.Confirm(req.Request.Context(), userId, code, request.ClientIP(req.Request))
+	if e.writeTwoFactorError(resp, err) {
+		return
+	}
+	resp.WriteAsJson(RecoveryCodesResponseData{RecoveryCodes: codes})
+}
+
+// Disables two-factor authentication, with a code from the authenticator app or a recovery code
+func (e *UserHttpHandler) DisableTwoFactor(req *restful.Request, resp *restful.Response) {
+	userId, code, ok := twoFactorCodeRequest(req, resp)
+	if !ok {
+		return
+	}
+	err := e.twoFactorSvc.Disable(req.Request.Context(), userId, code, request.ClientIP(req.Request))
+	if e.writeTwoFactorError(resp, err) {
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// Replaces the recovery codes, with a code from the authenticator app
+func (e *UserHttpHandler) RegenerateRecoveryCodes(req *restful.Request, resp *restful.Response) {
+	userId, code, ok := twoFactorCodeRequest(req, resp)
+	if !ok {
+		return
+	}
+	codes, err := e.twoFactorSvc.RegenerateRecoveryCodes(req.Request.Context(), userId, code, request.ClientIP(req.Request))
+	if e.writeTwoFactorError(resp, err) {
+		return
+	}
+	resp.WriteAsJson(RecoveryCodesResponseData{RecoveryCodes: codes})
+}
+
+// Writes the error of managing two-factor authentication, returns false if there was none
+func (e *UserHttpHandler) writeTwoFactorError(resp *restful.Response, err error) bool {
+	var blocked *domain.LoginBlockedError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &blocked):
+		resp.AddHeader("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		response.WriteError(resp, err)
+	case errors.Is(err, domain.ErrInvalidTwoFactorCode):
+		// a wrong code is a bad request here, rather than a failed login
+		response.WriteError(resp, response.NewBadRequestError("invalid code").WithErrorCode(response.ErrCodeInvalidTwoFactor).WithInternal(err))
+	default:
+		response.WriteError(resp, err)
+	}
+	return true
+}
 
+func twoFactorCodeRequest(req *restful.Request, resp *restful.Response) (string, string, bool) {
+	userId, ok := currentUserId(req, resp)
+	if !ok {
+		return "", "", false
+	}
+	var reqData TwoFactorCodeRequestData
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return "", "", false
+	}
+	return userId, reqData.Code, true
+}
+
+// Returns the logged in user's ID, writing an error if there is none
+func currentUserId(req *restful.Request, resp *restful.Response) (string, bool) {
+	userId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(userId) == 0 {
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
+		return "", false
+	}
+	return userId, true
 }
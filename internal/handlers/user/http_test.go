@@ -4,14 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/emicklei/go-restful/v3"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/app"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/usecases"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/mail"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/testutil"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/totp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/crypto/bcrypt"
@@ -23,6 +31,7 @@ type HttpSuite struct {
 	suite.Suite
 	userHttpSvc UserHttpHandler
 	wsContainer *restful.Container
+	mailer      *mail.MemoryMailer
 }
 
 func (suite *HttpSuite) SetupTest() {
@@ -32,6 +41,10 @@ func (suite *HttpSuite) TearDownTest() {
 	testutil.CleanUpTables(*testApp.DB)
 }
 
+func (suite *HttpSuite) TearDownSuite() {
+	auth.SetSessionChecker(nil)
+}
+
 func (suite *HttpSuite) SetupSuite() {
 
 	testApp = testutil.InitTestApp()
@@ -39,8 +52,27 @@ func (suite *HttpSuite) SetupSuite() {
 	http.Handle("/", suite.wsContainer)
 
 	realUserRep := repo.NewUserRepository(testApp.DB)
-	realUserSvc := usecases.NewUserService(realUserRep)
-	suite.userHttpSvc = *NewUserHandler(realUserSvc, suite.wsContainer)
+	tokenRep := repo.NewUserTokenRepository(testApp.DB)
+	templates, err := mail.NewTemplates(domain.DefaultLocale)
+	if err != nil {
+		suite.T().Fatalf("Error loading mail templates: %s", err)
+	}
+	suite.mailer = mail.NewMemoryMailer()
+	realUserSvc := usecases.NewUserService(realUserRep, tokenRep, repo.NewOutboxRepository(testApp.DB),
+		testApp.DB, mail.NewAccountNotifier(suite.mailer, templates, "http://frontend"))
+	// no delay between the attempts, so the lockout is reached right away
+	auditRep := repo.NewAuthAuditRepository(testApp.DB)
+	twoFactorRep := repo.NewTwoFactorRepository(testApp.DB)
+	loginPolicy := domain.LoginPolicy{
+		MaxAccountFailures: 3,
+		LockoutDuration:    time.Hour,
+		Delay:              time.Nanosecond,
+		MaxDelay:           time.Nanosecond,
+	}
+	realLoginSvc := usecases.NewLoginService(realUserRep, auditRep, tokenRep, twoFactorRep, testApp.DB, loginPolicy)
+	realTwoFactorSvc := usecases.NewTwoFactorService(realUserRep, twoFactorRep, auditRep, testApp.DB, loginPolicy, "HEXFWK")
+	suite.userHttpSvc = *NewUserHandler(realUserSvc, realLoginSvc, realTwoFactorSvc, suite.wsContainer)
+	auth.SetSessionChecker(realUserSvc.CheckSession)
 
 }
 
@@ -53,12 +85,13 @@ func TestUserTestSuite(t *testing.T) {
 func (suite *HttpSuite) TestRegisterUser() {
 	// prepare registration data
 	postData := RegisterRequestData{
-		Email:   "testy@email.com",
-		Name:    "First name",
-		Surname: "Last name",
+		Email:    "testy@email.com",
+		Name:     "First name",
+		Surname:  "Last name",
+		Password: "password123",
 	}
 	// make request
-	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/user/register", postData, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/register", postData, nil)
 
 	// validate response
 	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
@@ -75,6 +108,41 @@ func (suite *HttpSuite) TestRegisterUser() {
 	assert.Equal(suite.T(), returnedUser.User.Name, postData.Name)
 }
 
+func (suite *HttpSuite) TestRegisterUserInvalid() {
+	postData := RegisterRequestData{Email: "not an email", Surname: "Last name", Password: "password"}
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/register", postData, nil)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code)
+	var problem response.Problem
+	json.Unmarshal(responseRec.Body.Bytes(), &problem)
+	fields := make([]string, 0, len(problem.Errors))
+	for _, fieldErr := range problem.Errors {
+		fields = append(fields, fieldErr.Field)
+	}
+	assert.ElementsMatch(suite.T(), []string{"Email", "Name", "Password"}, fields)
+}
+
+func (suite *HttpSuite) TestRegisterUserWithLocale() {
+	postData := RegisterRequestData{
+		Email:    "testy@email.com",
+		Name:     "First name",
+		Surname:  "Last name",
+		Password: "password123",
+		Locale:   "sr",
+	}
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/register", postData, nil)
+
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var returnedUser RegisterResponseData
+	json.Unmarshal(responseRec.Body.Bytes(), &returnedUser)
+	assert.Equal(suite.T(), "sr", returnedUser.User.Locale)
+
+	postData.Email = "other@email.com"
+	postData.Locale = "xx"
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/register", postData, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code)
+}
+
 func (suite *HttpSuite) TestLoginUser() {
 	// register the user before sending login request
 	userEmail := "testy@email.com"
@@ -88,7 +156,7 @@ func (suite *HttpSuite) TestLoginUser() {
 
 	// prepare login data
 	postData := LoginRequestData{Email: userEmail, Password: userPass}
-	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/user/login", postData, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", postData, nil)
 
 	// validate response
 	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
@@ -116,7 +184,7 @@ func (suite *HttpSuite) TestInvalidLogin() {
 
 	// prepare login data
 	postData := LoginRequestData{Email: userEmail, Password: "invalid password 123"}
-	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/user/login", postData, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", postData, nil)
 
 	// validate response
 	assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
@@ -125,12 +193,13 @@ func (suite *HttpSuite) TestInvalidLogin() {
 func (suite *HttpSuite) TestUpdateUser() {
 	// prepare registration data
 	postData := RegisterRequestData{
-		Email:   "testy@email.com",
-		Name:    "First name",
-		Surname: "Last name",
+		Email:    "testy@email.com",
+		Name:     "First name",
+		Surname:  "Last name",
+		Password: "password123",
 	}
 	// make request
-	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/user/register", postData, nil)
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/register", postData, nil)
 
 	// validate response
 	assert.Equal(suite.T(), http.StatusOK, responseRec.Code, "Error registering")
@@ -148,7 +217,7 @@ func (suite *HttpSuite) TestUpdateUser() {
 		Name:    "New name",
 		Surname: "New surname",
 	}
-	responseRec2 := testutil.MakeRequest(suite.wsContainer, "PUT", "/user", updateData, &token)
+	responseRec2 := testutil.MakeRequest(suite.wsContainer, "PUT", "/v1/user", updateData, &token)
 	assert.Equal(suite.T(), http.StatusOK, responseRec2.Code)
 
 	var updatedUser UserModel
@@ -160,3 +229,301 @@ func (suite *HttpSuite) TestUpdateUser() {
 	assert.Equal(suite.T(), updateData.Name, updatedUser.Name)
 	assert.Equal(suite.T(), updateData.Surname, updatedUser.Surname)
 }
+
+// Returns the token from the link in the last email sent to the address
+func (suite *HttpSuite) lastToken(email string, path string) string {
+	sent := suite.mailer.Sent()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if sent[i].To[0] != email {
+			continue
+		}
+		link := regexp.MustCompile(`http://frontend` + path + `\?\S+`).FindString(sent[i].Text)
+		if link == "" {
+			continue
+		}
+		parsed, _ := url.Parse(link)
+		return parsed.Query().Get("token")
+	}
+	suite.T().Fatalf("No %s email sent to %s", path, email)
+	return ""
+}
+
+func (suite *HttpSuite) TestResetPassword() {
+	userEmail := "testy@email.com"
+	passHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	suite.userHttpSvc.userSvc.RegisterUser(context.TODO(), &domain.User{
+		Email:        userEmail,
+		PasswordHash: string(passHash),
+	})
+	user, _ := suite.userHttpSvc.userSvc.FindByEmail(context.TODO(), userEmail)
+	oldToken, _ := auth.CreateJWT(user.Email, user.ID, user.Role, user.SessionVersion)
+
+	// unknown emails get the same response
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/password/forgot", ForgotPasswordRequestData{Email: "unknown@email.com"}, nil)
+	assert.Equal(suite.T(), http.StatusAccepted, responseRec.Code)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/password/forgot", ForgotPasswordRequestData{Email: userEmail}, nil)
+	assert.Equal(suite.T(), http.StatusAccepted, responseRec.Code)
+
+	resetData := ResetPasswordRequestData{Token: suite.lastToken(userEmail, "/reset-password"), Password: "new password1"}
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/password/reset", resetData, nil)
+	assert.Equal(suite.T(), http.StatusNoContent, responseRec.Code)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/password/reset", resetData, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", LoginRequestData{Email: userEmail, Password: "new password1"}, nil)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	// JWTs issued before the reset are revoked
+	responseRec = testutil.MakeRequest(suite.wsContainer, "PUT", "/v1/user", UpdateRequestData{Name: "Name"}, &oldToken)
+	assert.Equal(suite.T(), http.StatusUnauthorized, responseRec.Code)
+}
+
+func (suite *HttpSuite) TestChangePassword() {
+	userEmail := "testy@email.com"
+	passHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	suite.userHttpSvc.userSvc.RegisterUser(context.TODO(), &domain.User{
+		Email:        userEmail,
+		PasswordHash: string(passHash),
+	})
+	user, _ := suite.userHttpSvc.userSvc.FindByEmail(context.TODO(), userEmail)
+	token, _ := auth.CreateJWT(user.Email, user.ID, user.Role, user.SessionVersion)
+
+	changeData := ChangePasswordRequestData{CurrentPassword: "wrong password", NewPassword: "new password1"}
+	responseRec := testutil.MakeRequest(suite.wsContainer, "PUT", "/v1/user/password", changeData, &token)
+	assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
+
+	changeData.CurrentPassword = "password123"
+	responseRec = testutil.MakeRequest(suite.wsContainer, "PUT", "/v1/user/password", changeData, &token)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var changed ChangePasswordResponseData
+	json.Unmarshal(responseRec.Body.Bytes(), &changed)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "PUT", "/v1/user", UpdateRequestData{Name: "Name"}, &token)
+	assert.Equal(suite.T(), http.StatusUnauthorized, responseRec.Code)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "PUT", "/v1/user", UpdateRequestData{Name: "Name"}, &changed.AuthToken)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+}
+
+// Test that the current password can't be guessed by changing it, the failures count towards the lockout
+func (suite *HttpSuite) TestChangePasswordIsThrottled() {
+	passHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	suite.userHttpSvc.userSvc.RegisterUser(context.TODO(), &domain.User{
+		Email:        "testy@email.com",
+		PasswordHash: string(passHash),
+	})
+	user, _ := suite.userHttpSvc.userSvc.FindByEmail(context.TODO(), "testy@email.com")
+	token, _ := auth.CreateJWT(user.Email, user.ID, user.Role, user.SessionVersion)
+
+	changeData := ChangePasswordRequestData{CurrentPassword: "wrong password", NewPassword: "new password1"}
+	for i := 0; i < 3; i++ {
+		responseRec := testutil.MakeRequest(suite.wsContainer, "PUT", "/v1/user/password", changeData, &token)
+		assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
+	}
+	changeData.CurrentPassword = "password123"
+	responseRec := testutil.MakeRequest(suite.wsContainer, "PUT", "/v1/user/password", changeData, &token)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, responseRec.Code)
+	assert.NotEmpty(suite.T(), responseRec.Header().Get("Retry-After"))
+
+	events, _ := suite.userHttpSvc.loginSvc.FindAuthEvents(context.TODO(), user.ID, 10)
+	if assert.Len(suite.T(), *events, 3) {
+		assert.Equal(suite.T(), domain.AuthLoginFailed, (*events)[0].Type)
+	}
+}
+
+func (suite *HttpSuite) TestVerifyEmail() {
+	postData := RegisterRequestData{Email: "testy@email.com", Name: "First name", Surname: "Last name", Password: "password123"}
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/register", postData, nil)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var registered RegisterResponseData
+	json.Unmarshal(responseRec.Body.Bytes(), &registered)
+	assert.Nil(suite.T(), registered.User.EmailVerifiedAt)
+
+	verifyData := VerifyEmailRequestData{Token: suite.lastToken(postData.Email, "/verify-email")}
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/email/verify", verifyData, nil)
+	assert.Equal(suite.T(), http.StatusNoContent, responseRec.Code)
+
+	user, _ := suite.userHttpSvc.userSvc.FindByEmail(context.TODO(), postData.Email)
+	assert.True(suite.T(), user.IsVerified())
+}
+
+func (suite *HttpSuite) TestUnknownEmailLoginLooksTheSame() {
+	passHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	suite.userHttpSvc.userSvc.RegisterUser(context.TODO(), &domain.User{
+		Email:        "testy@email.com",
+		PasswordHash: string(passHash),
+	})
+
+	wrongPassword := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", LoginRequestData{Email: "testy@email.com", Password: "wrong"}, nil)
+	unknownEmail := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", LoginRequestData{Email: "unknown@email.com", Password: "wrong"}, nil)
+
+	assert.Equal(suite.T(), http.StatusForbidden, wrongPassword.Code)
+	assert.Equal(suite.T(), wrongPassword.Code, unknownEmail.Code)
+	assert.Equal(suite.T(), wrongPassword.Body.String(), unknownEmail.Body.String())
+}
+
+func (suite *HttpSuite) TestLockoutAndUnlock() {
+	userEmail := "testy@email.com"
+	passHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	suite.userHttpSvc.userSvc.RegisterUser(context.TODO(), &domain.User{
+		Email:        userEmail,
+		PasswordHash: string(passHash),
+	})
+	user, _ := suite.userHttpSvc.userSvc.FindByEmail(context.TODO(), userEmail)
+
+	for i := 0; i < 3; i++ {
+		responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", LoginRequestData{Email: userEmail, Password: "wrong"}, nil)
+		assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
+	}
+	// locked out, even with the right password
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", LoginRequestData{Email: userEmail, Password: "password123"}, nil)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, responseRec.Code)
+	assert.NotEmpty(suite.T(), responseRec.Header().Get("Retry-After"))
+
+	// only admins can unlock
+	userToken, _ := auth.CreateJWT(user.Email, user.ID, domain.RoleUser, user.SessionVersion)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/admin/user/"+user.ID+"/unlock", nil, &userToken)
+	assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
+
+	suite.userHttpSvc.userSvc.RegisterUser(context.TODO(), &domain.User{
+		Email: "admin@email.com", Name: "Admin", Surname: "Admin", PasswordHash: string(passHash),
+	})
+	admin, _ := suite.userHttpSvc.userSvc.FindByEmail(context.TODO(), "admin@email.com")
+	testutil.SetUserRole(*testApp.DB, admin.ID, domain.RoleAdmin)
+	adminToken, _ := auth.CreateJWT(admin.Email, admin.ID, domain.RoleAdmin, admin.SessionVersion)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/admin/user/"+user.ID+"/unlock", nil, &adminToken)
+	assert.Equal(suite.T(), http.StatusNoContent, responseRec.Code)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", LoginRequestData{Email: userEmail, Password: "password123"}, nil)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "GET", "/v1/admin/user/"+user.ID+"/auth-events", nil, &adminToken)
+	var events []AuthEventModel
+	json.Unmarshal(responseRec.Body.Bytes(), &events)
+	// the blocked attempt isn't attributed to the user, the email wasn't looked up
+	if assert.Len(suite.T(), events, 5) {
+		assert.Equal(suite.T(), string(domain.AuthLoginSucceeded), events[0].Type)
+		assert.Equal(suite.T(), string(domain.AuthAccountUnlocked), events[1].Type)
+	}
+}
+
+// Test that logins made at the same time can't all be attempted before their failures are counted
+func (suite *HttpSuite) TestConcurrentLoginsAreCounted() {
+	passHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	suite.userHttpSvc.userSvc.RegisterUser(context.TODO(), &domain.User{
+		Email:        "testy@email.com",
+		PasswordHash: string(passHash),
+	})
+
+	var mu sync.Mutex
+	codes := map[int]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", LoginRequestData{Email: "testy@email.com", Password: "wrong"}, nil)
+			mu.Lock()
+			codes[responseRec.Code]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// the password is only checked until the account is locked out
+	assert.Equal(suite.T(), map[int]int{http.StatusForbidden: 3, http.StatusTooManyRequests: 7}, codes)
+}
+
+func (suite *HttpSuite) TestTwoFactorLogin() {
+	userEmail := "testy@email.com"
+	passHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	suite.userHttpSvc.userSvc.RegisterUser(context.TODO(), &domain.User{
+		Email:        userEmail,
+		PasswordHash: string(passHash),
+	})
+	user, _ := suite.userHttpSvc.userSvc.FindByEmail(context.TODO(), userEmail)
+	token, _ := auth.CreateJWT(user.Email, user.ID, user.Role, user.SessionVersion)
+
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/2fa/enroll", nil, &token)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var enrollment TwoFactorEnrollResponseData
+	json.Unmarshal(responseRec.Body.Bytes(), &enrollment)
+	assert.Contains(suite.T(), enrollment.URI, "otpauth://totp/")
+
+	step := totp.Step(time.Now())
+	code, _ := totp.Code(enrollment.Secret, step)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/2fa/confirm", TwoFactorCodeRequestData{Code: code}, &token)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var recovery RecoveryCodesResponseData
+	json.Unmarshal(responseRec.Body.Bytes(), &recovery)
+	assert.Len(suite.T(), recovery.RecoveryCodes, domain.RecoveryCodeCount)
+
+	// the password only gets a challenge now
+	loginData := LoginRequestData{Email: userEmail, Password: "password123"}
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", loginData, nil)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var challenge LoginResponseData
+	json.Unmarshal(responseRec.Body.Bytes(), &challenge)
+	assert.True(suite.T(), challenge.TwoFactorRequired)
+	assert.Empty(suite.T(), challenge.AuthToken)
+
+	// the code used to confirm can't be used again
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login/2fa",
+		LoginTwoFactorRequestData{ChallengeToken: challenge.ChallengeToken, Code: code}, nil)
+	assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
+
+	nextCode, _ := totp.Code(enrollment.Secret, step+1)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login/2fa",
+		LoginTwoFactorRequestData{ChallengeToken: challenge.ChallengeToken, Code: nextCode}, nil)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	var loggedIn LoginResponseData
+	json.Unmarshal(responseRec.Body.Bytes(), &loggedIn)
+	assert.NotEmpty(suite.T(), loggedIn.AuthToken)
+
+	// the challenge is used up, a recovery code works once with a new one
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login/2fa",
+		LoginTwoFactorRequestData{ChallengeToken: challenge.ChallengeToken, Code: recovery.RecoveryCodes[0]}, nil)
+	assert.Equal(suite.T(), http.StatusUnauthorized, responseRec.Code)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", loginData, nil)
+	json.Unmarshal(responseRec.Body.Bytes(), &challenge)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login/2fa",
+		LoginTwoFactorRequestData{ChallengeToken: challenge.ChallengeToken, Code: recovery.RecoveryCodes[0]}, nil)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "GET", "/v1/user/2fa", nil, &token)
+	var status TwoFactorStatusResponseData
+	json.Unmarshal(responseRec.Body.Bytes(), &status)
+	assert.True(suite.T(), status.Enabled)
+	assert.Equal(suite.T(), domain.RecoveryCodeCount-1, status.RecoveryCodesLeft)
+}
+
+// Test that the codes managing two-factor authentication can't be guessed without locking the account out
+func (suite *HttpSuite) TestTwoFactorCodesAreThrottled() {
+	passHash, _ := bcrypt.GenerateFromPassword([]byte("password123"), 10)
+	suite.userHttpSvc.userSvc.RegisterUser(context.TODO(), &domain.User{
+		Email:        "testy@email.com",
+		PasswordHash: string(passHash),
+	})
+	user, _ := suite.userHttpSvc.userSvc.FindByEmail(context.TODO(), "testy@email.com")
+	token, _ := auth.CreateJWT(user.Email, user.ID, user.Role, user.SessionVersion)
+
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/2fa/enroll", nil, &token)
+	var enrollment TwoFactorEnrollResponseData
+	json.Unmarshal(responseRec.Body.Bytes(), &enrollment)
+	step := totp.Step(time.Now())
+	code, _ := totp.Code(enrollment.Secret, step)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/2fa/confirm", TwoFactorCodeRequestData{Code: code}, &token)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+
+	for _, path := range []string{"/v1/user/2fa/disable", "/v1/user/2fa/recovery-codes", "/v1/user/2fa/disable"} {
+		responseRec = testutil.MakeRequest(suite.wsContainer, "POST", path, TwoFactorCodeRequestData{Code: "000000"}, &token)
+		assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code, path)
+	}
+	// locked out, even with the right code
+	nextCode, _ := totp.Code(enrollment.Secret, step+1)
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/2fa/disable", TwoFactorCodeRequestData{Code: nextCode}, &token)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, responseRec.Code)
+	assert.NotEmpty(suite.T(), responseRec.Header().Get("Retry-After"))
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "POST", "/v1/user/login", LoginRequestData{Email: "testy@email.com", Password: "password123"}, nil)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, responseRec.Code)
+}
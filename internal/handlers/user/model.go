@@ -12,6 +12,10 @@ type UserModel struct {
 	Name         string `json:"name"`
 	Surname      string `json:"surname"`
 	PasswordHash string `json:"password_hash"`
+	Locale       string `json:"locale"`
+	Role         string `json:"role"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -26,6 +30,9 @@ func (e *UserModel) FromDomain(user *domain.User) {
 	e.Email = user.Email
 	e.Name = user.Name
 	e.Surname = user.Surname
+	e.Locale = user.Locale
+	e.Role = user.Role
+	e.EmailVerifiedAt = user.EmailVerifiedAt
 	e.CreatedAt = user.CreatedAt
 	// do not populate the password hash, because we do not wish to expose that when loading from the domain
 }
@@ -42,5 +49,32 @@ func (e *UserModel) ToDomain() *domain.User {
 		Name:         e.Name,
 		Surname:      e.Surname,
 		PasswordHash: e.PasswordHash,
+		Locale:       e.Locale,
+		Role:         e.Role,
+
+		EmailVerifiedAt: e.EmailVerifiedAt,
 	}
 }
+
+type AuthEventModel struct {
+	ID      string  `json:"id"`
+	Type    string  `json:"type"`
+	Email   string  `json:"email"`
+	ActorID *string `json:"actor_id,omitempty"`
+	IP      string  `json:"ip"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (e *AuthEventModel) FromDomain(event *domain.AuthEvent) {
+	if e == nil || event == nil {
+		return
+	}
+
+	e.ID = event.ID
+	e.Type = string(event.Type)
+	e.Email = event.Email
+	e.ActorID = event.ActorID
+	e.IP = event.IP
+	e.CreatedAt = event.CreatedAt
+}
@@ -1,10 +1,12 @@
 package user
 
 type RegisterRequestData struct {
-	Email    string
-	Name     string
-	Surname  string
-	Password string
+	Email    string `validate:"required,email,max=255"`
+	Name     string `validate:"required,max=255"`
+	Surname  string `validate:"required,max=255"`
+	Password string `validate:"required,password"`
+	// Optional, the language of the emails sent to the user
+	Locale string `validate:"omitempty,max=10"`
 }
 
 type RegisterResponseData struct {
@@ -13,16 +15,67 @@ type RegisterResponseData struct {
 }
 
 type UpdateRequestData struct {
-	Name    string
-	Surname string
+	Name    string `validate:"max=255"`
+	Surname string `validate:"max=255"`
 }
 
 type LoginRequestData struct {
-	Email    string
-	Password string
+	Email    string `validate:"required"`
+	Password string `validate:"required"`
 }
 
 type LoginResponseData struct {
 	AuthToken string
 	User      UserModel
+	// With two-factor authentication enabled, only the challenge is returned, for completing the login with a code
+	TwoFactorRequired bool   `json:",omitempty"`
+	ChallengeToken    string `json:",omitempty"`
+}
+
+type LoginTwoFactorRequestData struct {
+	ChallengeToken string `validate:"required"`
+	// From the authenticator app, or a recovery code
+	Code string `validate:"required"`
+}
+
+type TwoFactorStatusResponseData struct {
+	Enabled           bool
+	RecoveryCodesLeft int
+}
+
+type TwoFactorEnrollResponseData struct {
+	Secret string
+	URI    string
+}
+
+type TwoFactorCodeRequestData struct {
+	Code string `validate:"required"`
+}
+
+type RecoveryCodesResponseData struct {
+	// Only shown this once, each can be used in place of a code from the authenticator app once
+	RecoveryCodes []string
+}
+
+type ForgotPasswordRequestData struct {
+	Email string `validate:"required,email"`
+}
+
+type ResetPasswordRequestData struct {
+	Token    string `validate:"required"`
+	Password string `validate:"required,password"`
+}
+
+type ChangePasswordRequestData struct {
+	CurrentPassword string `validate:"required"`
+	NewPassword     string `validate:"required,password"`
+}
+
+type ChangePasswordResponseData struct {
+	// Replaces the JWTs issued before, which are revoked
+	AuthToken string
+}
+
+type VerifyEmailRequestData struct {
+	Token string `validate:"required"`
 }
@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/openapi"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/params"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/request"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+)
+
+// How many deliveries are returned when no limit is set, and at most
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+)
+
+type WebhookHttpHandler struct {
+	webhookSvc ports.WebhookUsecase
+}
+
+func NewWebhookHandler(webhookSvc ports.WebhookUsecase, wsCont *restful.Container) *WebhookHttpHandler {
+	httpHandler := &WebhookHttpHandler{
+		webhookSvc: webhookSvc,
+	}
+
+	// the events carry the data of every user, so webhooks are only managed by admins
+	apiversion.Register(wsCont, "/webhook", func(v apiversion.Version, ws *restful.WebService) {
+		ws.Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON).
+			Filter(auth.AuthJWT).Filter(auth.RequireRole(domain.RoleAdmin))
+		idParam := ws.PathParameter("id", "ID of the subscription")
+
+		ws.Route(ws.GET("").To(httpHandler.GetSubscriptions).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("List the webhook subscriptions of the logged in admin").
+			Writes([]SubscriptionModel{}).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil))
+		ws.Route(ws.GET("/{id}").To(httpHandler.GetSubscription).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Get a webhook subscription").
+			Param(idParam).
+			Writes(SubscriptionModel{}).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil).
+			Returns(http.StatusNotFound, "Subscription doesn't exist", nil))
+		ws.Route(ws.POST("").To(httpHandler.CreateSubscription).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Subscribe to events").
+			Notes("The deliveries are signed with the secret, which is only returned here").
+			Reads(SubscriptionRequest{}).
+			Writes(SubscriptionModel{}).
+			Returns(http.StatusCreated, "Subscription created", nil).
+			Returns(http.StatusBadRequest, "Invalid subscription", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil))
+		ws.Route(ws.DELETE("/{id}").To(httpHandler.DeleteSubscription).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("Delete a webhook subscription").
+			Param(idParam).
+			Writes(Response{}).
+			Returns(http.StatusOK, "Subscription deleted", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil).
+			Returns(http.StatusNotFound, "Subscription doesn't exist", nil))
+		ws.Route(ws.GET("/{id}/deliveries").To(httpHandler.GetDeliveries).
+			Do(openapi.Secured(openapi.BearerAuth)).
+			Doc("List the latest deliveries of a webhook subscription").
+			Param(idParam).
+			Param(ws.QueryParameter("status", "Only the deliveries in this status").
+				PossibleValues([]string{string(domain.DeliveryPending), string(domain.DeliverySucceeded), string(domain.DeliveryDeadLettered)})).
+			Param(ws.QueryParameter("limit", "How many deliveries, at most "+strconv.Itoa(maxDeliveriesLimit)).
+				DataType("integer").DefaultValue(strconv.Itoa(defaultDeliveriesLimit))).
+			Writes([]DeliveryModel{}).
+			Returns(http.StatusOK, "OK", nil).
+			Returns(http.StatusBadRequest, "Invalid status or limit", nil).
+			Returns(http.StatusUnauthorized, "Not authenticated", nil).
+			Returns(http.StatusForbidden, "Not an admin", nil).
+			Returns(http.StatusNotFound, "Subscription doesn't exist", nil))
+	})
+
+	return httpHandler
+}
+
+func (e *WebhookHttpHandler) GetSubscriptions(req *restful.Request, resp *restful.Response) {
+	userId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(userId) == 0 {
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
+		return
+	}
+	subscriptions, err := e.webhookSvc.FindSubscriptionsByUser(req.Request.Context(), userId)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	retSubscriptions := []SubscriptionModel{}
+	for _, subscription := range *subscriptions {
+		var retSubscription SubscriptionModel
+		retSubscription.FromDomain(&subscription)
+		retSubscriptions = append(retSubscriptions, retSubscription)
+	}
+	resp.WriteAsJson(retSubscriptions)
+}
+
+func (e *WebhookHttpHandler) GetSubscription(req *restful.Request, resp *restful.Response) {
+	subscription, ok := e.ownSubscription(req, resp)
+	if !ok {
+		return
+	}
+	var retSubscription *SubscriptionModel = &SubscriptionModel{}
+	retSubscription.FromDomain(subscription)
+	resp.WriteAsJson(retSubscription)
+}
+
+// Subscribes the user to the given event types
+// The response carries the signing secret, which isn't returned again afterwards
+func (e *WebhookHttpHandler) CreateSubscription(req *restful.Request, resp *restful.Response) {
+	userId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(userId) == 0 {
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
+		return
+	}
+	var reqData SubscriptionRequest
+	if err := request.ReadBody(req.Request, &reqData); err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+
+	// only deliveries over HTTP(S) are supported
+	var fields []domain.FieldError
+	target, err := url.Parse(reqData.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || len(target.Host) == 0 {
+		fields = append(fields, domain.FieldError{Field: "url", Message: "must be an http or https URL"})
+	}
+	for _, t := range reqData.EventTypes {
+		if !domain.EventType(t).IsValid() {
+			fields = append(fields, domain.FieldError{Field: "eventTypes", Message: "unknown event type " + t})
+		}
+	}
+	if len(fields) > 0 {
+		response.WriteError(resp, domain.NewValidationError("invalid request body", fields...))
+		return
+	}
+
+	var subscription *SubscriptionModel = &SubscriptionModel{}
+	subscription.URL = reqData.URL
+	subscription.EventTypes = reqData.EventTypes
+	subscription.Secret = reqData.Secret
+	created := subscription.ToDomain()
+	created.UserID = userId
+	err = e.webhookSvc.CreateSubscription(req.Request.Context(), created)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	subscription.FromDomain(created)
+	subscription.Secret = created.Secret
+	resp.WriteHeaderAndJson(http.StatusCreated, subscription, restful.MIME_JSON)
+}
+
+func (e *WebhookHttpHandler) DeleteSubscription(req *restful.Request, resp *restful.Response) {
+	subscription, ok := e.ownSubscription(req, resp)
+	if !ok {
+		return
+	}
+	_, err := e.webhookSvc.DeleteSubscription(req.Request.Context(), subscription.ID)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	resp.WriteAsJson(Response{ID: subscription.ID, Name: "webhook subscription deleted"})
+}
+
+// Returns the delivery log of the subscription, newest first
+// Can be filtered by status, e.g. ?status=DEAD_LETTERED lists the deliveries which were given up on
+func (e *WebhookHttpHandler) GetDeliveries(req *restful.Request, resp *restful.Response) {
+	subscription, ok := e.ownSubscription(req, resp)
+	if !ok {
+		return
+	}
+	status := domain.WebhookDeliveryStatus(req.QueryParameter("status"))
+	if status != "" && status != domain.DeliveryPending && status != domain.DeliverySucceeded && status != domain.DeliveryDeadLettered {
+		response.WriteError(resp, response.NewBadRequestError("invalid delivery status"))
+		return
+	}
+	limit := defaultDeliveriesLimit
+	if limitS := req.QueryParameter("limit"); len(limitS) > 0 {
+		var err error
+		limit, err = strconv.Atoi(limitS)
+		if err != nil || limit < 1 || limit > maxDeliveriesLimit {
+			response.WriteError(resp, response.NewBadRequestError("invalid limit"))
+			return
+		}
+	}
+
+	deliveries, err := e.webhookSvc.FindDeliveries(req.Request.Context(), subscription.ID, status, limit)
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+	retDeliveries := []DeliveryModel{}
+	for _, delivery := range *deliveries {
+		var retDelivery DeliveryModel
+		retDelivery.FromDomain(&delivery)
+		retDeliveries = append(retDeliveries, retDelivery)
+	}
+	resp.WriteAsJson(retDeliveries)
+}
+
+// Loads the subscription from the path, writing an error unless it belongs to the user
+func (e *WebhookHttpHandler) ownSubscription(req *restful.Request, resp *restful.Response) (*domain.WebhookSubscription, bool) {
+	userId, err := params.StringFrom(req.Request, auth.USER_ID_CTX_KEY)
+	if err != nil || len(userId) == 0 {
+		response.WriteError(resp, response.NewBadRequestError("no id found for user"))
+		return nil, false
+	}
+	subscription, err := e.webhookSvc.FindSubscriptionById(req.Request.Context(), req.PathParameter("id"))
+	if err != nil {
+		response.WriteError(resp, err)
+		return nil, false
+	}
+	// users can only see their own subscriptions
+	if subscription.UserID != userId {
+		response.WriteError(resp, domain.NewNotFoundError("webhook subscription"))
+		return nil, false
+	}
+	return subscription, true
+}
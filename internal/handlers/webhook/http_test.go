@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/app"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/usecases"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/testutil"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/webhooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+var testApp *app.App
+
+type HttpSuite struct {
+	suite.Suite
+	webhookHttpSvc WebhookHttpHandler
+	wsContainer    *restful.Container
+	userRep        *repo.UserRepository
+}
+
+func (suite *HttpSuite) SetupTest() {
+}
+
+func (suite *HttpSuite) TearDownTest() {
+	testutil.CleanUpTables(*testApp.DB)
+}
+
+func (suite *HttpSuite) SetupSuite() {
+
+	testApp = testutil.InitTestApp()
+	suite.wsContainer = restful.NewContainer()
+	http.Handle("/", suite.wsContainer)
+
+	suite.userRep = repo.NewUserRepository(testApp.DB)
+	auth.SetSessionChecker(usecases.NewUserService(suite.userRep, repo.NewUserTokenRepository(testApp.DB),
+		repo.NewOutboxRepository(testApp.DB), testApp.DB, nil).CheckSession)
+	realWebhookSvc := usecases.NewWebhookService(repo.NewWebhookRepository(testApp.DB), webhooks.NewGuard(false))
+	suite.webhookHttpSvc = *NewWebhookHandler(realWebhookSvc, suite.wsContainer)
+}
+
+func (suite *HttpSuite) TearDownSuite() {
+	auth.SetSessionChecker(nil)
+}
+
+func TestWebhookTestSuite(t *testing.T) {
+	suite.Run(t, new(HttpSuite))
+}
+
+// Registers a user with the given role, and returns a JWT for them
+func (suite *HttpSuite) createUser(email string, role string) string {
+	user := &domain.User{Email: email, Name: "Name", Surname: "Surname", PasswordHash: "hash"}
+	err := suite.userRep.Insert(context.TODO(), user)
+	if err != nil {
+		suite.T().Fatalf("Error creating test user: %s", err)
+	}
+	testutil.SetUserRole(*testApp.DB, user.ID, role)
+	token, err := auth.CreateJWT(user.Email, user.ID, role, user.SessionVersion)
+	if err != nil {
+		suite.T().Fatalf("Error creating test token: %s", err)
+	}
+	return token
+}
+
+func (suite *HttpSuite) TestCreateSubscription() {
+	token := suite.createUser("partner@email.com", domain.RoleAdmin)
+	postData := SubscriptionRequest{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{string(domain.EventOrderCreated)},
+	}
+
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/webhook", postData, &token)
+
+	assert.Equal(suite.T(), http.StatusCreated, responseRec.Code)
+	var created SubscriptionModel
+	err := json.Unmarshal(responseRec.Body.Bytes(), &created)
+	if err != nil {
+		suite.T().Fatalf("Error unmarshalling subscription response: %s", err)
+	}
+	assert.NotEmpty(suite.T(), created.ID)
+	assert.NotEmpty(suite.T(), created.Secret)
+	assert.True(suite.T(), created.Active)
+
+	// the secret isn't returned again
+	responseRec = testutil.MakeRequest(suite.wsContainer, "GET", "/v1/webhook/"+created.ID, nil, &token)
+	var found SubscriptionModel
+	json.Unmarshal(responseRec.Body.Bytes(), &found)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	assert.Equal(suite.T(), postData.URL, found.URL)
+	assert.Empty(suite.T(), found.Secret)
+}
+
+func (suite *HttpSuite) TestCreateSubscriptionWithUnknownEvent() {
+	token := suite.createUser("partner@email.com", domain.RoleAdmin)
+	postData := SubscriptionRequest{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{"order.shipped"},
+	}
+
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/webhook", postData, &token)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code)
+}
+
+func (suite *HttpSuite) TestOtherUsersDeliveriesAreHidden() {
+	token := suite.createUser("partner@email.com", domain.RoleAdmin)
+	otherToken := suite.createUser("other@email.com", domain.RoleAdmin)
+	postData := SubscriptionRequest{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{string(domain.EventOrderCreated)},
+	}
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/webhook", postData, &token)
+	var created SubscriptionModel
+	json.Unmarshal(responseRec.Body.Bytes(), &created)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "GET", "/v1/webhook/"+created.ID+"/deliveries", nil, &otherToken)
+	assert.Equal(suite.T(), http.StatusNotFound, responseRec.Code)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "GET", "/v1/webhook/"+created.ID+"/deliveries", nil, &token)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+}
+
+func (suite *HttpSuite) TestOnlyAdminsSubscribe() {
+	token := suite.createUser("user@email.com", domain.RoleUser)
+	postData := SubscriptionRequest{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{string(domain.EventOrderCreated)},
+	}
+
+	responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/webhook", postData, &token)
+	assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
+
+	responseRec = testutil.MakeRequest(suite.wsContainer, "GET", "/v1/webhook", nil, &token)
+	assert.Equal(suite.T(), http.StatusForbidden, responseRec.Code)
+}
+
+func (suite *HttpSuite) TestInternalURLsAreRefused() {
+	token := suite.createUser("partner@email.com", domain.RoleAdmin)
+
+	for _, url := range []string{"http://127.0.0.1:8080/hooks", "http://10.0.0.5/hooks", "http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hooks", "http://localhost/hooks"} {
+		postData := SubscriptionRequest{URL: url, EventTypes: []string{string(domain.EventOrderCreated)}}
+
+		responseRec := testutil.MakeRequest(suite.wsContainer, "POST", "/v1/webhook", postData, &token)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, responseRec.Code, url)
+	}
+}
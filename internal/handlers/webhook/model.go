@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+)
+
+type SubscriptionModel struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"eventTypes"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	// Only returned once, when the subscription is created
+	Secret string `json:"secret,omitempty"`
+}
+
+type DeliveryModel struct {
+	ID             string          `json:"id"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastStatusCode int             `json:"lastStatusCode"`
+	LastError      string          `json:"lastError"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+}
+
+func (e *SubscriptionModel) FromDomain(subscription *domain.WebhookSubscription) {
+	if e == nil || subscription == nil {
+		return
+	}
+	e.ID = subscription.ID
+	e.URL = subscription.URL
+	e.EventTypes = []string{}
+	for _, t := range subscription.EventTypes {
+		e.EventTypes = append(e.EventTypes, string(t))
+	}
+	e.Active = subscription.Active
+	e.CreatedAt = subscription.CreatedAt
+	e.UpdatedAt = subscription.UpdatedAt
+}
+
+func (e *SubscriptionModel) ToDomain() *domain.WebhookSubscription {
+	if e == nil {
+		return &domain.WebhookSubscription{}
+	}
+	var eventTypes []domain.EventType
+	for _, t := range e.EventTypes {
+		eventTypes = append(eventTypes, domain.EventType(t))
+	}
+	return &domain.WebhookSubscription{
+		ID:         e.ID,
+		URL:        e.URL,
+		EventTypes: eventTypes,
+		Secret:     e.Secret,
+		Active:     e.Active,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+	}
+}
+
+func (e *DeliveryModel) FromDomain(delivery *domain.WebhookDelivery) {
+	if e == nil || delivery == nil {
+		return
+	}
+	e.ID = delivery.ID
+	e.EventID = delivery.EventID
+	e.EventType = string(delivery.EventType)
+	e.Payload = delivery.Payload
+	e.Status = string(delivery.Status)
+	e.Attempts = delivery.Attempts
+	e.NextAttemptAt = delivery.NextAttemptAt
+	e.LastStatusCode = delivery.LastStatusCode
+	e.LastError = delivery.LastError
+	e.CreatedAt = delivery.CreatedAt
+	e.DeliveredAt = delivery.DeliveredAt
+}
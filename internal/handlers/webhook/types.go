@@ -0,0 +1,13 @@
+package webhook
+
+type Response struct {
+	ID   string
+	Name string
+}
+
+type SubscriptionRequest struct {
+	URL        string   `json:"url" validate:"required,url,max=2048"`
+	EventTypes []string `json:"eventTypes" validate:"required,min=1"`
+	// Optional, generated when not provided
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"context"
+	"sync"
+)
+
+type ctxKey int
+
+const (
+	loggerCtxKey ctxKey = iota
+	requestIDCtxKey
+	userCtxKey
+)
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger Logger = &NilLogger{}
+)
+
+// Sets the logger returned by FromContext for the contexts without one, e.g. of the background jobs
+func SetDefault(logger Logger) {
+	if logger == nil {
+		logger = &NilLogger{}
+	}
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = logger
+}
+
+// Returns the logger of the request ctx belongs to, with its request id, route and user attached
+// Outside of a request, the default logger
+func FromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerCtxKey).(Logger); ok {
+		return logger
+	}
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// Returns a copy of ctx carrying the given logger
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey, logger)
+}
+
+// Returns a copy of ctx whose logger adds the given key-value pairs to every message
+func WithFields(ctx context.Context, fields ...interface{}) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(fields...))
+}
+
+// Returns the id of the request ctx belongs to, empty outside of a request
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey).(string)
+	return id
+}
+
+// Attaches the authenticated user to ctx: its id is added to the messages of the logger,
+// and its name shows in the access log line of the request
+func WithUser(ctx context.Context, id string, name string) context.Context {
+	ctx = context.WithValue(ctx, userCtxKey, user{id: id, name: name})
+	return WithFields(ctx, "user_id", id)
+}
+
+// Returns the id and name of the user authenticated for the request ctx belongs to, empty when there is none
+func UserFrom(ctx context.Context) (id string, name string) {
+	u, _ := ctx.Value(userCtxKey).(user)
+	return u.id, u.name
+}
+
+type user struct {
+	id   string
+	name string
+}
@@ -7,6 +7,9 @@ type Logger interface {
 	Warn(msg string, fields ...interface{})
 	Error(msg string, fields ...interface{})
 	Errorf(msg string, fields ...interface{})
+
+	// Returns a logger adding the given key-value pairs to every message
+	With(fields ...interface{}) Logger
 }
 
 type NilLogger struct {
@@ -33,3 +36,7 @@ func (*NilLogger) Error(_ string, _ ...interface{}) {
 
 func (*NilLogger) Errorf(_ string, _ ...interface{}) {
 }
+
+func (l *NilLogger) With(_ ...interface{}) Logger {
+	return l
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	restful "github.com/emicklei/go-restful/v3"
+)
+
+// Header the id of a request is read from, and sent back in
+const RequestIDHeader = "X-Request-ID"
+
+// Longest request id accepted from the callers, longer ones are replaced
+const maxRequestIDLength = 128
+
+// Returns a filter giving every request an id, the one sent by the caller in X-Request-ID if valid
+// The id is sent back in the response, and the logger of the request context carries it with the route,
+// so the messages logged while answering a request can be found together
+func RequestIDFilter(logger Logger) restful.FilterFunction {
+	if logger == nil {
+		logger = &NilLogger{}
+	}
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		id := req.Request.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		resp.AddHeader(RequestIDHeader, id)
+
+		ctx := context.WithValue(req.Request.Context(), requestIDCtxKey, id)
+		ctx = NewContext(ctx, logger.With("request_id", id, "route", req.SelectedRoutePath()))
+		req.Request = req.Request.WithContext(ctx)
+
+		chain.ProcessFilter(req, resp)
+	}
+}
+
+// Only printable ASCII is accepted, so the ids can't forge log lines or headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
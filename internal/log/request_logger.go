@@ -5,35 +5,33 @@ import (
 	"time"
 
 	"github.com/emicklei/go-restful/v3"
-	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
-	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/params"
 )
 
 // Returns a filter function that outputs request logs according to the NCSA standard
-// The passed logger will be used to output the message (this is likely the app logger itself - zap_logger)
+// The lines are written by the logger of the request context when there is one (see RequestIDFilter),
+// so they carry the request id, otherwise by the passed logger (likely the app logger itself - zap_logger)
 func NCSACommonLogFormatLogger(logger Logger) restful.FilterFunction {
+	if logger == nil {
+		logger = &NilLogger{}
+	}
 	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		chain.ProcessFilter(req, resp)
 
-		// sanity check: nil during tests
-		if logger == nil {
-			return
-		}
-
+		// the user making the request is known once it has been authenticated
 		var username = "-"
-
-		// extract user making the request from the context, if possible
-		reqId, err := params.StringFrom(req.Request, auth.USER_EMAIL_CTX_KEY)
-		if reqId != "" && err == nil {
-			username = reqId
+		if _, name := UserFrom(req.Request.Context()); name != "" {
+			username = name
 		}
 
-		chain.ProcessFilter(req, resp)
-
-		logger.Infof("%s - %s [%s] \"%s %s %s\" %d %d",
+		reqLogger := logger
+		if RequestIDFrom(req.Request.Context()) != "" {
+			reqLogger = FromContext(req.Request.Context())
+		}
+		reqLogger.Infof("%s - %s [%s] \"%s %s %s\" %d %d",
 			strings.Split(req.Request.RemoteAddr, ":")[0],
 			username,
 			time.Now().Format("02/Jan/2006:15:04:05 -0700"),
-			"req.Request.Method",
+			req.Request.Method,
 			req.Request.URL.RequestURI(),
 			req.Request.Proto,
 			resp.StatusCode(),
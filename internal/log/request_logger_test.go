@@ -0,0 +1,117 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// Logger keeping the lines written, with the fields they carry
+type recordingLogger struct {
+	mu     *sync.Mutex
+	lines  *[]string
+	fields []interface{}
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{mu: &sync.Mutex{}, lines: &[]string{}}
+}
+
+func (l *recordingLogger) record(msg string, fields []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.lines = append(*l.lines, fmt.Sprint(msg, append(append([]interface{}{}, l.fields...), fields...)))
+}
+
+func (l *recordingLogger) Debug(msg string, fields ...interface{}) { l.record(msg, fields) }
+func (l *recordingLogger) Info(msg string, fields ...interface{})  { l.record(msg, fields) }
+func (l *recordingLogger) Warn(msg string, fields ...interface{})  { l.record(msg, fields) }
+func (l *recordingLogger) Error(msg string, fields ...interface{}) { l.record(msg, fields) }
+func (l *recordingLogger) Infof(msg string, fields ...interface{}) {
+	l.record(fmt.Sprintf(msg, fields...), nil)
+}
+func (l *recordingLogger) Errorf(msg string, fields ...interface{}) {
+	l.record(fmt.Sprintf(msg, fields...), nil)
+}
+func (l *recordingLogger) With(fields ...interface{}) Logger {
+	return &recordingLogger{mu: l.mu, lines: l.lines, fields: append(append([]interface{}{}, l.fields...), fields...)}
+}
+
+type RequestLoggerSuite struct {
+	suite.Suite
+	logger      *recordingLogger
+	wsContainer *restful.Container
+}
+
+func (suite *RequestLoggerSuite) SetupTest() {
+	suite.logger = newRecordingLogger()
+	suite.wsContainer = restful.NewContainer()
+	suite.wsContainer.Filter(RequestIDFilter(suite.logger))
+	suite.wsContainer.Filter(NCSACommonLogFormatLogger(suite.logger))
+	ws := new(restful.WebService)
+	ws.Path("/item")
+	ws.Route(ws.GET("/{id}").Filter(func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		// as done by the authentication filters
+		req.Request = req.Request.WithContext(WithUser(req.Request.Context(), "user-1", "testy@email.com"))
+		chain.ProcessFilter(req, resp)
+	}).To(func(req *restful.Request, resp *restful.Response) {
+		FromContext(req.Request.Context()).Info("item read")
+		resp.WriteHeader(http.StatusTeapot)
+	}))
+	suite.wsContainer.Add(ws)
+}
+
+func TestRequestLoggerTestSuite(t *testing.T) {
+	suite.Run(t, new(RequestLoggerSuite))
+}
+
+func (suite *RequestLoggerSuite) serve(requestID string) *httptest.ResponseRecorder {
+	httpRequest, _ := http.NewRequest("GET", "/item/42", nil)
+	if requestID != "" {
+		httpRequest.Header.Set(RequestIDHeader, requestID)
+	}
+	responseRec := httptest.NewRecorder()
+	suite.wsContainer.ServeHTTP(responseRec, httpRequest)
+	return responseRec
+}
+
+func (suite *RequestLoggerSuite) TestTheRequestIdOfTheCallerIsKept() {
+	responseRec := suite.serve("caller-id-1")
+
+	assert.Equal(suite.T(), http.StatusTeapot, responseRec.Code)
+	assert.Equal(suite.T(), "caller-id-1", responseRec.Header().Get(RequestIDHeader))
+	if assert.Len(suite.T(), *suite.logger.lines, 2) {
+		// the handler's message carries the request, route and user
+		assert.Equal(suite.T(), "item read[request_id caller-id-1 route /item/{id} user_id user-1]", (*suite.logger.lines)[0])
+		// then the access log line, with the method and user
+		access := (*suite.logger.lines)[1]
+		assert.Contains(suite.T(), access, ` - testy@email.com [`)
+		assert.Contains(suite.T(), access, `"GET /item/42 HTTP/1.1" 418`)
+		assert.True(suite.T(), strings.HasSuffix(access, "[request_id caller-id-1 route /item/{id} user_id user-1]"), access)
+	}
+}
+
+func (suite *RequestLoggerSuite) TestInvalidRequestIdsAreReplaced() {
+	for _, id := range []string{"", "has space", "new\nline", strings.Repeat("a", maxRequestIDLength+1)} {
+		responseRec := suite.serve(id)
+		generated := responseRec.Header().Get(RequestIDHeader)
+		assert.Len(suite.T(), generated, 32, id)
+		assert.NotEqual(suite.T(), id, generated)
+	}
+}
+
+func (suite *RequestLoggerSuite) TestOutsideOfARequestTheDefaultLoggerIsUsed() {
+	SetDefault(suite.logger)
+	defer SetDefault(nil)
+
+	httpRequest, _ := http.NewRequest("GET", "/", nil)
+	assert.Same(suite.T(), suite.logger, FromContext(httpRequest.Context()))
+	assert.Equal(suite.T(), "", RequestIDFrom(httpRequest.Context()))
+}
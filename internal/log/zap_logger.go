@@ -1,6 +1,7 @@
 package log
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/projectpath"
@@ -13,12 +14,22 @@ const (
 	LogFileRelativePath string = "/logs/out.log"
 )
 
+// Formats of the log lines
+const (
+	// One JSON object per line, for the log collectors
+	FormatJSON string = "json"
+	// Colored, human readable lines, for local runs
+	FormatConsole string = "console"
+)
+
 type ZapLogger struct {
 	zlog *zap.SugaredLogger
 }
 
-func NewLogger(pretty bool) (*ZapLogger, error) {
-	zlog, err := newZapLogger(pretty)
+// Creates the app logger, writing messages of the given level and above (debug, info, warn or error)
+// in the given format
+func NewLogger(level string, format string) (*ZapLogger, error) {
+	zlog, err := newZapLogger(level, format)
 	if err != nil {
 		return nil, errors.Wrap(err, "new zap logger")
 	}
@@ -29,10 +40,23 @@ func NewLogger(pretty bool) (*ZapLogger, error) {
 	}, nil
 }
 
-func newZapLogger(pretty bool) (*zap.Logger, error) {
-	cfg := zap.NewProductionConfig()
-	if pretty {
+func newZapLogger(level string, format string) (*zap.Logger, error) {
+	var cfg zap.Config
+	switch format {
+	case FormatJSON:
+		cfg = zap.NewProductionConfig()
+	case FormatConsole:
 		cfg = zap.NewDevelopmentConfig()
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected %s or %s", format, FormatJSON, FormatConsole)
+	}
+
+	if level != "" {
+		lvl, err := zap.ParseAtomicLevel(level)
+		if err != nil {
+			return nil, errors.Wrap(err, "log level")
+		}
+		cfg.Level = lvl
 	}
 
 	// Disable sampling of log messages until needed.
@@ -77,3 +101,7 @@ func (log *ZapLogger) Error(msg string, fields ...interface{}) {
 func (log *ZapLogger) Errorf(msg string, fields ...interface{}) {
 	log.zlog.Errorf(msg, fields...)
 }
+
+func (log *ZapLogger) With(fields ...interface{}) Logger {
+	return &ZapLogger{zlog: log.zlog.With(fields...)}
+}
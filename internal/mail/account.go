@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+)
+
+var _ ports.UserNotifier = (*AccountNotifier)(nil)
+
+// Data the account templates are rendered with
+type TokenData struct {
+	User *domain.User
+	// Link carrying the token, for the user to follow
+	Link string
+}
+
+// AccountNotifier emails the users links for managing their account
+type AccountNotifier struct {
+	mailer    ports.Mailer
+	templates *Templates
+	baseURL   string
+}
+
+func NewAccountNotifier(mailer ports.Mailer, templates *Templates, baseURL string) *AccountNotifier {
+	return &AccountNotifier{
+		mailer:    mailer,
+		templates: templates,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (n *AccountNotifier) SendPasswordReset(ctx context.Context, user *domain.User, token string) error {
+	return n.send(ctx, user, "password_reset", "/reset-password", token)
+}
+
+func (n *AccountNotifier) SendEmailVerification(ctx context.Context, user *domain.User, token string) error {
+	return n.send(ctx, user, "email_verification", "/verify-email", token)
+}
+
+func (n *AccountNotifier) send(ctx context.Context, user *domain.User, template string, path string, token string) error {
+	link := n.baseURL + path + "?" + url.Values{"token": {token}}.Encode()
+	email, err := n.templates.Render(template, user.Locale, TokenData{User: user, Link: link})
+	if err != nil {
+		return err
+	}
+	email.To = []string{user.Email}
+	return n.mailer.Send(ctx, email)
+}
@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountEmailsCarryTheLink(t *testing.T) {
+	templates, err := NewTemplates(domain.DefaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := NewMemoryMailer()
+	notifier := NewAccountNotifier(mailer, templates, "https://shop.example.com/")
+	user := &domain.User{Email: "testy@email.com", Name: "Testy", Locale: "sr"}
+
+	assert.Nil(t, notifier.SendPasswordReset(context.TODO(), user, "reset-token"))
+	assert.Nil(t, notifier.SendEmailVerification(context.TODO(), user, "verify-token"))
+
+	sent := mailer.Sent()
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, []string{user.Email}, sent[0].To)
+		assert.Contains(t, sent[0].Text, "https://shop.example.com/reset-password?token=reset-token")
+		assert.Contains(t, sent[0].HTML, "https://shop.example.com/reset-password?token=reset-token")
+		assert.Contains(t, sent[1].Text, "https://shop.example.com/verify-email?token=verify-token")
+	}
+}
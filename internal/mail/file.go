@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/pkg/errors"
+)
+
+var _ ports.Mailer = (*FileMailer)(nil)
+
+var unsafeFilenameChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]+`)
+
+// FileMailer writes the emails as .eml files, which can be opened by any mail client
+// Meant for local runs, where no SMTP server is available
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (m *FileMailer) Send(_ context.Context, email *domain.Email) error {
+	now := time.Now()
+	msg, err := buildMessage(m.from, email, now)
+	if err != nil {
+		return errors.Wrap(err, "build message")
+	}
+	err = os.MkdirAll(m.dir, 0755)
+	if err != nil {
+		return errors.Wrap(err, "create mail dir")
+	}
+	name := fmt.Sprintf("%s_%s.eml", now.Format("20060102T150405.000000"), unsafeFilenameChars.ReplaceAllString(strings.Join(email.To, "_"), "-"))
+	err = ioutil.WriteFile(filepath.Join(m.dir, name), msg, 0644)
+	if err != nil {
+		return errors.Wrap(err, "write mail")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"fmt"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+)
+
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// Directory the file driver writes to, when none is configured
+const DefaultDir = "logs/mail"
+
+// Creates the mailer chosen by the config, the file mailer when none is
+func NewMailer(cfg config.MailConfig) (ports.Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case DriverFile, "":
+		dir := cfg.Dir
+		if len(dir) == 0 {
+			dir = DefaultDir
+		}
+		return NewFileMailer(dir, cfg.From), nil
+	case DriverMemory:
+		return NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+}
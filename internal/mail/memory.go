@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+)
+
+var _ ports.Mailer = (*MemoryMailer)(nil)
+
+// MemoryMailer keeps the sent emails, so tests can check them
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []domain.Email
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(_ context.Context, email *domain.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, *email)
+	return nil
+}
+
+// Returns the emails sent so far
+func (m *MemoryMailer) Sent() []domain.Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]domain.Email{}, m.sent...)
+}
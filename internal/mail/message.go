@@ -0,0 +1,106 @@
+// Adapters sending the transactional emails, and the templates they are rendered from
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+)
+
+// Builds the MIME message for the email:
+// the text and HTML bodies as alternatives, wrapped together with the attachments when there are any
+func buildMessage(from string, email *domain.Email, date time.Time) ([]byte, error) {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(email.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+
+	var body bytes.Buffer
+	alternative := multipart.NewWriter(&body)
+	err := writeTextPart(alternative, "text/plain", email.Text)
+	if err != nil {
+		return nil, err
+	}
+	if len(email.HTML) > 0 {
+		err = writeTextPart(alternative, "text/html", email.HTML)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = alternative.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(email.Attachments) == 0 {
+		fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", alternative.Boundary())
+		msg.Write(body.Bytes())
+		return msg.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mixed.Boundary())
+	part, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alternative.Boundary()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	part.Write(body.Bytes())
+	for _, attachment := range email.Attachments {
+		err = writeAttachment(mixed, attachment)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = mixed.Close()
+	if err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+func writeTextPart(w *multipart.Writer, contentType string, text string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	_, err = qp.Write([]byte(text))
+	if err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeAttachment(w *multipart.Writer, attachment domain.Attachment) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {attachment.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+	})
+	if err != nil {
+		return err
+	}
+	// base64 lines must not be longer than 76 characters
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		part.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	_, err = part.Write([]byte(encoded + "\r\n"))
+	return err
+}
@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+)
+
+// Data the templates are rendered with
+type UserData struct {
+	User *domain.User
+}
+
+type OrderData struct {
+	User      *domain.User
+	Order     *domain.Order
+	OldStatus string
+}
+
+// Notifier emails the users about the events concerning them, it is meant to be subscribed to the event bus
+// An email which can't be rendered is logged and skipped, as retrying wouldn't help; the mailer failing, or its queue
+// being full, makes the event be retried for this subscriber only
+type Notifier struct {
+	mailer    ports.Mailer
+	templates *Templates
+	userSvc   ports.UserUsecase
+	orderSvc  ports.OrderUsecase
+	logger    log.Logger
+}
+
+func NewNotifier(mailer ports.Mailer, templates *Templates, userSvc ports.UserUsecase, orderSvc ports.OrderUsecase, logger log.Logger) *Notifier {
+	return &Notifier{
+		mailer:    mailer,
+		templates: templates,
+		userSvc:   userSvc,
+		orderSvc:  orderSvc,
+		logger:    logger,
+	}
+}
+
+// Welcomes the registered user
+func (n *Notifier) UserRegistered(ctx context.Context, event *domain.Event) error {
+	var payload domain.UserRegisteredPayload
+	err := event.DecodePayload(&payload)
+	if err != nil {
+		return n.skip(event, err)
+	}
+	user, err := n.userSvc.FindByID(ctx, payload.UserID)
+	if err != nil {
+		return n.skip(event, err)
+	}
+	return n.send(ctx, event, user, "welcome", UserData{User: user})
+}
+
+// Confirms the order, with the invoice attached
+func (n *Notifier) OrderCreated(ctx context.Context, event *domain.Event) error {
+	var payload domain.OrderCreatedPayload
+	err := event.DecodePayload(&payload)
+	if err != nil {
+		return n.skip(event, err)
+	}
+	order, err := n.orderSvc.FindOrderById(ctx, payload.OrderID)
+	if err != nil {
+		return n.skip(event, err)
+	}
+	invoice, err := n.orderSvc.InvoicePdf(ctx, order)
+	if err != nil {
+		return n.skip(event, err)
+	}
+	return n.send(ctx, event, order.User, "order_confirmation", OrderData{User: order.User, Order: order}, domain.Attachment{
+		Filename:    "invoice_" + order.ID + ".pdf",
+		ContentType: "application/pdf",
+		Data:        invoice,
+	})
+}
+
+// Lets the user know the order moved on
+func (n *Notifier) OrderStatusChanged(ctx context.Context, event *domain.Event) error {
+	var payload domain.OrderStatusChangedPayload
+	err := event.DecodePayload(&payload)
+	if err != nil {
+		return n.skip(event, err)
+	}
+	order, err := n.orderSvc.FindOrderById(ctx, payload.OrderID)
+	if err != nil {
+		return n.skip(event, err)
+	}
+	// the order may have changed again since, the email describes the change of this event
+	order.Status = payload.Status
+	return n.send(ctx, event, order.User, "order_status_changed", OrderData{User: order.User, Order: order, OldStatus: payload.OldStatus})
+}
+
+func (n *Notifier) send(ctx context.Context, event *domain.Event, user *domain.User, template string, data interface{}, attachments ...domain.Attachment) error {
+	if user == nil {
+		n.logger.Warn("no recipient for notification", "event", event.ID, "type", event.Type)
+		return nil
+	}
+	email, err := n.templates.Render(template, user.Locale, data)
+	if err != nil {
+		return n.skip(event, err)
+	}
+	email.To = []string{user.Email}
+	email.Attachments = attachments
+	return n.mailer.Send(ctx, email)
+}
+
+func (n *Notifier) skip(event *domain.Event, err error) error {
+	n.logger.Error("error preparing notification, skipping it", "event", event.ID, "type", event.Type, "err", err)
+	return nil
+}
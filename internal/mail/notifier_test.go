@@ -0,0 +1,138 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// the usecases the notifier depends on, serving fixed data
+type fakeUserSvc struct {
+	ports.UserUsecase
+	user *domain.User
+}
+
+func (f *fakeUserSvc) FindByID(_ context.Context, id string) (*domain.User, error) {
+	return f.user, nil
+}
+
+type fakeOrderSvc struct {
+	ports.OrderUsecase
+	order *domain.Order
+}
+
+func (f *fakeOrderSvc) FindOrderById(_ context.Context, id string) (*domain.Order, error) {
+	order := *f.order
+	return &order, nil
+}
+
+func (f *fakeOrderSvc) InvoicePdf(_ context.Context, order *domain.Order) ([]byte, error) {
+	return []byte("%PDF-invoice " + order.ID), nil
+}
+
+type NotifierSuite struct {
+	suite.Suite
+	mailer   *MemoryMailer
+	queue    *Queue
+	notifier *Notifier
+	user     *domain.User
+}
+
+func (suite *NotifierSuite) SetupTest() {
+	templates, err := NewTemplates("en")
+	if err != nil {
+		suite.T().Fatalf("Error loading templates: %s", err)
+	}
+	suite.user = &domain.User{ID: "user-1", Email: "pera@email.com", Name: "Pera", Locale: "sr"}
+	order := &domain.Order{ID: "order-1", Status: "CREATED", User: suite.user,
+		ProductItems: &[]domain.OrderedProduct{{ProductId: 7, Quantity: 2}}}
+
+	suite.mailer = NewMemoryMailer()
+	suite.queue = NewQueue(suite.mailer, 10, log.NewNilLogger())
+	suite.notifier = NewNotifier(suite.queue, templates, &fakeUserSvc{user: suite.user}, &fakeOrderSvc{order: order}, log.NewNilLogger())
+}
+
+func TestNotifierTestSuite(t *testing.T) {
+	suite.Run(t, new(NotifierSuite))
+}
+
+func (suite *NotifierSuite) TestOrderConfirmationHasInvoice() {
+	event, _ := domain.NewEvent(domain.EventOrderCreated, "order-1", domain.OrderCreatedPayload{OrderID: "order-1"})
+
+	suite.queue.Start()
+	err := suite.notifier.OrderCreated(context.TODO(), event)
+	suite.queue.Stop()
+
+	assert.Nil(suite.T(), err)
+	sent := suite.mailer.Sent()
+	if assert.Len(suite.T(), sent, 1) {
+		assert.Equal(suite.T(), []string{"pera@email.com"}, sent[0].To)
+		// in the user's locale
+		assert.Contains(suite.T(), sent[0].Subject, "Porudžbina order-1")
+		if assert.Len(suite.T(), sent[0].Attachments, 1) {
+			assert.Equal(suite.T(), "application/pdf", sent[0].Attachments[0].ContentType)
+			assert.Equal(suite.T(), "%PDF-invoice order-1", string(sent[0].Attachments[0].Data))
+		}
+	}
+}
+
+func (suite *NotifierSuite) TestFullQueueFailsTheEvent() {
+	// never started, so nothing is taken out of the queue
+	queue := NewQueue(suite.mailer, 1, log.NewNilLogger())
+	suite.notifier.mailer = queue
+	event, _ := domain.NewEvent(domain.EventUserRegistered, "user-1", domain.UserRegisteredPayload{UserID: "user-1"})
+
+	assert.Nil(suite.T(), suite.notifier.UserRegistered(context.TODO(), event))
+	// the event is retried by the relay once the queue has room
+	assert.ErrorIs(suite.T(), suite.notifier.UserRegistered(context.TODO(), event), ErrQueueFull)
+	assert.Len(suite.T(), queue.emails, 1)
+}
+
+func (suite *NotifierSuite) TestMessageIsValidMime() {
+	email := &domain.Email{
+		To:      []string{"pera@email.com"},
+		Subject: "Račun",
+		Text:    "text body",
+		HTML:    "<p>html body</p>",
+		Attachments: []domain.Attachment{
+			{Filename: "invoice.pdf", ContentType: "application/pdf", Data: bytes.Repeat([]byte("pdf"), 100)},
+		},
+	}
+
+	msg, err := buildMessage("Shop <shop@email.com>", email, time.Now())
+	assert.Nil(suite.T(), err)
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		suite.T().Fatalf("Error parsing message: %s", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	assert.Equal(suite.T(), "Račun", subject)
+
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.Equal(suite.T(), "multipart/mixed", mediaType)
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+
+	body, err := reader.NextPart()
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), strings.HasPrefix(body.Header.Get("Content-Type"), "multipart/alternative"))
+
+	attachment, err := reader.NextPart()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "invoice.pdf", attachment.FileName())
+	// the multipart reader doesn't decode base64
+	encoded, _ := ioutil.ReadAll(attachment)
+	assert.NotEmpty(suite.T(), encoded)
+}
@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+)
+
+var _ ports.Mailer = (*Queue)(nil)
+
+// Returned when an email is sent while the queue is full
+var ErrQueueFull = errors.New("mail queue is full")
+
+// Queue size used when none is configured
+const DefaultQueueSize = 100
+
+// How many times an email is tried, and the delay before the first retry (doubled on every following one)
+const (
+	sendAttempts = 3
+	retryDelay   = time.Second
+)
+
+// Queue is a mailer which accepts emails right away, and sends them in the background through the wrapped mailer
+// Queued emails live in memory only: they are sent before Stop returns, but are lost if the process dies
+type Queue struct {
+	mailer ports.Mailer
+	emails chan domain.Email
+	logger log.Logger
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewQueue(mailer ports.Mailer, size int, logger log.Logger) *Queue {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	return &Queue{
+		mailer: mailer,
+		emails: make(chan domain.Email, size),
+		logger: logger,
+	}
+}
+
+// Queues the email, returns ErrQueueFull instead of waiting for room in the queue
+func (q *Queue) Send(_ context.Context, email *domain.Email) error {
+	select {
+	case q.emails <- *email:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Starts sending the queued emails, until Stop is called
+func (q *Queue) Start() {
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+	go func() {
+		defer close(q.done)
+		for {
+			select {
+			case <-q.stop:
+				q.drain()
+				return
+			case email := <-q.emails:
+				q.send(&email)
+			}
+		}
+	}()
+}
+
+// Stops the queue, after sending the emails still in it
+func (q *Queue) Stop() {
+	if q.stop == nil {
+		return
+	}
+	close(q.stop)
+	<-q.done
+}
+
+func (q *Queue) drain() {
+	for {
+		select {
+		case email := <-q.emails:
+			q.send(&email)
+		default:
+			return
+		}
+	}
+}
+
+func (q *Queue) send(email *domain.Email) {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := q.mailer.Send(context.Background(), email)
+		if err == nil {
+			return
+		}
+		if attempt == sendAttempts {
+			q.logger.Error("error sending email", "to", email.To, "subject", email.Subject, "err", err)
+			return
+		}
+		q.logger.Warn("error sending email, retrying", "to", email.To, "attempt", attempt, "err", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
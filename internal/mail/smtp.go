@@ -0,0 +1,140 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/pkg/errors"
+)
+
+var _ ports.Mailer = (*SMTPMailer)(nil)
+
+// How long the SMTP server has to take an email when no timeout is configured
+const DefaultTimeout = 30 * time.Second
+
+// SMTPMailer sends emails through an SMTP server
+// Authentication is only used when a user is configured, STARTTLS whenever the server supports it
+type SMTPMailer struct {
+	host    string
+	addr    string
+	auth    smtp.Auth
+	from    string
+	timeout time.Duration
+}
+
+func NewSMTPMailer(cfg config.MailConfig) *SMTPMailer {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	mailer := &SMTPMailer{
+		host:    cfg.Host,
+		addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		from:    cfg.From,
+		timeout: cfg.Timeout,
+	}
+	if len(cfg.User) > 0 {
+		mailer.auth = smtp.PlainAuth("", cfg.User, cfg.Pass, cfg.Host)
+	}
+	return mailer
+}
+
+// Checks the SMTP server accepts connections, a health check
+func (m *SMTPMailer) Ping(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return errors.Wrap(err, "dial smtp server")
+	}
+	return conn.Close()
+}
+
+// Sends the email over a connection of its own, which is given up on once the timeout (or the ctx deadline) passes,
+// so a server which stops responding can't hold up the queue
+func (m *SMTPMailer) Send(ctx context.Context, email *domain.Email) error {
+	msg, err := buildMessage(m.from, email, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "build message")
+	}
+	// the envelope needs the bare addresses, without the display names
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return errors.Wrap(err, "parse sender address")
+	}
+
+	dialer := net.Dialer{Timeout: m.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return errors.Wrap(err, "dial smtp server")
+	}
+	deadline := time.Now().Add(m.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "set smtp deadline")
+	}
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "start smtp session")
+	}
+	defer client.Close()
+
+	err = m.send(client, from.Address, email.To, msg)
+	if err != nil {
+		return errors.Wrap(err, "send mail")
+	}
+	return nil
+}
+
+// Same steps as smtp.SendMail, over the given client
+func (m *SMTPMailer) send(client *smtp.Client, from string, to []string, msg []byte) error {
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err := client.StartTLS(&tls.Config{ServerName: m.host})
+		if err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server doesn't support AUTH")
+		}
+		err := client.Auth(m.auth)
+		if err != nil {
+			return err
+		}
+	}
+	err := client.Mail(from)
+	if err != nil {
+		return err
+	}
+	for _, addr := range to {
+		err = client.Rcpt(addr)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
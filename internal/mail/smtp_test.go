@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStalledServerTimesOut(t *testing.T) {
+	// accepts the connections, but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	mailer := NewSMTPMailer(config.MailConfig{
+		From: "Shop <no-reply@example.com>", Host: "127.0.0.1", Port: addr.Port, Timeout: 100 * time.Millisecond,
+	})
+
+	start := time.Now()
+	err = mailer.Send(context.TODO(), &domain.Email{To: []string{"testy@email.com"}, Subject: "Hi", Text: "Hi"})
+
+	assert.NotNil(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,102 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+)
+
+// Each email has a text template in templates/<locale>/<name>.txt, which also defines its "subject",
+// and optionally an HTML template next to it, in <name>.html
+
+//go:embed templates
+var templateFS embed.FS
+
+// Templates renders emails in the recipient's locale, falling back to the default locale
+type Templates struct {
+	defaultLocale string
+	text          map[string]*texttemplate.Template
+	html          map[string]*htmltemplate.Template
+}
+
+func NewTemplates(defaultLocale string) (*Templates, error) {
+	if len(defaultLocale) == 0 {
+		defaultLocale = domain.DefaultLocale
+	}
+	t := &Templates{
+		defaultLocale: defaultLocale,
+		text:          map[string]*texttemplate.Template{},
+		html:          map[string]*htmltemplate.Template{},
+	}
+	err := fs.WalkDir(templateFS, "templates", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		locale := path.Base(path.Dir(file))
+		name := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		switch path.Ext(file) {
+		case ".txt":
+			tmpl, err := texttemplate.ParseFS(templateFS, file)
+			if err != nil {
+				return err
+			}
+			if tmpl.Lookup("subject") == nil {
+				return fmt.Errorf("template %s doesn't define a subject", file)
+			}
+			t.text[locale+"/"+name] = tmpl
+		case ".html":
+			tmpl, err := htmltemplate.ParseFS(templateFS, file)
+			if err != nil {
+				return err
+			}
+			t.html[locale+"/"+name] = tmpl
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Renders the named email for the locale, the recipients are left to the caller
+func (t *Templates) Render(name string, locale string, data interface{}) (*domain.Email, error) {
+	if _, ok := t.text[locale+"/"+name]; !ok {
+		locale = t.defaultLocale
+	}
+	text, ok := t.text[locale+"/"+name]
+	if !ok {
+		return nil, fmt.Errorf("no %s email template", name)
+	}
+
+	var subject, body bytes.Buffer
+	err := text.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return nil, err
+	}
+	err = text.Execute(&body, data)
+	if err != nil {
+		return nil, err
+	}
+	email := &domain.Email{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+	}
+
+	if html, ok := t.html[locale+"/"+name]; ok {
+		var htmlBody bytes.Buffer
+		err = html.Execute(&htmlBody, data)
+		if err != nil {
+			return nil, err
+		}
+		email.HTML = htmlBody.String()
+	}
+	return email, nil
+}
@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TemplatesSuite struct {
+	suite.Suite
+	templates *Templates
+}
+
+func (suite *TemplatesSuite) SetupSuite() {
+	templates, err := NewTemplates("en")
+	if err != nil {
+		suite.T().Fatalf("Error loading templates: %s", err)
+	}
+	suite.templates = templates
+}
+
+func TestTemplatesTestSuite(t *testing.T) {
+	suite.Run(t, new(TemplatesSuite))
+}
+
+func (suite *TemplatesSuite) TestRenderLocale() {
+	data := UserData{User: &domain.User{Name: "Pera", Email: "pera@email.com"}}
+
+	en, err := suite.templates.Render("welcome", "en", data)
+	assert.Nil(suite.T(), err)
+	sr, err := suite.templates.Render("welcome", "sr", data)
+	assert.Nil(suite.T(), err)
+
+	assert.Equal(suite.T(), "Welcome, Pera!", en.Subject)
+	assert.Equal(suite.T(), "Dobrodošli, Pera!", sr.Subject)
+	assert.Contains(suite.T(), en.Text, "pera@email.com")
+	assert.Contains(suite.T(), en.HTML, "<strong>pera@email.com</strong>")
+}
+
+func (suite *TemplatesSuite) TestRenderFallsBackToDefaultLocale() {
+	data := UserData{User: &domain.User{Name: "Pera"}}
+
+	email, err := suite.templates.Render("welcome", "de", data)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Welcome, Pera!", email.Subject)
+}
+
+func (suite *TemplatesSuite) TestHTMLIsEscaped() {
+	data := UserData{User: &domain.User{Name: "<script>"}}
+
+	email, err := suite.templates.Render("welcome", "en", data)
+
+	assert.Nil(suite.T(), err)
+	assert.NotContains(suite.T(), email.HTML, "<script>")
+}
+
+func (suite *TemplatesSuite) TestAllTemplatesRender() {
+	data := OrderData{
+		User:  &domain.User{Name: "Pera"},
+		Order: &domain.Order{ID: "order-1", Status: "PENDING", ProductItems: &[]domain.OrderedProduct{{ProductId: 1, Quantity: 2}}},
+	}
+	for _, locale := range domain.Locales {
+		for _, name := range []string{"welcome", "order_confirmation", "order_status_changed"} {
+			email, err := suite.templates.Render(name, locale, data)
+			assert.Nil(suite.T(), err, locale+"/"+name)
+			if err == nil {
+				assert.NotEmpty(suite.T(), email.Subject, locale+"/"+name)
+				assert.NotEmpty(suite.T(), email.HTML, locale+"/"+name)
+			}
+		}
+	}
+}
+
+func (suite *TemplatesSuite) TestUnknownTemplate() {
+	_, err := suite.templates.Render("newsletter", "en", nil)
+
+	assert.NotNil(suite.T(), err)
+}
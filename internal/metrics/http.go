@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Route label of the requests restful couldn't route, so unknown paths don't each add a series
+const unmatchedRoute = "unmatched"
+
+// Counts the requests and measures their duration, by the path of the route they matched
+// e.g. /v1/product/{id} rather than /v1/product/42
+func RequestFilter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	start := time.Now()
+	completed := false
+	defer func() {
+		status := resp.StatusCode()
+		if !completed {
+			// the panic is answered with 500 by the recover handler, once it went up the chain
+			status = http.StatusInternalServerError
+		}
+		route := req.SelectedRoutePath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		labels := []string{req.Request.Method, route, strconv.Itoa(status)}
+		HTTPRequests.WithLabelValues(labels...).Inc()
+		HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	}()
+	chain.ProcessFilter(req, resp)
+	completed = true
+}
+
+type MetricsHandler struct {
+	handler http.Handler
+}
+
+// Serves the metrics of the registry at /metrics, for Prometheus to scrape
+func NewMetricsHandler(registry prometheus.Gatherer, wsCont *restful.Container) *MetricsHandler {
+	httpHandler := &MetricsHandler{handler: promhttp.HandlerFor(registry, promhttp.HandlerOpts{})}
+
+	// separate service, as the root path "/" is taken
+	ws := new(restful.WebService)
+	ws.Path("/metrics").Produces("text/plain")
+	ws.Route(ws.GET("").To(httpHandler.GetMetrics).
+		Doc("Metrics of the server, in the Prometheus text format").
+		Returns(http.StatusOK, "OK", nil))
+	wsCont.Add(ws)
+
+	return httpHandler
+}
+
+func (e *MetricsHandler) GetMetrics(req *restful.Request, resp *restful.Response) {
+	e.handler.ServeHTTP(resp, req.Request)
+}
@@ -0,0 +1,95 @@
+// Package metrics holds every metric the server exposes at /metrics, in the Prometheus text format
+// New metrics are declared here, so their names stay consistent and are found in one place
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Prefix of the names of all the metrics
+const namespace = "hexfwk"
+
+// Registry served at /metrics, the metrics below are registered in it, with those of the Go runtime and the process
+var Default = prometheus.NewRegistry()
+
+// HTTP
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: "http_requests_total",
+		Help: "Requests answered, by route and status."}, []string{"method", "route", "status"})
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: namespace, Name: "http_request_duration_seconds",
+		Help: "Time taken to answer the requests, by route and status.", Buckets: prometheus.DefBuckets}, []string{"method", "route", "status"})
+)
+
+// Database
+var (
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: namespace, Name: "db_query_duration_seconds",
+		Help: "Time taken by the database queries, by operation and whether they failed.", Buckets: prometheus.DefBuckets}, []string{"operation", "outcome"})
+)
+
+// Cache
+var (
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: "cache_requests_total",
+		Help: "Cache lookups, by cache and whether the value was found."}, []string{"cache", "result"})
+)
+
+// Results of the cache lookups
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// Business
+// OrdersCreated and OrdersOutOfStock are counted by the handler answering the request, once the order is committed.
+// StockOuts and EventsPublished are counted from the events, which the outbox delivers at least once:
+// an event redelivered after its delivery couldn't be recorded is counted again
+var (
+	OrdersCreated = prometheus.NewCounter(prometheus.CounterOpts{Namespace: namespace, Name: "orders_created_total",
+		Help: "Orders created."})
+	StockOuts = prometheus.NewCounter(prometheus.CounterOpts{Namespace: namespace, Name: "stock_outs_total",
+		Help: "Times a product ran out of stock."})
+	OrdersOutOfStock = prometheus.NewCounter(prometheus.CounterOpts{Namespace: namespace, Name: "orders_out_of_stock_total",
+		Help: "Orders refused because too few items of a product were in stock."})
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: "login_failures_total",
+		Help: "Failed logins, by reason."}, []string{"reason"})
+	EventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: "events_published_total",
+		Help: "Domain events relayed from the outbox, by type."}, []string{"type"})
+)
+
+// Reasons of the failed logins
+const (
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginInvalidCode        = "invalid_code"
+	LoginBlocked            = "blocked"
+)
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPRequestDuration,
+		DBQueryDuration,
+		CacheRequests,
+		OrdersCreated, StockOuts, OrdersOutOfStock, LoginFailures, EventsPublished,
+	)
+}
+
+// Gauges of the connection pool, read from stats when the metrics are scraped
+func DBPoolCollectors(stats func() sql.DBStats) []prometheus.Collector {
+	return []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: namespace, Name: "db_max_open_connections", Help: "Maximum number of open connections of the pool."},
+			func() float64 { return float64(stats().MaxOpenConnections) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: namespace, Name: "db_open_connections", Help: "Connections open, in use or idle."},
+			func() float64 { return float64(stats().OpenConnections) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: namespace, Name: "db_in_use_connections", Help: "Connections in use."},
+			func() float64 { return float64(stats().InUse) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: namespace, Name: "db_idle_connections", Help: "Idle connections."},
+			func() float64 { return float64(stats().Idle) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{Namespace: namespace, Name: "db_wait_count_total", Help: "Times a query waited for a connection."},
+			func() float64 { return float64(stats().WaitCount) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{Namespace: namespace, Name: "db_wait_duration_seconds_total", Help: "Time spent waiting for a connection."},
+			func() float64 { return stats().WaitDuration.Seconds() }),
+	}
+}
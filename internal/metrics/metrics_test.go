@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsSuite struct {
+	suite.Suite
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsSuite))
+}
+
+func (suite *MetricsSuite) TestRequestFilterLabelsTheRoute() {
+	wsContainer := restful.NewContainer()
+	wsContainer.Filter(RequestFilter)
+	ws := new(restful.WebService)
+	ws.Path("/metrics-test")
+	ws.Route(ws.GET("/{id}").To(func(req *restful.Request, resp *restful.Response) {
+		resp.WriteHeader(http.StatusTeapot)
+	}))
+	wsContainer.Add(ws)
+
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/metrics-test/1/unknown"} {
+		httpRequest, _ := http.NewRequest("GET", path, nil)
+		wsContainer.ServeHTTP(httptest.NewRecorder(), httpRequest)
+	}
+
+	assert.Equal(suite.T(), float64(2), testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/metrics-test/{id}", "418")))
+	assert.Equal(suite.T(), float64(1), testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+
+	// served by the handler, in the text format
+	NewMetricsHandler(Default, wsContainer)
+	httpRequest, _ := http.NewRequest("GET", "/metrics", nil)
+	responseRec := httptest.NewRecorder()
+	wsContainer.ServeHTTP(responseRec, httpRequest)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	assert.True(suite.T(), strings.HasPrefix(responseRec.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+	assert.Contains(suite.T(), responseRec.Body.String(), `hexfwk_http_requests_total{method="GET",route="/metrics-test/{id}",status="418"} 2`)
+	assert.Contains(suite.T(), responseRec.Body.String(), "hexfwk_orders_created_total 0")
+}
+
+func (suite *MetricsSuite) TestRuntimeAndProcessMetrics() {
+	families, err := Default.Gather()
+	assert.NoError(suite.T(), err)
+	names := map[string]bool{}
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	assert.True(suite.T(), names["go_goroutines"])
+	assert.True(suite.T(), names["process_cpu_seconds_total"])
+}
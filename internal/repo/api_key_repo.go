@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+)
+
+var ErrApiKeyNotFound = domain.NewNotFoundError("API key")
+
+var _ ports.ApiKeyRepo = (*ApiKeyRepository)(nil)
+
+type ApiKeyRepository struct {
+	db *database.DB
+}
+
+func NewApiKeyRepository(db *database.DB) *ApiKeyRepository {
+	return &ApiKeyRepository{
+		db: db,
+	}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, COALESCE(created_by::text, ''), expires_at, last_used_at, revoked_at, created_at`
+
+func (repo *ApiKeyRepository) InsertApiKey(ctx context.Context, key *domain.ApiKey) error {
+	err := repo.db.QueryRow(ctx, `INSERT INTO hex_fwk.api_key (name, prefix, key_hash, scopes, created_by, expires_at) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		key.Name, key.Prefix, key.KeyHash, pq.Array(scopeStrings(key.Scopes)), key.CreatedBy, key.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *ApiKeyRepository) FindApiKeyById(ctx context.Context, id string) (*domain.ApiKey, error) {
+	return repo.findApiKey(ctx, `SELECT `+apiKeyColumns+` FROM hex_fwk.api_key WHERE id = $1`, id)
+}
+
+func (repo *ApiKeyRepository) FindApiKeyByHash(ctx context.Context, keyHash string) (*domain.ApiKey, error) {
+	return repo.findApiKey(ctx, `SELECT `+apiKeyColumns+` FROM hex_fwk.api_key WHERE key_hash = $1`, keyHash)
+}
+
+func (repo *ApiKeyRepository) findApiKey(ctx context.Context, query string, args ...interface{}) (*domain.ApiKey, error) {
+	key, err := scanApiKey(repo.db.QueryRow(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrApiKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (repo *ApiKeyRepository) GetAllApiKeys(ctx context.Context) (*[]domain.ApiKey, error) {
+	rows, err := repo.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM hex_fwk.api_key ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []domain.ApiKey{}
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return &keys, rows.Err()
+}
+
+// Revokes the key, returns false if it doesn't exist or was already revoked
+func (repo *ApiKeyRepository) RevokeApiKey(ctx context.Context, id string) (bool, error) {
+	res, err := repo.db.Exec(ctx, `UPDATE hex_fwk.api_key SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// Records that the key was used
+// Only updates a last use older than the given precision, so busy keys don't write on every request
+func (repo *ApiKeyRepository) TouchApiKey(ctx context.Context, id string, precision time.Duration) error {
+	_, err := repo.db.Exec(ctx, `UPDATE hex_fwk.api_key SET last_used_at = NOW() WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2)`,
+		id, time.Now().Add(-precision))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func scanApiKey(row rowScanner) (*domain.ApiKey, error) {
+	var key domain.ApiKey
+	var scopes []string
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&scopes), &key.CreatedBy,
+		&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range scopes {
+		key.Scopes = append(key.Scopes, domain.ApiKeyScope(s))
+	}
+	return &key, nil
+}
+
+func scopeStrings(scopes []domain.ApiKeyScope) []string {
+	strs := make([]string, len(scopes))
+	for i, s := range scopes {
+		strs[i] = string(s)
+	}
+	return strs
+}
@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+)
+
+var _ ports.AuthAuditRepo = (*AuthAuditRepository)(nil)
+
+type AuthAuditRepository struct {
+	db *database.DB
+}
+
+func NewAuthAuditRepository(db *database.DB) *AuthAuditRepository {
+	return &AuthAuditRepository{
+		db: db,
+	}
+}
+
+func (repo *AuthAuditRepository) InsertAuthEvent(ctx context.Context, event *domain.AuthEvent) error {
+	err := repo.db.QueryRow(ctx,
+		`INSERT INTO hex_fwk.auth_audit (event, email, user_id, actor_id, ip) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		event.Type, event.Email, event.UserID, event.ActorID, event.IP).
+		Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Locks the account until the end of the transaction, for its logins to be checked one at a time
+// The lock is taken on the email, so the attempts to log in to unknown accounts are serialized too
+func (repo *AuthAuditRepository) LockAccount(ctx context.Context, email string) error {
+	_, err := repo.db.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('login:' || $1))`, email)
+	return err
+}
+
+// Counts the failed logins to the account since the given time
+// The count starts over after a successful login, or after an admin unlocked the account
+func (repo *AuthAuditRepository) CountAccountFailures(ctx context.Context, email string, since time.Time) (*domain.LoginFailures, error) {
+	return repo.countFailures(ctx, `SELECT COUNT(*), MAX(created_at) FROM hex_fwk.auth_audit
+		WHERE email = $1 AND event = $3 AND created_at > GREATEST($2, (
+			SELECT MAX(created_at) FROM hex_fwk.auth_audit WHERE email = $1 AND event IN ($4, $5)))`,
+		email, since, domain.AuthLoginFailed, domain.AuthLoginSucceeded, domain.AuthAccountUnlocked)
+}
+
+// Counts the failed logins from the IP since the given time, to any account
+func (repo *AuthAuditRepository) CountIPFailures(ctx context.Context, ip string, since time.Time) (*domain.LoginFailures, error) {
+	return repo.countFailures(ctx, `SELECT COUNT(*), MAX(created_at) FROM hex_fwk.auth_audit
+		WHERE ip = $1 AND created_at > $2 AND event = $3`,
+		ip, since, domain.AuthLoginFailed)
+}
+
+// The failures are counted on the primary, a replica lagging behind would let more attempts through
+func (repo *AuthAuditRepository) countFailures(ctx context.Context, query string, args ...interface{}) (*domain.LoginFailures, error) {
+	var failures domain.LoginFailures
+	var last sql.NullTime
+	err := repo.db.QueryRow(database.WithPrimary(ctx), query, args...).Scan(&failures.Count, &last)
+	if err != nil {
+		return nil, err
+	}
+	failures.Last = last.Time
+	return &failures, nil
+}
+
+// Returns the latest auth events of the user, newest first
+func (repo *AuthAuditRepository) FindAuthEventsByUser(ctx context.Context, userId string, limit int) (*[]domain.AuthEvent, error) {
+	rows, err := repo.db.Query(ctx, `SELECT id, event, email, user_id, actor_id, ip, created_at FROM hex_fwk.auth_audit
+		WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []domain.AuthEvent{}
+	for rows.Next() {
+		var event domain.AuthEvent
+		err := rows.StructScan(&event)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return &events, rows.Err()
+}
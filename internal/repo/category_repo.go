@@ -3,7 +3,6 @@ package repo
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"time"
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
@@ -25,13 +24,13 @@ func NewCategoryRepository(db *database.DB) *CategoryRepository {
 
 func (repo *CategoryRepository) GetAllCategories(ctx context.Context) (*[]domain.Category, error) {
 	var categories []domain.Category
-	rows, err := repo.db.Query(ctx, `SELECT * FROM hex_fwk.category`)
+	rows, err := repo.db.Query(ctx, `SELECT category_id, category_name, created_at, updated_at, version FROM hex_fwk.category`)
 	if err != nil {
 		return nil, err
 	}
 	for rows.Next() {
 		var category domain.Category
-		err = rows.Scan(&category.Id, &category.Name, &category.CreatedAt, &category.UpdatedAt)
+		err = rows.Scan(&category.Id, &category.Name, &category.CreatedAt, &category.UpdatedAt, &category.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -46,10 +45,10 @@ func (repo *CategoryRepository) FindCategoryById(ctx context.Context, id int64)
 	// err := repo.db.QueryRow(ctx, `SELECT category_id, category_name, created_at, updated_at FROM hex_fwk.category WHERE category_id = $1`, id).
 	// 	StructScan(&category)
 
-	err := repo.db.QueryRow(ctx, `SELECT category_id, category_name, created_at, updated_at FROM hex_fwk.category WHERE category_id = $1`, id).
-		Scan(&category.Id, &category.Name, &category.CreatedAt, &category.UpdatedAt)
+	err := repo.db.QueryRow(ctx, `SELECT category_id, category_name, created_at, updated_at, version FROM hex_fwk.category WHERE category_id = $1`, id).
+		Scan(&category.Id, &category.Name, &category.CreatedAt, &category.UpdatedAt, &category.Version)
 	if err == sql.ErrNoRows {
-		err = fmt.Errorf("category not found")
+		err = domain.NewNotFoundError("category")
 		return nil, err
 	}
 	if err != nil {
@@ -69,9 +68,11 @@ func (repo *CategoryRepository) InsertCategory(ctx context.Context, category *do
 	return id, nil
 }
 
-func (repo *CategoryRepository) DeleteCategory(ctx context.Context, id int64) (int64, error) {
-	res, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.category WHERE category_id = $1`,
-		id)
+// Deletes the category, if it's still at the given version
+// A version of 0 deletes the category regardless of its version
+func (repo *CategoryRepository) DeleteCategory(ctx context.Context, id int64, version int) (int64, error) {
+	res, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.category WHERE category_id = $1 AND ($2 = 0 OR version = $2)`,
+		id, version)
 	if err != nil {
 		return 0, err
 	}
@@ -79,19 +80,67 @@ func (repo *CategoryRepository) DeleteCategory(ctx context.Context, id int64) (i
 	if err != nil {
 		return 0, err
 	}
+	if rows == 0 && version != 0 {
+		return 0, repo.versionConflict(ctx, id)
+	}
 	return rows, nil
 }
 
+// Updates the category, if it's still at category.Version, and sets category.Version to the new version
+// A version of 0 updates the category regardless of its version
 func (repo *CategoryRepository) UpdateCategory(ctx context.Context, category *domain.Category, id int64) (int64, error) {
 	updatedAt := time.Now()
-	res, err := repo.db.Exec(ctx, `UPDATE hex_fwk.category SET category_name = $2, updated_at = $3 WHERE category_id = $1`,
-		id, category.Name, updatedAt)
+	err := repo.db.QueryRow(ctx, `UPDATE hex_fwk.category SET category_name = $2, updated_at = $3, version = version + 1
+		WHERE category_id = $1 AND ($4 = 0 OR version = $4) RETURNING version`,
+		id, category.Name, updatedAt, category.Version).
+		Scan(&category.Version)
+	if err == sql.ErrNoRows {
+		if category.Version != 0 {
+			return 0, repo.versionConflict(ctx, id)
+		}
+		return 0, nil
+	}
 	if err != nil {
 		return 0, err
 	}
-	rows, err := res.RowsAffected()
+	return 1, nil
+}
+
+// Updates only the fields set in the patch, if the category is still at patch.Version,
+// and sets patch.Version to the new version
+func (repo *CategoryRepository) PatchCategory(ctx context.Context, id int64, patch *domain.CategoryPatch) (int64, error) {
+	b := newUpdateBuilder(id, patch.Version)
+	if patch.Name != nil {
+		b.set("category_name", *patch.Name)
+	}
+	b.set("updated_at", time.Now())
+	b.setExpr("version", "version + 1")
+
+	err := repo.db.QueryRow(ctx, `UPDATE hex_fwk.category SET `+b.clause()+` WHERE category_id = $1 AND ($2 = 0 OR version = $2) RETURNING version`,
+		b.args...).
+		Scan(&patch.Version)
+	if err == sql.ErrNoRows {
+		if patch.Version != 0 {
+			return 0, repo.versionConflict(ctx, id)
+		}
+		return 0, nil
+	}
 	if err != nil {
 		return 0, err
 	}
-	return rows, nil
+	return 1, nil
+}
+
+// Called when a conditional write didn't affect any rows: reports a conflict if the category exists,
+// otherwise there was nothing to write to
+func (repo *CategoryRepository) versionConflict(ctx context.Context, id int64) error {
+	var exists bool
+	err := repo.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM hex_fwk.category WHERE category_id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return domain.ErrVersionConflict
+	}
+	return nil
 }
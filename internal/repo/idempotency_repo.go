@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+)
+
+var _ ports.IdempotencyRepo = (*IdempotencyRepository)(nil)
+
+type IdempotencyRepository struct {
+	db *database.DB
+}
+
+func NewIdempotencyRepository(db *database.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db: db,
+	}
+}
+
+func (repo *IdempotencyRepository) FindIdempotencyKey(ctx context.Context, key string, scope string) (*domain.IdempotencyKey, error) {
+	var record domain.IdempotencyKey
+	var body []byte
+	// looked up right after the key was found taken, which a replica may not know yet
+	err := repo.db.QueryRow(database.WithPrimary(ctx), `SELECT key, scope, fingerprint, status_code, content_type, response_body, created_at, expires_at
+		FROM hex_fwk.idempotency_key WHERE key = $1 AND scope = $2 AND expires_at > NOW()`, key, scope).
+		Scan(&record.Key, &record.Scope, &record.Fingerprint, &record.StatusCode, &record.ContentType, &body, &record.CreatedAt, &record.ExpiresAt)
+	if err == sql.ErrNoRows {
+		err = fmt.Errorf("idempotency key not found")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	record.ResponseBody = body
+	return &record, nil
+}
+
+// Inserts a new, in-progress record for the given key
+// Returns false if a record which hasn't expired yet already exists for the key
+func (repo *IdempotencyRepository) InsertIdempotencyKey(ctx context.Context, record *domain.IdempotencyKey) (bool, error) {
+	var key string
+	err := repo.db.QueryRow(ctx, `INSERT INTO hex_fwk.idempotency_key (key, scope, fingerprint, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key, scope) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status_code = 0, content_type = '',
+			response_body = NULL, created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+		WHERE hex_fwk.idempotency_key.expires_at <= NOW()
+		RETURNING key`,
+		record.Key, record.Scope, record.Fingerprint, record.ExpiresAt).
+		Scan(&key)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (repo *IdempotencyRepository) UpdateIdempotencyKey(ctx context.Context, record *domain.IdempotencyKey) error {
+	_, err := repo.db.Exec(ctx, `UPDATE hex_fwk.idempotency_key SET status_code = $3, content_type = $4, response_body = $5
+		WHERE key = $1 AND scope = $2`,
+		record.Key, record.Scope, record.StatusCode, record.ContentType, record.ResponseBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *IdempotencyRepository) DeleteIdempotencyKey(ctx context.Context, key string, scope string) error {
+	_, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.idempotency_key WHERE key = $1 AND scope = $2`, key, scope)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *IdempotencyRepository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	res, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.idempotency_key WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return rows, nil
+}
@@ -3,7 +3,6 @@ package repo
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"time"
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
@@ -30,9 +29,9 @@ func NewOrderRepository(db *database.DB) *OrderRepository {
 func (repo *OrderRepository) FindOrderById(ctx context.Context, id string) (*domain.Order, error) {
 	var order domain.Order
 	var userId string
-	err := repo.db.QueryRow(ctx, `SELECT id, status, user_id, created_at, updated_at FROM hex_fwk.order WHERE id = $1`, id).Scan(&order.ID, &order.Status, &userId, &order.CreatedAt, &order.UpdatedAt)
+	err := repo.db.QueryRow(ctx, `SELECT id, status, user_id, created_at, updated_at, version FROM hex_fwk.order WHERE id = $1`, id).Scan(&order.ID, &order.Status, &userId, &order.CreatedAt, &order.UpdatedAt, &order.Version)
 	if err == sql.ErrNoRows {
-		err = fmt.Errorf("order not found")
+		err = domain.NewNotFoundError("order")
 		return nil, err
 	}
 	if err != nil {
@@ -52,13 +51,13 @@ func (repo *OrderRepository) FindOrderById(ctx context.Context, id string) (*dom
 }
 func (repo *OrderRepository) CreateOrder(ctx context.Context, order *domain.Order) (*domain.Order, error) {
 	order.Status = "CREATED"
-	err := repo.db.QueryRow(ctx, `INSERT INTO hex_fwk.order (status, user_id) VALUES ($1, $2) RETURNING id, status, user_id, created_at, updated_at`, order.Status, order.User.ID).
-		Scan(&order.ID, &order.Status, &order.User.ID, &order.CreatedAt, &order.UpdatedAt)
+	err := repo.db.QueryRow(ctx, `INSERT INTO hex_fwk.order (status, user_id) VALUES ($1, $2) RETURNING id, status, user_id, created_at, updated_at, version`, order.Status, order.User.ID).
+		Scan(&order.ID, &order.Status, &order.User.ID, &order.CreatedAt, &order.UpdatedAt, &order.Version)
 	if err != nil {
 		return nil, err
 	}
 	for _, product := range *order.ProductItems {
-		err := repo.OrderProductRepository.Add(ctx, order.ID, product.ProductId, product.Quantity)
+		err := repo.OrderProductRepository.Add(ctx, order.ID, product)
 		if err != nil {
 			return nil, err
 		}
@@ -75,9 +74,20 @@ func (repo *OrderRepository) CreateOrder(ctx context.Context, order *domain.Orde
 	order.ProductItems = productItems
 	return order, nil
 }
+
+// Updates the order status, if the order is still at order.Version, and sets order.Version to the new version
+// A version of 0 updates the order regardless of its version
 func (repo *OrderRepository) UpdateOrderStatus(ctx context.Context, order *domain.Order) (*domain.Order, error) {
 	updatedAt := time.Now()
-	_, err := repo.db.Exec(ctx, `UPDATE hex_fwk.order SET status = $2, updated_at = $3 WHERE id = $1`, order.ID, order.Status, updatedAt)
+	err := repo.db.QueryRow(ctx, `UPDATE hex_fwk.order SET status = $2, updated_at = $3, version = version + 1
+		WHERE id = $1 AND ($4 = 0 OR version = $4) RETURNING version`, order.ID, order.Status, updatedAt, order.Version).
+		Scan(&order.Version)
+	if err == sql.ErrNoRows {
+		if order.Version != 0 {
+			return nil, repo.versionConflict(ctx, order.ID)
+		}
+		return nil, domain.NewNotFoundError("order")
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +95,8 @@ func (repo *OrderRepository) UpdateOrderStatus(ctx context.Context, order *domai
 	return order, nil
 }
 
+// Deletes the order, if it's still at order.Version
+// A version of 0 deletes the order regardless of its version
 func (repo *OrderRepository) DeleteOrder(ctx context.Context, order *domain.Order) error {
 	for _, product := range *order.ProductItems {
 		err := repo.OrderProductRepository.Delete(ctx, order.ID, product.ProductId)
@@ -92,9 +104,30 @@ func (repo *OrderRepository) DeleteOrder(ctx context.Context, order *domain.Orde
 			return err
 		}
 	}
-	_, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.order WHERE id = $1`, order.ID)
+	res, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.order WHERE id = $1 AND ($2 = 0 OR version = $2)`,
+		order.ID, order.Version)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if rows == 0 && order.Version != 0 {
+		return repo.versionConflict(ctx, order.ID)
+	}
 	return nil
 }
+
+// Called when a conditional write didn't affect any rows: reports a conflict if the order exists
+func (repo *OrderRepository) versionConflict(ctx context.Context, id string) error {
+	var exists bool
+	err := repo.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM hex_fwk.order WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return domain.ErrVersionConflict
+	}
+	return domain.NewNotFoundError("order")
+}
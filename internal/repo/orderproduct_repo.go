@@ -11,44 +11,37 @@ import (
 var _ ports.OrderProductRepo = (*OrderProductRepository)(nil)
 
 type OrderProductRepository struct {
-	db                *database.DB
-	ProductRepository *ProductRepository
+	db *database.DB
 }
 
 func NewOrderProductRepository(db *database.DB) *OrderProductRepository {
 	return &OrderProductRepository{
-		db:                db,
-		ProductRepository: NewProductRepository(db),
+		db: db,
 	}
 }
 
+// The lines of the order, the foreign key keeping their products from being deleted while ordered
 func (repo *OrderProductRepository) GetProducts(ctx context.Context, orderId string) (*[]domain.OrderedProduct, error) {
-	var products []domain.OrderedProduct
-	rows, err := repo.db.Query(ctx, `SELECT product_id, quantity FROM hex_fwk.order_product WHERE order_id = $1`, orderId)
+	rows, err := repo.db.Query(ctx, `SELECT product_id, quantity, unit_price FROM hex_fwk.order_product WHERE order_id = $1 ORDER BY product_id`, orderId)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+	var products []domain.OrderedProduct
 	for rows.Next() {
-		var productId int64
-		var quantity int
-		err = rows.Scan(&productId, &quantity)
-		if err != nil {
-			return nil, err
-		}
-		product, err := repo.ProductRepository.FindProductById(ctx, productId)
+		var orderProduct domain.OrderedProduct
+		err = rows.Scan(&orderProduct.ProductId, &orderProduct.Quantity, &orderProduct.UnitPrice)
 		if err != nil {
 			return nil, err
 		}
-		var orderProduct *domain.OrderedProduct = &domain.OrderedProduct{}
-		orderProduct.ProductId = int64(product.ProductId)
-		orderProduct.Quantity = quantity
-		products = append(products, *orderProduct)
+		products = append(products, orderProduct)
 	}
-	return &products, err
+	return &products, rows.Err()
 }
 
-func (repo *OrderProductRepository) Add(ctx context.Context, orderId string, productId int64, quantity int) error {
-	_, err := repo.db.Exec(ctx, `INSERT INTO hex_fwk.order_product (order_id, product_id, quantity) VALUES ($1, $2, $3)`, orderId, productId, quantity)
+func (repo *OrderProductRepository) Add(ctx context.Context, orderId string, product domain.OrderedProduct) error {
+	_, err := repo.db.Exec(ctx, `INSERT INTO hex_fwk.order_product (order_id, product_id, quantity, unit_price) VALUES ($1, $2, $3, $4)`,
+		orderId, product.ProductId, product.Quantity, product.UnitPrice)
 	if err != nil {
 		return err
 	}
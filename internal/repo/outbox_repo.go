@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+)
+
+var _ ports.OutboxRepo = (*OutboxRepository)(nil)
+
+type OutboxRepository struct {
+	db *database.DB
+}
+
+func NewOutboxRepository(db *database.DB) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+// Stores the event, as part of the transaction in ctx (if any)
+func (repo *OutboxRepository) InsertEvent(ctx context.Context, event *domain.Event) error {
+	err := repo.db.QueryRow(ctx, `INSERT INTO hex_fwk.outbox (event_type, aggregate_id, payload, occurred_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		event.Type, event.AggregateID, []byte(event.Payload), event.OccurredAt).
+		Scan(&event.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the oldest events which weren't published yet, and are due for an attempt
+// The events aren't locked, each subscriber locks the event with LockEvent before handling it
+func (repo *OutboxRepository) FindDueEvents(ctx context.Context, limit int) (*[]domain.Event, error) {
+	var events []domain.Event
+	rows, err := repo.db.Query(database.WithPrimary(ctx), `SELECT id, event_type, aggregate_id, payload, occurred_at, attempts, delivered_to FROM hex_fwk.outbox
+		WHERE published_at IS NULL AND dead_at IS NULL AND next_attempt_at <= NOW() ORDER BY occurred_at LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event domain.Event
+		var payload []byte
+		err = rows.Scan(&event.ID, &event.Type, &event.AggregateID, &payload, &event.OccurredAt, &event.Attempts, pq.Array(&event.DeliveredTo))
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	return &events, rows.Err()
+}
+
+// Locks the unpublished event until the transaction in ctx ends, and returns the subscribers which handled it already
+// Returns false when the event is locked by another relay, or was published or dead-lettered meanwhile
+func (repo *OutboxRepository) LockEvent(ctx context.Context, id string) ([]string, bool, error) {
+	var deliveredTo []string
+	err := repo.db.QueryRow(ctx, `SELECT delivered_to FROM hex_fwk.outbox WHERE id = $1 AND published_at IS NULL AND dead_at IS NULL
+		FOR UPDATE SKIP LOCKED`, id).Scan(pq.Array(&deliveredTo))
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return deliveredTo, true, nil
+}
+
+// Records the subscriber handled the event
+func (repo *OutboxRepository) MarkEventDelivered(ctx context.Context, id string, subscriber string) error {
+	_, err := repo.db.Exec(ctx, `UPDATE hex_fwk.outbox SET delivered_to = array_append(delivered_to, $2) WHERE id = $1`, id, subscriber)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *OutboxRepository) MarkEventPublished(ctx context.Context, id string) error {
+	_, err := repo.db.Exec(ctx, `UPDATE hex_fwk.outbox SET published_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Records the failed attempt, and when to attempt the event again
+func (repo *OutboxRepository) MarkEventFailed(ctx context.Context, id string, reason string, nextAttemptAt time.Time) error {
+	_, err := repo.db.Exec(ctx, `UPDATE hex_fwk.outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1`,
+		id, reason, nextAttemptAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Records the last failed attempt, the event is no longer relayed
+func (repo *OutboxRepository) MarkEventDead(ctx context.Context, id string, reason string) error {
+	_, err := repo.db.Exec(ctx, `UPDATE hex_fwk.outbox SET attempts = attempts + 1, last_error = $2, dead_at = NOW() WHERE id = $1`, id, reason)
+	if err != nil {
+		return err
+	}
+	return nil
+}
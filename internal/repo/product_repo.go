@@ -3,9 +3,9 @@ package repo
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
@@ -14,55 +14,87 @@ import (
 var _ ports.ProductRepo = (*ProductRepository)(nil)
 
 type ProductRepository struct {
-	db                 *database.DB
-	CategoryRepository *CategoryRepository
+	db *database.DB
 }
 
 func NewProductRepository(db *database.DB) *ProductRepository {
 	return &ProductRepository{
-		db:                 db,
-		CategoryRepository: NewCategoryRepository(db),
+		db: db,
 	}
 }
+
+// Columns of the products joined with their category, as scanned by scanProduct
+const productColumns = `p.id, p.name, p.short_description, p.description, p.price, p.quantity, p.created_at, p.updated_at, p.version,
+	c.category_id, c.category_name, c.created_at, c.updated_at, c.version`
+
+// Selects the products joined with their category, so both are read in the same query
+const selectProducts = `SELECT ` + productColumns + `
+	FROM hex_fwk.product p JOIN hex_fwk.category c ON c.category_id = p.category_id`
+
 func (repo *ProductRepository) GetAllProducts(ctx context.Context) (*[]domain.Product, error) {
-	var products []domain.Product
-	rows, err := repo.db.Query(ctx, `SELECT id, name, short_description, description, price, category_id, quantity, created_at, updated_at FROM hex_fwk.product`)
+	rows, err := repo.db.Query(ctx, selectProducts+` ORDER BY p.id`)
 	if err != nil {
 		return nil, err
 	}
-	for rows.Next() {
-		var product domain.Product
-		var categoryId int64
-		err = rows.Scan(&product.ProductId, &product.Name, &product.ShortDescription, &product.Description, &product.Price,
-			&categoryId, &product.Quantity, &product.CreatedAt, &product.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		product.Category, err = repo.CategoryRepository.FindCategoryById(ctx, categoryId)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-	return &products, err
+	return scanProducts(rows)
 }
 
 func (repo *ProductRepository) FindProductById(ctx context.Context, id int64) (*domain.Product, error) {
-	var product domain.Product
-	var categoryId int64
-	err := repo.db.QueryRow(ctx, `SELECT id, name, short_description, description, price, category_id, quantity, created_at, updated_at FROM hex_fwk.product WHERE id = $1`, id).Scan(&product.ProductId, &product.Name, &product.ShortDescription, &product.Description, &product.Price,
-		&categoryId, &product.Quantity, &product.CreatedAt, &product.UpdatedAt)
+	product, err := scanProduct(repo.db.QueryRow(ctx, selectProducts+` WHERE p.id = $1`, id))
 	if err == sql.ErrNoRows {
-		err = fmt.Errorf("product not found")
+		err = domain.NewNotFoundError("product")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// Finds the products with the given ids in a single query, the ones which don't exist are left out
+func (repo *ProductRepository) FindProductsByIds(ctx context.Context, ids []int64) (map[int64]*domain.Product, error) {
+	found := map[int64]*domain.Product{}
+	if len(ids) == 0 {
+		return found, nil
+	}
+	rows, err := repo.db.Query(ctx, selectProducts+` WHERE p.id = ANY($1)`, pq.Array(ids))
+	if err != nil {
 		return nil, err
 	}
+	products, err := scanProducts(rows)
 	if err != nil {
 		return nil, err
 	}
-	product.Category, err = repo.CategoryRepository.FindCategoryById(ctx, categoryId)
+	for i := range *products {
+		product := &(*products)[i]
+		found[int64(product.ProductId)] = product
+	}
+	return found, nil
+}
+
+func scanProducts(rows *database.Rows) (*[]domain.Product, error) {
+	defer rows.Close()
+	var products []domain.Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *product)
+	}
+	return &products, rows.Err()
+}
+
+func scanProduct(row rowScanner) (*domain.Product, error) {
+	var product domain.Product
+	var category domain.Category
+	err := row.Scan(&product.ProductId, &product.Name, &product.ShortDescription, &product.Description, &product.Price,
+		&product.Quantity, &product.CreatedAt, &product.UpdatedAt, &product.Version,
+		&category.Id, &category.Name, &category.CreatedAt, &category.UpdatedAt, &category.Version)
 	if err != nil {
 		return nil, err
 	}
+	product.Category = &category
 	return &product, nil
 }
 
@@ -77,9 +109,11 @@ func (repo *ProductRepository) InsertProduct(ctx context.Context, product *domai
 	return id, nil
 }
 
-func (repo *ProductRepository) DeleteProduct(ctx context.Context, id int64) (int64, error) {
-	res, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.product WHERE id = $1`,
-		id)
+// Deletes the product, if it's still at the given version
+// A version of 0 deletes the product regardless of its version
+func (repo *ProductRepository) DeleteProduct(ctx context.Context, id int64, version int) (int64, error) {
+	res, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.product WHERE id = $1 AND ($2 = 0 OR version = $2)`,
+		id, version)
 	if err != nil {
 		return 0, err
 	}
@@ -87,20 +121,108 @@ func (repo *ProductRepository) DeleteProduct(ctx context.Context, id int64) (int
 	if err != nil {
 		return 0, err
 	}
+	if rows == 0 && version != 0 {
+		return 0, repo.versionConflict(ctx, id)
+	}
 	return rows, nil
 }
 
+// Updates the product, if it's still at product.Version, and sets product.Version to the new version
+// A version of 0 updates the product regardless of its version
 func (repo *ProductRepository) UpdateProduct(ctx context.Context, product *domain.Product, id int64) (int64, error) {
 	updatedAt := time.Now()
-	res, err := repo.db.Exec(ctx, `UPDATE hex_fwk.product SET name = $2, short_description = $3, description = $4, 
-	price = $5, updated_at = $6, quantity = $7, category_id = $8 WHERE id = $1`,
-		id, product.Name, product.ShortDescription, product.Description, product.Price, updatedAt, product.Quantity, product.Category.Id)
+	err := repo.db.QueryRow(ctx, `UPDATE hex_fwk.product SET name = $2, short_description = $3, description = $4, 
+	price = $5, updated_at = $6, quantity = $7, category_id = $8, version = version + 1 WHERE id = $1 AND ($9 = 0 OR version = $9) RETURNING version`,
+		id, product.Name, product.ShortDescription, product.Description, product.Price, updatedAt, product.Quantity, product.Category.Id, product.Version).
+		Scan(&product.Version)
+	if err == sql.ErrNoRows {
+		if product.Version != 0 {
+			return 0, repo.versionConflict(ctx, id)
+		}
+		return 0, nil
+	}
 	if err != nil {
 		return 0, err
 	}
-	rows, err := res.RowsAffected()
+	return 1, nil
+}
+
+// Updates only the fields set in the patch, if the product is still at patch.Version,
+// and sets patch.Version to the new version
+func (repo *ProductRepository) PatchProduct(ctx context.Context, id int64, patch *domain.ProductPatch) (int64, error) {
+	b := newUpdateBuilder(id, patch.Version)
+	if patch.Name != nil {
+		b.set("name", *patch.Name)
+	}
+	if patch.ShortDescription != nil {
+		b.set("short_description", *patch.ShortDescription)
+	}
+	if patch.Description != nil {
+		b.set("description", *patch.Description)
+	}
+	if patch.Price != nil {
+		b.set("price", *patch.Price)
+	}
+	if patch.Quantity != nil {
+		b.set("quantity", *patch.Quantity)
+	}
+	if patch.CategoryId != nil {
+		b.set("category_id", *patch.CategoryId)
+	}
+	b.set("updated_at", time.Now())
+	b.setExpr("version", "version + 1")
+
+	err := repo.db.QueryRow(ctx, `UPDATE hex_fwk.product SET `+b.clause()+` WHERE id = $1 AND ($2 = 0 OR version = $2) RETURNING version`,
+		b.args...).
+		Scan(&patch.Version)
+	if err == sql.ErrNoRows {
+		if patch.Version != 0 {
+			return 0, repo.versionConflict(ctx, id)
+		}
+		return 0, nil
+	}
 	if err != nil {
 		return 0, err
 	}
-	return rows, nil
+	return 1, nil
+}
+
+// Takes the quantity out of stock in a single statement, so orders made at the same time don't conflict,
+// and returns the product as it is afterwards
+// Fails with an InsufficientStockError when fewer items are in stock
+func (repo *ProductRepository) TakeFromStock(ctx context.Context, id int64, quantity int) (*domain.Product, error) {
+	product, err := scanProduct(repo.db.QueryRow(ctx, `WITH p AS (
+		UPDATE hex_fwk.product SET quantity = quantity - $2, updated_at = $3, version = version + 1
+		WHERE id = $1 AND quantity >= $2 RETURNING *)
+	SELECT `+productColumns+` FROM p JOIN hex_fwk.category c ON c.category_id = p.category_id`,
+		id, quantity, time.Now()))
+	if err == sql.ErrNoRows {
+		var available int
+		err = repo.db.QueryRow(ctx, `SELECT quantity FROM hex_fwk.product WHERE id = $1`, id).Scan(&available)
+		if err == sql.ErrNoRows {
+			return nil, domain.NewNotFoundError("product")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, &domain.InsufficientStockError{ProductID: id, Requested: quantity, Available: available}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// Called when a conditional write didn't affect any rows: reports a conflict if the product exists,
+// otherwise there was nothing to write to
+func (repo *ProductRepository) versionConflict(ctx context.Context, id int64) error {
+	var exists bool
+	err := repo.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM hex_fwk.product WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return domain.ErrVersionConflict
+	}
+	return nil
 }
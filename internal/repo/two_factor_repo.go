@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+)
+
+var ErrTOTPNotFound = domain.NewNotFoundError("totp")
+
+var _ ports.TwoFactorRepo = (*TwoFactorRepository)(nil)
+
+type TwoFactorRepository struct {
+	db *database.DB
+}
+
+func NewTwoFactorRepository(db *database.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{
+		db: db,
+	}
+}
+
+// Stores the user's new, unconfirmed secret, replacing an earlier unconfirmed one
+// Does nothing if the user already confirmed a secret
+func (repo *TwoFactorRepository) InsertTOTP(ctx context.Context, totp *domain.UserTOTP) error {
+	err := repo.db.QueryRow(ctx,
+		`INSERT INTO hex_fwk.user_totp (user_id, secret) VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = NOW() WHERE hex_fwk.user_totp.confirmed_at IS NULL
+		 RETURNING created_at`,
+		totp.UserID, totp.Secret).
+		Scan(&totp.CreatedAt)
+	if err == sql.ErrNoRows {
+		return domain.ErrTwoFactorEnabled
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *TwoFactorRepository) FindTOTP(ctx context.Context, userId string) (*domain.UserTOTP, error) {
+	var totp domain.UserTOTP
+	err := repo.db.QueryRow(ctx,
+		`SELECT user_id, secret, confirmed_at, last_used_step, created_at FROM hex_fwk.user_totp WHERE user_id = $1`, userId).
+		StructScan(&totp)
+	if err == sql.ErrNoRows {
+		return nil, ErrTOTPNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+func (repo *TwoFactorRepository) ConfirmTOTP(ctx context.Context, userId string) error {
+	res, err := repo.db.Exec(ctx, `UPDATE hex_fwk.user_totp SET confirmed_at = NOW() WHERE user_id = $1 AND confirmed_at IS NULL`, userId)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTOTPNotFound
+	}
+	return nil
+}
+
+// Records the time step of an accepted code
+// Returns false when a code of that step, or a later one, was already accepted, so a code can't be replayed
+func (repo *TwoFactorRepository) UseTOTPStep(ctx context.Context, userId string, step int64) (bool, error) {
+	res, err := repo.db.Exec(ctx, `UPDATE hex_fwk.user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`, userId, step)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// Deletes the user's secret and recovery codes
+func (repo *TwoFactorRepository) DeleteTOTP(ctx context.Context, userId string) error {
+	_, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.user_recovery_code WHERE user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec(ctx, `DELETE FROM hex_fwk.user_totp WHERE user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Replaces the user's recovery codes, the earlier ones can't be used anymore
+func (repo *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	_, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.user_recovery_code WHERE user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		_, err = repo.db.Exec(ctx, `INSERT INTO hex_fwk.user_recovery_code (user_id, code_hash) VALUES ($1, $2)`, userId, codeHash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Uses up the recovery code, returns false if the user has no such unused code
+func (repo *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userId string, codeHash string) (bool, error) {
+	res, err := repo.db.Exec(ctx,
+		`UPDATE hex_fwk.user_recovery_code SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (repo *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userId string) (int, error) {
+	var count int
+	err := repo.db.QueryRow(ctx, `SELECT COUNT(*) FROM hex_fwk.user_recovery_code WHERE user_id = $1 AND used_at IS NULL`, userId).
+		Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
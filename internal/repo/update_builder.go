@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Builds the SET part of an UPDATE statement, out of the columns which are actually changed
+// The arguments passed when creating the builder come first, so the WHERE part can refer to them as $1, $2...
+type updateBuilder struct {
+	sets []string
+	args []interface{}
+}
+
+func newUpdateBuilder(whereArgs ...interface{}) *updateBuilder {
+	return &updateBuilder{
+		args: whereArgs,
+	}
+}
+
+func (b *updateBuilder) set(column string, value interface{}) {
+	b.args = append(b.args, value)
+	b.sets = append(b.sets, fmt.Sprintf("%s = $%d", column, len(b.args)))
+}
+
+func (b *updateBuilder) setExpr(column string, expr string) {
+	b.sets = append(b.sets, fmt.Sprintf("%s = %s", column, expr))
+}
+
+func (b *updateBuilder) clause() string {
+	return strings.Join(b.sets, ", ")
+}
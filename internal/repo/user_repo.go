@@ -4,16 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"regexp"
-
-	"github.com/pkg/errors"
+	"time"
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
 )
 
-var ErrDuplicateEmail = errors.New("email already exists")
-var ErrUserNotFound = errors.New("user not found")
+var ErrDuplicateEmail = domain.NewConflictError("email already exists")
+var ErrUserNotFound = domain.NewNotFoundError("user")
 
 // Verify the impl matches the interface
 var _ ports.UserRepo = (*UserRepository)(nil)
@@ -28,10 +27,12 @@ func NewUserRepository(db *database.DB) *UserRepository {
 	}
 }
 
+// Inserts the user, and populates its generated ID
 func (repo *UserRepository) Insert(ctx context.Context, user *domain.User) error {
-	_, err := repo.db.Exec(ctx,
-		"INSERT INTO hex_fwk.user (email, first_name, surname,password_hash) VALUES ($1, $2, $3, $4)",
-		user.Email, user.Name, user.Surname, user.PasswordHash)
+	err := repo.db.QueryRow(ctx,
+		"INSERT INTO hex_fwk.user (email, first_name, surname,password_hash, locale) VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), $6)) RETURNING id, created_at, locale, role",
+		user.Email, user.Name, user.Surname, user.PasswordHash, user.Locale, domain.DefaultLocale).
+		Scan(&user.ID, &user.CreatedAt, &user.Locale, &user.Role)
 	if err != nil {
 		alreadyExists, _ := regexp.Match(`user_email_key`, []byte(err.Error()))
 		if alreadyExists {
@@ -59,11 +60,40 @@ func (repo *UserRepository) Update(ctx context.Context, user *domain.User) error
 	return nil
 }
 
+// Updates only the fields set in the patch
+func (repo *UserRepository) Patch(ctx context.Context, id string, patch *domain.UserPatch) error {
+	b := newUpdateBuilder(id)
+	if patch.Name != nil {
+		b.set("first_name", *patch.Name)
+	}
+	if patch.Surname != nil {
+		b.set("surname", *patch.Surname)
+	}
+	if patch.Locale != nil {
+		b.set("locale", *patch.Locale)
+	}
+	b.set("updated_at", time.Now())
+
+	res, err := repo.db.Exec(ctx, `UPDATE hex_fwk.user SET `+b.clause()+` WHERE id = $1`, b.args...)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
 func (repo *UserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
 	var user domain.User
 
 	err := repo.db.
-		QueryRow(ctx, `SELECT id, email, first_name, surname, password_hash FROM hex_fwk.user WHERE id = $1`, id).
+		QueryRow(ctx, `SELECT id, email, first_name, surname, password_hash, locale, role, email_verified_at, session_version, created_at FROM hex_fwk.user WHERE id = $1`, id).
 		StructScan(&user)
 	if err == sql.ErrNoRows {
 		return nil, ErrUserNotFound
@@ -79,7 +109,7 @@ func (repo *UserRepository) FindByEmail(ctx context.Context, email string) (*dom
 	var user domain.User
 
 	err := repo.db.QueryRow(ctx,
-		`SELECT id, email, first_name, surname, password_hash FROM hex_fwk.user WHERE email = $1`,
+		`SELECT id, email, first_name, surname, password_hash, locale, role, email_verified_at, session_version, created_at FROM hex_fwk.user WHERE email = $1`,
 		email).
 		StructScan(&user)
 	if err == sql.ErrNoRows {
@@ -91,3 +121,35 @@ func (repo *UserRepository) FindByEmail(ctx context.Context, email string) (*dom
 
 	return &user, nil
 }
+
+func (repo *UserRepository) MarkEmailVerified(ctx context.Context, id string) error {
+	res, err := repo.db.Exec(ctx, `UPDATE hex_fwk.user SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Sets the new password, and revokes the user's sessions by bumping their version
+// Returns the new session version
+func (repo *UserRepository) UpdatePassword(ctx context.Context, id string, passwordHash string) (int, error) {
+	var sessionVersion int
+	err := repo.db.QueryRow(ctx,
+		`UPDATE hex_fwk.user SET password_hash = $2, session_version = session_version + 1, updated_at = NOW() WHERE id = $1 RETURNING session_version`,
+		id, passwordHash).
+		Scan(&sessionVersion)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return sessionVersion, nil
+}
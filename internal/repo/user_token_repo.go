@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+)
+
+var _ ports.UserTokenRepo = (*UserTokenRepository)(nil)
+
+type UserTokenRepository struct {
+	db *database.DB
+}
+
+func NewUserTokenRepository(db *database.DB) *UserTokenRepository {
+	return &UserTokenRepository{
+		db: db,
+	}
+}
+
+func (repo *UserTokenRepository) InsertToken(ctx context.Context, token *domain.UserToken) error {
+	err := repo.db.QueryRow(ctx, `INSERT INTO hex_fwk.user_token (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the token with the given hash, or domain.ErrInvalidToken if there is none
+func (repo *UserTokenRepository) FindTokenByHash(ctx context.Context, tokenHash string, purpose domain.TokenPurpose) (*domain.UserToken, error) {
+	var token domain.UserToken
+	err := repo.db.QueryRow(ctx, `SELECT id, user_id, purpose, token_hash, created_at, expires_at, used_at FROM hex_fwk.user_token
+		WHERE token_hash = $1 AND purpose = $2`, tokenHash, purpose).
+		Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Uses up the token
+// Returns false when it was already used, so concurrent requests can't both use it
+func (repo *UserTokenRepository) MarkTokenUsed(ctx context.Context, id string) (bool, error) {
+	res, err := repo.db.Exec(ctx, `UPDATE hex_fwk.user_token SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// Deletes the user's tokens for the purpose, e.g. the outstanding reset tokens once the password was reset
+func (repo *UserTokenRepository) DeleteUserTokens(ctx context.Context, userId string, purpose domain.TokenPurpose) error {
+	_, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.user_token WHERE user_id = $1 AND purpose = $2`, userId, purpose)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+)
+
+var _ ports.WebhookRepo = (*WebhookRepository)(nil)
+
+type WebhookRepository struct {
+	db *database.DB
+}
+
+func NewWebhookRepository(db *database.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+const subscriptionColumns = `id, user_id, url, event_types, secret, active, created_at, updated_at`
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, created_at, delivered_at`
+
+func (repo *WebhookRepository) InsertSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	err := repo.db.QueryRow(ctx, `INSERT INTO hex_fwk.webhook_subscription (user_id, url, event_types, secret, active) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		subscription.UserID, subscription.URL, pq.Array(eventTypeStrings(subscription.EventTypes)), subscription.Secret, subscription.Active).
+		Scan(&subscription.ID, &subscription.CreatedAt, &subscription.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *WebhookRepository) FindSubscriptionById(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	subscription, err := scanSubscription(repo.db.QueryRow(ctx, `SELECT `+subscriptionColumns+` FROM hex_fwk.webhook_subscription WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		err = domain.NewNotFoundError("webhook subscription")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (repo *WebhookRepository) FindSubscriptionsByUser(ctx context.Context, userId string) (*[]domain.WebhookSubscription, error) {
+	rows, err := repo.db.Query(ctx, `SELECT `+subscriptionColumns+` FROM hex_fwk.webhook_subscription WHERE user_id = $1 ORDER BY created_at`, userId)
+	if err != nil {
+		return nil, err
+	}
+	return scanSubscriptions(rows)
+}
+
+// Returns the active subscriptions which want events of the given type, of the users who have the given role
+func (repo *WebhookRepository) FindSubscriptionsForEvent(ctx context.Context, eventType domain.EventType, ownerRole string) (*[]domain.WebhookSubscription, error) {
+	rows, err := repo.db.Query(ctx, `SELECT s.id, s.user_id, s.url, s.event_types, s.secret, s.active, s.created_at, s.updated_at
+		FROM hex_fwk.webhook_subscription s JOIN hex_fwk.user u ON u.id = s.user_id
+		WHERE s.active AND $1 = ANY(s.event_types) AND u.role = $2`, eventType, ownerRole)
+	if err != nil {
+		return nil, err
+	}
+	return scanSubscriptions(rows)
+}
+
+// Deletes the subscription together with its delivery log
+func (repo *WebhookRepository) DeleteSubscription(ctx context.Context, id string) (int64, error) {
+	res, err := repo.db.Exec(ctx, `DELETE FROM hex_fwk.webhook_subscription WHERE id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Stores a pending delivery
+// An event is delivered to a subscription at most once, so inserting it again (when the event is relayed twice) is a no-op
+func (repo *WebhookRepository) InsertDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	_, err := repo.db.Exec(ctx, `INSERT INTO hex_fwk.webhook_delivery (subscription_id, event_id, event_type, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		delivery.SubscriptionID, delivery.EventID, delivery.EventType, []byte(delivery.Payload), delivery.Status, delivery.NextAttemptAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Claims the pending deliveries whose next attempt is due, oldest first, until leasedUntil
+// The claimed deliveries aren't due again before then, so no other deliverer sends them in the meantime,
+// and a deliverer stopping halfway leaves them to be claimed again once the lease runs out
+func (repo *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, leasedUntil time.Time) (*[]domain.WebhookDelivery, error) {
+	rows, err := repo.db.Query(ctx, `UPDATE hex_fwk.webhook_delivery SET next_attempt_at = $3 WHERE id IN (
+			SELECT id FROM hex_fwk.webhook_delivery WHERE status = $1 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING `+deliveryColumns, domain.DeliveryPending, limit, leasedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// Stores the outcome of a delivery attempt
+func (repo *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	_, err := repo.db.Exec(ctx, `UPDATE hex_fwk.webhook_delivery SET status = $2, attempts = $3, next_attempt_at = $4,
+		last_status_code = $5, last_error = $6, delivered_at = $7 WHERE id = $1`,
+		delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatusCode, delivery.LastError, delivery.DeliveredAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the delivery log of the subscription, newest first, optionally only the deliveries with the given status
+func (repo *WebhookRepository) FindDeliveries(ctx context.Context, subscriptionId string, status domain.WebhookDeliveryStatus, limit int) (*[]domain.WebhookDelivery, error) {
+	rows, err := repo.db.Query(ctx, `SELECT `+deliveryColumns+` FROM hex_fwk.webhook_delivery
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC LIMIT $3`,
+		subscriptionId, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// implemented by both a single row, and rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row rowScanner) (*domain.WebhookSubscription, error) {
+	var subscription domain.WebhookSubscription
+	var eventTypes []string
+	err := row.Scan(&subscription.ID, &subscription.UserID, &subscription.URL, pq.Array(&eventTypes), &subscription.Secret,
+		&subscription.Active, &subscription.CreatedAt, &subscription.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range eventTypes {
+		subscription.EventTypes = append(subscription.EventTypes, domain.EventType(t))
+	}
+	return &subscription, nil
+}
+
+func scanSubscriptions(rows *database.Rows) (*[]domain.WebhookSubscription, error) {
+	defer rows.Close()
+	subscriptions := []domain.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return &subscriptions, rows.Err()
+}
+
+func scanDeliveries(rows *database.Rows) (*[]domain.WebhookDelivery, error) {
+	defer rows.Close()
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var delivery domain.WebhookDelivery
+		var payload []byte
+		err := rows.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType, &payload, &delivery.Status,
+			&delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastStatusCode, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		delivery.Payload = payload
+		deliveries = append(deliveries, delivery)
+	}
+	return &deliveries, rows.Err()
+}
+
+func eventTypeStrings(eventTypes []domain.EventType) []string {
+	types := make([]string, len(eventTypes))
+	for i, t := range eventTypes {
+		types[i] = string(t)
+	}
+	return types
+}
@@ -0,0 +1,122 @@
+package apiversion
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+)
+
+type Version int
+
+const (
+	V1 Version = iota
+	V2
+)
+
+// Versions the API is served in, the oldest first
+var Versions = []Version{V1, V2}
+
+// The version new clients should use, the older ones are deprecated
+const Latest = V2
+
+// e.g. v1
+func (v Version) String() string {
+	return "v" + strconv.Itoa(int(v)+1)
+}
+
+// Root path of the routes of the version, e.g. /v1
+func (v Version) Prefix() string {
+	return "/" + v.String()
+}
+
+func (v Version) Deprecated() bool {
+	return v < Latest
+}
+
+// Version of the routes the path belongs to, false for the unversioned ones like /openapi.json and the aliases of V1
+func FromPath(path string) (Version, bool) {
+	for _, v := range Versions {
+		if path == v.Prefix() || strings.HasPrefix(path, v.Prefix()+"/") {
+			return v, true
+		}
+	}
+	return V1, false
+}
+
+// Version of the route the request was made to
+func FromRequest(req *restful.Request) Version {
+	v, _ := FromPath(req.Request.URL.Path)
+	return v
+}
+
+// The path without its version prefix, e.g. /product/1 for /v2/product/1
+func StripPrefix(path string) string {
+	if v, ok := FromPath(path); ok {
+		return "/" + strings.TrimPrefix(strings.TrimPrefix(path, v.Prefix()), "/")
+	}
+	return path
+}
+
+// Root paths mounted without a version prefix too, see Register
+var (
+	aliasesMu sync.RWMutex
+	aliases   = map[string]bool{}
+)
+
+// Mounts a web service at the root path under every version, e.g. /v1/product and /v2/product
+// build declares the routes of each version, so a version can add routes or change their responses
+// The routes of V1 are also mounted at the unversioned root path, e.g. /product, where they were served before the
+// versions existed; they're deprecated aliases, left out of the OpenAPI document
+func Register(wsCont *restful.Container, rootPath string, build func(v Version, ws *restful.WebService)) {
+	for _, v := range Versions {
+		ws := new(restful.WebService)
+		ws.Path(v.Prefix() + rootPath)
+		build(v, ws)
+		wsCont.Add(ws)
+	}
+
+	aliasesMu.Lock()
+	aliases[rootPath] = true
+	aliasesMu.Unlock()
+	alias := new(restful.WebService)
+	alias.Path(rootPath)
+	build(V1, alias)
+	wsCont.Add(alias)
+}
+
+// Whether the path is served by an unversioned alias of V1, e.g. /product/1
+func IsAlias(path string) bool {
+	if _, ok := FromPath(path); ok {
+		return false
+	}
+	aliasesMu.RLock()
+	defer aliasesMu.RUnlock()
+	for root := range aliases {
+		if path == root || strings.HasPrefix(path, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Announces the deprecation of the routes of the older versions and of the unversioned aliases (RFC 8594), pointing
+// to the same route of the latest version
+// sunset is when the deprecated versions stop being served, left out of the headers when zero
+func DeprecationFilter(sunset time.Time) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		path := req.Request.URL.Path
+		if v, ok := FromPath(path); (ok && v.Deprecated()) || IsAlias(path) {
+			header := resp.Header()
+			header.Set("Deprecation", "true")
+			if !sunset.IsZero() {
+				header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			header.Add("Link", "<"+Latest.Prefix()+StripPrefix(path)+`>; rel="successor-version"`)
+		}
+		chain.ProcessFilter(req, resp)
+	}
+}
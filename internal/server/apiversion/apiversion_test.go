@@ -0,0 +1,88 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ApiVersionSuite struct {
+	suite.Suite
+	wsContainer *restful.Container
+}
+
+func (suite *ApiVersionSuite) SetupTest() {
+	suite.wsContainer = restful.NewContainer()
+	suite.wsContainer.Filter(DeprecationFilter(time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)))
+	Register(suite.wsContainer, "/item", func(v Version, ws *restful.WebService) {
+		ws.Route(ws.GET("/{id}").To(func(req *restful.Request, resp *restful.Response) {
+			resp.Write([]byte(FromRequest(req).String()))
+		}))
+	})
+}
+
+func TestApiVersionTestSuite(t *testing.T) {
+	suite.Run(t, new(ApiVersionSuite))
+}
+
+func (suite *ApiVersionSuite) get(path string) *httptest.ResponseRecorder {
+	httpRequest, _ := http.NewRequest("GET", path, nil)
+	responseRec := httptest.NewRecorder()
+	suite.wsContainer.ServeHTTP(responseRec, httpRequest)
+	return responseRec
+}
+
+func (suite *ApiVersionSuite) TestFromPath() {
+	v, ok := FromPath("/v2/product/1")
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), V2, v)
+	v, ok = FromPath("/v1")
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), V1, v)
+	_, ok = FromPath("/v10/product")
+	assert.False(suite.T(), ok)
+	_, ok = FromPath("/openapi.json")
+	assert.False(suite.T(), ok)
+
+	assert.Equal(suite.T(), "/product/1", StripPrefix("/v2/product/1"))
+	assert.Equal(suite.T(), "/", StripPrefix("/v1"))
+	assert.Equal(suite.T(), "/ping", StripPrefix("/ping"))
+}
+
+func (suite *ApiVersionSuite) TestRegisterMountsEveryVersion() {
+	for _, v := range Versions {
+		responseRec := suite.get(v.Prefix() + "/item/1")
+		assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+		assert.Equal(suite.T(), v.String(), responseRec.Body.String())
+	}
+}
+
+func (suite *ApiVersionSuite) TestUnversionedPathsServeV1() {
+	responseRec := suite.get("/item/1")
+
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	assert.Equal(suite.T(), V1.String(), responseRec.Body.String())
+	assert.Equal(suite.T(), "true", responseRec.Header().Get("Deprecation"))
+	assert.Equal(suite.T(), `</v2/item/1>; rel="successor-version"`, responseRec.Header().Get("Link"))
+
+	assert.True(suite.T(), IsAlias("/item/1"))
+	assert.False(suite.T(), IsAlias("/v1/item/1"))
+	assert.False(suite.T(), IsAlias("/items"))
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get("/items/1").Code)
+}
+
+func (suite *ApiVersionSuite) TestDeprecatedVersionsAnnounceTheSunset() {
+	responseRec := suite.get("/v1/item/1")
+	assert.Equal(suite.T(), "true", responseRec.Header().Get("Deprecation"))
+	assert.Equal(suite.T(), "Wed, 30 Jun 2027 00:00:00 GMT", responseRec.Header().Get("Sunset"))
+	assert.Equal(suite.T(), `</v2/item/1>; rel="successor-version"`, responseRec.Header().Get("Link"))
+
+	responseRec = suite.get("/v2/item/1")
+	assert.Empty(suite.T(), responseRec.Header().Get("Deprecation"))
+	assert.Empty(suite.T(), responseRec.Header().Get("Sunset"))
+}
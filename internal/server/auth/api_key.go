@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/julienschmidt/httprouter"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/params"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+)
+
+// Where the scopes of the API key will be stored in the request context, comma separated
+const USER_SCOPES_CTX_KEY = "SCOPES"
+
+const apiKeyScheme = "ApiKey "
+
+// Returns the API key the request was made with, or domain.ErrInvalidApiKey if it can't be used
+type ApiKeyAuthenticator func(ctx context.Context, key string) (*domain.ApiKey, error)
+
+var apiKeyAuthenticator ApiKeyAuthenticator
+
+// Sets the authenticator used by AuthApiKey, without one every API key is refused
+func SetApiKeyAuthenticator(authenticator ApiKeyAuthenticator) {
+	apiKeyAuthenticator = authenticator
+}
+
+// Authenticates requests by their API key, sent as `Authorization: ApiKey xxx`
+// The key is attached to the request context the same way AuthJWT attaches the user: its name as the email,
+// its ID, domain.RoleService as the role, and its scopes
+func AuthApiKey(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	authHeader := req.HeaderParameter("Authorization")
+	if !strings.HasPrefix(authHeader, apiKeyScheme) || apiKeyAuthenticator == nil {
+		response.WriteError(resp, response.NewUnauthorizedError("not authorized"))
+		return
+	}
+
+	key, err := apiKeyAuthenticator(req.Request.Context(), strings.TrimSpace(strings.TrimPrefix(authHeader, apiKeyScheme)))
+	// invalid keys are answered with a 401 by the error mapping
+	if err != nil {
+		response.WriteError(resp, err)
+		return
+	}
+
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+	req.Request = params.WithRequest(req.Request, httprouter.Params{
+		httprouter.Param{Key: USER_EMAIL_CTX_KEY, Value: key.Name},
+		httprouter.Param{Key: USER_ID_CTX_KEY, Value: key.ID},
+		httprouter.Param{Key: USER_ROLE_CTX_KEY, Value: domain.RoleService},
+		httprouter.Param{Key: USER_SCOPES_CTX_KEY, Value: strings.Join(scopes, ",")},
+	})
+	req.Request = req.Request.WithContext(log.WithUser(req.Request.Context(), key.ID, key.Name))
+
+	chain.ProcessFilter(req, resp)
+}
+
+// Returns the ID of the API key the Authorization header carries, if it's one which can be used
+func ApiKeyID(ctx context.Context, authHeader string) (string, bool) {
+	if !strings.HasPrefix(authHeader, apiKeyScheme) || apiKeyAuthenticator == nil {
+		return "", false
+	}
+	key, err := apiKeyAuthenticator(ctx, strings.TrimSpace(strings.TrimPrefix(authHeader, apiKeyScheme)))
+	if err != nil {
+		return "", false
+	}
+	return key.ID, true
+}
+
+// Authenticates requests either by an API key, or by a user's JWT
+func Authenticate(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	if strings.HasPrefix(req.HeaderParameter("Authorization"), apiKeyScheme) {
+		AuthApiKey(req, resp, chain)
+		return
+	}
+	AuthJWT(req, resp, chain)
+}
+
+// Only lets through the API keys with the given scope, and admins, it has to come after Authenticate
+func RequireScope(scope domain.ApiKeyScope) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		if !HasScope(req.Request, scope) {
+			response.WriteError(resp, response.NewForbiddenError("forbidden"))
+			return
+		}
+		chain.ProcessFilter(req, resp)
+	}
+}
+
+// Whether the request was made with an API key with the given scope, or by an admin, who has all the scopes
+func HasScope(r *http.Request, scope domain.ApiKeyScope) bool {
+	role, err := params.StringFrom(r, USER_ROLE_CTX_KEY)
+	if err != nil {
+		return false
+	}
+	if role == domain.RoleAdmin {
+		return true
+	}
+	if role != domain.RoleService {
+		return false
+	}
+	scopes, _ := params.StringFrom(r, USER_SCOPES_CTX_KEY)
+	for _, s := range strings.Split(scopes, ",") {
+		if s == string(scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that API keys are let through only with the required scope, and that JWTs still work alongside them
+func (suite *AuthSuite) TestRequireScope() {
+	SetApiKeyAuthenticator(func(_ context.Context, key string) (*domain.ApiKey, error) {
+		switch key {
+		case "hfk_stock":
+			return &domain.ApiKey{ID: "key-1", Name: "inventory", Scopes: []domain.ApiKeyScope{domain.ScopeWriteStock}}, nil
+		case "hfk_orders":
+			return &domain.ApiKey{ID: "key-2", Name: "reporting", Scopes: []domain.ApiKeyScope{domain.ScopeReadOrders}}, nil
+		}
+		return nil, domain.ErrInvalidApiKey
+	})
+	defer SetApiKeyAuthenticator(nil)
+	SetSessionChecker(func(_ context.Context, userId string, sessionVersion int) (string, bool, error) {
+		if userId == "abcd-456" {
+			return domain.RoleAdmin, true, nil
+		}
+		return domain.RoleUser, true, nil
+	})
+	defer SetSessionChecker(nil)
+
+	ws := new(restful.WebService)
+	ws.Consumes(restful.MIME_JSON)
+	ws.Route(ws.GET("/api-key/stock").Filter(Authenticate).Filter(RequireScope(domain.ScopeWriteStock)).
+		To(func(r1 *restful.Request, r2 *restful.Response) {}))
+	container := restful.NewContainer()
+	container.Add(ws)
+
+	userToken, err := CreateJWT("testy@email.com", "abcd-123", domain.RoleUser, 0)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	adminToken, err := CreateJWT("admin@email.com", "abcd-456", domain.RoleAdmin, 0)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	for authHeader, status := range map[string]int{
+		"ApiKey hfk_stock":     http.StatusOK,
+		"ApiKey hfk_orders":    http.StatusForbidden,
+		"ApiKey hfk_unknown":   http.StatusUnauthorized,
+		"Bearer " + userToken:  http.StatusForbidden,
+		"Bearer " + adminToken: http.StatusOK,
+		"":                     http.StatusUnauthorized,
+	} {
+		httpRequest, _ := http.NewRequest("GET", "/api-key/stock", nil)
+		httpRequest.Header.Set("Authorization", authHeader)
+		responseRec := httptest.NewRecorder()
+		container.ServeHTTP(responseRec, httpRequest)
+
+		assert.Equal(suite.T(), status, responseRec.Code, authHeader)
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
@@ -8,31 +9,59 @@ import (
 	restful "github.com/emicklei/go-restful/v3"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/julienschmidt/httprouter"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/params"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
 )
 
-// The signing key used to create JWT
-var (
-	jwtSigningKey = []byte("DebugSigningKey")
-)
+// The key the JWTs are signed and verified with, set from the config with SetSigningKey
+var jwtSigningKey []byte
+
+var errNoSigningKey = errors.New("JWT signing key not set")
 
-// Where the user's email and ID will be stored in the request context
+// Sets the key the JWTs are signed and verified with, no JWT is issued or accepted without one
+func SetSigningKey(key string) {
+	jwtSigningKey = []byte(key)
+}
+
+// Where the user's email, ID and role will be stored in the request context
 const USER_EMAIL_CTX_KEY = "EMAIL"
 const USER_ID_CTX_KEY = "ID"
+const USER_ROLE_CTX_KEY = "ROLE"
 
 type CustomClaims struct {
 	Email string `json:"email"`
 	ID    string `json:"id"`
+	// Role of the user when the JWT was issued, for the clients: the role checked is the one stored for the user
+	Role string `json:"role"`
+	// Version of the user's sessions the JWT was issued for
+	SessionVersion int `json:"sv"`
 	jwt.RegisteredClaims
 }
 
-// Creates a JWT for the given email
+// Checks whether the user's sessions of the given version are still valid, and returns the user's current role
+type SessionChecker func(ctx context.Context, userId string, sessionVersion int) (role string, valid bool, err error)
+
+var sessionChecker SessionChecker
+
+// Sets the checker used by AuthJWT to reject JWTs of revoked sessions, and to look up the role of the users
+// Without one, every JWT with a valid signature is accepted until it expires, and no user has a role
+func SetSessionChecker(checker SessionChecker) {
+	sessionChecker = checker
+}
+
+// Creates a JWT for the given email and role, for the given version of the user's sessions
 // Returns the JWT, or an error
-func CreateJWT(email string, id string) (string, error) {
+func CreateJWT(email string, id string, role string, sessionVersion int) (string, error) {
+	if len(jwtSigningKey) == 0 {
+		return "", errNoSigningKey
+	}
 
 	claims := &CustomClaims{
 		email,
 		id,
+		role,
+		sessionVersion,
 		jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
 			Issuer:    "HEXFWK",
@@ -63,11 +92,14 @@ func IsJWTHeaderValid(authHeader string) bool {
 	}
 
 	jwtToken := strings.Split(authHeader, " ")
-	if len(jwtToken) < 2 {
+	if len(jwtToken) < 2 || len(jwtSigningKey) == 0 {
 		return false
 	}
 	// since the JWT format is xxx.yyy.zzz, we need to split by .
 	parts := strings.Split(jwtToken[1], ".")
+	if len(parts) != 3 {
+		return false
+	}
 	// payload is composed of xxx.yyy, and the signature is zzz
 	payload := strings.Join(parts[0:2], ".")
 	err := jwt.SigningMethodHS512.Verify(payload, parts[2], jwtSigningKey)
@@ -77,7 +109,15 @@ func IsJWTHeaderValid(authHeader string) bool {
 
 // Extracts the claims from the given JWT
 func GetJWTClaims(token string) (jwt.MapClaims, error) {
-	res, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) { return jwtSigningKey, nil })
+	res, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		if len(jwtSigningKey) == 0 {
+			return nil, errNoSigningKey
+		}
+		if t.Method != jwt.SigningMethodHS512 {
+			return nil, errors.New("unexpected JWT signing method")
+		}
+		return jwtSigningKey, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -94,24 +134,61 @@ func AuthJWT(req *restful.Request, resp *restful.Response, chain *restful.Filter
 	authHeader := req.HeaderParameter("Authorization")
 
 	if !IsJWTHeaderValid(authHeader) {
-		resp.WriteErrorString(401, "401: Not Authorized")
+		response.WriteError(resp, response.NewUnauthorizedError("not authorized"))
 		return
 	}
 
 	// unpack JWT
+	// e.g. expired, or not valid yet
 	claims, err := GetJWTClaims(UnwrapJWTHeader(authHeader))
 	if err != nil {
-		resp.WriteErrorString(500, "Server error")
+		response.WriteError(resp, response.NewUnauthorizedError("invalid token").WithErrorCode(response.ErrCodeInvalidToken))
+		return
 	}
 
 	// attach user email to request context
-	userEmail := claims["email"].(string)
-	userId := claims["id"].(string)
+	userEmail, okEmail := claims["email"].(string)
+	userId, okId := claims["id"].(string)
+	if !okEmail || !okId {
+		response.WriteError(resp, response.NewUnauthorizedError("invalid token").WithErrorCode(response.ErrCodeInvalidToken))
+		return
+	}
+
+	// reject the JWTs issued before the user's sessions were revoked (e.g. by changing the password)
+	// the role is the one currently stored for the user, not the claim of the JWT
+	var userRole string
+	if sessionChecker != nil {
+		sessionVersion, _ := claims["sv"].(float64)
+		role, valid, err := sessionChecker(req.Request.Context(), userId, int(sessionVersion))
+		if err != nil {
+			response.WriteError(resp, err)
+			return
+		}
+		if !valid {
+			response.WriteError(resp, response.NewUnauthorizedError("not authorized"))
+			return
+		}
+		userRole = role
+	}
 	updated := params.WithRequest(req.Request, httprouter.Params{
 		httprouter.Param{Key: USER_EMAIL_CTX_KEY, Value: userEmail},
 		httprouter.Param{Key: USER_ID_CTX_KEY, Value: userId},
+		httprouter.Param{Key: USER_ROLE_CTX_KEY, Value: userRole},
 	})
-	req.Request = updated
+	// the messages logged while answering the request carry the user
+	req.Request = updated.WithContext(log.WithUser(updated.Context(), userId, userEmail))
 
 	chain.ProcessFilter(req, resp)
 }
+
+// Only lets through the users with the given role, it has to come after AuthJWT
+func RequireRole(role string) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		userRole, err := params.StringFrom(req.Request, USER_ROLE_CTX_KEY)
+		if err != nil || userRole != role {
+			response.WriteError(resp, response.NewForbiddenError("forbidden"))
+			return
+		}
+		chain.ProcessFilter(req, resp)
+	}
+}
@@ -1,11 +1,14 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/emicklei/go-restful/v3"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/params"
 
 	"github.com/stretchr/testify/assert"
@@ -16,6 +19,10 @@ type AuthSuite struct {
 	suite.Suite
 }
 
+func (suite *AuthSuite) SetupSuite() {
+	SetSigningKey("0123456789abcdef0123456789abcdef")
+}
+
 func TestUserTestSuite(t *testing.T) {
 	suite.Run(t, new(AuthSuite))
 }
@@ -24,7 +31,7 @@ func (suite *AuthSuite) TestCreateToken() {
 	userEmail := "testy@email.com"
 	userId := "abcd-1234"
 	// create the token
-	jwtToken, err := CreateJWT(userEmail, userId)
+	jwtToken, err := CreateJWT(userEmail, userId, "USER", 0)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
@@ -61,7 +68,7 @@ func (suite *AuthSuite) TestJWTAttachesContext() {
 	}))
 
 	userEmail := "testy@email.com"
-	jwtToken, err := CreateJWT(userEmail, "abcd-123")
+	jwtToken, err := CreateJWT(userEmail, "abcd-123", "USER", 0)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
@@ -74,3 +81,94 @@ func (suite *AuthSuite) TestJWTAttachesContext() {
 
 	assert.Equal(suite.T(), routeParam, userEmail)
 }
+
+// Test that JWTs of revoked sessions are rejected
+func (suite *AuthSuite) TestRevokedSessionIsRejected() {
+	SetSessionChecker(func(_ context.Context, userId string, sessionVersion int) (string, bool, error) {
+		return "USER", sessionVersion == 2, nil
+	})
+	defer SetSessionChecker(nil)
+
+	ws := new(restful.WebService)
+	ws.Consumes(restful.MIME_JSON)
+	ws.Route(ws.GET("/jwt/session").Filter(AuthJWT).To(func(r1 *restful.Request, r2 *restful.Response) {}))
+	container := restful.NewContainer()
+	container.Add(ws)
+
+	for sessionVersion, status := range map[int]int{1: http.StatusUnauthorized, 2: http.StatusOK} {
+		jwtToken, err := CreateJWT("testy@email.com", "abcd-123", "USER", sessionVersion)
+		if err != nil {
+			suite.T().Fatal(err)
+		}
+		httpRequest, _ := http.NewRequest("GET", "/jwt/session", nil)
+		httpRequest.Header.Set("Authorization", "Bearer "+jwtToken)
+		responseRec := httptest.NewRecorder()
+		container.ServeHTTP(responseRec, httpRequest)
+
+		assert.Equal(suite.T(), status, responseRec.Code)
+	}
+}
+
+// Test that only the users with the role are let through, whatever the role claimed by their JWT
+func (suite *AuthSuite) TestRequireRole() {
+	roles := map[string]string{"user-1": "USER", "admin-1": "ADMIN"}
+	SetSessionChecker(func(_ context.Context, userId string, sessionVersion int) (string, bool, error) {
+		return roles[userId], true, nil
+	})
+	defer SetSessionChecker(nil)
+
+	ws := new(restful.WebService)
+	ws.Consumes(restful.MIME_JSON)
+	ws.Route(ws.GET("/jwt/admin").Filter(AuthJWT).Filter(RequireRole("ADMIN")).To(func(r1 *restful.Request, r2 *restful.Response) {}))
+	container := restful.NewContainer()
+	container.Add(ws)
+
+	for _, tc := range []struct {
+		userId string
+		claim  string
+		status int
+	}{
+		{"user-1", "USER", http.StatusForbidden},
+		{"admin-1", "ADMIN", http.StatusOK},
+		// an ADMIN claim doesn't make a user an admin
+		{"user-1", "ADMIN", http.StatusForbidden},
+		// nor does a demoted admin keep the role of the JWT
+		{"admin-2", "ADMIN", http.StatusForbidden},
+	} {
+		jwtToken, err := CreateJWT("testy@email.com", tc.userId, tc.claim, 0)
+		if err != nil {
+			suite.T().Fatal(err)
+		}
+		httpRequest, _ := http.NewRequest("GET", "/jwt/admin", nil)
+		httpRequest.Header.Set("Authorization", "Bearer "+jwtToken)
+		responseRec := httptest.NewRecorder()
+		container.ServeHTTP(responseRec, httpRequest)
+
+		assert.Equal(suite.T(), tc.status, responseRec.Code, tc)
+	}
+}
+
+// Test that expired tokens, and tokens without the user's claims, are answered 401 rather than failing
+func (suite *AuthSuite) TestInvalidClaimsAreUnauthorized() {
+	ws := new(restful.WebService)
+	ws.Route(ws.GET("/jwt/claims").Filter(AuthJWT).To(func(r1 *restful.Request, r2 *restful.Response) {}))
+	container := restful.NewContainer()
+	container.Add(ws)
+
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS512, &CustomClaims{"testy@email.com", "abcd-123", "USER", 0,
+		jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))}})
+	withoutId := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{"email": "testy@email.com"})
+	for _, token := range []*jwt.Token{expired, withoutId} {
+		signed, err := token.SignedString(jwtSigningKey)
+		if err != nil {
+			suite.T().Fatal(err)
+		}
+		httpRequest, _ := http.NewRequest("GET", "/jwt/claims", nil)
+		httpRequest.Header.Set("Authorization", WrapJWTHeader(signed))
+		responseRec := httptest.NewRecorder()
+		container.ServeHTTP(responseRec, httpRequest)
+
+		assert.Equal(suite.T(), http.StatusUnauthorized, responseRec.Code)
+		assert.Contains(suite.T(), responseRec.Body.String(), "1103")
+	}
+}
@@ -0,0 +1,54 @@
+package health
+
+import (
+	"net/http"
+
+	restful "github.com/emicklei/go-restful/v3"
+)
+
+type HealthHandler struct {
+	registry *Registry
+}
+
+// Serves the probes of the orchestrators, without authentication
+// /healthz tells the process is alive, /readyz that the components it depends on are up
+func NewHealthHandler(registry *Registry, wsCont *restful.Container) *HealthHandler {
+	httpHandler := &HealthHandler{registry: registry}
+
+	// separate services, as the root path "/" is taken
+	liveWs := new(restful.WebService)
+	liveWs.Path("/healthz").Produces(restful.MIME_JSON)
+	liveWs.Route(liveWs.GET("").To(httpHandler.GetLiveness).
+		Doc("Check the server is alive").
+		Notes("Doesn't check the components the server depends on, see /readyz").
+		Writes(Report{}).
+		Returns(http.StatusOK, "Alive", nil))
+	wsCont.Add(liveWs)
+
+	readyWs := new(restful.WebService)
+	readyWs.Path("/readyz").Produces(restful.MIME_JSON)
+	readyWs.Route(readyWs.GET("").To(httpHandler.GetReadiness).
+		Doc("Check the server can take requests").
+		Notes("Reports the status of every component, the server is ready while only optional ones are down").
+		Writes(Report{}).
+		Returns(http.StatusOK, "Ready", nil).
+		Returns(http.StatusServiceUnavailable, "Not ready", Report{}))
+	wsCont.Add(readyWs)
+
+	return httpHandler
+}
+
+func (e *HealthHandler) GetLiveness(req *restful.Request, resp *restful.Response) {
+	resp.WriteAsJson(Report{Status: StatusUp, Components: map[string]ComponentStatus{}})
+}
+
+func (e *HealthHandler) GetReadiness(req *restful.Request, resp *restful.Response) {
+	report := e.registry.Run(req.Request.Context())
+	// probes shouldn't see a stale answer
+	resp.AddHeader("Cache-Control", "no-store")
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	resp.WriteHeaderAndJson(status, report, restful.MIME_JSON)
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HealthSuite struct {
+	suite.Suite
+	registry    *Registry
+	wsContainer *restful.Container
+}
+
+func (suite *HealthSuite) SetupTest() {
+	suite.registry = NewRegistry(50 * time.Millisecond)
+	suite.registry.Register("database", func(ctx context.Context) error { return nil })
+	suite.wsContainer = restful.NewContainer()
+	NewHealthHandler(suite.registry, suite.wsContainer)
+}
+
+func TestHealthTestSuite(t *testing.T) {
+	suite.Run(t, new(HealthSuite))
+}
+
+func (suite *HealthSuite) get(path string) (*httptest.ResponseRecorder, Report) {
+	httpRequest, _ := http.NewRequest("GET", path, nil)
+	responseRec := httptest.NewRecorder()
+	suite.wsContainer.ServeHTTP(responseRec, httpRequest)
+	var report Report
+	if err := json.Unmarshal(responseRec.Body.Bytes(), &report); err != nil {
+		suite.T().Fatalf("Error unmarshalling health response: %s", err)
+	}
+	return responseRec, report
+}
+
+func (suite *HealthSuite) TestReady() {
+	responseRec, report := suite.get("/readyz")
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	assert.Equal(suite.T(), StatusUp, report.Status)
+	assert.Equal(suite.T(), StatusUp, report.Components["database"].Status)
+}
+
+func (suite *HealthSuite) TestOptionalComponentDown() {
+	suite.registry.RegisterOptional("mail", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	responseRec, report := suite.get("/readyz")
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	assert.Equal(suite.T(), StatusDegraded, report.Status)
+	assert.Equal(suite.T(), StatusDown, report.Components["mail"].Status)
+	assert.True(suite.T(), report.Components["mail"].Optional)
+	assert.Equal(suite.T(), "connection refused", report.Components["mail"].Error)
+}
+
+func (suite *HealthSuite) TestRequiredComponentDown() {
+	suite.registry.Register("migrations", func(ctx context.Context) error { return errors.New("1 pending migrations") })
+	// checks which don't return in time, or panic, report their component down
+	suite.registry.Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	suite.registry.Register("broken", func(ctx context.Context) error { panic("nil pointer") })
+
+	start := time.Now()
+	responseRec, report := suite.get("/readyz")
+	assert.Less(suite.T(), time.Since(start), time.Second)
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, responseRec.Code)
+	assert.Equal(suite.T(), StatusDown, report.Status)
+	assert.Equal(suite.T(), StatusUp, report.Components["database"].Status)
+	assert.Equal(suite.T(), StatusDown, report.Components["migrations"].Status)
+	assert.Equal(suite.T(), context.DeadlineExceeded.Error(), report.Components["slow"].Error)
+	assert.Equal(suite.T(), "check panicked: nil pointer", report.Components["broken"].Error)
+
+	// liveness doesn't depend on the components
+	responseRec, report = suite.get("/healthz")
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+	assert.Equal(suite.T(), StatusUp, report.Status)
+}
@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	// Overall status when only optional components are down, the server still takes requests
+	StatusDegraded = "degraded"
+)
+
+// Check returns an error when the component it checks can't be used
+type Check func(ctx context.Context) error
+
+// Implemented by the adapters which can check their connection, e.g. the SMTP mailer
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type component struct {
+	name  string
+	check Check
+	// when down the server still takes requests, e.g. emails are queued until the mail server is back
+	optional bool
+}
+
+// Registry of the checks of the components the server depends on
+// Adapters register their own checks when they're wired, e.g. the database its ping
+type Registry struct {
+	mu         sync.RWMutex
+	components []component
+	// How long a check can take before its component is reported down
+	timeout time.Duration
+}
+
+// Timeout of the checks when none is configured
+const DefaultTimeout = 2 * time.Second
+
+func NewRegistry(timeout time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Registry{timeout: timeout}
+}
+
+// Registers a component the server isn't ready without
+func (r *Registry) Register(name string, check Check) {
+	r.add(component{name: name, check: check})
+}
+
+// Registers a component the server can do without for a while
+func (r *Registry) RegisterOptional(name string, check Check) {
+	r.add(component{name: name, check: check, optional: true})
+}
+
+func (r *Registry) add(c component) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.components = append(r.components, c)
+	sort.SliceStable(r.components, func(i, j int) bool { return r.components[i].name < r.components[j].name })
+}
+
+type ComponentStatus struct {
+	Status   string `json:"status"`
+	Optional bool   `json:"optional,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// How long the check took, in milliseconds
+	Duration int64 `json:"durationMs"`
+}
+
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Whether the server can take requests
+func (r Report) Ready() bool {
+	return r.Status != StatusDown
+}
+
+// Runs all the checks concurrently, each with the timeout of the registry
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	components := make([]component, len(r.components))
+	copy(components, r.components)
+	r.mu.RUnlock()
+
+	statuses := make([]ComponentStatus, len(components))
+	var wg sync.WaitGroup
+	for i, c := range components {
+		wg.Add(1)
+		go func(i int, c component) {
+			defer wg.Done()
+			statuses[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Components: map[string]ComponentStatus{}}
+	for i, c := range components {
+		report.Components[c.name] = statuses[i]
+		if statuses[i].Status == StatusUp {
+			continue
+		}
+		if !c.optional {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c component) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	// checks which ignore the context don't hold up the report
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				errc <- fmt.Errorf("check panicked: %v", rec)
+			}
+		}()
+		errc <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	status := ComponentStatus{Status: StatusUp, Optional: c.optional, Duration: time.Since(start).Milliseconds()}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
@@ -0,0 +1,194 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+)
+
+// Header used by clients to mark a request as safe to retry
+const HeaderKey = "Idempotency-Key"
+
+// Header set on responses which were replayed from the store
+const HeaderReplayed = "Idempotent-Replayed"
+
+// How long a stored response is replayed, unless configured otherwise
+const DefaultTTL = 24 * time.Hour
+
+const maxKeyLength = 255
+
+// How long the key is given to be stored or released once the request was served
+const storeTimeout = 5 * time.Second
+
+// Filter makes POST requests carrying an Idempotency-Key header safe to retry:
+// the first response for a key is stored, and replayed for every duplicate request
+type Filter struct {
+	store  ports.IdempotencyRepo
+	ttl    time.Duration
+	logger log.Logger
+}
+
+func NewFilter(store ports.IdempotencyRepo, ttl time.Duration, logger log.Logger) *Filter {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Filter{
+		store:  store,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+func (f *Filter) Filter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	key := req.HeaderParameter(HeaderKey)
+	if req.Request.Method != http.MethodPost || key == "" {
+		chain.ProcessFilter(req, resp)
+		return
+	}
+	if len(key) > maxKeyLength {
+		response.WriteError(resp, response.NewBadRequestError("idempotency key too long"))
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		response.WriteError(resp, response.NewBadRequestError("error reading request body"))
+		return
+	}
+	req.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	ctx := req.Request.Context()
+	record := &domain.IdempotencyKey{
+		Key:         key,
+		Scope:       scope(req),
+		Fingerprint: fingerprint(req.Request, body),
+		ExpiresAt:   time.Now().Add(f.ttl),
+	}
+
+	inserted, err := f.store.InsertIdempotencyKey(ctx, record)
+	if err != nil {
+		f.logError("error reserving idempotency key", err)
+		response.WriteError(resp, response.NewInternalServerError("an error occured"))
+		return
+	}
+	if !inserted {
+		f.replay(req, resp, record)
+		return
+	}
+
+	// the key is stored or released even when the client went away, or the handler panicked,
+	// otherwise it would be left reserved until it expires
+	storeCtx, cancel := context.WithTimeout(detachedContext{ctx}, storeTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			f.release(storeCtx, record)
+			panic(r)
+		}
+	}()
+
+	recorder := &responseRecorder{ResponseWriter: resp.ResponseWriter}
+	resp.ResponseWriter = recorder
+	chain.ProcessFilter(req, resp)
+	resp.ResponseWriter = recorder.ResponseWriter
+
+	// server errors are not stored, so the client can retry them with the same key
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		f.release(storeCtx, record)
+		return
+	}
+
+	record.StatusCode = resp.StatusCode()
+	record.ContentType = resp.Header().Get("Content-Type")
+	record.ResponseBody = recorder.body.Bytes()
+	if err := f.store.UpdateIdempotencyKey(storeCtx, record); err != nil {
+		f.logError("error storing idempotent response", err)
+	}
+}
+
+// Releases the key, so the client can retry the request with it
+func (f *Filter) release(ctx context.Context, record *domain.IdempotencyKey) {
+	if err := f.store.DeleteIdempotencyKey(ctx, record.Key, record.Scope); err != nil {
+		f.logError("error releasing idempotency key", err)
+	}
+}
+
+// Answers a duplicate request from the stored record
+func (f *Filter) replay(req *restful.Request, resp *restful.Response, record *domain.IdempotencyKey) {
+	stored, err := f.store.FindIdempotencyKey(req.Request.Context(), record.Key, record.Scope)
+	if err != nil {
+		// the record expired or was released in the meantime
+		response.WriteError(resp, response.NewConflictError("request with this idempotency key is being processed"))
+		return
+	}
+	if stored.Fingerprint != record.Fingerprint {
+		response.WriteError(resp, response.NewUnprocessableEntityError("idempotency key was already used with a different request"))
+		return
+	}
+	if !stored.IsCompleted() {
+		response.WriteError(resp, response.NewConflictError("request with this idempotency key is being processed"))
+		return
+	}
+
+	if stored.ContentType != "" {
+		resp.Header().Set("Content-Type", stored.ContentType)
+	}
+	resp.Header().Set(HeaderReplayed, "true")
+	resp.WriteHeader(stored.StatusCode)
+	resp.Write(stored.ResponseBody)
+}
+
+func (f *Filter) logError(msg string, err error) {
+	if f.logger == nil {
+		return
+	}
+	f.logger.Error(msg, "err", err)
+}
+
+// Keys are scoped to the credentials of the caller, so different users can't replay each other's responses
+func scope(req *restful.Request) string {
+	sum := sha256.Sum256([]byte(req.HeaderParameter("Authorization")))
+	return hex.EncodeToString(sum[:])
+}
+
+// The fingerprint identifies the request a key was first used with
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Keeps the values of the request's context, like its trace, but not its cancellation nor its deadline
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// Passes the response through, keeping a copy of the written body
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
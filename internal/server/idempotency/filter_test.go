@@ -0,0 +1,177 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// in-memory store, standing in for the database repository
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyKey
+}
+
+func (m *memoryStore) FindIdempotencyKey(_ context.Context, key string, scope string) (*domain.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.records[key+scope]
+	if !ok || record.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("idempotency key not found")
+	}
+	return &record, nil
+}
+
+func (m *memoryStore) InsertIdempotencyKey(_ context.Context, record *domain.IdempotencyKey) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.records[record.Key+record.Scope]
+	if ok && existing.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+	m.records[record.Key+record.Scope] = *record
+	return true, nil
+}
+
+func (m *memoryStore) UpdateIdempotencyKey(ctx context.Context, record *domain.IdempotencyKey) error {
+	// like the database, nothing is stored with a canceled context
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[record.Key+record.Scope] = *record
+	return nil
+}
+
+func (m *memoryStore) DeleteIdempotencyKey(ctx context.Context, key string, scope string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key+scope)
+	return nil
+}
+
+func (m *memoryStore) DeleteExpiredIdempotencyKeys(_ context.Context) (int64, error) {
+	return 0, nil
+}
+
+type FilterSuite struct {
+	suite.Suite
+	store       *memoryStore
+	wsContainer *restful.Container
+	calls       int
+	status      int
+	panics      bool
+}
+
+func (suite *FilterSuite) SetupTest() {
+	suite.store = &memoryStore{records: map[string]domain.IdempotencyKey{}}
+	suite.calls = 0
+	suite.status = http.StatusOK
+	suite.panics = false
+
+	suite.wsContainer = restful.NewContainer()
+	suite.wsContainer.Filter(NewFilter(suite.store, time.Hour, nil).Filter)
+
+	ws := new(restful.WebService)
+	ws.Path("/resource").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	ws.Route(ws.POST("").To(func(req *restful.Request, resp *restful.Response) {
+		suite.calls++
+		if suite.panics {
+			panic("handler failed")
+		}
+		resp.WriteHeaderAndJson(suite.status, map[string]int{"call": suite.calls}, restful.MIME_JSON)
+	}))
+	suite.wsContainer.Add(ws)
+}
+
+func TestFilterTestSuite(t *testing.T) {
+	suite.Run(t, new(FilterSuite))
+}
+
+func (suite *FilterSuite) post(key string, body string) *httptest.ResponseRecorder {
+	httpRequest, _ := http.NewRequest("POST", "/resource", bytes.NewReader([]byte(body)))
+	httpRequest.Header.Set("Content-Type", restful.MIME_JSON)
+	if key != "" {
+		httpRequest.Header.Set(HeaderKey, key)
+	}
+	responseRec := httptest.NewRecorder()
+	suite.wsContainer.ServeHTTP(responseRec, httpRequest)
+	return responseRec
+}
+
+func (suite *FilterSuite) TestReplaysDuplicateRequest() {
+	first := suite.post("key-1", `{"a":1}`)
+	second := suite.post("key-1", `{"a":1}`)
+
+	assert.Equal(suite.T(), 1, suite.calls)
+	assert.Equal(suite.T(), http.StatusOK, second.Code)
+	assert.Equal(suite.T(), first.Body.String(), second.Body.String())
+	assert.Equal(suite.T(), "true", second.Header().Get(HeaderReplayed))
+}
+
+func (suite *FilterSuite) TestRejectsKeyReusedWithDifferentBody() {
+	suite.post("key-1", `{"a":1}`)
+	responseRec := suite.post("key-1", `{"a":2}`)
+
+	assert.Equal(suite.T(), 1, suite.calls)
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, responseRec.Code)
+}
+
+func (suite *FilterSuite) TestRequestsWithoutKeyAreNotStored() {
+	suite.post("", `{"a":1}`)
+	suite.post("", `{"a":1}`)
+
+	assert.Equal(suite.T(), 2, suite.calls)
+	assert.Empty(suite.T(), suite.store.records)
+}
+
+func (suite *FilterSuite) TestServerErrorsAreNotStored() {
+	suite.status = http.StatusInternalServerError
+	suite.post("key-1", `{"a":1}`)
+	suite.status = http.StatusOK
+	responseRec := suite.post("key-1", `{"a":1}`)
+
+	assert.Equal(suite.T(), 2, suite.calls)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+}
+
+func (suite *FilterSuite) TestKeyIsReleasedWhenTheHandlerPanics() {
+	suite.panics = true
+	assert.Panics(suite.T(), func() { suite.post("key-1", `{"a":1}`) })
+	suite.panics = false
+	responseRec := suite.post("key-1", `{"a":1}`)
+
+	assert.Equal(suite.T(), 2, suite.calls)
+	assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+}
+
+func (suite *FilterSuite) TestResponseIsStoredWhenTheClientWentAway() {
+	ctx, cancel := context.WithCancel(context.Background())
+	httpRequest, _ := http.NewRequestWithContext(ctx, "POST", "/resource", bytes.NewReader([]byte(`{"a":1}`)))
+	httpRequest.Header.Set("Content-Type", restful.MIME_JSON)
+	httpRequest.Header.Set(HeaderKey, "key-1")
+	suite.wsContainer.Filter(func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		chain.ProcessFilter(req, resp)
+		cancel()
+	})
+	suite.wsContainer.ServeHTTP(httptest.NewRecorder(), httpRequest)
+
+	stored, err := suite.store.FindIdempotencyKey(context.Background(), "key-1", scope(restful.NewRequest(httpRequest)))
+	if assert.Nil(suite.T(), err) {
+		assert.True(suite.T(), stored.IsCompleted())
+	}
+}
@@ -0,0 +1,67 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+)
+
+// Sweeper periodically removes expired idempotency keys from the store
+type Sweeper struct {
+	store    ports.IdempotencyRepo
+	interval time.Duration
+	logger   log.Logger
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewSweeper(store ports.IdempotencyRepo, interval time.Duration, logger log.Logger) *Sweeper {
+	return &Sweeper{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Starts sweeping in the background, until Stop is called
+func (s *Sweeper) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.sweep()
+			}
+		}
+	}()
+}
+
+// Stops the sweeper, and waits for a running sweep to finish
+func (s *Sweeper) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+func (s *Sweeper) sweep() {
+	deleted, err := s.store.DeleteExpiredIdempotencyKeys(context.Background())
+	if err != nil {
+		s.logger.Error("error deleting expired idempotency keys", "err", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Debug("deleted expired idempotency keys", "count", deleted)
+	}
+}
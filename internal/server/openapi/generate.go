@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/patch"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+)
+
+// Security schemes of the API, see the auth package
+const (
+	// JWT from the login, sent as `Authorization: Bearer xxx`
+	BearerAuth = "bearerAuth"
+	// Key created by an admin, sent as `Authorization: ApiKey xxx`
+	ApiKeyAuth = "apiKeyAuth"
+)
+
+// Route metadata holding the security schemes the route accepts
+const KeySecurity = "openapi.security"
+
+// Documents that the route requires authentication with one of the schemes
+// Used as ws.GET("").Do(openapi.Secured(openapi.BearerAuth)), next to the auth filters
+func Secured(schemes ...string) func(*restful.RouteBuilder) {
+	return func(b *restful.RouteBuilder) {
+		b.Metadata(KeySecurity, schemes)
+	}
+}
+
+// Path parameters, with an optional regular expression, e.g. {id} or {id:[0-9]+}
+var pathParamPattern = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// Generates the OpenAPI document of all the routes registered in the container
+func Generate(cont *restful.Container, info Info) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{
+				BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "JWT returned by the login"},
+				ApiKeyAuth: {Type: "apiKey", In: "header", Name: "Authorization", Description: "API key created by an admin, as `ApiKey <key>`"},
+			},
+		},
+	}
+	s := schemas(doc.Components.Schemas)
+	for _, ws := range cont.RegisteredWebServices() {
+		// the unversioned aliases are only kept for the older clients
+		if apiversion.IsAlias(ws.RootPath()) {
+			continue
+		}
+		// the versions of a route are listed together
+		tag := strings.Trim(apiversion.StripPrefix(ws.RootPath()), "/")
+		for _, route := range ws.Routes() {
+			routePath := pathParamPattern.ReplaceAllString(route.Path, "{$1}")
+			item, ok := doc.Paths[routePath]
+			if !ok {
+				item = &PathItem{}
+				doc.Paths[routePath] = item
+			}
+			op := operation(s, route)
+			if v, ok := apiversion.FromPath(route.Path); ok && len(op.OperationID) > 0 {
+				// the operation ids must be unique across the versions
+				op.OperationID = v.String() + strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:]
+				op.Deprecated = op.Deprecated || v.Deprecated()
+			}
+			if len(tag) > 0 {
+				op.Tags = []string{tag}
+			}
+			(*item)[strings.ToLower(route.Method)] = op
+		}
+	}
+	return doc
+}
+
+func operation(s schemas, route restful.Route) *Operation {
+	op := &Operation{
+		Summary:     route.Doc,
+		Description: route.Notes,
+		OperationID: route.Operation,
+		Responses:   map[string]*Response{},
+		Deprecated:  route.Deprecated,
+	}
+	if schemes, ok := route.Metadata[KeySecurity].([]string); ok {
+		// any one of the schemes is enough
+		for _, scheme := range schemes {
+			op.Security = append(op.Security, SecurityRequirement{scheme: {}})
+		}
+	}
+
+	for _, param := range route.ParameterDocs {
+		data := param.Data()
+		if param.Kind() == restful.BodyParameterKind {
+			op.RequestBody = requestBody(s, route, data)
+			continue
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        data.Name,
+			In:          parameterLocation(param.Kind()),
+			Description: data.Description,
+			Required:    data.Required,
+			Schema:      parameterSchema(data),
+		})
+	}
+
+	for code, resp := range route.ResponseErrors {
+		op.Responses[strconv.Itoa(code)] = responseOf(s, route, code, resp)
+	}
+	if route.DefaultResponse != nil {
+		op.Responses["default"] = responseOf(s, route, http.StatusInternalServerError, *route.DefaultResponse)
+	}
+	return op
+}
+
+func requestBody(s schemas, route restful.Route, data restful.ParameterData) *RequestBody {
+	body := &RequestBody{Description: data.Description, Required: data.Required, Content: map[string]*MediaType{}}
+	for _, mime := range route.Consumes {
+		schema := s.of(route.ReadSample)
+		if mime == patch.MIME_JSON_PATCH {
+			// a list of operations instead of the (partial) resource
+			schema = &Schema{Type: "array", Items: s.of(patch.Operation{})}
+		}
+		body.Content[mime] = &MediaType{Schema: schema}
+	}
+	return body
+}
+
+func responseOf(s schemas, route restful.Route, code int, resp restful.ResponseError) *Response {
+	result := &Response{Description: resp.Message, Content: map[string]*MediaType{}}
+	if len(result.Description) == 0 {
+		result.Description = http.StatusText(code)
+	}
+	for name, header := range resp.Headers {
+		if result.Headers == nil {
+			result.Headers = map[string]*Header{}
+		}
+		result.Headers[name] = &Header{Description: header.Description, Schema: &Schema{Type: header.Type, Format: header.Format}}
+	}
+
+	model := resp.Model
+	switch {
+	case model == nil && code >= http.StatusBadRequest:
+		// every error is answered with a problem
+		result.Content[response.MIME_PROBLEM_JSON] = &MediaType{Schema: s.of(response.Problem{})}
+		return result
+	case model == nil && code < http.StatusMultipleChoices && code != http.StatusNoContent:
+		model = route.WriteSample
+	}
+	if model == nil {
+		result.Content = nil
+		return result
+	}
+	for _, mime := range route.Produces {
+		result.Content[mime] = &MediaType{Schema: s.of(model)}
+	}
+	return result
+}
+
+func parameterLocation(kind int) string {
+	switch kind {
+	case restful.PathParameterKind:
+		return "path"
+	case restful.HeaderParameterKind:
+		return "header"
+	}
+	return "query"
+}
+
+func parameterSchema(data restful.ParameterData) *Schema {
+	schema := &Schema{Type: data.DataType, Format: data.DataFormat, Enum: data.PossibleValues}
+	if len(schema.Type) == 0 {
+		schema.Type = "string"
+	}
+	return schema
+}
+
+// Lists the routes missing documentation, as "METHOD /path: what is missing"
+// A route is documented with a Doc, a successful response and its path parameters
+func Undocumented(cont *restful.Container) []string {
+	var missing []string
+	for _, ws := range cont.RegisteredWebServices() {
+		// the unversioned aliases are only kept for the older clients
+		if apiversion.IsAlias(ws.RootPath()) {
+			continue
+		}
+		for _, route := range ws.Routes() {
+			name := route.Method + " " + route.Path
+			if len(strings.TrimSpace(route.Doc)) == 0 {
+				missing = append(missing, name+": no Doc")
+			}
+			successful := false
+			for code := range route.ResponseErrors {
+				successful = successful || code < http.StatusBadRequest
+			}
+			if !successful {
+				missing = append(missing, name+": no successful response in Returns")
+			}
+			for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+				if !hasParameter(route, match[1], restful.PathParameterKind) {
+					missing = append(missing, name+": no Param for {"+match[1]+"}")
+				}
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func hasParameter(route restful.Route, name string, kind int) bool {
+	for _, param := range route.ParameterDocs {
+		if param.Data().Name == name && param.Kind() == kind {
+			return true
+		}
+	}
+	return false
+}
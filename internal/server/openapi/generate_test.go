@@ -0,0 +1,115 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type testItem struct {
+	Name     string `json:"name" validate:"required,max=75"`
+	Price    float32
+	Quantity int    `json:"quantity" validate:"gte=0"`
+	Status   string `json:"status,omitempty" validate:"omitempty,oneof=NEW OLD"`
+	internal string
+}
+
+func noop(req *restful.Request, resp *restful.Response) {}
+
+func testContainer() *restful.Container {
+	cont := restful.NewContainer()
+	ws := new(restful.WebService)
+	ws.Path("/item").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/{id}").To(noop).
+		Do(Secured(BearerAuth)).
+		Doc("Get an item").
+		Param(ws.PathParameter("id", "ID of the item").DataType("integer")).
+		Writes(testItem{}).
+		Returns(http.StatusOK, "OK", nil).
+		Returns(http.StatusNotFound, "Item doesn't exist", nil))
+	ws.Route(ws.POST("").To(noop).
+		Doc("Create an item").
+		Reads(testItem{}).
+		Returns(http.StatusNoContent, "Created", nil))
+	cont.Add(ws)
+	return cont
+}
+
+func TestGenerate(t *testing.T) {
+	doc := Generate(testContainer(), Info{Title: "test", Version: "1"})
+
+	get := (*doc.Paths["/item/{id}"])["get"]
+	if assert.NotNil(t, get) {
+		assert.Equal(t, "Get an item", get.Summary)
+		assert.Equal(t, []string{"item"}, get.Tags)
+		assert.Equal(t, []SecurityRequirement{{BearerAuth: {}}}, get.Security)
+		assert.Equal(t, &Parameter{Name: "id", In: "path", Description: "ID of the item", Required: true, Schema: &Schema{Type: "integer"}}, get.Parameters[0])
+		assert.Equal(t, "#/components/schemas/openapi.testItem", get.Responses["200"].Content[restful.MIME_JSON].Schema.Ref)
+		assert.Equal(t, "#/components/schemas/response.Problem", get.Responses["404"].Content[response.MIME_PROBLEM_JSON].Schema.Ref)
+	}
+	post := (*doc.Paths["/item"])["post"]
+	if assert.NotNil(t, post) && assert.NotNil(t, post.RequestBody) {
+		assert.Equal(t, "#/components/schemas/openapi.testItem", post.RequestBody.Content[restful.MIME_JSON].Schema.Ref)
+		assert.Nil(t, post.Responses["204"].Content)
+	}
+
+	item := doc.Components.Schemas["openapi.testItem"]
+	if assert.NotNil(t, item) {
+		assert.Equal(t, []string{"name"}, item.Required)
+		assert.ElementsMatch(t, []string{"name", "Price", "quantity", "status"}, keys(item.Properties))
+		assert.Equal(t, int64(75), *item.Properties["name"].MaxLength)
+		assert.Equal(t, "float", item.Properties["Price"].Format)
+		assert.Equal(t, float64(0), *item.Properties["quantity"].Minimum)
+		assert.Equal(t, []string{"NEW", "OLD"}, item.Properties["status"].Enum)
+	}
+}
+
+func TestUndocumented(t *testing.T) {
+	cont := testContainer()
+	ws := new(restful.WebService)
+	ws.Path("/other")
+	ws.Route(ws.GET("/{id}").To(noop))
+	ws.Route(ws.DELETE("/{id}").To(noop).Doc("Delete other").Returns(http.StatusNoContent, "Deleted", nil))
+	cont.Add(ws)
+
+	assert.Equal(t, []string{
+		"DELETE /other/{id}: no Param for {id}",
+		"GET /other/{id}: no Doc",
+		"GET /other/{id}: no Param for {id}",
+		"GET /other/{id}: no successful response in Returns",
+	}, Undocumented(cont))
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	cont := testContainer()
+	NewOpenAPIHandler(config.OpenAPIConfig{SwaggerUI: true}, Info{Title: "test", Version: "1"}, cont)
+
+	responseRec := httptest.NewRecorder()
+	cont.ServeHTTP(responseRec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, responseRec.Code)
+	var doc Document
+	err := json.Unmarshal(responseRec.Body.Bytes(), &doc)
+	assert.Nil(t, err)
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/item/{id}")
+	assert.Contains(t, doc.Paths, "/openapi.json")
+
+	responseRec = httptest.NewRecorder()
+	cont.ServeHTTP(responseRec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, responseRec.Code)
+	assert.Contains(t, responseRec.Body.String(), "/openapi.json")
+}
+
+func keys(m map[string]*Schema) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
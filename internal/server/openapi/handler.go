@@ -0,0 +1,76 @@
+package openapi
+
+import (
+	"net/http"
+	"sync"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+)
+
+const MIME_HTML = "text/html"
+
+type OpenAPIHandler struct {
+	cont *restful.Container
+	info Info
+
+	// generated on the first request, once all the routes are registered
+	once sync.Once
+	doc  *Document
+}
+
+// Serves the OpenAPI document of the routes in the container, and the Swagger UI when enabled
+func NewOpenAPIHandler(cfg config.OpenAPIConfig, info Info, wsCont *restful.Container) *OpenAPIHandler {
+	httpHandler := &OpenAPIHandler{cont: wsCont, info: info}
+
+	// separate services, as the root path "/" is taken
+	ws := new(restful.WebService)
+	ws.Path("/openapi.json").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("").To(httpHandler.GetDocument).
+		Doc("OpenAPI document of the API").
+		Returns(http.StatusOK, "OK", nil))
+	wsCont.Add(ws)
+
+	if cfg.SwaggerUI {
+		uiWs := new(restful.WebService)
+		uiWs.Path("/docs").Produces(MIME_HTML)
+		uiWs.Route(uiWs.GET("").To(httpHandler.GetSwaggerUI).
+			Doc("Swagger UI for browsing the API").
+			Returns(http.StatusOK, "OK", nil))
+		wsCont.Add(uiWs)
+	}
+
+	return httpHandler
+}
+
+func (e *OpenAPIHandler) GetDocument(req *restful.Request, resp *restful.Response) {
+	e.once.Do(func() {
+		e.doc = Generate(e.cont, e.info)
+	})
+	resp.WriteAsJson(e.doc)
+}
+
+func (e *OpenAPIHandler) GetSwaggerUI(req *restful.Request, resp *restful.Response) {
+	resp.AddHeader("Content-Type", MIME_HTML+"; charset=utf-8")
+	resp.Write([]byte(swaggerUI))
+}
+
+// Loads the Swagger UI from a CDN, pointed at the document
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>API documentation</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
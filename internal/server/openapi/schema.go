@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Builds the schemas of Go values the way encoding/json serializes them
+// Named structs are collected as components and referenced, so each is described once
+type schemas map[string]*Schema
+
+// Schema of the type of the sample value
+func (s schemas) of(sample interface{}) *Schema {
+	return s.ofType(reflect.TypeOf(sample))
+}
+
+func (s schemas) ofType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		// any JSON value
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.ofType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.ofType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := componentName(t)
+		if _, ok := s[name]; !ok {
+			// registered before the fields are described, for the structs referencing themselves
+			s[name] = &Schema{}
+			*s[name] = *s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// e.g. product.ProductRequest, as the handler packages reuse type names like Response
+func componentName(t reflect.Type) string {
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+func (s schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			// the fields of embedded structs are serialized as the struct's own
+			embedded := s.object(field.Type)
+			for propName, prop := range embedded.Properties {
+				schema.Properties[propName] = prop
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := s.ofType(field.Type)
+		if applyRules(prop, field.Type, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+	return schema
+}
+
+// Describes the validate tag rules the schema can express, as checked by request.Validate
+// Returns whether the field is required
+func applyRules(schema *Schema, t reflect.Type, rules string) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		tag, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			tag, param = rule[:i], rule[i+1:]
+		}
+		switch tag {
+		case "dive":
+			// the rules after apply to the elements
+			return required
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "password":
+			schema.Format = "password"
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "gt", "gte":
+			if bound, err := strconv.ParseFloat(param, 64); err == nil {
+				schema.Minimum = &bound
+				schema.ExclusiveMinimum = tag == "gt"
+			}
+		case "min", "max":
+			bound, err := strconv.ParseInt(param, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case t.Kind() == reflect.String && tag == "min":
+				schema.MinLength = &bound
+			case t.Kind() == reflect.String:
+				schema.MaxLength = &bound
+			case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && tag == "min":
+				schema.MinItems = &bound
+			case tag == "min":
+				floatBound := float64(bound)
+				schema.Minimum = &floatBound
+			case t.Kind() != reflect.Slice && t.Kind() != reflect.Array:
+				floatBound := float64(bound)
+				schema.Maximum = &floatBound
+			}
+		}
+	}
+	return required
+}
@@ -0,0 +1,302 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+)
+
+// A single JSON Patch operation
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+	// whether the operation has a value member, as null is a value of its own
+	hasValue bool
+}
+
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	type operation Operation
+	if err := json.Unmarshal(data, (*operation)(op)); err != nil {
+		return err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	_, op.hasValue = members["value"]
+	return nil
+}
+
+// Applies a JSON Patch (RFC 6902) to the given document
+// The operations are applied in order, and the patch fails as a whole if any of them fails
+func JSONPatch(doc []byte, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, response.NewInternalServerError("invalid document").WithInternal(err)
+	}
+
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, response.NewBadRequestError("invalid json patch").WithInternal(err)
+	}
+
+	var err error
+	for i, op := range ops {
+		target, err = applyOperation(target, op)
+		if err != nil {
+			if uerr, ok := err.(response.UserError); ok {
+				return nil, uerr
+			}
+			return nil, response.NewBadRequestError(fmt.Sprintf("operation %d (%s %s): %s", i, op.Op, op.Path, err)).WithInternal(err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func applyOperation(doc interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "replace":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		doc, _, err = remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "move":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		// a value can't be moved into one of its own children (RFC 6902, section 4.4)
+		if isProperPrefix(from, path) {
+			return nil, fmt.Errorf("cannot move %s into one of its children", op.From)
+		}
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(value))
+	case "test":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, value) {
+			return nil, response.NewConflictError(fmt.Sprintf("test failed for path %s", op.Path))
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown operation")
+	}
+}
+
+func (op Operation) value() (interface{}, error) {
+	if !op.hasValue {
+		return nil, fmt.Errorf("missing value")
+	}
+	if len(op.Value) == 0 {
+		return nil, nil
+	}
+	var value interface{}
+	err := json.Unmarshal(op.Value, &value)
+	return value, err
+}
+
+// Splits a JSON pointer (RFC 6901) into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path not found")
+			}
+			doc = child
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[idx]
+		default:
+			return nil, fmt.Errorf("path not found")
+		}
+	}
+	return doc, nil
+}
+
+// Adds the value at the given path, and returns the updated document
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("path not found")
+		}
+		child, err := add(child, path[1:], value)
+		node[token] = child
+		return node, err
+	case []interface{}:
+		if len(path) == 1 {
+			idx := len(node)
+			if token != "-" {
+				var err error
+				idx, err = arrayIndex(token, len(node))
+				if err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[idx+1:], node[idx:])
+			node[idx] = value
+			return node, nil
+		}
+		idx, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[idx], err = add(node[idx], path[1:], value)
+		return node, err
+	default:
+		return nil, fmt.Errorf("path not found")
+	}
+}
+
+// Removes the value at the given path, and returns the updated document along with the removed value
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path not found")
+		}
+		if len(path) == 1 {
+			delete(node, token)
+			return node, child, nil
+		}
+		child, removed, err := remove(child, path[1:])
+		node[token] = child
+		return node, removed, err
+	case []interface{}:
+		idx, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(path) == 1 {
+			removed := node[idx]
+			return append(node[:idx], node[idx+1:]...), removed, nil
+		}
+		child, removed, err := remove(node[idx], path[1:])
+		node[idx] = child
+		return node, removed, err
+	default:
+		return nil, nil, fmt.Errorf("path not found")
+	}
+}
+
+// Whether prefix points to an ancestor of path
+func isProperPrefix(prefix []string, path []string) bool {
+	if len(prefix) >= len(path) {
+		return false
+	}
+	for i, token := range prefix {
+		if path[i] != token {
+			return false
+		}
+	}
+	return true
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return idx, nil
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			c[k] = deepCopy(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, child := range v {
+			c[i] = deepCopy(child)
+		}
+		return c
+	default:
+		return v
+	}
+}
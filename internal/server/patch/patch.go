@@ -0,0 +1,87 @@
+// Applies JSON Merge Patch (RFC 7386) and JSON Patch (RFC 6902) documents to resource representations
+package patch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+)
+
+const (
+	MIME_MERGE_PATCH = "application/merge-patch+json"
+	MIME_JSON_PATCH  = "application/json-patch+json"
+)
+
+// Applies the patch from the request body to the JSON representation of original,
+// and decodes the result into patched
+// The patch format is chosen from the request Content-Type
+func Apply(r *http.Request, original interface{}, patched interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return response.NewBadRequestError("error reading patch").WithInternal(err)
+	}
+
+	doc, err := json.Marshal(original)
+	if err != nil {
+		return response.NewInternalServerError("error encoding resource").WithInternal(err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var result []byte
+	switch mediaType {
+	case MIME_MERGE_PATCH:
+		result, err = MergePatch(doc, body)
+	case MIME_JSON_PATCH:
+		result, err = JSONPatch(doc, body)
+	default:
+		return response.NewUnsupportMediaTypeError("expected " + MIME_MERGE_PATCH + " or " + MIME_JSON_PATCH)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(result, patched)
+	if err != nil {
+		return response.NewValidationError("patched resource is invalid").WithInternal(err)
+	}
+
+	return nil
+}
+
+// Applies a JSON Merge Patch (RFC 7386) to the given document
+func MergePatch(doc []byte, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, response.NewInternalServerError("invalid document").WithInternal(err)
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, response.NewBadRequestError("invalid merge patch").WithInternal(err)
+	}
+
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+		} else {
+			targetObj[k] = mergeValue(targetObj[k], v)
+		}
+	}
+
+	return targetObj
+}
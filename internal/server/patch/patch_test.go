@@ -0,0 +1,91 @@
+package patch
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PatchSuite struct {
+	suite.Suite
+}
+
+func TestPatchTestSuite(t *testing.T) {
+	suite.Run(t, new(PatchSuite))
+}
+
+// Example from RFC 7386, section 3
+func (suite *PatchSuite) TestMergePatch() {
+	doc := `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`
+	patch := `{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`
+
+	res, err := MergePatch([]byte(doc), []byte(patch))
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	assert.JSONEq(suite.T(), `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],
+		"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`, string(res))
+}
+
+func (suite *PatchSuite) TestJSONPatch() {
+	doc := `{"name":"test","tags":["a","b"],"category":{"categoryId":1}}`
+	patch := `[
+		{"op":"test","path":"/name","value":"test"},
+		{"op":"replace","path":"/name","value":"renamed"},
+		{"op":"add","path":"/tags/1","value":"c"},
+		{"op":"remove","path":"/tags/0"},
+		{"op":"copy","from":"/category/categoryId","path":"/categoryId"},
+		{"op":"move","from":"/tags","path":"/labels"}
+	]`
+
+	res, err := JSONPatch([]byte(doc), []byte(patch))
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	assert.JSONEq(suite.T(), `{"name":"renamed","labels":["c","b"],"category":{"categoryId":1},"categoryId":1}`, string(res))
+}
+
+func (suite *PatchSuite) TestJSONPatchFailedTest() {
+	_, err := JSONPatch([]byte(`{"name":"test"}`), []byte(`[{"op":"test","path":"/name","value":"other"}]`))
+
+	uerr, ok := err.(response.UserError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusConflict, uerr.Code)
+}
+
+func (suite *PatchSuite) TestJSONPatchInvalidPath() {
+	_, err := JSONPatch([]byte(`{"name":"test"}`), []byte(`[{"op":"replace","path":"/missing","value":"x"}]`))
+
+	uerr, ok := err.(response.UserError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, uerr.Code)
+}
+
+func (suite *PatchSuite) TestJSONPatchNullValue() {
+	res, err := JSONPatch([]byte(`{"name":"test"}`), []byte(`[{"op":"add","path":"/description","value":null},{"op":"test","path":"/description","value":null}]`))
+	assert.Nil(suite.T(), err)
+	assert.JSONEq(suite.T(), `{"name":"test","description":null}`, string(res))
+
+	_, err = JSONPatch([]byte(`{"name":"test"}`), []byte(`[{"op":"add","path":"/description"}]`))
+	uerr, ok := err.(response.UserError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, uerr.Code)
+}
+
+func (suite *PatchSuite) TestJSONPatchMoveIntoChild() {
+	doc := `{"category":{"categoryId":1}}`
+	_, err := JSONPatch([]byte(doc), []byte(`[{"op":"move","from":"/category","path":"/category/parent"}]`))
+	uerr, ok := err.(response.UserError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, uerr.Code)
+
+	// a sibling sharing the start of the name isn't a child
+	res, err := JSONPatch([]byte(doc), []byte(`[{"op":"move","from":"/category","path":"/categoryCopy"}]`))
+	assert.Nil(suite.T(), err)
+	assert.JSONEq(suite.T(), `{"categoryCopy":{"categoryId":1}}`, string(res))
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/params"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/request"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+)
+
+// Headers describing the limit of the client, see draft-ietf-httpapi-ratelimit-headers
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+	HeaderPolicy    = "RateLimit-Policy"
+)
+
+type group struct {
+	prefix string
+	limit  Limit
+}
+
+// Filter limits the requests of each client with a token bucket per route group
+// Clients are told apart by their valid API key, their user ID, or else their IP
+type Filter struct {
+	store  Store
+	groups []group
+	logger log.Logger
+}
+
+func NewFilter(store Store, cfg config.RateLimitConfig, logger log.Logger) *Filter {
+	f := &Filter{
+		store:  store,
+		logger: logger,
+	}
+	for _, g := range cfg.Groups {
+		f.groups = append(f.groups, group{prefix: g.Prefix, limit: toLimit(g.RateLimit)})
+	}
+	// the longest prefix is matched first
+	sort.SliceStable(f.groups, func(i, j int) bool { return len(f.groups[i].prefix) > len(f.groups[j].prefix) })
+	f.groups = append(f.groups, group{prefix: "/", limit: toLimit(cfg.Default)})
+	return f
+}
+
+func toLimit(cfg config.RateLimit) Limit {
+	return Limit{Requests: cfg.Requests, Period: cfg.Period, Burst: cfg.Burst}
+}
+
+func (f *Filter) Filter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	// the groups are shared by the versions of the API, /v1/user/login and /v2/user/login draw from one bucket
+	g := f.match(apiversion.StripPrefix(req.Request.URL.Path))
+	if g.limit.Requests <= 0 || g.limit.Period <= 0 {
+		chain.ProcessFilter(req, resp)
+		return
+	}
+
+	res, err := f.store.Take(req.Request.Context(), g.prefix+" "+clientKey(req.Request), g.limit)
+	if err != nil {
+		// the limiter being down shouldn't take the API down with it
+		f.logError("error taking rate limit token", err)
+		chain.ProcessFilter(req, resp)
+		return
+	}
+
+	header := resp.Header()
+	header.Set(HeaderLimit, strconv.Itoa(g.limit.Capacity()))
+	header.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+	header.Set(HeaderReset, seconds(res.ResetAfter))
+	header.Set(HeaderPolicy, strconv.Itoa(g.limit.Requests)+";w="+seconds(g.limit.Period)+";burst="+strconv.Itoa(g.limit.Capacity()))
+	if !res.Allowed {
+		header.Set("Retry-After", seconds(res.RetryAfter))
+		response.WriteError(resp, response.NewTooManyRequestsError("too many requests, try again later"))
+		return
+	}
+
+	chain.ProcessFilter(req, resp)
+}
+
+// Returns the group the path belongs to, the default one when no other matches
+func (f *Filter) match(path string) group {
+	for _, g := range f.groups {
+		if path == g.prefix || strings.HasPrefix(path, strings.TrimSuffix(g.prefix, "/")+"/") {
+			return g
+		}
+	}
+	return f.groups[len(f.groups)-1]
+}
+
+func (f *Filter) logError(msg string, err error) {
+	if f.logger == nil {
+		return
+	}
+	f.logger.Error(msg, "err", err)
+}
+
+// Identifies the client making the request
+// The container filters run before the authentication of the routes, so the credentials are checked here,
+// the unknown ones falling back to the IP, otherwise every made up key or JWT would get a bucket of its own
+func clientKey(r *http.Request) string {
+	if userId, err := params.StringFrom(r, auth.USER_ID_CTX_KEY); err == nil && len(userId) > 0 {
+		return "user:" + userId
+	}
+	authHeader := r.Header.Get("Authorization")
+	if keyId, ok := auth.ApiKeyID(r.Context(), authHeader); ok {
+		return "key:" + keyId
+	}
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		claims, err := auth.GetJWTClaims(strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer ")))
+		if userId, ok := claims["id"].(string); err == nil && ok && len(userId) > 0 {
+			return "user:" + userId
+		}
+	}
+	return "ip:" + request.ClientIP(r)
+}
+
+// Formats the duration as whole seconds, rounded up so clients don't retry too early
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
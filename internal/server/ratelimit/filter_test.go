@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// store which is always down
+type failingStore struct{}
+
+func (failingStore) Take(_ context.Context, _ string, _ Limit) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+type FilterSuite struct {
+	suite.Suite
+	store       *MemoryStore
+	now         time.Time
+	wsContainer *restful.Container
+}
+
+var testConfig = config.RateLimitConfig{
+	Default: config.RateLimit{Requests: 60, Period: time.Minute, Burst: 3},
+	Groups: []config.RateLimitGroup{
+		{Prefix: "/user/login", RateLimit: config.RateLimit{Requests: 6, Period: time.Minute, Burst: 1}},
+		{Prefix: "/open", RateLimit: config.RateLimit{}},
+	},
+}
+
+func (suite *FilterSuite) SetupSuite() {
+	auth.SetSigningKey("0123456789abcdef0123456789abcdef")
+	auth.SetApiKeyAuthenticator(func(_ context.Context, key string) (*domain.ApiKey, error) {
+		switch key {
+		case "hfk_first":
+			return &domain.ApiKey{ID: "key-1"}, nil
+		case "hfk_second":
+			return &domain.ApiKey{ID: "key-2"}, nil
+		}
+		return nil, domain.ErrInvalidApiKey
+	})
+}
+
+func (suite *FilterSuite) TearDownSuite() {
+	auth.SetApiKeyAuthenticator(nil)
+}
+
+func (suite *FilterSuite) SetupTest() {
+	suite.now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	suite.store = NewMemoryStore()
+	suite.store.now = func() time.Time { return suite.now }
+	suite.wsContainer = suite.container(suite.store)
+}
+
+func (suite *FilterSuite) container(store Store) *restful.Container {
+	wsContainer := restful.NewContainer()
+	wsContainer.Filter(NewFilter(store, testConfig, nil).Filter)
+	ws := new(restful.WebService)
+	for _, path := range []string{"/product", "/user/login", "/user/login/2fa", "/open", "/v1/user/login", "/v2/user/login"} {
+		ws.Route(ws.GET(path).To(func(req *restful.Request, resp *restful.Response) {}))
+	}
+	wsContainer.Add(ws)
+	return wsContainer
+}
+
+func TestFilterTestSuite(t *testing.T) {
+	suite.Run(t, new(FilterSuite))
+}
+
+func (suite *FilterSuite) get(path string, remoteAddr string, authHeader string) *httptest.ResponseRecorder {
+	httpRequest, _ := http.NewRequest("GET", path, nil)
+	httpRequest.RemoteAddr = remoteAddr
+	if authHeader != "" {
+		httpRequest.Header.Set("Authorization", authHeader)
+	}
+	responseRec := httptest.NewRecorder()
+	suite.wsContainer.ServeHTTP(responseRec, httpRequest)
+	return responseRec
+}
+
+func (suite *FilterSuite) TestLimitsBurstAndRefills() {
+	for i := 2; i >= 0; i-- {
+		responseRec := suite.get("/product", "10.0.0.1:1234", "")
+		assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+		assert.Equal(suite.T(), "3", responseRec.Header().Get(HeaderLimit))
+		assert.Equal(suite.T(), strconv.Itoa(i), responseRec.Header().Get(HeaderRemaining))
+	}
+	assert.Equal(suite.T(), "60;w=60;burst=3", suite.get("/product", "10.0.0.2:1234", "").Header().Get(HeaderPolicy))
+
+	responseRec := suite.get("/product", "10.0.0.1:1234", "")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, responseRec.Code)
+	assert.Equal(suite.T(), "1", responseRec.Header().Get("Retry-After"))
+	assert.Equal(suite.T(), "3", responseRec.Header().Get(HeaderReset))
+
+	// a token is refilled every second
+	suite.now = suite.now.Add(time.Second)
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/product", "10.0.0.1:1234", "").Code)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, suite.get("/product", "10.0.0.1:1234", "").Code)
+
+	// the refilled buckets are dropped
+	suite.now = suite.now.Add(time.Hour)
+	suite.get("/product", "10.0.0.3:1234", "")
+	assert.Len(suite.T(), suite.store.buckets, 1)
+}
+
+func (suite *FilterSuite) TestGroupsHaveTheirOwnLimits() {
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/user/login", "10.0.0.1:1234", "").Code)
+	responseRec := suite.get("/user/login/2fa", "10.0.0.1:1234", "")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, responseRec.Code)
+	assert.Equal(suite.T(), "10", responseRec.Header().Get("Retry-After"))
+
+	// other routes are counted separately
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/product", "10.0.0.1:1234", "").Code)
+
+	// groups without a limit aren't limited
+	for i := 0; i < 5; i++ {
+		responseRec = suite.get("/open", "10.0.0.1:1234", "")
+		assert.Equal(suite.T(), http.StatusOK, responseRec.Code)
+		assert.Empty(suite.T(), responseRec.Header().Get(HeaderLimit))
+	}
+}
+
+func (suite *FilterSuite) TestVersionsShareTheGroups() {
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/v1/user/login", "10.0.0.1:1234", "").Code)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, suite.get("/v2/user/login", "10.0.0.1:1234", "").Code)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, suite.get("/user/login", "10.0.0.1:1234", "").Code)
+}
+
+func (suite *FilterSuite) TestClientsAreToldApart() {
+	token, err := auth.CreateJWT("testy@email.com", "abcd-123", "USER", 0)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	otherToken, err := auth.CreateJWT("other@email.com", "abcd-456", "USER", 0)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/user/login", "10.0.0.1:1234", "").Code)
+	// the user is limited wherever they call from
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/user/login", "10.0.0.1:1234", "Bearer "+token).Code)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, suite.get("/user/login", "10.0.0.2:1234", "Bearer "+token).Code)
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/user/login", "10.0.0.1:1234", "Bearer "+otherToken).Code)
+
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/user/login", "10.0.0.1:1234", "ApiKey hfk_first").Code)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, suite.get("/user/login", "10.0.0.2:1234", "ApiKey hfk_first").Code)
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/user/login", "10.0.0.1:1234", "ApiKey hfk_second").Code)
+
+	// invalid JWTs and unknown API keys fall back to the IP
+	assert.Equal(suite.T(), http.StatusTooManyRequests, suite.get("/user/login", "10.0.0.1:1234", "Bearer invalid").Code)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, suite.get("/user/login", "10.0.0.1:1234", "ApiKey hfk_made_up").Code)
+	assert.Equal(suite.T(), http.StatusOK, suite.get("/user/login", "10.0.0.3:1234", "ApiKey hfk_made_up").Code)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, suite.get("/user/login", "10.0.0.3:1234", "ApiKey hfk_other_made_up").Code)
+}
+
+func (suite *FilterSuite) TestStoreErrorsLetRequestsThrough() {
+	suite.wsContainer = suite.container(failingStore{})
+	for i := 0; i < 5; i++ {
+		assert.Equal(suite.T(), http.StatusOK, suite.get("/user/login", "10.0.0.1:1234", "").Code)
+	}
+}
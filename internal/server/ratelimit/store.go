@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit of a token bucket: it holds up to Burst tokens, and is refilled with Requests tokens every Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// How many requests the bucket lets through at once
+func (l Limit) Capacity() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// How long it takes to refill a single token
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Outcome of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// When the next token will be available, zero while there are tokens left
+	RetryAfter time.Duration
+	// When the bucket will be full again
+	ResetAfter time.Duration
+}
+
+// Store keeps the token buckets of the clients
+// The in-memory store only limits the requests of a single instance, instances sharing the limits need a shared store
+type Store interface {
+	// Takes a token from the bucket of the key, a new key starts with a full bucket
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// How often the memory store drops the buckets which refilled
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	// When the tokens were last counted
+	updatedAt time.Time
+	// When the bucket will be full again
+	fullAt time.Time
+}
+
+// MemoryStore keeps the buckets in memory, the buckets which refilled are dropped
+// since a full bucket is the same as a missing one
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	now func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	capacity := float64(limit.Capacity())
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updatedAt: now}
+		s.buckets[key] = b
+	}
+	// refill the tokens for the time passed since the last request
+	interval := limit.interval()
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.updatedAt))/float64(interval))
+	b.updatedAt = now
+
+	var res Result
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+	res.Remaining = int(b.tokens)
+	res.ResetAfter = time.Duration((capacity - b.tokens) * float64(interval))
+	b.fullAt = now.Add(res.ResetAfter)
+	return res, nil
+}
+
+// Drops the buckets which are full again
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !b.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,29 @@
+package request
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+)
+
+// Returns the entity version the client expects, taken from the If-Match header
+// Returns 0 if the header is set to *, meaning any version matches, the writes without the header are refused
+// so the clients can't overwrite the changes they haven't seen by mistake
+func IfMatchVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, response.NewPreconditionRequiredError("If-Match header required, with the ETag of the entity or *")
+	}
+	if header == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version <= 0 || strings.HasPrefix(header, "W/") {
+		return 0, response.NewBadRequestError("invalid If-Match header").WithInternal(err)
+	}
+
+	return version, nil
+}
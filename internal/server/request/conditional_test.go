@@ -0,0 +1,41 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	cases := map[string]int{
+		`"3"`:   http.StatusOK,
+		"*":     http.StatusOK,
+		"":      http.StatusPreconditionRequired,
+		"abc":   http.StatusBadRequest,
+		`W/"3"`: http.StatusBadRequest,
+	}
+	for header, status := range cases {
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		if header != "" {
+			req.Header.Set("If-Match", header)
+		}
+		_, err := IfMatchVersion(req)
+		if status == http.StatusOK {
+			assert.Nil(t, err, header)
+			continue
+		}
+		var uerr response.UserError
+		if assert.True(t, errors.As(err, &uerr), header) {
+			assert.Equal(t, status, uerr.Code, header)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	req.Header.Set("If-Match", `"3"`)
+	version, _ := IfMatchVersion(req)
+	assert.Equal(t, 3, version)
+}
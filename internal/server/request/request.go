@@ -3,31 +3,30 @@ package request
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
 	"github.com/pkg/errors"
-	"gopkg.in/go-playground/validator.v9"
 )
 
+// Decodes the JSON body of the request into v, and validates it
+// Returns a validation error with the fields which couldn't be decoded or are invalid
 func ReadBody(r *http.Request, v interface{}) error {
 	err := json.NewDecoder(r.Body).Decode(v)
-	if err != nil {
-		return response.NewValidationError("invalid request body").WithInternal(err)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return response.NewValidationError("invalid request body").
+			WithFields(domain.FieldError{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()}).WithInternal(err)
 	}
-
-	validate := validator.New()
-	err = validate.Struct(v)
 	if err != nil {
-		for _, fieldErr := range err.(validator.ValidationErrors) {
-			return response.NewValidationError("Error in field: " + strings.ToLower(fieldErr.Field()) +
-				". Expected: " + fieldErr.ActualTag() + " " + fieldErr.Param()).WithInternal(err)
-		}
+		return response.NewValidationError("invalid request body").WithInternal(err)
 	}
 
-	return nil
+	return Validate(v)
 }
 
 type MissingParamError struct {
@@ -111,3 +110,13 @@ func BoolQueryParam(r *http.Request, k string, v bool) (bool, error) {
 
 	return b, nil
 }
+
+// Returns the IP the request came from
+// Forwarding headers are ignored, since any client can set them
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
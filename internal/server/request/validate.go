@@ -0,0 +1,111 @@
+package request
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// Passwords need at least this many characters, with a letter and a digit among them
+const MinPasswordLength = 8
+
+// Validator of the request bodies, with the rules of this API registered
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// fields are reported by the names the clients send them under
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	v.RegisterValidation("password", isStrongPassword)
+	return v
+}
+
+func isStrongPassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		hasLetter = hasLetter || unicode.IsLetter(r)
+		hasDigit = hasDigit || unicode.IsDigit(r)
+	}
+	return utf8.RuneCountInString(password) >= MinPasswordLength && hasLetter && hasDigit
+}
+
+// Validate checks v against the rules in its validate tags
+// Returns a validation error listing every field at fault, not just the first one
+func Validate(v interface{}) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+	fieldErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return response.NewInternalServerError("error validating request").WithInternal(err)
+	}
+	fields := make([]domain.FieldError, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		fields = append(fields, domain.FieldError{Field: fieldPath(fieldErr), Message: fieldMessage(fieldErr)})
+	}
+	return response.NewValidationError("invalid request body").WithFields(fields...).WithInternal(err)
+}
+
+// Path of the field in the body, e.g. product_items[0].productId
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	// the namespace starts with the name of the validated struct
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "url":
+		return "must be a valid URL"
+	case "password":
+		return fmt.Sprintf("must be at least %d characters, with a letter and a digit", MinPasswordLength)
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be at least " + param
+	case "lt":
+		return "must be less than " + param
+	case "lte":
+		return "must be at most " + param
+	case "min", "max":
+		bound := "at least "
+		if fieldErr.Tag() == "max" {
+			bound = "at most "
+		}
+		switch fieldErr.Kind() {
+		case reflect.String:
+			return "must be " + bound + param + " characters long"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return "must have " + bound + param + " items"
+		}
+		return "must be " + bound + param
+	}
+	return "must satisfy " + fieldErr.Tag()
+}
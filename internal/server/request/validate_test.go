@@ -0,0 +1,67 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type testItem struct {
+	Quantity int `json:"quantity" validate:"gt=0"`
+}
+
+type testBody struct {
+	Email    string      `validate:"required,email"`
+	Password string      `validate:"required,password"`
+	Name     string      `json:"name" validate:"max=5"`
+	Items    *[]testItem `json:"items" validate:"omitempty,dive"`
+}
+
+func readTestBody(t *testing.T, body string) []domain.FieldError {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	var v testBody
+	err := ReadBody(req, &v)
+	var uerr response.UserError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected a user error, got %v", err)
+	}
+	assert.Equal(t, http.StatusBadRequest, uerr.Code)
+	return uerr.Fields
+}
+
+func TestReadBodyListsEveryInvalidField(t *testing.T) {
+	fields := readTestBody(t, `{"Email": "not an email", "name": "too long", "items": [{"quantity": 1}, {"quantity": 0}]}`)
+
+	assert.ElementsMatch(t, []domain.FieldError{
+		{Field: "Email", Message: "must be a valid email"},
+		{Field: "Password", Message: "is required"},
+		{Field: "name", Message: "must be at most 5 characters long"},
+		{Field: "items[1].quantity", Message: "must be greater than 0"},
+	}, fields)
+}
+
+func TestReadBodyReportsMistypedField(t *testing.T) {
+	fields := readTestBody(t, `{"name": 12}`)
+
+	assert.Equal(t, []domain.FieldError{{Field: "name", Message: "must be a string"}}, fields)
+}
+
+func TestPasswordStrength(t *testing.T) {
+	cases := map[string]bool{
+		"password123": true,
+		"pässwörd1":   true,
+		"password":    false,
+		"12345678":    false,
+		"pass1":       false,
+	}
+	for password, valid := range cases {
+		err := Validate(&testBody{Email: "testy@email.com", Password: password})
+		assert.Equal(t, valid, err == nil, password)
+	}
+}
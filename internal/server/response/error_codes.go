@@ -1,3 +1,34 @@
 package response
 
-var ()
+// Machine-readable codes of the errors, sent as error_code so clients don't have to match on the messages
+// The hundreds group the codes by their kind
+const (
+	ErrCodeBadRequest           = 1000
+	ErrCodeValidation           = 1001
+	ErrCodeUnsupportedMediaType = 1002
+	ErrCodeNotAcceptable        = 1003
+	ErrCodeMethodNotAllowed     = 1004
+
+	ErrCodeUnauthorized       = 1100
+	ErrCodeForbidden          = 1101
+	ErrCodeInvalidCredentials = 1102
+	ErrCodeInvalidToken       = 1103
+	ErrCodeEmailNotVerified   = 1104
+	ErrCodeInvalidTwoFactor   = 1105
+	ErrCodeInvalidApiKey      = 1106
+
+	ErrCodeNotFound = 1200
+
+	ErrCodeConflict            = 1300
+	ErrCodeVersionConflict     = 1301
+	ErrCodeInsufficientStock   = 1302
+	ErrCodeTwoFactorEnabled    = 1303
+	ErrCodeTwoFactorNotEnabled = 1304
+	ErrCodeVersionRequired     = 1305
+
+	ErrCodeTooManyRequests = 1400
+	ErrCodeLoginBlocked    = 1401
+
+	ErrCodeInternal           = 1500
+	ErrCodeServiceUnavailable = 1501
+)
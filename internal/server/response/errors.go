@@ -1,102 +1,161 @@
 package response
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+)
 
 type UserError struct {
 	Code      int    `json:"-"`
 	Message   string `json:"error"`
 	ErrorCode *int   `json:"error_code"`
-	Internal  error  `json:"-"`
+	// Fields at fault, for validation errors
+	Fields   []domain.FieldError `json:"errors,omitempty"`
+	Internal error               `json:"-"`
 }
 
 func NewValidationError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusBadRequest,
-		Message: msg,
+		Code:      http.StatusBadRequest,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeValidation),
 	}
 }
 
 func NewForbiddenError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusForbidden,
-		Message: msg,
+		Code:      http.StatusForbidden,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeForbidden),
 	}
 }
 
 func NewConflictError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusConflict,
-		Message: msg,
+		Code:      http.StatusConflict,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeConflict),
 	}
 }
 
 func NewNotAcceptableError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusNotAcceptable,
-		Message: msg,
+		Code:      http.StatusNotAcceptable,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeNotAcceptable),
+	}
+}
+
+func NewMethodNotAllowedError(msg string) UserError {
+	return UserError{
+		Code:      http.StatusMethodNotAllowed,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeMethodNotAllowed),
 	}
 }
 
 func NewNotAcceptableForLegalReasonsError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusUnavailableForLegalReasons,
-		Message: msg,
+		Code:      http.StatusUnavailableForLegalReasons,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeForbidden),
 	}
 }
 
 func NewPaymentRequiredError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusPaymentRequired,
-		Message: msg,
+		Code:      http.StatusPaymentRequired,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeForbidden),
 	}
 }
 
 func NewUnauthorizedError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusUnauthorized,
-		Message: msg,
+		Code:      http.StatusUnauthorized,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeUnauthorized),
 	}
 }
 
 func NewTooEarlyError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusTooEarly,
-		Message: msg,
+		Code:      http.StatusTooEarly,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeTooManyRequests),
 	}
 }
 
 func NewNotFoundError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusNotFound,
-		Message: msg,
+		Code:      http.StatusNotFound,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeNotFound),
 	}
 }
 
 func NewInternalServerError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusInternalServerError,
-		Message: msg,
+		Code:      http.StatusInternalServerError,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeInternal),
 	}
 }
 
 func NewServiceUnavailableError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusServiceUnavailable,
-		Message: msg,
+		Code:      http.StatusServiceUnavailable,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeServiceUnavailable),
 	}
 }
 
 func NewUnsupportMediaTypeError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusUnsupportedMediaType,
-		Message: msg,
+		Code:      http.StatusUnsupportedMediaType,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeUnsupportedMediaType),
+	}
+}
+
+func NewPreconditionFailedError(msg string) UserError {
+	return UserError{
+		Code:      http.StatusPreconditionFailed,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeVersionConflict),
+	}
+}
+
+func NewPreconditionRequiredError(msg string) UserError {
+	return UserError{
+		Code:      http.StatusPreconditionRequired,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeVersionRequired),
+	}
+}
+
+func NewUnprocessableEntityError(msg string) UserError {
+	return UserError{
+		Code:      http.StatusUnprocessableEntity,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeValidation),
+	}
+}
+
+func NewTooManyRequestsError(msg string) UserError {
+	return UserError{
+		Code:      http.StatusTooManyRequests,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeTooManyRequests),
 	}
 }
 
 func NewBadRequestError(msg string) UserError {
 	return UserError{
-		Code:    http.StatusBadRequest,
-		Message: msg,
+		Code:      http.StatusBadRequest,
+		Message:   msg,
+		ErrorCode: errorCode(ErrCodeBadRequest),
 	}
 }
 
@@ -115,3 +174,18 @@ func (e UserError) WithErrorCode(errorCode int) UserError {
 
 	return e
 }
+
+func (e UserError) WithFields(fields ...domain.FieldError) UserError {
+	e.Fields = fields
+
+	return e
+}
+
+// The wrapped error, so errors.Is and errors.As see through the UserError
+func (e UserError) Unwrap() error {
+	return e.Internal
+}
+
+func errorCode(code int) *int {
+	return &code
+}
@@ -0,0 +1,16 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Formats an entity version as a strong ETag
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Sets the ETag header for the given entity version
+func SetETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", ETag(version))
+}
@@ -0,0 +1,121 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+)
+
+// Media type of the error responses, see RFC 7807
+const MIME_PROBLEM_JSON = "application/problem+json"
+
+// Problem is the body of every error response, as described in RFC 7807
+// The problems aren't documented by their type, so it's always about:blank with the status text as the title,
+// error_code tells them apart instead
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	ErrorCode int                 `json:"error_code"`
+	Errors    []domain.FieldError `json:"errors,omitempty"`
+}
+
+func (e UserError) Problem() Problem {
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(e.Code),
+		Status: e.Code,
+		Detail: e.Message,
+		Errors: e.Fields,
+	}
+	if e.ErrorCode != nil {
+		problem.ErrorCode = *e.ErrorCode
+	}
+	return problem
+}
+
+// FromError maps an error to the UserError it's answered with
+// This is the one place where the domain errors are given their HTTP status and error code,
+// errors which aren't known are answered with a 500 which doesn't reveal them
+func FromError(err error) UserError {
+	var uerr UserError
+	if errors.As(err, &uerr) {
+		return uerr
+	}
+
+	var notFound *domain.NotFoundError
+	var validation *domain.ValidationError
+	var conflict *domain.ConflictError
+	var insufficientStock *domain.InsufficientStockError
+	var loginBlocked *domain.LoginBlockedError
+	switch {
+	case errors.As(err, &notFound):
+		uerr = NewNotFoundError(notFound.Error())
+	case errors.As(err, &validation):
+		uerr = NewValidationError(validation.Error()).WithFields(validation.Fields...)
+	case errors.As(err, &insufficientStock):
+		uerr = NewConflictError(insufficientStock.Error()).WithErrorCode(ErrCodeInsufficientStock)
+	case errors.As(err, &conflict):
+		uerr = NewConflictError(conflict.Error())
+	case errors.As(err, &loginBlocked):
+		uerr = NewTooManyRequestsError("too many failed logins, try again later").WithErrorCode(ErrCodeLoginBlocked)
+	case errors.Is(err, domain.ErrVersionConflict):
+		uerr = NewPreconditionFailedError(domain.ErrVersionConflict.Error())
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		uerr = NewForbiddenError(domain.ErrInvalidCredentials.Error()).WithErrorCode(ErrCodeInvalidCredentials)
+	case errors.Is(err, domain.ErrInvalidTwoFactorCode):
+		uerr = NewForbiddenError(domain.ErrInvalidTwoFactorCode.Error()).WithErrorCode(ErrCodeInvalidTwoFactor)
+	case errors.Is(err, domain.ErrInvalidToken):
+		uerr = NewBadRequestError(domain.ErrInvalidToken.Error()).WithErrorCode(ErrCodeInvalidToken)
+	case errors.Is(err, domain.ErrEmailNotVerified):
+		uerr = NewForbiddenError(domain.ErrEmailNotVerified.Error()).WithErrorCode(ErrCodeEmailNotVerified)
+	case errors.Is(err, domain.ErrTwoFactorEnabled):
+		uerr = NewConflictError(domain.ErrTwoFactorEnabled.Error()).WithErrorCode(ErrCodeTwoFactorEnabled)
+	case errors.Is(err, domain.ErrTwoFactorNotEnrolled):
+		uerr = NewConflictError(domain.ErrTwoFactorNotEnrolled.Error()).WithErrorCode(ErrCodeTwoFactorNotEnabled)
+	case errors.Is(err, domain.ErrInvalidApiKey):
+		uerr = NewUnauthorizedError(domain.ErrInvalidApiKey.Error()).WithErrorCode(ErrCodeInvalidApiKey)
+	default:
+		uerr = NewInternalServerError("an error occured")
+	}
+	return uerr.WithInternal(err)
+}
+
+// WriteError answers the request with the problem the error maps to
+func WriteError(w http.ResponseWriter, err error) {
+	uerr := FromError(err)
+	if uerr.Code >= http.StatusInternalServerError {
+		recordError(w, err)
+	}
+	w.Header().Set("Content-Type", MIME_PROBLEM_JSON)
+	w.WriteHeader(uerr.Code)
+	json.NewEncoder(w).Encode(uerr.Problem())
+}
+
+// ErrorRecorder is implemented by the response writers wanting the errors answered with a 5xx, e.g. to report them
+type ErrorRecorder interface {
+	RecordError(err error)
+}
+
+// Hands the error to the first ErrorRecorder the writer wraps, the writers of the filters are unwrapped
+// through their Unwrap method
+func recordError(w http.ResponseWriter, err error) {
+	if resp, ok := w.(*restful.Response); ok {
+		w = resp.ResponseWriter
+	}
+	for w != nil {
+		if recorder, ok := w.(ErrorRecorder); ok {
+			recorder.RecordError(err)
+			return
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = unwrapper.Unwrap()
+	}
+}
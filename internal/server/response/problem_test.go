@@ -0,0 +1,72 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromErrorMapsDomainErrors(t *testing.T) {
+	cases := []struct {
+		err       error
+		status    int
+		errorCode int
+	}{
+		{domain.NewNotFoundError("product"), http.StatusNotFound, ErrCodeNotFound},
+		{domain.NewValidationError("invalid order"), http.StatusBadRequest, ErrCodeValidation},
+		{domain.NewConflictError("email already exists"), http.StatusConflict, ErrCodeConflict},
+		{&domain.InsufficientStockError{ProductID: 1, Requested: 5, Available: 2}, http.StatusConflict, ErrCodeInsufficientStock},
+		{&domain.LoginBlockedError{}, http.StatusTooManyRequests, ErrCodeLoginBlocked},
+		{domain.ErrVersionConflict, http.StatusPreconditionFailed, ErrCodeVersionConflict},
+		{domain.ErrInvalidCredentials, http.StatusForbidden, ErrCodeInvalidCredentials},
+		{domain.ErrInvalidToken, http.StatusBadRequest, ErrCodeInvalidToken},
+		{domain.ErrEmailNotVerified, http.StatusForbidden, ErrCodeEmailNotVerified},
+		{domain.ErrInvalidApiKey, http.StatusUnauthorized, ErrCodeInvalidApiKey},
+		{NewUnsupportMediaTypeError("expected json"), http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType},
+		{errors.New("connection refused"), http.StatusInternalServerError, ErrCodeInternal},
+	}
+	for _, c := range cases {
+		// usecases wrap the errors they pass on
+		uerr := FromError(pkgerrors.Wrap(c.err, "Failed to do something"))
+		assert.Equal(t, c.status, uerr.Code, c.err.Error())
+		if assert.NotNil(t, uerr.ErrorCode, c.err.Error()) {
+			assert.Equal(t, c.errorCode, *uerr.ErrorCode, c.err.Error())
+		}
+	}
+}
+
+func TestWriteErrorWritesProblem(t *testing.T) {
+	responseRec := httptest.NewRecorder()
+	WriteError(responseRec, domain.NewValidationError("invalid order", domain.FieldError{Field: "status", Message: "required"}))
+
+	assert.Equal(t, http.StatusBadRequest, responseRec.Code)
+	assert.Equal(t, MIME_PROBLEM_JSON, responseRec.Header().Get("Content-Type"))
+	var problem Problem
+	err := json.Unmarshal(responseRec.Body.Bytes(), &problem)
+	if err != nil {
+		t.Fatalf("Error unmarshalling problem: %s", err)
+	}
+	assert.Equal(t, Problem{
+		Type:      "about:blank",
+		Title:     "Bad Request",
+		Status:    http.StatusBadRequest,
+		Detail:    "invalid order",
+		ErrorCode: ErrCodeValidation,
+		Errors:    []domain.FieldError{{Field: "status", Message: "required"}},
+	}, problem)
+}
+
+// Test that internal errors don't leak into the responses
+func TestWriteErrorHidesUnknownErrors(t *testing.T) {
+	responseRec := httptest.NewRecorder()
+	WriteError(responseRec, errors.New("pq: password authentication failed"))
+
+	assert.Equal(t, http.StatusInternalServerError, responseRec.Code)
+	assert.NotContains(t, responseRec.Body.String(), "pq:")
+}
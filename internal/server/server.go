@@ -7,34 +7,77 @@ import (
 	"time"
 
 	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/cache"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/usecases"
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/errorreport"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/events"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/apikey"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/category"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/order"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/product"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/user"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/handlers/webhook"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/mail"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/metrics"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/repo"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/apiversion"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/health"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/idempotency"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/openapi"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/ratelimit"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/response"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/tracing"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/webhooks"
+	"github.com/pkg/errors"
 )
 
 type Server struct {
 	srv    *http.Server
 	wsCont *restful.Container
 
+	// background jobs, running while the server is up
+	workers []worker
+	// how long the workers are given to stop, once the requests are drained
+	stopTimeout time.Duration
+
+	// checks of the components reported by /readyz
+	Health *health.Registry
+
+	// flushes the spans left on shutdown
+	tracing *tracing.Provider
+
 	RequestLogger log.Logger
 }
 
-type ApiVersion int
+type worker interface {
+	Start()
+	Stop()
+}
+
+// How long the requests are drained for, and then the workers given to stop, when not configured
+const DefaultShutdownTimeout = 30 * time.Second
+
+type ApiVersion = apiversion.Version
 
 const (
-	V1 ApiVersion = iota
-	V2
+	V1 = apiversion.V1
+	V2 = apiversion.V2
 )
 
 func NewServer(cfg config.ServerConfig, db *database.DB) *Server {
+	if cfg.Logger == nil {
+		cfg.Logger = log.NewNilLogger()
+	}
+	// the logger of the contexts which aren't a request's
+	log.SetDefault(cfg.Logger)
+	auth.SetSigningKey(cfg.JWTSigningKey)
 
 	// http Server
 	httpSrv := &http.Server{
@@ -53,12 +96,50 @@ func NewServer(cfg config.ServerConfig, db *database.DB) *Server {
 		srv:    httpSrv,
 		wsCont: wsCont,
 
+		stopTimeout: cfg.ShutdownTimeout,
+
 		RequestLogger: cfg.Logger,
 	}
 
-	// add logging
+	// spans of the requests, with the usecases and queries they run as children
+	tracingProvider, err := tracing.Init(cfg.Tracing)
+	if err != nil {
+		panic(errors.Wrap(err, "error setting up tracing"))
+	}
+	fullSrv.tracing = tracingProvider
+	usecases.SetTracer(tracing.UsecaseTracer{})
+	wsCont.Filter(tracing.Filter)
+
+	// give every request an id, carried by the logger of its context, then add logging, and the request metrics
+	wsCont.Filter(log.RequestIDFilter(cfg.Logger))
 	wsCont.Filter(log.NCSACommonLogFormatLogger(cfg.Logger))
 
+	// panics and 5xx errors are reported in the background, with the request and its breadcrumbs
+	errorReporter, err := errorreport.NewReporter(cfg.ErrorReporting)
+	if err != nil {
+		panic(errors.Wrap(err, "error creating error reporter"))
+	}
+	errorQueue := errorreport.NewQueue(errorReporter, cfg.ErrorReporting.QueueSize, cfg.Logger)
+	fullSrv.workers = append(fullSrv.workers, errorQueue)
+	wsCont.Filter(errorreport.NewCapturer(errorQueue, cfg.ErrorReporting, cfg.Logger).Filter)
+
+	wsCont.Filter(metrics.RequestFilter)
+
+	// the older versions of the API announce when they go away
+	sunset, err := apiVersionsSunset(cfg.ApiVersions)
+	if err != nil {
+		panic(errors.Wrap(err, "error reading api_versions.sunset"))
+	}
+	wsCont.Filter(apiversion.DeprecationFilter(sunset))
+
+	// limit the requests of each client, before any work is done for them
+	wsCont.Filter(ratelimit.NewFilter(ratelimit.NewMemoryStore(), cfg.RateLimit, cfg.Logger).Filter)
+
+	// replay responses of retried POST requests
+	idempotencyRep := repo.NewIdempotencyRepository(db)
+	wsCont.Filter(idempotency.NewFilter(idempotencyRep, cfg.IdempotencyTTL, cfg.Logger).Filter)
+	fullSrv.workers = append(fullSrv.workers, idempotency.NewSweeper(idempotencyRep, time.Hour, cfg.Logger))
+
 	// add error handling
 	wsCont.DoNotRecover(false)
 	wsCont.ServiceErrorHandler(fullSrv.WriteServiceErrorJson)
@@ -67,25 +148,114 @@ func NewServer(cfg config.ServerConfig, db *database.DB) *Server {
 	// base server paths
 	baseWs := new(restful.WebService)
 	baseWs.Path("/")
-	baseWs.Route(baseWs.GET("/ping").Filter(auth.AuthJWT).To(ping))
+	baseWs.Route(baseWs.GET("/ping").Filter(auth.AuthJWT).To(ping).
+		Do(openapi.Secured(openapi.BearerAuth)).
+		Doc("Check the JWT is accepted").
+		Returns(http.StatusOK, "PONG", nil).
+		Returns(http.StatusUnauthorized, "Not authenticated", nil))
 
 	wsCont.Add(baseWs)
 
+	// domain events are stored in the outbox by the usecases, and relayed to the bus subscribers
+	outboxRep := repo.NewOutboxRepository(db)
+	eventBus := events.NewBus()
+	eventBus.SubscribeAll("log", events.NewLogPublisher(cfg.Logger).Publish)
+	eventBus.SubscribeAll("metrics", events.NewMetricsPublisher().Publish)
+	fullSrv.workers = append(fullSrv.workers, events.NewRelay(outboxRep, db, eventBus, time.Second, 100, cfg.Logger))
+
+	// events are delivered to the webhook subscriptions interested in them
+	webhookRep := repo.NewWebhookRepository(db)
+	eventBus.SubscribeAll("webhooks", webhooks.NewDispatcher(webhookRep).Enqueue)
+	fullSrv.workers = append(fullSrv.workers, webhooks.NewDeliverer(webhookRep, cfg.Webhooks, time.Second, 20, cfg.Logger))
+
+	// emails go out through a queue, so slow mail servers don't hold up the requests and the relay
+	mailer, err := mail.NewMailer(cfg.Mail)
+	if err != nil {
+		panic(errors.Wrap(err, "error creating mailer"))
+	}
+	mailTemplates, err := mail.NewTemplates(cfg.Mail.DefaultLocale)
+	if err != nil {
+		panic(errors.Wrap(err, "error loading mail templates"))
+	}
+	mailQueue := mail.NewQueue(mailer, cfg.Mail.QueueSize, cfg.Logger)
+	fullSrv.workers = append(fullSrv.workers, mailQueue)
+
+	// probes of the orchestrators, the adapters register the checks of the components they connect to
+	fullSrv.Health = health.NewRegistry(cfg.Health.Timeout)
+	fullSrv.Health.Register("database", db.Ping)
+	for i, check := range db.ReplicaChecks() {
+		// the reads go to the primary while a replica is down
+		fullSrv.Health.RegisterOptional(fmt.Sprintf("database_replica_%d", i), check)
+	}
+	fullSrv.Health.Register("migrations", database.NewMigrationProcess(db, cfg.Logger).CheckCurrent(cfg.Health.MigrationsDir))
+	if pinger, ok := mailer.(health.Pinger); ok {
+		// emails wait in the queue while the mail server is down
+		fullSrv.Health.RegisterOptional("mail", pinger.Ping)
+	}
+	health.NewHealthHandler(fullSrv.Health, wsCont)
+
+	// scraped by Prometheus
+	if cfg.Metrics.Enabled {
+		metrics.Default.MustRegister(metrics.DBPoolCollectors(db.Stats)...)
+		metrics.NewMetricsHandler(metrics.Default, wsCont)
+	}
+
 	// register routes
 	userRep := repo.NewUserRepository(db)
-	userSvc := usecases.NewUserService(userRep)
+	tokenRep := repo.NewUserTokenRepository(db)
+	accountNotifier := mail.NewAccountNotifier(mailQueue, mailTemplates, cfg.Mail.BaseURL)
+	userSvc := usecases.NewUserService(userRep, tokenRep, outboxRep, db, accountNotifier)
+	// JWTs issued before a password change are rejected, and roles changes applied, as soon as they're made,
+	// so not by a replica lagging behind
+	auth.SetSessionChecker(func(ctx context.Context, userId string, sessionVersion int) (string, bool, error) {
+		return userSvc.CheckSession(database.WithPrimary(ctx), userId, sessionVersion)
+	})
+	auditRep := repo.NewAuthAuditRepository(db)
+	twoFactorRep := repo.NewTwoFactorRepository(db)
+	loginPolicy := domain.LoginPolicy{
+		Window:             cfg.Login.Window,
+		MaxAccountFailures: cfg.Login.MaxAccountFailures,
+		MaxIPFailures:      cfg.Login.MaxIPFailures,
+		LockoutDuration:    cfg.Login.LockoutDuration,
+		Delay:              cfg.Login.Delay,
+		MaxDelay:           cfg.Login.MaxDelay,
+	}
+	twoFactorSvc := usecases.NewTwoFactorService(userRep, twoFactorRep, auditRep, db, loginPolicy, cfg.Login.TOTPIssuer)
+	loginSvc := usecases.NewLoginService(userRep, auditRep, tokenRep, twoFactorRep, db, loginPolicy)
+	apiKeySvc := usecases.NewApiKeyService(repo.NewApiKeyRepository(db))
+	// other services call the API with the keys created by admins
+	auth.SetApiKeyAuthenticator(apiKeySvc.Authenticate)
 
-	categoryRep := repo.NewCategoryRepository(db)
+	// the catalog reads are cached, the writes drop what they change once committed
+	var categoryRep ports.CategoryRepo = repo.NewCategoryRepository(db)
+	var productRep ports.ProductRepo = repo.NewProductRepository(db)
+	catalogCache, err := cache.NewCache(cfg.Cache)
+	if err != nil {
+		panic(errors.Wrap(err, "error creating cache"))
+	}
+	if catalogCache != nil {
+		categoryRep = cache.NewCategoryRepo(categoryRep, catalogCache, cfg.Cache.TTL)
+		productRep = cache.NewProductRepo(productRep, categoryRep, catalogCache, cfg.Cache.TTL)
+	}
 	categorySvc := usecases.NewCategoryService(categoryRep)
 
-	productRep := repo.NewProductRepository(db)
-	productSvc := usecases.NewProductService(productRep)
+	productSvc := usecases.NewProductService(productRep, outboxRep, db)
 	orderRep := repo.NewOrderRepository(db)
-	orderSvc := usecases.NewOrderService(orderRep, productRep, userRep)
+	orderSvc := usecases.NewOrderService(orderRep, productRep, userRep, outboxRep, db)
+
+	// users are emailed about the events concerning them
+	notifier := mail.NewNotifier(mailQueue, mailTemplates, userSvc, orderSvc, cfg.Logger)
+	eventBus.Subscribe("mail", domain.EventUserRegistered, notifier.UserRegistered)
+	eventBus.Subscribe("mail", domain.EventOrderCreated, notifier.OrderCreated)
+	eventBus.Subscribe("mail", domain.EventOrderStatusChanged, notifier.OrderStatusChanged)
+
 	product.NewProductHandler(productSvc, categorySvc, wsCont)
 	category.NewCategoryHandler(categorySvc, wsCont)
 	order.NewOrderHandler(orderSvc, productSvc, categorySvc, userSvc, wsCont)
-	user.NewUserHandler(userSvc, wsCont)
+	user.NewUserHandler(userSvc, loginSvc, twoFactorSvc, wsCont)
+	webhook.NewWebhookHandler(usecases.NewWebhookService(webhookRep, webhooks.NewGuard(cfg.Webhooks.AllowPrivateNetworks)), wsCont)
+	apikey.NewApiKeyHandler(apiKeySvc, wsCont)
+	openapi.NewOpenAPIHandler(cfg.OpenAPI, openapi.Info{Title: "HEXFWK API", Version: "1.0.0"}, wsCont)
 
 	http.Handle("/", wsCont)
 
@@ -93,24 +263,100 @@ func NewServer(cfg config.ServerConfig, db *database.DB) *Server {
 }
 
 func (s *Server) ListenAndServe(env string, domain string) error {
+	for _, w := range s.workers {
+		w.Start()
+	}
 	return s.srv.ListenAndServe()
 }
 
+// Stops the server: new connections are refused and the requests in flight drained until ctx is done,
+// then the workers are stopped in the order they were added, so the ones producing work, like the relay,
+// stop before the queues they feed
+// The workers get a budget of their own, as ctx may be used up by the draining, and the database pool
+// is only to be closed once they stopped
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.srv.Shutdown(ctx)
+	s.RequestLogger.Info("shutdown: refusing new connections, draining the requests in flight")
+	err := s.srv.Shutdown(ctx)
+	if err != nil {
+		s.RequestLogger.Error("shutdown: requests still in flight at the deadline", "err", err)
+		err = errors.Wrap(err, "drain requests")
+	}
+	stopTimeout := s.stopTimeout
+	if stopTimeout <= 0 {
+		stopTimeout = DefaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	for _, w := range s.workers {
+		name := fmt.Sprintf("%T", w)
+		s.RequestLogger.Info("shutdown: stopping worker", "worker", name)
+		if werr := stopWorker(ctx, w); werr != nil {
+			s.RequestLogger.Error("shutdown: worker didn't stop before the deadline", "worker", name)
+			if err == nil {
+				err = errors.Wrapf(werr, "stop %s", name)
+			}
+		}
+	}
+	s.RequestLogger.Info("shutdown: flushing the spans")
+	if terr := s.tracing.Shutdown(ctx); terr != nil {
+		s.RequestLogger.Error("shutdown: spans not flushed", "err", terr)
+	}
+	s.RequestLogger.Info("shutdown: server stopped")
+	return err
+}
+
+// Waits for the worker to stop, until ctx is done
+func stopWorker(ctx context.Context, w worker) error {
+	done := make(chan struct{})
+	go func() {
+		w.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func apiVersionsSunset(cfg config.ApiVersionsConfig) (time.Time, error) {
+	if cfg.Sunset == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", cfg.Sunset)
 }
 
 func ping(req *restful.Request, resp *restful.Response) {
 	resp.Write([]byte("PONG"))
 }
 
+// Answers the requests restful couldn't route, e.g. to unknown paths or with an unsupported content type
 func (s *Server) WriteServiceErrorJson(err restful.ServiceError, req *restful.Request, resp *restful.Response) {
-	s.RequestLogger.Errorf("Service error: ", err)
-	resp.WriteHeader(500)
-	resp.WriteAsJson("Internal server error")
+	log.FromContext(req.Request.Context()).Warn("service error", "code", err.Code, "message", err.Message)
+	response.WriteError(resp, serviceError(err))
+}
+
+func serviceError(err restful.ServiceError) response.UserError {
+	switch err.Code {
+	case http.StatusNotFound:
+		return response.NewNotFoundError(err.Message)
+	case http.StatusMethodNotAllowed:
+		return response.NewMethodNotAllowedError(err.Message)
+	case http.StatusNotAcceptable:
+		return response.NewNotAcceptableError(err.Message)
+	case http.StatusUnsupportedMediaType:
+		return response.NewUnsupportMediaTypeError(err.Message)
+	}
+	if err.Code >= http.StatusBadRequest && err.Code < http.StatusInternalServerError {
+		uerr := response.NewBadRequestError(err.Message)
+		uerr.Code = err.Code
+		return uerr
+	}
+	return response.NewInternalServerError("an error occured")
 }
 
 func (s *Server) RecoverHandler(i interface{}, w http.ResponseWriter) {
-	s.RequestLogger.Error("Server panic error: ", i)
-	w.Write([]byte("Internal server error"))
+	s.RequestLogger.Error("server panic", "panic", i)
+	response.WriteError(w, response.NewInternalServerError("an error occured"))
 }
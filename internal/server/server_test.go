@@ -1,13 +1,19 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/emicklei/go-restful/v3"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/openapi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -26,11 +32,16 @@ func (suite *ServerSuite) TearDownTest() {
 func (suite *ServerSuite) SetupSuite() {
 
 	cfg := config.ServerConfig{
-		Port:   3001,
-		Logger: nil,
+		Port:          3001,
+		Logger:        nil,
+		JWTSigningKey: "0123456789abcdef0123456789abcdef",
 	}
 
 	suite.server = *NewServer(cfg, nil)
+	// there is no database to look the sessions up in
+	auth.SetSessionChecker(func(ctx context.Context, userId string, sessionVersion int) (string, bool, error) {
+		return domain.RoleUser, true, nil
+	})
 }
 
 func TestUserTestSuite(t *testing.T) {
@@ -40,7 +51,7 @@ func TestUserTestSuite(t *testing.T) {
 // Validate the ping route is working, using basic JWT auth
 func (suite *ServerSuite) TestPingRoute() {
 	userEmail := "testy@email.com"
-	jwtToken, err := auth.CreateJWT(userEmail, "id-123")
+	jwtToken, err := auth.CreateJWT(userEmail, "id-123", domain.RoleUser, 0)
 	if err != nil {
 		suite.T().Fatal(err)
 	}
@@ -54,4 +65,89 @@ func (suite *ServerSuite) TestPingRoute() {
 	suite.server.wsCont.ServeHTTP(responseRec, httpRequest)
 
 	assert.Equal(suite.T(), 200, responseRec.Result().StatusCode)
+	assert.Equal(suite.T(), "PONG", responseRec.Body.String())
+	assert.NotEmpty(suite.T(), responseRec.Header().Get(log.RequestIDHeader))
+}
+
+// Every route must be described in the OpenAPI document, for the API clients generated from it
+func (suite *ServerSuite) TestRoutesAreDocumented() {
+	for _, missing := range openapi.Undocumented(suite.server.wsCont) {
+		suite.T().Error(missing)
+	}
+}
+
+func (suite *ServerSuite) TestOpenAPIDocument() {
+	doc := openapi.Generate(suite.server.wsCont, openapi.Info{Title: "test", Version: "1"})
+
+	if assert.Contains(suite.T(), doc.Paths, "/v1/product/{id}") {
+		if assert.Contains(suite.T(), *doc.Paths["/v1/product/{id}"], "patch") {
+			op := (*doc.Paths["/v1/product/{id}"])["patch"]
+			assert.Equal(suite.T(), "v1PatchProduct", op.OperationID)
+			assert.True(suite.T(), op.Deprecated)
+		}
+	}
+	if assert.Contains(suite.T(), doc.Paths, "/v2/product/{id}") {
+		assert.False(suite.T(), (*doc.Paths["/v2/product/{id}"])["get"].Deprecated)
+	}
+	assert.Contains(suite.T(), doc.Components.Schemas, "product.ProductRequest")
+	assert.Contains(suite.T(), doc.Components.Schemas, "product.ProductModelV2")
+	_, err := json.Marshal(doc)
+	assert.Nil(suite.T(), err)
+}
+
+type fakeWorker struct {
+	name    string
+	stopped *[]string
+	delay   time.Duration
+}
+
+func (w *fakeWorker) Start() {}
+
+func (w *fakeWorker) Stop() {
+	time.Sleep(w.delay)
+	*w.stopped = append(*w.stopped, w.name)
+}
+
+func (suite *ServerSuite) TestShutdownStopsTheWorkersInOrder() {
+	var stopped []string
+	srv := &Server{
+		srv:           &http.Server{},
+		RequestLogger: &log.NilLogger{},
+		workers: []worker{
+			&fakeWorker{name: "relay", stopped: &stopped},
+			&fakeWorker{name: "queue", stopped: &stopped},
+		},
+	}
+	err := srv.Shutdown(context.Background())
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"relay", "queue"}, stopped)
+}
+
+func (suite *ServerSuite) TestShutdownGivesUpAtTheDeadline() {
+	var stopped []string
+	srv := &Server{
+		srv:           &http.Server{},
+		RequestLogger: &log.NilLogger{},
+		workers:       []worker{&fakeWorker{name: "slow", stopped: &stopped, delay: time.Second}},
+		stopTimeout:   10 * time.Millisecond,
+	}
+	start := time.Now()
+	err := srv.Shutdown(context.Background())
+	assert.ErrorIs(suite.T(), err, context.DeadlineExceeded)
+	assert.Less(suite.T(), time.Since(start), time.Second)
+}
+
+func (suite *ServerSuite) TestWorkersStopAfterTheDrainingDeadline() {
+	var stopped []string
+	srv := &Server{
+		srv:           &http.Server{},
+		RequestLogger: &log.NilLogger{},
+		workers:       []worker{&fakeWorker{name: "relay", stopped: &stopped, delay: 10 * time.Millisecond}},
+		stopTimeout:   time.Second,
+	}
+	// used up by the draining
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	srv.Shutdown(ctx)
+	assert.Equal(suite.T(), []string{"relay"}, stopped)
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/emicklei/go-restful/v3"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/app"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/database"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/server/auth"
 	"github.com/pkg/errors"
 )
 
@@ -28,6 +29,7 @@ func InitTestApp() *app.App {
 	if err != nil {
 		panic(errors.Wrap(err, "error migrating DB"))
 	}
+	auth.SetSigningKey(app.Config.Http.JWTSigningKey)
 
 	return app
 }
@@ -50,12 +52,43 @@ func MakeRequest(container *restful.Container, method string, path string, postD
 	return responseRec
 }
 
+// Sends a HTTP request with a raw body and the given headers to the set path, using the set method
+func MakeRawRequest(container *restful.Container, method string, path string, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	httpRequest, _ := http.NewRequest(method, path, bytes.NewReader(body))
+	for k, v := range headers {
+		httpRequest.Header.Set(k, v)
+	}
+	responseRec := httptest.NewRecorder()
+
+	// send request
+	container.ServeHTTP(responseRec, httpRequest)
+
+	return responseRec
+}
+
+// Sets the role of the user, admins being promoted in the database
+func SetUserRole(db database.DB, id string, role string) {
+	_, err := db.Exec(context.TODO(), "UPDATE hex_fwk.user SET role = $2 WHERE id = $1", id, role)
+	if err != nil {
+		panic(errors.Wrap(err, "error setting the user's role"))
+	}
+}
+
 // Deletes all records from all tables
 func CleanUpTables(db database.DB) {
 	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.order_product CASCADE")
 	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.order CASCADE")
 	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.product CASCADE")
 	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.category CASCADE")
+	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.auth_audit CASCADE")
+	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.user_recovery_code CASCADE")
+	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.user_totp CASCADE")
+	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.user_token CASCADE")
 	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.user CASCADE")
+	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.idempotency_key CASCADE")
+	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.outbox CASCADE")
+	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.webhook_delivery CASCADE")
+	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.webhook_subscription CASCADE")
+	db.Exec(context.TODO(), "TRUNCATE TABLE hex_fwk.api_key CASCADE")
 
 }
@@ -0,0 +1,57 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the SHA-1 test secret of RFC 6238, appendix B
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeMatchesRFCVectors(t *testing.T) {
+	// the RFC lists 8 digit codes, these are their last 6 digits
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, expected := range vectors {
+		code, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidateAcceptsAdjacentSteps(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	previous, _ := Code(rfcSecret, Step(now)-1)
+	tooOld, _ := Code(rfcSecret, Step(now)-2)
+
+	step, ok := Validate(rfcSecret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now)-1, step)
+
+	_, ok = Validate(rfcSecret, tooOld, now)
+	assert.False(t, ok)
+	_, ok = Validate(rfcSecret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.Nil(t, err)
+
+	uri := URI("HEXFWK", "testy@email.com", secret)
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/HEXFWK:testy@email.com?"))
+	assert.Contains(t, uri, "secret="+secret)
+	assert.Contains(t, uri, "issuer=HEXFWK")
+}
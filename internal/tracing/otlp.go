@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporter sending the spans to an OpenTelemetry collector over gRPC
+// Without an endpoint, the OTEL_EXPORTER_OTLP_* variables are used, then localhost:4317
+func newOTLPGRPCExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	var opts []otlptracegrpc.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+	}
+	// the connection is made in the background, a collector which is down doesn't keep the server from starting
+	return otlptracegrpc.New(context.Background(), opts...)
+}
+
+// Exporter sending the spans to an OpenTelemetry collector over HTTP, as protobuf
+// Without an endpoint, the OTEL_EXPORTER_OTLP_* variables are used, then localhost:4318
+func newOTLPHTTPExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	return otlptracehttp.New(context.Background(), opts...)
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Starts the span of the request, a child of the traceparent sent by the caller if any
+// The routes name the spans, e.g. "GET /v1/order/{id}", so the requests to a route are grouped
+func Filter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	ctx := otel.GetTextMapPropagator().Extract(req.Request.Context(), propagation.HeaderCarrier(req.Request.Header))
+	route := req.SelectedRoutePath()
+	name := req.Request.Method + " " + route
+	if route == "" {
+		name = req.Request.Method
+	}
+	ctx, span := Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethod(req.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.HTTPTarget(req.Request.URL.RequestURI()),
+		))
+	req.Request = req.Request.WithContext(ctx)
+
+	completed := false
+	defer func() {
+		status := resp.StatusCode()
+		if !completed {
+			// answered with 500 by the recover handler
+			status = http.StatusInternalServerError
+		}
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
+	}()
+	chain.ProcessFilter(req, resp)
+	completed = true
+}
+
+// Starts the span of a database call, with its SQL statement
+// The returned function ends it, sql.ErrNoRows isn't counted as a failure
+func StartQuery(ctx context.Context, operation string, query string) (context.Context, func(err error)) {
+	ctx, span := Tracer().Start(ctx, "db."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBStatementKey.String(query),
+		))
+	return ctx, func(err error) {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+var _ ports.Tracer = UsecaseTracer{}
+
+// UsecaseTracer starts the spans of the usecase methods, see usecases.SetTracer
+type UsecaseTracer struct{}
+
+func (UsecaseTracer) Start(ctx context.Context, name string) (context.Context, ports.Span) {
+	ctx, span := Tracer().Start(ctx, name)
+	return ctx, usecaseSpan{span}
+}
+
+type usecaseSpan struct {
+	span trace.Span
+}
+
+func (s usecaseSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
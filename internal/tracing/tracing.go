@@ -0,0 +1,150 @@
+// Package tracing sets up OpenTelemetry, and starts the spans of the requests, usecases and queries
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name the spans of the server are reported under
+const instrumentationName = "github.com/mitrovicsoftcoder/go-hexagonal-framework"
+
+const (
+	// Spans are dropped, the traceparent of the callers is still passed on
+	ExporterNone = "none"
+	// Spans are written to stdout as JSON, for local runs
+	ExporterStdout = "stdout"
+	// Spans are appended to a file as JSON, for local runs
+	ExporterFile = "file"
+	// Spans are sent to an OpenTelemetry collector, over gRPC or HTTP
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterOTLPHTTP = "otlp-http"
+)
+
+// Creates the exporter the spans are sent to
+type ExporterFactory func(cfg config.TracingConfig) (sdktrace.SpanExporter, error)
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = map[string]ExporterFactory{
+		ExporterStdout: func(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+			return stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		},
+		ExporterFile:     newFileExporter,
+		ExporterOTLPGRPC: newOTLPGRPCExporter,
+		ExporterOTLPHTTP: newOTLPHTTPExporter,
+	}
+)
+
+// Makes another exporter available in the config, e.g. a vendor's one
+func RegisterExporter(name string, factory ExporterFactory) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	exporters[name] = factory
+}
+
+// Tracer the spans of the server are started with
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Provider flushes the spans left when the server shuts down
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	closer   io.Closer
+}
+
+// Sets up the global tracer provider with the exporter of the config
+// The W3C trace context is propagated whatever the exporter, so the traces of the callers aren't broken
+func Init(cfg config.TracingConfig) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Exporter == "" || cfg.Exporter == ExporterNone {
+		return &Provider{}, nil
+	}
+
+	exportersMu.RLock()
+	factory, ok := exporters[cfg.Exporter]
+	exportersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	exporter, err := factory(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "create "+cfg.Exporter+" exporter")
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "hexfwk"
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		// the callers decide for the traces they started
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	p := &Provider{provider: provider}
+	if closer, ok := exporter.(io.Closer); ok {
+		p.closer = closer
+	}
+	return p, nil
+}
+
+// Exports the spans still buffered, until ctx is done
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil || p.provider == nil {
+		return nil
+	}
+	err := p.provider.Shutdown(ctx)
+	if p.closer != nil {
+		if cerr := p.closer.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Exporter writing to a file, which is closed with it
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func newFileExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	if cfg.File == "" {
+		return nil, errors.New("tracing.file is required by the file exporter")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.File), 0755); err != nil {
+		return nil, errors.Wrap(err, "create traces directory")
+	}
+	file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "open traces file")
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileExporter{SpanExporter: exporter, file: file}, nil
+}
+
+func (e *fileExporter) Close() error {
+	return e.file.Close()
+}
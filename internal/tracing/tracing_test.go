@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type TracingSuite struct {
+	suite.Suite
+	recorder    *tracetest.SpanRecorder
+	wsContainer *restful.Container
+}
+
+func (suite *TracingSuite) SetupTest() {
+	suite.recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(suite.recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	suite.wsContainer = restful.NewContainer()
+	suite.wsContainer.Filter(Filter)
+	ws := new(restful.WebService)
+	ws.Path("/item")
+	ws.Route(ws.GET("/{id}").To(func(req *restful.Request, resp *restful.Response) {
+		ctx, span := UsecaseTracer{}.Start(req.Request.Context(), "ItemService.FindItemById")
+		_, done := StartQuery(ctx, "get", "SELECT * FROM item WHERE id = $1")
+		done(sql.ErrNoRows)
+		span.End(errors.New("item not found"))
+		resp.WriteHeader(http.StatusInternalServerError)
+	}))
+	suite.wsContainer.Add(ws)
+}
+
+func TestTracingTestSuite(t *testing.T) {
+	suite.Run(t, new(TracingSuite))
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func (suite *TracingSuite) TestSpansOfARequest() {
+	httpRequest, _ := http.NewRequest("GET", "/item/42", nil)
+	httpRequest.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	suite.wsContainer.ServeHTTP(httptest.NewRecorder(), httpRequest)
+
+	spans := suite.recorder.Ended()
+	if !assert.Len(suite.T(), spans, 3) {
+		return
+	}
+	query, usecase, request := spans[0], spans[1], spans[2]
+
+	// the request continues the trace of the caller
+	assert.Equal(suite.T(), "GET /item/{id}", request.Name())
+	assert.Equal(suite.T(), "4bf92f3577b34da6a3ce929d0e0e4736", request.SpanContext().TraceID().String())
+	assert.Equal(suite.T(), "00f067aa0ba902b7", request.Parent().SpanID().String())
+	assert.Equal(suite.T(), int64(500), attributes(request)["http.status_code"].AsInt64())
+	assert.Equal(suite.T(), codes.Error, request.Status().Code)
+
+	assert.Equal(suite.T(), request.SpanContext().SpanID(), usecase.Parent().SpanID())
+	assert.Equal(suite.T(), "item not found", usecase.Status().Description)
+
+	assert.Equal(suite.T(), usecase.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(suite.T(), "db.get", query.Name())
+	assert.Equal(suite.T(), "SELECT * FROM item WHERE id = $1", attributes(query)["db.statement"].AsString())
+	// no rows isn't a failure of the database
+	assert.Equal(suite.T(), codes.Unset, query.Status().Code)
+}
+
+func (suite *TracingSuite) TestInitWithFileExporter() {
+	file := filepath.Join(suite.T().TempDir(), "traces", "spans.json")
+	provider, err := Init(config.TracingConfig{Exporter: ExporterFile, File: file, SampleRatio: 1})
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	_, span := Tracer().Start(context.Background(), "test")
+	span.End()
+	assert.Nil(suite.T(), provider.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(file)
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), string(data), `"Name":"test"`)
+
+	_, err = Init(config.TracingConfig{Exporter: "jaeger"})
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *TracingSuite) TestInitWithOTLPExporter() {
+	received := make(chan *http.Request, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- r:
+		default:
+		}
+	}))
+	defer collector.Close()
+
+	provider, err := Init(config.TracingConfig{Exporter: ExporterOTLPHTTP, Endpoint: strings.TrimPrefix(collector.URL, "http://"),
+		Insecure: true, Headers: map[string]string{"x-api-key": "collector-key"}, SampleRatio: 1})
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	_, span := Tracer().Start(context.Background(), "test")
+	span.End()
+	assert.Nil(suite.T(), provider.Shutdown(context.Background()))
+
+	select {
+	case r := <-received:
+		assert.Equal(suite.T(), "/v1/traces", r.URL.Path)
+		assert.Equal(suite.T(), "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(suite.T(), "collector-key", r.Header.Get("x-api-key"))
+	default:
+		suite.T().Fatal("no spans sent to the collector")
+	}
+
+	// the gRPC connection is made in the background
+	provider, err = Init(config.TracingConfig{Exporter: ExporterOTLPGRPC, Endpoint: "localhost:4317", Insecure: true})
+	assert.Nil(suite.T(), err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	provider.Shutdown(ctx)
+}
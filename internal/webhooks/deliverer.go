@@ -0,0 +1,209 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+)
+
+// Used for the settings missing from the config
+const (
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = 30 * time.Second
+	DefaultMaxBackoff  = time.Hour
+	DefaultTimeout     = 10 * time.Second
+)
+
+// Deliverer periodically sends the due deliveries to the subscriptions
+// Failed deliveries are retried with exponential backoff, and dead-lettered once they run out of attempts
+type Deliverer struct {
+	repo      ports.WebhookRepo
+	client    *http.Client
+	cfg       config.WebhookConfig
+	interval  time.Duration
+	batchSize int
+	logger    log.Logger
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewDeliverer(repo ports.WebhookRepo, cfg config.WebhookConfig, interval time.Duration, batchSize int, logger log.Logger) *Deliverer {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	guard := NewGuard(cfg.AllowPrivateNetworks)
+	return &Deliverer{
+		repo: repo,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// no proxy, the address connected to is the receiver's, and checked by the guard
+			Transport: &http.Transport{
+				DialContext:         (&net.Dialer{Timeout: cfg.Timeout, Control: guard.Control}).DialContext,
+				TLSHandshakeTimeout: cfg.Timeout,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// a redirect counts as a failed delivery, the subscription should be updated instead
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg:       cfg,
+		interval:  interval,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Starts delivering in the background, until Stop is called
+func (d *Deliverer) Start() {
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				_, err := d.DeliverBatch(context.Background())
+				if err != nil {
+					d.logger.Error("error delivering webhooks", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stops the deliverer, and waits for a running batch to finish
+func (d *Deliverer) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+}
+
+// Attempts one batch of due deliveries
+// The deliveries are claimed first, and sent outside of any transaction, so slow receivers hold neither
+// a connection nor row locks; each outcome is then stored on its own
+// Returns the number of attempted deliveries
+func (d *Deliverer) DeliverBatch(ctx context.Context) (int, error) {
+	// long enough for every delivery of the batch to time out
+	leasedUntil := time.Now().Add(time.Duration(d.batchSize)*d.cfg.Timeout + time.Minute)
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, d.batchSize, leasedUntil)
+	if err != nil {
+		return 0, err
+	}
+	attempted := 0
+	subscriptions := map[string]*domain.WebhookSubscription{}
+	for i := range *deliveries {
+		delivery := &(*deliveries)[i]
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = d.repo.FindSubscriptionById(ctx, delivery.SubscriptionID)
+			if err != nil {
+				// attempted again once the lease runs out, unless the subscription was deleted meanwhile
+				d.logger.Warn("error retrieving webhook subscription", "id", delivery.SubscriptionID, "err", err)
+				continue
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		d.attempt(ctx, subscription, delivery)
+		err = d.repo.UpdateDelivery(ctx, delivery)
+		if err != nil {
+			return attempted, err
+		}
+		attempted++
+	}
+	return attempted, nil
+}
+
+// Sends the delivery, and records the outcome on it
+func (d *Deliverer) attempt(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.WebhookDelivery) {
+	delivery.Attempts++
+
+	statusCode, err := d.send(ctx, subscription, delivery)
+	delivery.LastStatusCode = statusCode
+	if err == nil {
+		now := time.Now()
+		delivery.Status = domain.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.cfg.MaxAttempts {
+		d.logger.Warn("webhook delivery dead-lettered", "id", delivery.ID, "subscription", subscription.ID, "err", err)
+		delivery.Status = domain.DeliveryDeadLettered
+		return
+	}
+	delivery.NextAttemptAt = time.Now().Add(d.Backoff(delivery.Attempts))
+}
+
+// Posts the signed delivery to the subscription URL
+// Returns the response status code (0 when no response was received), and an error unless it is a 2xx
+func (d *Deliverer) send(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "HEXFWK-Webhooks")
+	req.Header.Set(HeaderDeliveryID, delivery.ID)
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain the body, so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Returns how long to wait before the next attempt, after the given number of failed ones
+func (d *Deliverer) Backoff(attempts int) time.Duration {
+	backoff := d.cfg.Backoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= d.cfg.MaxBackoff {
+			return d.cfg.MaxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,286 @@
+package webhooks
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// in-memory webhook store, standing in for the database repository
+type memoryRepo struct {
+	subscriptions map[string]*domain.WebhookSubscription
+	deliveries    []*domain.WebhookDelivery
+	// roles of the subscription owners
+	roles map[string]string
+}
+
+func (m *memoryRepo) InsertSubscription(_ context.Context, subscription *domain.WebhookSubscription) error {
+	m.subscriptions[subscription.ID] = subscription
+	return nil
+}
+
+func (m *memoryRepo) FindSubscriptionById(_ context.Context, id string) (*domain.WebhookSubscription, error) {
+	return m.subscriptions[id], nil
+}
+
+func (m *memoryRepo) FindSubscriptionsByUser(_ context.Context, userId string) (*[]domain.WebhookSubscription, error) {
+	var subscriptions []domain.WebhookSubscription
+	for _, subscription := range m.subscriptions {
+		if subscription.UserID == userId {
+			subscriptions = append(subscriptions, *subscription)
+		}
+	}
+	return &subscriptions, nil
+}
+
+func (m *memoryRepo) FindSubscriptionsForEvent(_ context.Context, eventType domain.EventType, ownerRole string) (*[]domain.WebhookSubscription, error) {
+	var subscriptions []domain.WebhookSubscription
+	for _, subscription := range m.subscriptions {
+		if subscription.Accepts(eventType) && m.roles[subscription.UserID] == ownerRole {
+			subscriptions = append(subscriptions, *subscription)
+		}
+	}
+	return &subscriptions, nil
+}
+
+func (m *memoryRepo) DeleteSubscription(_ context.Context, id string) (int64, error) {
+	delete(m.subscriptions, id)
+	return 1, nil
+}
+
+func (m *memoryRepo) InsertDelivery(_ context.Context, delivery *domain.WebhookDelivery) error {
+	for _, d := range m.deliveries {
+		if d.SubscriptionID == delivery.SubscriptionID && d.EventID == delivery.EventID {
+			return nil
+		}
+	}
+	delivery.ID = delivery.SubscriptionID + "/" + delivery.EventID
+	m.deliveries = append(m.deliveries, delivery)
+	return nil
+}
+
+func (m *memoryRepo) ClaimDueDeliveries(_ context.Context, limit int, leasedUntil time.Time) (*[]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.Status == domain.DeliveryPending && !d.NextAttemptAt.After(time.Now()) && len(deliveries) < limit {
+			d.NextAttemptAt = leasedUntil
+			deliveries = append(deliveries, *d)
+		}
+	}
+	return &deliveries, nil
+}
+
+func (m *memoryRepo) UpdateDelivery(_ context.Context, delivery *domain.WebhookDelivery) error {
+	for i, d := range m.deliveries {
+		if d.ID == delivery.ID {
+			updated := *delivery
+			m.deliveries[i] = &updated
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) FindDeliveries(_ context.Context, subscriptionId string, status domain.WebhookDeliveryStatus, limit int) (*[]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.SubscriptionID == subscriptionId && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, *d)
+		}
+	}
+	return &deliveries, nil
+}
+
+// a received delivery
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+type DelivererSuite struct {
+	suite.Suite
+	repo       *memoryRepo
+	dispatcher *Dispatcher
+	deliverer  *Deliverer
+
+	receiver *httptest.Server
+	// status code the receiver responds with
+	status int
+	// called when a delivery is received, before responding
+	onReceive func()
+	mu        sync.Mutex
+	received  []received
+}
+
+func (suite *DelivererSuite) SetupTest() {
+	suite.status = http.StatusOK
+	suite.onReceive = func() {}
+	suite.received = nil
+	suite.receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		suite.mu.Lock()
+		suite.received = append(suite.received, received{header: r.Header, body: body})
+		suite.mu.Unlock()
+		suite.onReceive()
+		w.WriteHeader(suite.status)
+	}))
+
+	suite.repo = &memoryRepo{subscriptions: map[string]*domain.WebhookSubscription{}, roles: map[string]string{"admin": domain.RoleAdmin}}
+	suite.repo.InsertSubscription(context.TODO(), &domain.WebhookSubscription{
+		ID:         "orders",
+		UserID:     "admin",
+		URL:        suite.receiver.URL,
+		EventTypes: []domain.EventType{domain.EventOrderCreated, domain.EventOrderStatusChanged},
+		Secret:     "whsec_test",
+		Active:     true,
+	})
+	suite.dispatcher = NewDispatcher(suite.repo)
+	suite.deliverer = NewDeliverer(suite.repo, config.WebhookConfig{
+		MaxAttempts: 3,
+		// retries are due right away, so the test can attempt them without waiting
+		Backoff:    time.Nanosecond,
+		MaxBackoff: time.Nanosecond,
+		Timeout:    time.Second,
+		// the receiver runs on the loopback address
+		AllowPrivateNetworks: true,
+	}, time.Second, 10, log.NewNilLogger())
+}
+
+func (suite *DelivererSuite) TearDownTest() {
+	suite.receiver.Close()
+}
+
+func TestDelivererTestSuite(t *testing.T) {
+	suite.Run(t, new(DelivererSuite))
+}
+
+func (suite *DelivererSuite) enqueue(eventType domain.EventType) {
+	event, _ := domain.NewEvent(eventType, "order-1", domain.OrderCreatedPayload{OrderID: "order-1"})
+	event.ID = "event-" + string(eventType)
+	err := suite.dispatcher.Enqueue(context.TODO(), event)
+	if err != nil {
+		suite.T().Fatalf("Error enqueueing event: %s", err)
+	}
+}
+
+func (suite *DelivererSuite) TestDeliversSignedEvent() {
+	suite.enqueue(domain.EventOrderCreated)
+	// not subscribed to, so not delivered
+	suite.enqueue(domain.EventProductUpdated)
+
+	attempted, err := suite.deliverer.DeliverBatch(context.TODO())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, attempted)
+	if assert.Len(suite.T(), suite.received, 1) {
+		header := suite.received[0].header
+		assert.Equal(suite.T(), string(domain.EventOrderCreated), header.Get(HeaderEvent))
+		assert.True(suite.T(), Verify("whsec_test", header.Get(HeaderTimestamp), suite.received[0].body, header.Get(HeaderSignature)))
+		assert.False(suite.T(), Verify("other", header.Get(HeaderTimestamp), suite.received[0].body, header.Get(HeaderSignature)))
+		assert.Contains(suite.T(), string(suite.received[0].body), `"orderId":"order-1"`)
+	}
+
+	deliveries, _ := suite.repo.FindDeliveries(context.TODO(), "orders", "", 10)
+	if assert.Len(suite.T(), *deliveries, 1) {
+		delivery := (*deliveries)[0]
+		assert.Equal(suite.T(), domain.DeliverySucceeded, delivery.Status)
+		assert.Equal(suite.T(), 1, delivery.Attempts)
+		assert.Equal(suite.T(), http.StatusOK, delivery.LastStatusCode)
+		assert.NotNil(suite.T(), delivery.DeliveredAt)
+	}
+}
+
+func (suite *DelivererSuite) TestRetriesAndDeadLetters() {
+	suite.status = http.StatusInternalServerError
+	suite.enqueue(domain.EventOrderStatusChanged)
+
+	suite.deliverer.DeliverBatch(context.TODO())
+	deliveries, _ := suite.repo.FindDeliveries(context.TODO(), "orders", domain.DeliveryPending, 10)
+	if assert.Len(suite.T(), *deliveries, 1) {
+		assert.Equal(suite.T(), 1, (*deliveries)[0].Attempts)
+		assert.Equal(suite.T(), http.StatusInternalServerError, (*deliveries)[0].LastStatusCode)
+		assert.Contains(suite.T(), (*deliveries)[0].LastError, "500")
+	}
+
+	suite.deliverer.DeliverBatch(context.TODO())
+	suite.deliverer.DeliverBatch(context.TODO())
+	// dead-lettered deliveries aren't attempted anymore
+	attempted, _ := suite.deliverer.DeliverBatch(context.TODO())
+
+	assert.Equal(suite.T(), 0, attempted)
+	assert.Len(suite.T(), suite.received, 3)
+	deliveries, _ = suite.repo.FindDeliveries(context.TODO(), "orders", domain.DeliveryDeadLettered, 10)
+	if assert.Len(suite.T(), *deliveries, 1) {
+		assert.Equal(suite.T(), 3, (*deliveries)[0].Attempts)
+	}
+}
+
+func (suite *DelivererSuite) TestRelayedEventIsDeliveredOnce() {
+	suite.enqueue(domain.EventOrderCreated)
+	suite.enqueue(domain.EventOrderCreated)
+
+	suite.deliverer.DeliverBatch(context.TODO())
+
+	assert.Len(suite.T(), suite.received, 1)
+}
+
+func (suite *DelivererSuite) TestClaimedDeliveryIsSentOnce() {
+	suite.enqueue(domain.EventOrderCreated)
+	// another deliverer runs while the delivery is being sent
+	other := NewDeliverer(suite.repo, config.WebhookConfig{Timeout: time.Second, AllowPrivateNetworks: true}, time.Second, 10, log.NewNilLogger())
+	otherAttempted := -1
+	suite.onReceive = func() {
+		otherAttempted, _ = other.DeliverBatch(context.TODO())
+	}
+
+	attempted, err := suite.deliverer.DeliverBatch(context.TODO())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, attempted)
+	assert.Equal(suite.T(), 0, otherAttempted)
+	assert.Len(suite.T(), suite.received, 1)
+}
+
+func (suite *DelivererSuite) TestEventsAreOnlyDeliveredToAdmins() {
+	suite.repo.roles["admin"] = domain.RoleUser
+	suite.enqueue(domain.EventOrderCreated)
+
+	attempted, _ := suite.deliverer.DeliverBatch(context.TODO())
+
+	assert.Equal(suite.T(), 0, attempted)
+	assert.Len(suite.T(), suite.received, 0)
+}
+
+func (suite *DelivererSuite) TestInternalReceiversAreNotCalled() {
+	deliverer := NewDeliverer(suite.repo, config.WebhookConfig{MaxAttempts: 3, Timeout: time.Second},
+		time.Second, 10, log.NewNilLogger())
+	suite.enqueue(domain.EventOrderCreated)
+
+	attempted, _ := deliverer.DeliverBatch(context.TODO())
+
+	assert.Equal(suite.T(), 1, attempted)
+	assert.Len(suite.T(), suite.received, 0)
+	deliveries, _ := suite.repo.FindDeliveries(context.TODO(), "orders", domain.DeliveryPending, 10)
+	if assert.Len(suite.T(), *deliveries, 1) {
+		assert.Contains(suite.T(), (*deliveries)[0].LastError, ErrForbiddenAddress.Error())
+	}
+}
+
+func (suite *DelivererSuite) TestBackoffDoubles() {
+	deliverer := NewDeliverer(suite.repo, config.WebhookConfig{Backoff: time.Second, MaxBackoff: 10 * time.Second},
+		time.Second, 10, log.NewNilLogger())
+
+	assert.Equal(suite.T(), time.Second, deliverer.Backoff(1))
+	assert.Equal(suite.T(), 2*time.Second, deliverer.Backoff(2))
+	assert.Equal(suite.T(), 8*time.Second, deliverer.Backoff(4))
+	assert.Equal(suite.T(), 10*time.Second, deliverer.Backoff(5))
+}
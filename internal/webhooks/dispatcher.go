@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/domain"
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/pkg/errors"
+)
+
+// Dispatcher turns published events into pending deliveries, one per interested subscription
+// It is subscribed to the event bus, so the deliveries are stored in the transaction the relay records the event handled in
+type Dispatcher struct {
+	repo ports.WebhookRepo
+}
+
+func NewDispatcher(repo ports.WebhookRepo) *Dispatcher {
+	return &Dispatcher{
+		repo: repo,
+	}
+}
+
+// Handles an event from the bus
+func (d *Dispatcher) Enqueue(ctx context.Context, event *domain.Event) error {
+	// events carry the data of every user, so they're only delivered to the subscriptions of the admins
+	subscriptions, err := d.repo.FindSubscriptionsForEvent(ctx, event.Type, domain.RoleAdmin)
+	if err != nil {
+		return errors.Wrap(err, "find webhook subscriptions")
+	}
+	if len(*subscriptions) == 0 {
+		return nil
+	}
+	// receivers get the whole event, so they can tell events apart by id
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "marshal event")
+	}
+	for _, subscription := range *subscriptions {
+		err = d.repo.InsertDelivery(ctx, &domain.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        body,
+			Status:         domain.DeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+		if err != nil {
+			return errors.Wrap(err, "store webhook delivery")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package webhooks
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"syscall"
+
+	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/core/ports"
+	"github.com/pkg/errors"
+)
+
+var _ ports.WebhookURLChecker = (*Guard)(nil)
+
+// ErrForbiddenAddress is returned for receivers on a loopback, private or link-local address
+var ErrForbiddenAddress = errors.New("must not point to a loopback, private or link-local address")
+
+// Guard keeps the webhooks from reaching into the internal network, where the subscriptions could probe
+// the services which aren't exposed (SSRF)
+type Guard struct {
+	allowPrivate bool
+	resolver     *net.Resolver
+}
+
+// allowPrivate lets the receivers run on any address, it is only meant for development
+func NewGuard(allowPrivate bool) *Guard {
+	return &Guard{
+		allowPrivate: allowPrivate,
+		resolver:     net.DefaultResolver,
+	}
+}
+
+// Checks the URL is an http(s) one, whose host doesn't resolve to a forbidden address
+// A host which doesn't resolve (yet) is accepted, the address is checked again whenever a delivery connects
+func (g *Guard) CheckURL(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || len(target.Hostname()) == 0 {
+		return errors.New("must be an http or https URL")
+	}
+	if g.allowPrivate {
+		return nil
+	}
+	if ip := net.ParseIP(target.Hostname()); ip != nil {
+		return checkIP(ip)
+	}
+	addrs, err := g.resolver.LookupIPAddr(ctx, target.Hostname())
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := checkIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set on the dialer of the deliveries, so the address actually connected to is checked, whatever the host
+// resolved to when the subscription was created
+func (g *Guard) Control(network, address string, _ syscall.RawConn) error {
+	if g.allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errors.Errorf("unexpected address %s", address)
+	}
+	return checkIP(ip)
+}
+
+func checkIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
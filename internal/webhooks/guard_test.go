@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://203.0.113.10/hooks", true},
+		{"ftp://203.0.113.10/hooks", false},
+		{"http://127.0.0.1:8080/hooks", false},
+		{"http://[::1]/hooks", false},
+		{"http://[::ffff:127.0.0.1]/hooks", false},
+		{"http://10.1.2.3/hooks", false},
+		{"http://172.16.0.1/hooks", false},
+		{"http://192.168.1.1/hooks", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[fe80::1]/hooks", false},
+		{"http://0.0.0.0/hooks", false},
+		{"http://localhost/hooks", false},
+	}
+	guard := NewGuard(false)
+	for _, test := range tests {
+		err := guard.CheckURL(context.TODO(), test.url)
+		assert.Equal(t, test.ok, err == nil, test.url)
+	}
+
+	// development setups may run the receivers locally
+	assert.Nil(t, NewGuard(true).CheckURL(context.TODO(), "http://127.0.0.1:8080/hooks"))
+}
+
+func TestControlChecksTheConnectedAddress(t *testing.T) {
+	guard := NewGuard(false)
+
+	assert.Nil(t, guard.Control("tcp4", "203.0.113.10:443", nil))
+	assert.Equal(t, ErrForbiddenAddress, guard.Control("tcp4", "127.0.0.1:443", nil))
+	assert.Equal(t, ErrForbiddenAddress, guard.Control("tcp6", "[fd00::1]:443", nil))
+}
@@ -0,0 +1,46 @@
+// Delivery of domain events to the HTTP endpoints of webhook subscriptions
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderDeliveryID = "X-Webhook-Id"
+	HeaderEvent      = "X-Webhook-Event"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// Signs the delivery body with the subscription secret
+// The timestamp is part of the signed content, so receivers can reject replayed deliveries
+// Returns the value of the signature header, in form of sha256=<hex encoded HMAC>
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Checks the signature header of a delivery, as a receiver would
+// timestamp is the value of the timestamp header
+func Verify(secret string, timestamp string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	expected := Sign(secret, time.Unix(unix, 0), body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -2,7 +2,12 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/app"
 	"github.com/mitrovicsoftcoder/go-hexagonal-framework/internal/config"
@@ -13,22 +18,58 @@ import (
 func runServer() error {
 	app := app.MustInitializeApp()
 
-	cfg := config.ServerConfig{
-		Port:   app.Config.Http.Port,
-		Logger: app.Logger,
-	}
+	cfg := app.Config.Http
+	cfg.Logger = app.Logger
+	cfg.ErrorReporting.DSN = app.Config.SentryDSN
+	cfg.ErrorReporting.Release = app.Config.Version
+	cfg.ErrorReporting.Environment = string(app.Config.Env)
 
 	srv := server.NewServer(cfg, app.DB)
 
+	// SIGTERM is sent by the orchestrators on deploys, SIGINT by ctrl+c
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
 	app.Logger.Infof("Server started at %d", cfg.Port)
-	err := srv.ListenAndServe("local", "domain")
-	if err != nil {
-		return errors.Wrap(err, "listen and serve")
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe("local", "domain")
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		err = errors.Wrap(err, "listen and serve")
+	case sig := <-signals:
+		app.Logger.Info("shutdown: received signal", "signal", sig.String())
+	}
+	// a second signal skips the draining
+	signal.Reset(syscall.SIGINT, syscall.SIGTERM)
+
+	timeout := cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = server.DefaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if shutdownErr := srv.Shutdown(ctx); shutdownErr != nil && err == nil {
+		err = errors.Wrap(shutdownErr, "shutdown")
 	}
-	return nil
+
+	// the pool is closed last, the workers use it until they stop
+	app.Logger.Info("shutdown: closing the database pool")
+	if closeErr := app.DB.Close(); closeErr != nil && err == nil {
+		err = errors.Wrap(closeErr, "close database")
+	}
+	app.Logger.Info("shutdown: done")
+	return err
 }
 
 func main() {
+	configFile := flag.String("config", "", "path of the config file, secrets/config.yaml by default")
+	flag.Parse()
+	config.SetFile(*configFile)
+
 	err := runServer()
 	if err != nil {
 		fmt.Printf("failed starting app: %s", err)
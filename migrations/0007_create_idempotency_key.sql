@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS hex_fwk.idempotency_key
+(
+    key VARCHAR(255) NOT NULL,
+    scope VARCHAR(64) NOT NULL,
+    PRIMARY KEY(key, scope),
+
+    fingerprint VARCHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    response_body BYTEA,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
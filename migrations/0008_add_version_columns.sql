@@ -0,0 +1,3 @@
+ALTER TABLE hex_fwk.category ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE hex_fwk.product ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE hex_fwk.order ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS hex_fwk.outbox
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    event_type VARCHAR(75) NOT NULL,
+    aggregate_id VARCHAR(75) NOT NULL,
+    payload JSONB NOT NULL,
+
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unpublished_idx ON hex_fwk.outbox (occurred_at) WHERE published_at IS NULL;
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS hex_fwk.webhook_subscription
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    user_id UUID NOT NULL REFERENCES hex_fwk.user(id) ON DELETE CASCADE,
+    url VARCHAR(2048) NOT NULL,
+    event_types VARCHAR(75)[] NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS hex_fwk.webhook_delivery
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    subscription_id UUID NOT NULL REFERENCES hex_fwk.webhook_subscription(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(75) NOT NULL,
+    payload JSONB NOT NULL,
+
+    status VARCHAR(75) NOT NULL DEFAULT 'PENDING',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP,
+
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON hex_fwk.webhook_delivery (next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS webhook_delivery_subscription_idx ON hex_fwk.webhook_delivery (subscription_id, created_at);
@@ -0,0 +1 @@
+ALTER TABLE hex_fwk.user ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'en';
@@ -0,0 +1,18 @@
+-- users created before email verification existed are considered verified
+ALTER TABLE hex_fwk.user ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP DEFAULT NOW();
+ALTER TABLE hex_fwk.user ALTER COLUMN email_verified_at DROP DEFAULT;
+-- bumped to revoke all the JWTs issued to the user
+ALTER TABLE hex_fwk.user ADD COLUMN IF NOT EXISTS session_version INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS hex_fwk.user_token
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    user_id UUID NOT NULL REFERENCES hex_fwk.user(id) ON DELETE CASCADE,
+    purpose VARCHAR(75) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
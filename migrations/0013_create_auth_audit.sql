@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS hex_fwk.auth_audit
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    event VARCHAR(75) NOT NULL,
+    -- the email attempted, which may not belong to any user
+    email VARCHAR(255) NOT NULL,
+    user_id UUID REFERENCES hex_fwk.user(id) ON DELETE SET NULL,
+    -- the admin who acted on the account, if any
+    actor_id UUID REFERENCES hex_fwk.user(id) ON DELETE SET NULL,
+    ip VARCHAR(45) NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS auth_audit_email_idx ON hex_fwk.auth_audit (email, created_at);
+CREATE INDEX IF NOT EXISTS auth_audit_ip_idx ON hex_fwk.auth_audit (ip, created_at);
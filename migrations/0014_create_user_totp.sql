@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS hex_fwk.user_totp
+(
+    user_id UUID PRIMARY KEY REFERENCES hex_fwk.user(id) ON DELETE CASCADE,
+
+    secret VARCHAR(64) NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS hex_fwk.user_recovery_code
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    user_id UUID NOT NULL REFERENCES hex_fwk.user(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP,
+
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS hex_fwk.api_key
+(
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes VARCHAR(75)[] NOT NULL,
+    created_by UUID REFERENCES hex_fwk.user(id) ON DELETE SET NULL,
+
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
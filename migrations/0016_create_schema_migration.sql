@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS hex_fwk.schema_migration
+(
+    name VARCHAR(255) PRIMARY KEY,
+
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,8 @@
+-- failed events are retried after a backoff, and dead-lettered once they run out of attempts
+ALTER TABLE hex_fwk.outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE hex_fwk.outbox ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP;
+-- the subscribers which handled the event already, so a retry doesn't hand it to them again
+ALTER TABLE hex_fwk.outbox ADD COLUMN IF NOT EXISTS delivered_to VARCHAR(75)[] NOT NULL DEFAULT '{}';
+
+DROP INDEX IF EXISTS hex_fwk.outbox_unpublished_idx;
+CREATE INDEX IF NOT EXISTS outbox_due_idx ON hex_fwk.outbox (occurred_at) WHERE published_at IS NULL AND dead_at IS NULL;
@@ -0,0 +1,7 @@
+-- the price the product was ordered at, so later price changes don't change the totals of the orders
+ALTER TABLE hex_fwk.order_product ADD COLUMN IF NOT EXISTS unit_price NUMERIC(19, 2);
+
+-- the orders placed before only have the current prices to go by
+UPDATE hex_fwk.order_product op SET unit_price = p.price FROM hex_fwk.product p WHERE p.id = op.product_id AND op.unit_price IS NULL;
+UPDATE hex_fwk.order_product SET unit_price = 0 WHERE unit_price IS NULL;
+ALTER TABLE hex_fwk.order_product ALTER COLUMN unit_price SET NOT NULL;
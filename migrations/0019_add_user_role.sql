@@ -0,0 +1,3 @@
+-- admins are promoted in the database, the stored role applies from their next request on
+-- the databases which ran 0013 before it was split off already have it
+ALTER TABLE hex_fwk.user ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'USER';